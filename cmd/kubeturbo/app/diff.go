@@ -0,0 +1,89 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/turbonomic/kubeturbo/pkg/discovery"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/diff"
+
+	"github.com/spf13/pflag"
+)
+
+const (
+	// The exit codes of the diff command, following diff(1).
+	DiffExitSame      = 0
+	DiffExitDifferent = 1
+	DiffExitError     = 2
+
+	diffOutputText = "text"
+	diffOutputJSON = "json"
+)
+
+// DiffCommand compares two saved discovery snapshots.
+type DiffCommand struct {
+	// The relative change of a commodity capacity or used value above which it is reported.
+	Threshold float64
+	// The output format, text or json.
+	Output string
+}
+
+func NewDiffCommand() *DiffCommand {
+	return &DiffCommand{
+		Threshold: 0.1,
+		Output:    diffOutputText,
+	}
+}
+
+// AddFlags adds flags for the diff command to the specified FlagSet
+func (c *DiffCommand) AddFlags(fs *pflag.FlagSet) {
+	fs.Float64Var(&c.Threshold, "threshold", c.Threshold, "The relative change of a commodity capacity or used value above which it is reported, e.g. 0.1 for 10%")
+	fs.StringVar(&c.Output, "output", c.Output, "The output format, text or json")
+}
+
+// Run compares the old and the new protobuf snapshots in args, and writes the difference to out. It returns the exit
+// code: 0 if there is no difference, 1 if there is, and 2 on error.
+func (c *DiffCommand) Run(args []string, out, errOut io.Writer) int {
+	if len(args) != 2 {
+		fmt.Fprintln(errOut, "Usage: kubeturbo diff [--threshold=0.1] [--output=text|json] <old snapshot> <new snapshot>")
+		return DiffExitError
+	}
+	if c.Threshold < 0 {
+		fmt.Fprintf(errOut, "Threshold %v must not be negative\n", c.Threshold)
+		return DiffExitError
+	}
+	if c.Output != diffOutputText && c.Output != diffOutputJSON {
+		fmt.Fprintf(errOut, "Output format %s is not text or json\n", c.Output)
+		return DiffExitError
+	}
+
+	oldResponse, err := discovery.LoadSnapshot(args[0])
+	if err != nil {
+		fmt.Fprintf(errOut, "Failed to load old snapshot: %s\n", err)
+		return DiffExitError
+	}
+	newResponse, err := discovery.LoadSnapshot(args[1])
+	if err != nil {
+		fmt.Fprintf(errOut, "Failed to load new snapshot: %s\n", err)
+		return DiffExitError
+	}
+
+	report := diff.Compare(oldResponse.GetEntityDTO(), newResponse.GetEntityDTO(), c.Threshold)
+	if c.Output == diffOutputJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	} else {
+		err = report.WriteText(out)
+	}
+	if err != nil {
+		fmt.Fprintf(errOut, "Failed to write the difference: %s\n", err)
+		return DiffExitError
+	}
+
+	if report.IsEmpty() {
+		return DiffExitSame
+	}
+	return DiffExitDifferent
+}
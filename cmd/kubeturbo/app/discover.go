@@ -0,0 +1,146 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	restclient "k8s.io/client-go/rest"
+
+	kubeturbo "github.com/turbonomic/kubeturbo/pkg"
+	"github.com/turbonomic/kubeturbo/pkg/discovery"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/configs"
+
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+	"github.com/turbonomic/turbo-go-sdk/pkg/service"
+
+	"github.com/golang/glog"
+	"github.com/spf13/pflag"
+)
+
+const (
+	discoverOutputJSON  = "json"
+	discoverOutputTable = "table"
+)
+
+// DiscoverCommand discovers the cluster directly and prints the entities, without connecting to Turbo.
+type DiscoverCommand struct {
+	*VMTServer
+
+	// Discover once and exit, otherwise discover every Interval until killed.
+	Once     bool
+	Interval time.Duration
+	// The output format, json or table.
+	Output string
+}
+
+func NewDiscoverCommand() *DiscoverCommand {
+	return &DiscoverCommand{
+		VMTServer: NewVMTServer(),
+		Once:      true,
+		Interval:  10 * time.Minute,
+		Output:    discoverOutputTable,
+	}
+}
+
+// AddFlags adds flags for the discover command to the specified FlagSet, including those of the service which
+// configure the cluster connection and the discovery.
+func (c *DiscoverCommand) AddFlags(fs *pflag.FlagSet) {
+	c.VMTServer.AddFlags(fs)
+	fs.BoolVar(&c.Once, "once", c.Once, "Discover once and exit. Otherwise discover every --interval until killed")
+	fs.DurationVar(&c.Interval, "interval", c.Interval, "The interval of discovering the cluster if --once is false")
+	fs.StringVar(&c.Output, "output", c.Output, "The output format, json or table")
+}
+
+// Run discovers the cluster of --kubeconfig or --master as the target in --turboconfig, or as a default target if
+// --turboconfig is not given, and writes the discovery responses to out.
+func (c *DiscoverCommand) Run(out io.Writer) error {
+	if c.Output != discoverOutputJSON && c.Output != discoverOutputTable {
+		return fmt.Errorf("output format %s is not json or table", c.Output)
+	}
+	if !c.Once && c.Interval <= 0 {
+		return fmt.Errorf("discovery interval %s must be positive", c.Interval)
+	}
+	if err := c.checkFlag(); err != nil {
+		return err
+	}
+
+	kubeConfig, err := c.createKubeConfig()
+	if err != nil {
+		return err
+	}
+	kubeClient, err := c.createKubeClient(kubeConfig)
+	if err != nil {
+		return err
+	}
+	k8sTAPSpec, err := c.loadK8sTAPServiceSpec()
+	if err != nil {
+		return err
+	}
+	probeConfig, err := c.createProbeConfig(kubeConfig, k8sTAPSpec, "")
+	if err != nil {
+		return fmt.Errorf("failed to build probe config: %s", err)
+	}
+
+	discoveryConfig := discovery.NewDiscoveryConfig(kubeClient, probeConfig, getTargetConfig(k8sTAPSpec, kubeConfig))
+	defer close(discoveryConfig.StopEverything)
+	discoveryClient, err := discovery.NewK8sDiscoveryClient(discoveryConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create discovery client: %s", err)
+	}
+	for {
+		response, err := discoveryClient.Discover(nil)
+		if err == nil {
+			err = c.writeDiscoveryResponse(out, response)
+		}
+		if c.Once {
+			return err
+		}
+		if err != nil {
+			glog.Errorf("Discovery failed: %s", err)
+		}
+		time.Sleep(c.Interval)
+	}
+}
+
+// Load the spec in --turboconfig for the commands run without connecting to Turbo. A spec without any Turbo server
+// or target is used if it is not given.
+func (s *VMTServer) loadK8sTAPServiceSpec() (*kubeturbo.K8sTAPServiceSpec, error) {
+	if s.K8sTAPSpec == "" {
+		return &kubeturbo.K8sTAPServiceSpec{TurboCommunicationConfig: &service.TurboCommunicationConfig{}}, nil
+	}
+	k8sTAPSpec, err := kubeturbo.ParseK8sTAPServiceSpec(s.K8sTAPSpec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load turboconfig: %s", err)
+	}
+	return k8sTAPSpec, nil
+}
+
+// Get the target config in the spec, or a default one identified by the API server address if there is none, e.g.
+// when the spec only lists clusters.
+func getTargetConfig(k8sTAPSpec *kubeturbo.K8sTAPServiceSpec, kubeConfig *restclient.Config) *configs.K8sTargetConfig {
+	if k8sTAPSpec.K8sTargetConfig != nil {
+		return k8sTAPSpec.K8sTargetConfig
+	}
+	return configs.NewK8sTargetConfig("Cloud Native", "Kubernetes", kubeConfig.Host, "", "")
+}
+
+func (c *DiscoverCommand) writeDiscoveryResponse(out io.Writer, response *proto.DiscoveryResponse) error {
+	if c.Output == discoverOutputJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(response); err != nil {
+			return fmt.Errorf("failed to write the discovery response: %s", err)
+		}
+		return nil
+	}
+
+	if err := discovery.WriteEntityTable(out, response.GetEntityDTO()); err != nil {
+		return fmt.Errorf("failed to write the discovery response: %s", err)
+	}
+	for _, errorDTO := range response.GetErrorDTO() {
+		fmt.Fprintf(out, "%s: %s\n", errorDTO.GetSeverity(), errorDTO.GetDescription())
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+
+	kubeturbo "github.com/turbonomic/kubeturbo/pkg"
+	"github.com/turbonomic/kubeturbo/pkg/action"
+	"github.com/turbonomic/kubeturbo/pkg/action/turboaction"
+	discutil "github.com/turbonomic/kubeturbo/pkg/discovery/util"
+
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+
+	"github.com/spf13/pflag"
+)
+
+const (
+	execActionMove  = "move"
+	execActionScale = "scale"
+
+	execUsage = "Usage: kubeturbo exec move --pod <namespace/name> --node <node> | " +
+		"kubeturbo exec scale --pod <namespace/name> --delta <N>"
+)
+
+// ExecCommand executes an action with the action executors and the action supervisor of the service, without
+// connecting to Turbo.
+type ExecCommand struct {
+	*VMTServer
+
+	// The pod to move, or a pod of the replication controller or deployment to scale, as namespace/name.
+	Pod string
+	// The name of the node to move the pod to.
+	Node string
+	// The number of replicas to add if positive, or to remove if negative.
+	Delta int
+}
+
+func NewExecCommand() *ExecCommand {
+	return &ExecCommand{
+		VMTServer: NewVMTServer(),
+	}
+}
+
+// AddFlags adds flags for the exec command to the specified FlagSet, including those of the service which configure
+// the cluster connection.
+func (c *ExecCommand) AddFlags(fs *pflag.FlagSet) {
+	c.VMTServer.AddFlags(fs)
+	fs.StringVar(&c.Pod, "pod", c.Pod, "The pod to move, or a pod of the replication controller or deployment to scale, as namespace/name")
+	fs.StringVar(&c.Node, "node", c.Node, "The name of the node to move the pod to")
+	fs.IntVar(&c.Delta, "delta", c.Delta, "The number of replicas to add if positive, or to remove if negative. The replicas are changed one by one")
+}
+
+// Run executes the action in args, move or scale, and writes each state of the turbo actions to out.
+func (c *ExecCommand) Run(args []string, out io.Writer) error {
+	if len(args) != 1 || (args[0] != execActionMove && args[0] != execActionScale) {
+		return errors.New(execUsage)
+	}
+	podNamespace, podName, err := parsePodName(c.Pod)
+	if err != nil {
+		return err
+	}
+	switch {
+	case args[0] == execActionMove && c.Node == "":
+		return fmt.Errorf("--node is required to move a pod")
+	case args[0] == execActionScale && c.Delta == 0:
+		return fmt.Errorf("--delta is required to scale a pod")
+	}
+	if err := c.checkFlag(); err != nil {
+		return err
+	}
+
+	kubeConfig, err := c.createKubeConfig()
+	if err != nil {
+		return err
+	}
+	k8sTAPSpec, err := c.loadK8sTAPServiceSpec()
+	if err != nil {
+		return err
+	}
+	vmtConfig, err := c.createVMTConfig(kubeConfig, k8sTAPSpec, "")
+	if err != nil {
+		return fmt.Errorf("failed to create turbo configuration: %s", err)
+	}
+	defer close(vmtConfig.StopEverything)
+	actionHandler := kubeturbo.NewClusterActionHandler(vmtConfig)
+
+	pod, err := vmtConfig.Client.CoreV1().Pods(podNamespace).Get(podName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get pod %s: %s", c.Pod, err)
+	}
+
+	if args[0] == execActionMove {
+		node, err := vmtConfig.Client.CoreV1().Nodes().Get(c.Node, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get node %s: %s", c.Node, err)
+		}
+		return executeActionItem(out, actionHandler, action.NewMoveActionItem(newExecActionUUID(), pod, node))
+	}
+
+	// Change the replicas one by one, as Turbo does.
+	steps := c.Delta
+	if steps < 0 {
+		steps = -steps
+	}
+	for i := 0; i < steps; i++ {
+		// A pod removed by scaling in is replaced by another pod of the same parent.
+		pod, err = getPodOrSibling(vmtConfig.Client, pod)
+		if err != nil {
+			return err
+		}
+		var actionItem *proto.ActionItemDTO
+		if c.Delta > 0 {
+			actionItem = action.NewProvisionActionItem(newExecActionUUID(), pod)
+		} else {
+			actionItem = action.NewUnbindActionItem(newExecActionUUID(), pod)
+		}
+		if err := executeActionItem(out, actionHandler, actionItem); err != nil {
+			return fmt.Errorf("step %d of %d failed: %s", i+1, steps, err)
+		}
+	}
+	return nil
+}
+
+func parsePodName(fullName string) (string, string, error) {
+	parts := strings.Split(fullName, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("pod %q is not namespace/name", fullName)
+	}
+	return parts[0], parts[1], nil
+}
+
+func newExecActionUUID() string {
+	return fmt.Sprintf("kubeturbo-exec-%d", time.Now().UnixNano())
+}
+
+func executeActionItem(out io.Writer, actionHandler *action.ActionHandler, actionItem *proto.ActionItemDTO) error {
+	fmt.Fprintf(out, "%s %s %s %s: pending\n", time.Now().Format(time.RFC3339), actionItem.GetActionType(),
+		actionItem.GetTargetSE().GetEntityType(), actionItem.GetTargetSE().GetDisplayName())
+	_, err := actionHandler.ExecuteActionItem(actionItem, func(turboAction *turboaction.TurboAction) {
+		writeTurboAction(out, turboAction)
+	})
+	return err
+}
+
+// Write the state of a turbo action as one line, followed by its spec as JSON.
+func writeTurboAction(out io.Writer, turboAction *turboaction.TurboAction) {
+	content := turboAction.Content
+	spec, err := json.Marshal(content.ActionSpec)
+	if err != nil {
+		spec = []byte(err.Error())
+	}
+	fmt.Fprintf(out, "%s %s %s %s/%s: %s %s\n", time.Now().Format(time.RFC3339), content.ActionType,
+		content.TargetObject.TargetObjectType, content.TargetObject.TargetObjectNamespace,
+		content.TargetObject.TargetObjectName, turboAction.Status, spec)
+}
+
+// Get the pod again, or another running pod of the same parent if it is removed.
+func getPodOrSibling(kubeClient *kubernetes.Clientset, pod *apiv1.Pod) (*apiv1.Pod, error) {
+	current, err := kubeClient.CoreV1().Pods(pod.Namespace).Get(pod.Name, metav1.GetOptions{})
+	if err == nil && current.DeletionTimestamp == nil {
+		return current, nil
+	}
+	parent, _ := discutil.FindParentReferenceObject(pod)
+	if parent == nil {
+		return nil, fmt.Errorf("pod %s/%s is removed, and it has no parent", pod.Namespace, pod.Name)
+	}
+	podList, err := kubeClient.CoreV1().Pods(pod.Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods in namespace %s: %s", pod.Namespace, err)
+	}
+	for i := range podList.Items {
+		sibling := &podList.Items[i]
+		if sibling.DeletionTimestamp != nil || sibling.Status.Phase != apiv1.PodRunning {
+			continue
+		}
+		if siblingParent, _ := discutil.FindParentReferenceObject(sibling); siblingParent != nil &&
+			siblingParent.UID == parent.UID {
+			return sibling, nil
+		}
+	}
+	return nil, fmt.Errorf("no running pod of %s %s/%s is left", parent.Kind, parent.Namespace, parent.Name)
+}
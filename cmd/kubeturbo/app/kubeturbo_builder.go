@@ -6,7 +6,10 @@ import (
 	"net/http"
 	"net/http/pprof"
 	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
+	"time"
 
 	"k8s.io/apiserver/pkg/server/healthz"
 	"k8s.io/client-go/kubernetes"
@@ -23,11 +26,16 @@ import (
 	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/k8sconntrack"
 	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/kubelet"
 	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/master"
+	promsource "github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/prometheus"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/sampling"
 	"github.com/turbonomic/kubeturbo/pkg/discovery/stitching"
+	"github.com/turbonomic/kubeturbo/pkg/leaderelection"
+	"github.com/turbonomic/kubeturbo/pkg/metrics"
 	"github.com/turbonomic/kubeturbo/pkg/turbostore"
 	"github.com/turbonomic/kubeturbo/test/flag"
 
 	"github.com/golang/glog"
+	"github.com/pborman/uuid"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/pflag"
 )
@@ -36,10 +44,12 @@ const (
 	// The default port for vmt service server
 	KubeturboPort   = 10265
 	K8sCadvisorPort = 4194
+
+	// Less than the default termination grace period of a pod, 30s.
+	DefaultShutdownTimeout = 25 * time.Second
 )
 
 // VMTServer has all the context and params needed to run a Scheduler
-// TODO: leaderElection is disabled now because of dependency problems.
 type VMTServer struct {
 	Port            int
 	Address         string
@@ -51,25 +61,65 @@ type VMTServer struct {
 	BindPodsBurst   int
 	CAdvisorPort    int
 
-	//LeaderElection componentconfig.LeaderElectionConfiguration
+	LeaderElection leaderelection.LeaderElectionConfiguration
 
 	EnableProfiling bool
 
+	// Serve the read-only JSON endpoints under /debug/ for inspecting the discoveries, actions and configuration.
+	EnableDebugEndpoints bool
+
+	// The max time to wait for the in-flight actions to finish on SIGTERM before disconnecting from Turbo.
+	ShutdownTimeout time.Duration
+
 	// If the underlying infrastructure is VMWare, we cannot reply on IP address for stitching. Instead we use the
 	// systemUUID of each node, which is equal to UUID of corresponding VM discovered by VM probe.
 	// The default value is false.
 	UseVMWare bool
 
 	// Kubelet related config
-	KubeletPort        uint
-	EnableKubeletHttps bool
+	KubeletPort           uint
+	EnableKubeletHttps    bool
+	NetThroughputCapacity float64
+
+	// Prometheus related config
+	PrometheusAddress string
+	PrometheusWindow  time.Duration
+
+	// Usage sampling related config
+	SamplingInterval time.Duration
+	SamplingWindow   time.Duration
+	UsagePercentile  float64
+
+	// Discovery fails if more nodes than this fraction fail to be discovered.
+	MaxFailedNodeFraction float64
+
+	// The number of discovery workers. It is derived from the cluster size if it is 0.
+	DiscoveryWorkerCount int
+
+	// The max time a monitoring source may take to scrape the nodes of a discovery task, unless a timeout is set
+	// for the source in turboconfig.
+	MonitoringTimeout time.Duration
+
+	// The interval of discovering in the background. Discovery is done on request if it is 0.
+	AsyncDiscoveryInterval time.Duration
+
+	// Discovery snapshot related config
+	SnapshotDir       string
+	SnapshotFormat    string
+	MaxSnapshots      int
+	MaxSnapshotSizeMB int
+	ReplaySnapshot    string
+
+	// The fixture of a simulated cluster to discover once instead of connecting to Turbo.
+	Simulate string
 }
 
 // NewVMTServer creates a new VMTServer with default parameters
 func NewVMTServer() *VMTServer {
 	s := VMTServer{
-		Port:    KubeturboPort,
-		Address: "127.0.0.1",
+		Port:           KubeturboPort,
+		Address:        "127.0.0.1",
+		LeaderElection: leaderelection.NewLeaderElectionConfiguration(),
 	}
 	return &s
 }
@@ -84,11 +134,29 @@ func (s *VMTServer) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&s.TestingFlagPath, "testingflag", s.TestingFlagPath, "Path to the testing flag.")
 	fs.StringVar(&s.KubeConfig, "kubeconfig", s.KubeConfig, "Path to kubeconfig file with authorization and master location information.")
 	fs.BoolVar(&s.EnableProfiling, "profiling", false, "Enable profiling via web interface host:port/debug/pprof/.")
+	fs.BoolVar(&s.EnableDebugEndpoints, "debug-endpoints", true, "Serve the read-only JSON endpoints host:port/debug/discovery, /debug/entities, /debug/actions and /debug/config for inspecting the state of kubeturbo")
+	fs.DurationVar(&s.ShutdownTimeout, "shutdown-timeout", DefaultShutdownTimeout, "The max time to wait on SIGTERM for the in-flight actions to be executed, checked and reported before disconnecting from Turbo. New actions are rejected meanwhile. It should be less than the termination grace period of the pod")
 	fs.BoolVar(&s.UseVMWare, "usevmware", false, "If the underlying infrastructure is VMWare.")
 	fs.UintVar(&s.KubeletPort, "kubelet-port", kubelet.DefaultKubeletPort, "The port of the kubelet runs on")
 	fs.BoolVar(&s.EnableKubeletHttps, "kubelet-https", kubelet.DefaultKubeletHttps, "Indicate if Kubelet is running on https server")
-
-	//leaderelection.BindFlags(&s.LeaderElection, fs)
+	fs.Float64Var(&s.NetThroughputCapacity, "net-throughput-capacity", kubelet.DefaultNetThroughputCapacity, "The network throughput capacity of a node in Mbit/s, unless the node has the annotation "+kubelet.NetThroughputCapacityAnnotation)
+	fs.StringVar(&s.PrometheusAddress, "prometheus-address", s.PrometheusAddress, "The address of the Prometheus server, e.g. http://prometheus:9090. If specified, resource usage is retrieved from Prometheus instead of Kubelet")
+	fs.DurationVar(&s.PrometheusWindow, "prometheus-window", promsource.DefaultPrometheusQueryWindow, "The time window over which Prometheus resource usage is averaged")
+	fs.DurationVar(&s.SamplingInterval, "sampling-interval", 0, "The interval of sampling resource usage between discoveries. The peak and percentile of the samples are reported. Sampling is disabled if it is 0")
+	fs.DurationVar(&s.SamplingWindow, "sampling-window", sampling.DefaultSamplingWindow, "Resource usage samples older than the window are dropped")
+	fs.Float64Var(&s.UsagePercentile, "usage-percentile", sampling.DefaultUsagePercentile, "The percentile of the sampled resource usage to report, in (0, 100]")
+	fs.IntVar(&s.DiscoveryWorkerCount, "discovery-workers", 0, "The number of workers discovering the nodes in parallel. If it is 0, one worker per 20 nodes is used, between 4 and 32")
+	fs.DurationVar(&s.AsyncDiscoveryInterval, "async-discovery-interval", 0, "The interval of discovering the cluster in the background. If it is set, a discovery request is answered immediately with the freshest complete result, or the last good result if the latest discovery failed. Discovery is done on request if it is 0")
+	fs.DurationVar(&s.MonitoringTimeout, "monitoring-timeout", configs.DefaultMonitoringTimeout, "The max time a monitoring source may take to scrape the nodes of a discovery worker, unless a timeout is set for the source in turboconfig. The metrics scraped before the timeout are still reported")
+	fs.StringVar(&s.SnapshotDir, "snapshot-dir", "", "The directory to write every discovery response to, for debugging. No snapshot is written if it is empty")
+	fs.StringVar(&s.SnapshotFormat, "snapshot-format", string(configs.SnapshotFormatProtobuf), "The format of the discovery snapshots, json or protobuf. Only protobuf snapshots can be replayed")
+	fs.IntVar(&s.MaxSnapshots, "max-snapshots", 20, "The max number of discovery snapshots kept per target. The oldest are removed first. There is no limit if it is 0")
+	fs.IntVar(&s.MaxSnapshotSizeMB, "max-snapshot-size-mb", 500, "The max total size in MB of the discovery snapshots kept per target. The oldest are removed first. There is no limit if it is 0")
+	fs.StringVar(&s.ReplaySnapshot, "replay-snapshot", "", "The protobuf discovery snapshot to report instead of discovering the cluster, to reproduce a discovery offline")
+	fs.StringVar(&s.Simulate, "simulate", "", "The YAML fixture of a simulated cluster. If it is set, the simulated cluster is discovered once without accessing any cluster or Turbo server, and the discovery response is written to stdout as JSON")
+	fs.Float64Var(&s.MaxFailedNodeFraction, "max-failed-node-fraction", 1, "Discovery fails if the fraction of the nodes that cannot be discovered is more than this, in [0, 1]. By default discovery never fails for failed nodes")
+
+	leaderelection.BindFlags(&s.LeaderElection, fs)
 }
 
 // create an eventRecorder to send events to Kubernetes APIserver
@@ -116,30 +184,110 @@ func (s *VMTServer) createKubeConfig() (*restclient.Config, error) {
 	return kubeConfig, nil
 }
 
+// Create the config to connect to the cluster of the given context in the kubeconfig file. The current context is
+// used if it is empty. --master is ignored, as it cannot apply to all the clusters.
+func (s *VMTServer) createKubeConfigForContext(kubeContext string) (*restclient.Config, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = s.KubeConfig
+	kubeConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules,
+		&clientcmd.ConfigOverrides{CurrentContext: kubeContext}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubeconfig of context %q: %s", kubeContext, err)
+	}
+	// This specifies the number and the max number of query per second to the api server.
+	kubeConfig.QPS = 20.0
+	kubeConfig.Burst = 30
+
+	return kubeConfig, nil
+}
+
 func (s *VMTServer) createKubeClient(kubeConfig *restclient.Config) (*kubernetes.Clientset, error) {
 	kubeClient, err := kubernetes.NewForConfig(kubeConfig)
 	if err != nil {
-		glog.Fatalf("Invalid API configuration: %v", err)
+		return nil, fmt.Errorf("invalid API configuration: %v", err)
 	}
 
 	return kubeClient, nil
 }
 
-func (s *VMTServer) createProbeConfig(kubeConfig *restclient.Config) (*configs.ProbeConfig, error) {
+// Create the probe config of a cluster. The stitching property type is chosen based on --usevmware if pType is empty.
+func (s *VMTServer) createProbeConfig(kubeConfig *restclient.Config, k8sTAPSpec *kubeturbo.K8sTAPServiceSpec,
+	pType stitching.StitchingPropertyType) (*configs.ProbeConfig, error) {
 	if s.CAdvisorPort == 0 {
 		s.CAdvisorPort = K8sCadvisorPort
 	}
 
-	// The default property type for stitching is IP.
-	pType := stitching.IP
+	if pType == "" {
+		pType = s.defaultStitchingPropertyType()
+	}
+
+	probeConfig := &configs.ProbeConfig{
+		CadvisorPort:          s.CAdvisorPort,
+		StitchingPropertyType: pType,
+		SamplingInterval:      s.SamplingInterval,
+		SamplingWindow:        s.SamplingWindow,
+		UsagePercentile:       s.UsagePercentile,
+		MaxFailedNodeFraction: s.MaxFailedNodeFraction,
+		DiscoveryWorkerCount:  s.DiscoveryWorkerCount,
+
+		DefaultMonitoringTimeout: s.MonitoringTimeout,
+		AsyncDiscoveryInterval:   s.AsyncDiscoveryInterval,
+
+		SnapshotDir:      s.SnapshotDir,
+		SnapshotFormat:   configs.SnapshotFormat(s.SnapshotFormat),
+		MaxSnapshots:     s.MaxSnapshots,
+		MaxSnapshotBytes: int64(s.MaxSnapshotSizeMB) * 1024 * 1024,
+		ReplaySnapshot:   s.ReplaySnapshot,
+	}
+
+	// If monitoring sources are specified in turboconfig, only the enabled sources are used.
+	if len(k8sTAPSpec.MonitoringSources) > 0 {
+		monitoringConfigs, monitoringTimeouts, err := monitoring.BuildMonitorWorkerConfigs(k8sTAPSpec.MonitoringSources, kubeConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build monitoring configs: %s", err)
+		}
+		probeConfig.MonitoringConfigs = monitoringConfigs
+		probeConfig.MonitoringTimeouts = monitoringTimeouts
+		return probeConfig, nil
+	}
+
+	monitoringConfigs, err := s.createDefaultMonitoringConfigs(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	probeConfig.MonitoringConfigs = monitoringConfigs
+
+	return probeConfig, nil
+}
+
+// The stitching property type chosen by --usevmware.
+func (s *VMTServer) defaultStitchingPropertyType() stitching.StitchingPropertyType {
 	if s.UseVMWare {
 		// If the underlying hypervisor is vCenter, use UUID.
 		// Refer to Bug: https://vmturbo.atlassian.net/browse/OM-18139
-		pType = stitching.UUID
+		return stitching.UUID
 	}
+	// The default property type for stitching is IP.
+	return stitching.IP
+}
 
-	// Create Kubelet monitoring
-	kubeletMonitoringConfig := kubelet.NewKubeletMonitorConfig(kubeConfig).WithPort(s.KubeletPort).EnableHttps(s.EnableKubeletHttps)
+// Create the monitoring configs based on command line flags.
+func (s *VMTServer) createDefaultMonitoringConfigs(kubeConfig *restclient.Config) ([]monitoring.MonitorWorkerConfig, error) {
+	// Create resource monitoring. Use Prometheus if it is specified, otherwise use Kubelet.
+	var resourceMonitoringConfig monitoring.MonitorWorkerConfig
+	kubeletMonitoringConfig := kubelet.NewKubeletMonitorConfig(kubeConfig).WithPort(s.KubeletPort).EnableHttps(s.EnableKubeletHttps).
+		WithNetThroughputCapacity(s.NetThroughputCapacity)
+	if s.PrometheusAddress != "" {
+		// The kubelets still provide the cpu frequency of the nodes whose node-exporter does not expose it.
+		cpuFrequencyGetter, err := kubelet.NewCpuFrequencyGetter(kubeletMonitoringConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build cpu frequency getter for Prometheus monitor: %s", err)
+		}
+		resourceMonitoringConfig = promsource.NewPrometheusMonitorConfig(s.PrometheusAddress).WithWindow(s.PrometheusWindow).
+			WithCpuFrequencyGetter(cpuFrequencyGetter)
+	} else {
+		resourceMonitoringConfig = kubeletMonitoringConfig
+	}
 
 	// Create cluster monitoring
 	masterMonitoringConfig, err := master.NewClusterMonitorConfig(kubeConfig)
@@ -147,9 +295,8 @@ func (s *VMTServer) createProbeConfig(kubeConfig *restclient.Config) (*configs.P
 		return nil, fmt.Errorf("failed to build monitoring config for master topology monitor: %s", err)
 	}
 
-	// TODO for now kubelet is the only monitoring source. As we have more sources, we should choose what to be added into the slice here.
 	monitoringConfigs := []monitoring.MonitorWorkerConfig{
-		kubeletMonitoringConfig,
+		resourceMonitoringConfig,
 		masterMonitoringConfig,
 	}
 
@@ -158,17 +305,11 @@ func (s *VMTServer) createProbeConfig(kubeConfig *restclient.Config) (*configs.P
 	k8sConntrackMonitoringConfig := k8sconntrack.NewK8sConntrackMonitorConfig()
 	monitoringConfigs = append(monitoringConfigs, k8sConntrackMonitoringConfig)
 
-	probeConfig := &configs.ProbeConfig{
-		CadvisorPort:          s.CAdvisorPort,
-		StitchingPropertyType: pType,
-		MonitoringConfigs:     monitoringConfigs,
-	}
-
-	return probeConfig, nil
+	return monitoringConfigs, nil
 }
 
 func (s *VMTServer) checkFlag() error {
-	if s.KubeConfig == "" && s.Master == "" {
+	if s.KubeConfig == "" && s.Master == "" && s.Simulate == "" {
 		glog.Warningf("Neither --kubeconfig nor --master was specified.  Using default API client.  This might not work.")
 	}
 
@@ -180,6 +321,63 @@ func (s *VMTServer) checkFlag() error {
 		flag.SetPath(s.TestingFlagPath)
 	}
 
+	if s.UsagePercentile <= 0 || s.UsagePercentile > 100 {
+		return fmt.Errorf("usage percentile %v is not in (0, 100]", s.UsagePercentile)
+	}
+
+	if s.DiscoveryWorkerCount < 0 {
+		return fmt.Errorf("discovery worker count %d is negative", s.DiscoveryWorkerCount)
+	}
+
+	if s.MaxFailedNodeFraction < 0 || s.MaxFailedNodeFraction > 1 {
+		return fmt.Errorf("max failed node fraction %v is not in [0, 1]", s.MaxFailedNodeFraction)
+	}
+
+	if s.AsyncDiscoveryInterval < 0 {
+		return fmt.Errorf("async discovery interval %s is negative", s.AsyncDiscoveryInterval)
+	}
+
+	if s.MonitoringTimeout <= 0 {
+		return fmt.Errorf("monitoring timeout %s must be positive", s.MonitoringTimeout)
+	}
+
+	switch configs.SnapshotFormat(s.SnapshotFormat) {
+	case configs.SnapshotFormatJSON, configs.SnapshotFormatProtobuf:
+	default:
+		return fmt.Errorf("snapshot format %s is not json or protobuf", s.SnapshotFormat)
+	}
+
+	if s.MaxSnapshots < 0 || s.MaxSnapshotSizeMB < 0 {
+		return fmt.Errorf("max snapshots %d and max snapshot size %dMB must not be negative", s.MaxSnapshots,
+			s.MaxSnapshotSizeMB)
+	}
+
+	if s.ReplaySnapshot != "" {
+		if _, err := os.Stat(s.ReplaySnapshot); err != nil {
+			return fmt.Errorf("invalid snapshot to replay: %s", err)
+		}
+	}
+
+	if s.Simulate != "" {
+		if _, err := os.Stat(s.Simulate); err != nil {
+			return fmt.Errorf("invalid simulation fixture: %s", err)
+		}
+	}
+
+	if s.NetThroughputCapacity <= 0 {
+		return fmt.Errorf("network throughput capacity %v must be positive", s.NetThroughputCapacity)
+	}
+
+	if s.ShutdownTimeout < 0 {
+		return fmt.Errorf("shutdown timeout %s must not be negative", s.ShutdownTimeout)
+	}
+
+	if s.LeaderElection.LeaderElect {
+		if err := s.LeaderElection.Validate(); err != nil {
+			return err
+		}
+	}
+
 	ip := net.ParseIP(s.Address)
 	if ip == nil {
 		return fmt.Errorf("wrong ip format:%s", s.Address)
@@ -195,6 +393,14 @@ func (s *VMTServer) Run(_ []string) error {
 		os.Exit(1)
 	}
 
+	if s.Simulate != "" {
+		if err := s.RunSimulation(os.Stdout); err != nil {
+			glog.Errorf("Simulation failed: %s", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	glog.V(3).Infof("spec path is: %v", s.K8sTAPSpec)
 	k8sTAPSpec, err := kubeturbo.ParseK8sTAPServiceSpec(s.K8sTAPSpec)
 	if err != nil {
@@ -202,21 +408,122 @@ func (s *VMTServer) Run(_ []string) error {
 		os.Exit(1)
 	}
 
+	var vmtConfigs []*kubeturbo.Config
+	if len(k8sTAPSpec.Clusters) > 0 {
+		vmtConfigs = s.createClusterVMTConfigs(k8sTAPSpec)
+		if len(vmtConfigs) == 0 {
+			glog.Errorf("Failed to set up any of the %d clusters", len(k8sTAPSpec.Clusters))
+			os.Exit(1)
+		}
+	} else {
+		kubeConfig, err := s.createKubeConfig()
+		if err != nil {
+			glog.Error(err)
+		}
+
+		vmtConfig, err := s.createVMTConfig(kubeConfig, k8sTAPSpec, "")
+		if err != nil {
+			glog.Errorf("Failed to create turbo configuration: %s", err)
+			os.Exit(1)
+		}
+		vmtConfigs = append(vmtConfigs, vmtConfig)
+	}
+
+	vmtService := kubeturbo.NewKubeturboService(vmtConfigs...)
+
+	run := func(_ <-chan struct{}) {
+		vmtService.Run()
+		select {}
+	}
+
+	go s.startHttp(vmtService)
+	go s.handleShutdown(vmtService)
+
+	if !s.LeaderElection.LeaderElect {
+		glog.V(2).Infof("No leader election")
+		metrics.LeaderElectionIsLeader.Set(1)
+		run(nil)
+
+		glog.Fatal("this statement is unreachable")
+		panic("unreachable")
+	}
+
+	// Only the leader connects to Turbo, schedules the pending pods and executes actions. The followers keep their
+	// cluster caches warm, and exit once they lose the leadership so that they restart as followers.
+	leaderElector, err := s.createLeaderElector(leaderelection.LeaderCallbacks{
+		OnStartedLeading: func(stop <-chan struct{}) {
+			metrics.LeaderElectionIsLeader.Set(1)
+			run(stop)
+		},
+		OnStoppedLeading: func() {
+			metrics.LeaderElectionIsLeader.Set(0)
+			glog.Fatalf("Lost the leadership of kubeturbo")
+		},
+	})
+	if err != nil {
+		glog.Errorf("Failed to set up leader election: %s", err)
+		os.Exit(1)
+	}
+	leaderElector.Run(nil)
+
+	glog.Fatal("this statement is unreachable")
+	panic("unreachable")
+}
+
+// Stop the service gracefully on SIGTERM or SIGINT, and exit. A second signal exits immediately.
+func (s *VMTServer) handleShutdown(vmtService *kubeturbo.KubeturboService) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	sig := <-signals
+	glog.Infof("Received %s, shutting down.", sig)
+	go func() {
+		sig := <-signals
+		glog.Errorf("Received %s again, exit immediately.", sig)
+		glog.Flush()
+		os.Exit(1)
+	}()
+
+	vmtService.Stop(s.ShutdownTimeout)
+	glog.Flush()
+	os.Exit(0)
+}
+
+// Create the leader elector with the lock in the cluster of --kubeconfig or --master. The identity of a candidate is
+// its host name, followed by a UUID to tell the restarts apart.
+func (s *VMTServer) createLeaderElector(callbacks leaderelection.LeaderCallbacks) (*leaderelection.LeaderElector, error) {
 	kubeConfig, err := s.createKubeConfig()
 	if err != nil {
-		glog.Error(err)
+		return nil, err
+	}
+	kubeClient, err := s.createKubeClient(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the host name: %s", err)
+	}
+	identity := hostname + "_" + uuid.New()
+
+	lock, err := leaderelection.NewResourceLock(s.LeaderElection.ResourceLock, s.LeaderElection.LockNamespace,
+		s.LeaderElection.LockName, kubeClient.CoreV1(), identity)
+	if err != nil {
+		return nil, err
 	}
+	return leaderelection.NewLeaderElector(s.LeaderElection, lock, callbacks)
+}
 
+// Create the turbo configuration of the cluster of the given kubeconfig.
+func (s *VMTServer) createVMTConfig(kubeConfig *restclient.Config, k8sTAPSpec *kubeturbo.K8sTAPServiceSpec,
+	pType stitching.StitchingPropertyType) (*kubeturbo.Config, error) {
 	kubeClient, err := s.createKubeClient(kubeConfig)
 	if err != nil {
-		glog.Errorf("Failed to get kubeClient: %v", err.Error())
-		os.Exit(1)
+		return nil, fmt.Errorf("failed to get kubeClient: %s", err)
 	}
 
-	probeConfig, err := s.createProbeConfig(kubeConfig)
+	probeConfig, err := s.createProbeConfig(kubeConfig, k8sTAPSpec, pType)
 	if err != nil {
-		glog.Errorf("Failed to build probe config: %s")
-		os.Exit(1)
+		return nil, fmt.Errorf("failed to build probe config: %s", err)
 	}
 
 	broker := turbostore.NewPodBroker()
@@ -224,39 +531,51 @@ func (s *VMTServer) Run(_ []string) error {
 	glog.V(3).Infof("Finished creating turbo configuration: %+v", vmtConfig)
 
 	vmtConfig.Recorder = createRecorder(kubeClient)
+	return vmtConfig, nil
+}
 
-	vmtService := kubeturbo.NewKubeturboService(vmtConfig)
-
-	run := func(_ <-chan struct{}) {
-		vmtService.Run()
-		select {}
+// Create the turbo configurations of the clusters in the spec. A cluster which cannot be set up is skipped, so that
+// it does not prevent the others from being discovered.
+func (s *VMTServer) createClusterVMTConfigs(k8sTAPSpec *kubeturbo.K8sTAPServiceSpec) []*kubeturbo.Config {
+	var vmtConfigs []*kubeturbo.Config
+	for _, cluster := range k8sTAPSpec.Clusters {
+		kubeConfig, err := s.createKubeConfigForContext(cluster.KubeContext)
+		if err != nil {
+			glog.Errorf("Skip cluster %s: %s", cluster.TargetIdentifier, err)
+			continue
+		}
+		vmtConfig, err := s.createVMTConfig(kubeConfig, k8sTAPSpec, cluster.StitchingPropertyType)
+		if err != nil {
+			glog.Errorf("Skip cluster %s: %s", cluster.TargetIdentifier, err)
+			continue
+		}
+		glog.V(2).Infof("Discover cluster of context %q as target %s.", cluster.KubeContext,
+			cluster.TargetIdentifier)
+		vmtConfigs = append(vmtConfigs, vmtConfig.WithTargetConfig(cluster.K8sTargetConfig))
 	}
-
-	go s.startHttp()
-
-	//if !s.LeaderElection.LeaderElect {
-	glog.V(2).Infof("No leader election")
-	run(nil)
-
-	glog.Fatal("this statement is unreachable")
-	panic("unreachable")
+	return vmtConfigs
 }
 
-func (s *VMTServer) startHttp() {
+func (s *VMTServer) startHttp(vmtService *kubeturbo.KubeturboService) {
 	mux := http.NewServeMux()
 
 	//healthz
 	healthz.InstallHandler(mux)
 
+	//prometheus.metrics
+	metrics.Register()
+	mux.Handle("/metrics", prometheus.Handler())
+
+	if s.EnableDebugEndpoints {
+		vmtService.InstallDebugHandlers(mux, s)
+	}
+
 	//debug
 	if s.EnableProfiling {
 		mux.HandleFunc("/debug/pprof/", pprof.Index)
 		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
 		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
 		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
-
-		//prometheus.metrics
-		mux.Handle("/metrics", prometheus.Handler())
 	}
 
 	server := &http.Server{
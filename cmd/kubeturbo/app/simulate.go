@@ -0,0 +1,83 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/turbonomic/kubeturbo/pkg/discovery"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/configs"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/kubelet"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/master"
+	"github.com/turbonomic/kubeturbo/test/simulation"
+)
+
+const (
+	simulationTargetIdentifier = "simulation"
+)
+
+// RunSimulation discovers the cluster simulated by the --simulate fixture once, through the same discovery pipeline as
+// a real cluster, and writes the discovery response to out as JSON.
+func (s *VMTServer) RunSimulation(out io.Writer) error {
+	fixture, err := simulation.LoadFixture(s.Simulate)
+	if err != nil {
+		return err
+	}
+	simulator, err := simulation.NewSimulator(fixture)
+	if err != nil {
+		return fmt.Errorf("failed to simulate the cluster: %s", err)
+	}
+
+	kubeConfig := simulator.KubeConfig()
+	kubeClient, err := s.createKubeClient(kubeConfig)
+	if err != nil {
+		return err
+	}
+	clusterMonitoringConfig, err := master.NewClusterMonitorConfig(kubeConfig)
+	if err != nil {
+		return fmt.Errorf("failed to build monitoring config for master topology monitor: %s", err)
+	}
+	kubeletMonitoringConfig := kubelet.NewKubeletMonitorConfig(kubeConfig).WithPort(s.KubeletPort).
+		WithNetThroughputCapacity(s.NetThroughputCapacity).WithTransport(simulator.KubeletTransport())
+
+	probeConfig := &configs.ProbeConfig{
+		StitchingPropertyType: s.defaultStitchingPropertyType(),
+		MonitoringConfigs: []monitoring.MonitorWorkerConfig{
+			kubeletMonitoringConfig,
+			clusterMonitoringConfig,
+		},
+		UsagePercentile:       s.UsagePercentile,
+		MaxFailedNodeFraction: s.MaxFailedNodeFraction,
+		DiscoveryWorkerCount:  s.DiscoveryWorkerCount,
+
+		DefaultMonitoringTimeout: s.MonitoringTimeout,
+
+		SnapshotDir:      s.SnapshotDir,
+		SnapshotFormat:   configs.SnapshotFormat(s.SnapshotFormat),
+		MaxSnapshots:     s.MaxSnapshots,
+		MaxSnapshotBytes: int64(s.MaxSnapshotSizeMB) * 1024 * 1024,
+	}
+	targetConfig := configs.NewK8sTargetConfig("Cloud Native", "Kubernetes", simulationTargetIdentifier, "", "")
+	if err := targetConfig.ValidateK8sTargetConfig(); err != nil {
+		return err
+	}
+
+	discoveryConfig := discovery.NewDiscoveryConfig(kubeClient, probeConfig, targetConfig)
+	defer close(discoveryConfig.StopEverything)
+	discoveryClient, err := discovery.NewK8sDiscoveryClient(discoveryConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create discovery client: %s", err)
+	}
+	response, err := discoveryClient.Discover(nil)
+	if err != nil {
+		return fmt.Errorf("failed to discover the simulated cluster: %s", err)
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(response); err != nil {
+		return fmt.Errorf("failed to write the discovery response: %s", err)
+	}
+	return nil
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package main
 
 import (
+	goflag "flag"
+	"os"
 	"runtime"
 
 	"k8s.io/apiserver/pkg/util/flag"
@@ -29,6 +31,21 @@ import (
 )
 
 func main() {
+	// The subcommands run a piece of kubeturbo instead of the service.
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "diff":
+			// Compare two discovery snapshots.
+			os.Exit(runDiff(os.Args[2:]))
+		case "discover":
+			// Discover the cluster without connecting to Turbo.
+			os.Exit(runDiscover(os.Args[2:]))
+		case "exec":
+			// Execute an action without connecting to Turbo.
+			os.Exit(runExec(os.Args[2:]))
+		}
+	}
+
 	runtime.GOMAXPROCS(runtime.NumCPU())
 	glog.V(2).Infof("*** Run Kubeturbo service ***")
 
@@ -41,3 +58,47 @@ func main() {
 
 	s.Run(pflag.CommandLine.Args())
 }
+
+func runDiff(args []string) int {
+	fs := pflag.NewFlagSet("kubeturbo diff", pflag.ExitOnError)
+	c := app.NewDiffCommand()
+	c.AddFlags(fs)
+	fs.Parse(args)
+	return c.Run(fs.Args(), os.Stdout, os.Stderr)
+}
+
+func runDiscover(args []string) int {
+	c := app.NewDiscoverCommand()
+	parseSubcommandFlags("kubeturbo discover", args, c.AddFlags)
+	defer logs.FlushLogs()
+
+	if err := c.Run(os.Stdout); err != nil {
+		glog.Errorf("Discovery failed: %s", err)
+		return 1
+	}
+	return 0
+}
+
+func runExec(args []string) int {
+	c := app.NewExecCommand()
+	fs := parseSubcommandFlags("kubeturbo exec", args, c.AddFlags)
+	defer logs.FlushLogs()
+
+	if err := c.Run(fs.Args(), os.Stdout); err != nil {
+		glog.Errorf("Action failed: %s", err)
+		return 1
+	}
+	return 0
+}
+
+// Parse the flags of a subcommand which runs a piece of the service, together with the logging flags.
+func parseSubcommandFlags(name string, args []string, addFlags func(*pflag.FlagSet)) *pflag.FlagSet {
+	fs := pflag.NewFlagSet(name, pflag.ExitOnError)
+	fs.SetNormalizeFunc(flag.WordSepNormalizeFunc)
+	addFlags(fs)
+	fs.AddGoFlagSet(goflag.CommandLine)
+	fs.Parse(args)
+
+	logs.InitLogs()
+	return fs
+}
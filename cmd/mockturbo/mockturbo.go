@@ -0,0 +1,41 @@
+// The mockturbo command is a local stand-in for the Turbonomic server. kubeturbo connects to it as to a real server,
+// by setting turboServer to its address in turboconfig. The probes and targets it registers can then be validated,
+// discovered and sent hand-written actions from the console.
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"k8s.io/apiserver/pkg/util/flag"
+	"k8s.io/kubernetes/pkg/util/logs"
+
+	"github.com/turbonomic/kubeturbo/test/mockturbo"
+
+	"github.com/golang/glog"
+	"github.com/spf13/pflag"
+)
+
+func main() {
+	address := pflag.String("address", "127.0.0.1:8080", "The address the mock server listens on")
+	timeout := pflag.Duration("request-timeout", mockturbo.DefaultRequestTimeout, "The max time to wait for the response of a request")
+	flag.InitFlags()
+
+	logs.InitLogs()
+	defer logs.FlushLogs()
+
+	server := mockturbo.NewMediationServer()
+	go func() {
+		glog.Fatal(http.ListenAndServe(*address, server.Handler()))
+	}()
+
+	fmt.Printf("Mock Turbonomic server is listening on %s. Set turboServer to http://%s in turboconfig.\n",
+		*address, *address)
+	fmt.Println(`Type "help" for the commands.`)
+	console := mockturbo.NewConsole(server, os.Stdout).WithTimeout(*timeout)
+	if err := console.Run(os.Stdin); err != nil {
+		glog.Errorf("Failed to read commands: %s", err)
+		os.Exit(1)
+	}
+}
@@ -3,6 +3,8 @@ package action
 import (
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"k8s.io/apimachinery/pkg/util/wait"
 	client "k8s.io/client-go/kubernetes"
@@ -10,6 +12,7 @@ import (
 	"github.com/turbonomic/kubeturbo/pkg/action/executor"
 	"github.com/turbonomic/kubeturbo/pkg/action/supervisor"
 	"github.com/turbonomic/kubeturbo/pkg/action/turboaction"
+	"github.com/turbonomic/kubeturbo/pkg/metrics"
 	turboscheduler "github.com/turbonomic/kubeturbo/pkg/scheduler"
 	"github.com/turbonomic/kubeturbo/pkg/turbostore"
 
@@ -19,6 +22,13 @@ import (
 	"github.com/golang/glog"
 )
 
+const (
+	// The action type in the metrics of the actions which are not supported.
+	unsupportedMetricsActionType = "unsupported"
+
+	shuttingDownDescription = "Kubeturbo is shutting down"
+)
+
 type ActionHandlerConfig struct {
 	kubeClient     *client.Clientset
 	broker         turbostore.Broker
@@ -54,6 +64,18 @@ type ActionHandler struct {
 	failedActionChan chan *turboaction.TurboAction
 
 	resultChan chan *proto.ActionResult
+
+	history *ActionHistory
+
+	schedulerNameReconciler *executor.SchedulerNameReconciler
+
+	// Guards stopping, so that no action becomes in-flight once the handler starts draining.
+	stopLock sync.Mutex
+	stopping bool
+	// Whether a result is returned to the SDK since the handler starts draining.
+	reportedWhileDraining bool
+	// The actions received from Turbo and not reported yet.
+	inFlight sync.WaitGroup
 }
 
 // Build new ActionHandler and start it.
@@ -77,6 +99,8 @@ func NewActionHandler(config *ActionHandlerConfig, scheduler *turboscheduler.Tur
 		failedActionChan:    failedActionChan,
 
 		resultChan: make(chan *proto.ActionResult),
+
+		history: NewActionHistory(defaultMaxRecentActions),
 	}
 
 	handler.registerActionExecutors()
@@ -89,6 +113,7 @@ func NewActionHandler(config *ActionHandlerConfig, scheduler *turboscheduler.Tur
 func (h *ActionHandler) registerActionExecutors() {
 	reScheduler := executor.NewReScheduler(h.config.kubeClient, h.config.broker)
 	h.actionExecutors[turboaction.ActionMove] = reScheduler
+	h.schedulerNameReconciler = executor.NewSchedulerNameReconciler(h.config.kubeClient, reScheduler)
 
 	horizontalScaler := executor.NewHorizontalScaler(h.config.kubeClient, h.config.broker, h.scheduler)
 	h.actionExecutors[turboaction.ActionProvision] = horizontalScaler
@@ -104,12 +129,19 @@ func (h *ActionHandler) Start() {
 	h.actionSupervisor.Start()
 }
 
+// Restore the controllers left on the placeholder scheduler by interrupted moves now and then every interval, until
+// stop is closed.
+func (h *ActionHandler) StartSchedulerNameReconciler(interval time.Duration, stop <-chan struct{}) {
+	go wait.Until(h.schedulerNameReconciler.Reconcile, interval, stop)
+}
+
 func (h *ActionHandler) getNextSucceededTurboAction() {
 	event := <-h.succeededActionChan
 	glog.V(3).Infof("Succeeded event is %v", event)
 	content := event.Content
 
 	glog.V(2).Infof("Action %s for %s-%s succeeded.", content.ActionType, content.TargetObject.TargetObjectType, content.TargetObject.TargetObjectName)
+	h.history.update(event)
 	progress := int32(100)
 	h.sendActionResult(proto.ActionResponseState_SUCCEEDED, progress, "Success")
 }
@@ -121,10 +153,16 @@ func (h *ActionHandler) getNextFailedTurboAction() {
 	content := event.Content
 
 	glog.V(2).Infof("Action %s for %s-%s failed.", content.ActionType, content.TargetObject.TargetObjectType, content.TargetObject.TargetObjectName)
+	h.history.update(event)
 	progress := int32(0)
 	h.sendActionResult(proto.ActionResponseState_FAILED, progress, "Failed 1")
 }
 
+// Get the actions received from Turbo which are in-flight or finished recently.
+func (h *ActionHandler) ActionHistory() *ActionHistory {
+	return h.history
+}
+
 // Implement ActionExecutorClient interface defined in Go SDK.
 // Execute the current action and return the action result.
 func (h *ActionHandler) ExecuteAction(actionExecutionDTO *proto.ActionExecutionDTO,
@@ -134,31 +172,97 @@ func (h *ActionHandler) ExecuteAction(actionExecutionDTO *proto.ActionExecutionD
 	actionItems := actionExecutionDTO.GetActionItem()
 	// TODO: only deal with one action item.
 	actionItemDTO := actionItems[0]
-	go h.execute(actionItemDTO)
-
-	glog.V(3).Infof("Now wait for action result")
-	result := <-h.resultChan
+	metrics.TurboRequestReceived()
+	start := time.Now()
+	metricsActionType := getMetricsActionType(actionItemDTO)
+	metrics.ActionsReceived.WithLabelValues(metricsActionType).Inc()
+	h.history.start(actionItemDTO)
+
+	var result *proto.ActionResult
+	if h.startAction() {
+		// The action is in flight until its result is returned to the SDK, which then sends it to Turbo.
+		defer h.inFlight.Done()
+		go h.execute(actionItemDTO)
+
+		glog.V(3).Infof("Now wait for action result")
+		result = <-h.resultChan
+	} else {
+		glog.Warningf("Reject action %s as kubeturbo is shutting down.", actionItemDTO.GetUuid())
+		result = newActionResult(proto.ActionResponseState_FAILED, int32(0), shuttingDownDescription)
+	}
+	h.reportAction()
+	h.history.finish(actionItemDTO.GetUuid(), result)
+	metrics.ObserveActionResult(metricsActionType,
+		result.GetResponse().GetActionResponseState() == proto.ActionResponseState_SUCCEEDED, start)
 	glog.V(4).Infof("Action result is %++v", result)
 	// TODO: currently the code in SDK make it share the actionExecution client between different workers. Once it is changed, need to close the channel.
 	//close(h.config.StopEverything)
 	return result, nil
 }
 
+// Count a new in-flight action, unless the handler is draining.
+func (h *ActionHandler) startAction() bool {
+	h.stopLock.Lock()
+	defer h.stopLock.Unlock()
+	if h.stopping {
+		return false
+	}
+	h.inFlight.Add(1)
+	return true
+}
+
+// Record that the result of an action is being returned to the SDK, which sends it to Turbo afterwards.
+func (h *ActionHandler) reportAction() {
+	h.stopLock.Lock()
+	defer h.stopLock.Unlock()
+	if h.stopping {
+		h.reportedWhileDraining = true
+	}
+}
+
+// ReportedWhileDraining returns whether the result of an action, either finished or rejected, is returned to the SDK
+// since the handler starts draining. The SDK may not have sent such a result to Turbo yet.
+func (h *ActionHandler) ReportedWhileDraining() bool {
+	h.stopLock.Lock()
+	defer h.stopLock.Unlock()
+	return h.reportedWhileDraining
+}
+
+// Drain stops accepting new actions, which are reported to Turbo as failed, and waits up to the timeout for the
+// in-flight actions to be executed, checked by the action supervisor and reported. It returns whether all of them
+// finish in time.
+func (h *ActionHandler) Drain(timeout time.Duration) bool {
+	h.stopLock.Lock()
+	h.stopping = true
+	h.stopLock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.inFlight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// Stop stops watching the succeeded and failed turbo actions, and stops the action supervisor.
+func (h *ActionHandler) Stop() {
+	close(h.config.StopEverything)
+	h.actionSupervisor.Stop()
+}
+
 func (h *ActionHandler) execute(actionItem *proto.ActionItemDTO) {
-	actionType, err := getActionTypeFromActionItemDTO(actionItem)
+	executor, err := h.getActionExecutor(actionItem)
 	if err != nil {
 		glog.Errorf("Failed to execute action: %s", err)
 		errorMsg := fmt.Sprintf("Failed to execute action: %s", err)
 		h.sendActionResult(proto.ActionResponseState_FAILED, int32(0), errorMsg)
 		return
 	}
-	executor, exist := h.actionExecutors[actionType]
-	if !exist {
-		glog.Errorf("action type %s is not support", actionType)
-		errorMsg := fmt.Sprintf("Failed to execute action. The action %s is currently not supported", actionType)
-		h.sendActionResult(proto.ActionResponseState_FAILED, int32(0), errorMsg)
-		return
-	}
 
 	action, err := executor.Execute(actionItem)
 	if err != nil {
@@ -167,9 +271,48 @@ func (h *ActionHandler) execute(actionItem *proto.ActionItemDTO) {
 		h.sendActionResult(proto.ActionResponseState_FAILED, int32(0), errorMsg)
 		return
 	}
+	h.history.update(action)
 	h.executedActionChan <- action
 }
 
+// ExecuteActionItem executes an action item with the registered executor, and waits for the action supervisor to
+// check it, without reporting the result to Turbo. The turbo action is passed to onUpdate once it is executed and
+// once it is checked.
+func (h *ActionHandler) ExecuteActionItem(actionItem *proto.ActionItemDTO,
+	onUpdate func(*turboaction.TurboAction)) (*turboaction.TurboAction, error) {
+	executor, err := h.getActionExecutor(actionItem)
+	if err != nil {
+		return nil, err
+	}
+	action, err := executor.Execute(actionItem)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute action: %s", err)
+	}
+	if onUpdate != nil {
+		onUpdate(action)
+	}
+
+	err = h.actionSupervisor.Supervise(action)
+	if onUpdate != nil {
+		onUpdate(action)
+	}
+	return action, err
+}
+
+// Get the registered executor of the action item.
+func (h *ActionHandler) getActionExecutor(actionItem *proto.ActionItemDTO) (executor.TurboActionExecutor, error) {
+	actionType, err := getActionTypeFromActionItemDTO(actionItem)
+	if err != nil {
+		return nil, err
+	}
+	executor, exist := h.actionExecutors[actionType]
+	if !exist {
+		glog.Errorf("action type %s is not support", actionType)
+		return nil, fmt.Errorf("The action %s is currently not supported", actionType)
+	}
+	return executor, nil
+}
+
 func getActionTypeFromActionItemDTO(actionItem *proto.ActionItemDTO) (turboaction.TurboActionType, error) {
 	var actionType turboaction.TurboActionType
 
@@ -204,8 +347,21 @@ func getActionTypeFromActionItemDTO(actionItem *proto.ActionItemDTO) (turboactio
 	return actionType, nil
 }
 
+// Get the turbo action type of the action item to label its metrics, or unsupported if it is not supported.
+func getMetricsActionType(actionItem *proto.ActionItemDTO) string {
+	actionType, err := getActionTypeFromActionItemDTO(actionItem)
+	if err != nil {
+		return unsupportedMetricsActionType
+	}
+	return string(actionType)
+}
+
 // Send action response to Turbonomic server.
 func (handler *ActionHandler) sendActionResult(state proto.ActionResponseState, progress int32, description string) {
+	handler.resultChan <- newActionResult(state, progress, description)
+}
+
+func newActionResult(state proto.ActionResponseState, progress int32, description string) *proto.ActionResult {
 	// 1. build response
 	response := &proto.ActionResponse{
 		ActionResponseState: &state,
@@ -213,8 +369,7 @@ func (handler *ActionHandler) sendActionResult(state proto.ActionResponseState,
 		ResponseDescription: &description,
 	}
 	// 2. built action result.
-	result := &proto.ActionResult{
+	return &proto.ActionResult{
 		Response: response,
 	}
-	handler.resultChan <- result
 }
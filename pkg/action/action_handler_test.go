@@ -0,0 +1,51 @@
+package action
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	api "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+)
+
+func TestActionHandlerDrain(t *testing.T) {
+	handler := NewActionHandler(NewActionHandlerConfig(nil, nil), nil)
+	defer handler.Stop()
+
+	// An action being executed.
+	if !handler.startAction() {
+		t.Fatal("Expected an action to be accepted before draining")
+	}
+	if handler.Drain(10 * time.Millisecond) {
+		t.Error("Expected draining to time out with an in-flight action")
+	}
+	if handler.ReportedWhileDraining() {
+		t.Error("Expected no action reported while draining yet")
+	}
+
+	// New actions are rejected once the handler is draining.
+	pod := &api.Pod{ObjectMeta: metav1.ObjectMeta{Name: "nginx-1", Namespace: "default", UID: "pod-uid"}}
+	actionItem := NewUnbindActionItem("rejected", pod)
+	result, err := handler.ExecuteAction(&proto.ActionExecutionDTO{ActionItem: []*proto.ActionItemDTO{actionItem}},
+		nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.GetResponse().GetActionResponseState() != proto.ActionResponseState_FAILED ||
+		result.GetResponse().GetResponseDescription() != shuttingDownDescription {
+		t.Errorf("Expected the action to be rejected, got %v", result)
+	}
+	if _, recent := handler.ActionHistory().List(); len(recent) != 1 || recent[0].UUID != "rejected" {
+		t.Errorf("Expected the rejected action in the history, got %v", recent)
+	}
+	if !handler.ReportedWhileDraining() {
+		t.Error("Expected the rejected action to be reported while draining")
+	}
+
+	go handler.inFlight.Done()
+	if !handler.Drain(time.Second) {
+		t.Error("Expected draining to finish once the in-flight action finishes")
+	}
+}
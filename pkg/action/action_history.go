@@ -0,0 +1,109 @@
+package action
+
+import (
+	"sync"
+	"time"
+
+	"github.com/turbonomic/kubeturbo/pkg/action/turboaction"
+
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+)
+
+const (
+	// The max number of finished actions kept in the action history.
+	defaultMaxRecentActions = 50
+)
+
+// ActionRecord is the state of an action received from Turbo, for debugging.
+type ActionRecord struct {
+	// The UUID of the action item, which is also the UID of the turbo action.
+	UUID       string `json:"uuid"`
+	ActionType string `json:"actionType"`
+	TargetSE   string `json:"targetSE,omitempty"`
+
+	ReceivedTime time.Time  `json:"receivedTime"`
+	FinishedTime *time.Time `json:"finishedTime,omitempty"`
+
+	// The turbo action built by the action executor, nil until the action is executed.
+	TurboAction *turboaction.TurboAction `json:"turboAction,omitempty"`
+
+	// The result reported to Turbo, empty until the action finishes.
+	State       string `json:"state,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// ActionHistory keeps the in-flight actions and the most recent finished ones. It is safe for concurrent use.
+type ActionHistory struct {
+	lock      sync.Mutex
+	inFlight  []*ActionRecord
+	recent    []*ActionRecord
+	maxRecent int
+}
+
+func NewActionHistory(maxRecent int) *ActionHistory {
+	return &ActionHistory{
+		maxRecent: maxRecent,
+	}
+}
+
+// Record an action item received from Turbo as in-flight.
+func (h *ActionHistory) start(actionItem *proto.ActionItemDTO) {
+	record := &ActionRecord{
+		UUID:         actionItem.GetUuid(),
+		ActionType:   actionItem.GetActionType().String(),
+		TargetSE:     actionItem.GetTargetSE().GetDisplayName(),
+		ReceivedTime: time.Now(),
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.inFlight = append(h.inFlight, record)
+}
+
+// Update the in-flight action of the turbo action with a copy of it, as the turbo action is still updated by the
+// action supervisor.
+func (h *ActionHistory) update(action *turboaction.TurboAction) {
+	actionCopy := *action
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for _, record := range h.inFlight {
+		if record.UUID == string(action.UID) {
+			record.TurboAction = &actionCopy
+			return
+		}
+	}
+}
+
+// Move the in-flight action of the given UUID to the recent actions, with the result reported to Turbo.
+func (h *ActionHistory) finish(uuid string, result *proto.ActionResult) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for i, record := range h.inFlight {
+		if record.UUID != uuid {
+			continue
+		}
+		now := time.Now()
+		record.FinishedTime = &now
+		record.State = result.GetResponse().GetActionResponseState().String()
+		record.Description = result.GetResponse().GetResponseDescription()
+
+		h.inFlight = append(h.inFlight[:i], h.inFlight[i+1:]...)
+		h.recent = append(h.recent, record)
+		if len(h.recent) > h.maxRecent {
+			h.recent = h.recent[len(h.recent)-h.maxRecent:]
+		}
+		return
+	}
+}
+
+// Get copies of the in-flight actions and the recent finished actions, both from the oldest to the newest.
+func (h *ActionHistory) List() (inFlight []ActionRecord, recent []ActionRecord) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for _, record := range h.inFlight {
+		inFlight = append(inFlight, *record)
+	}
+	for _, record := range h.recent {
+		recent = append(recent, *record)
+	}
+	return inFlight, recent
+}
@@ -0,0 +1,53 @@
+package action
+
+import (
+	"testing"
+
+	"github.com/turbonomic/kubeturbo/pkg/action/turboaction"
+
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+)
+
+func TestActionHistory(t *testing.T) {
+	history := NewActionHistory(2)
+	newActionItem := func(uuid string) *proto.ActionItemDTO {
+		actionType := proto.ActionItemDTO_MOVE
+		return &proto.ActionItemDTO{ActionType: &actionType, Uuid: &uuid}
+	}
+	newResult := func(state proto.ActionResponseState) *proto.ActionResult {
+		return &proto.ActionResult{Response: &proto.ActionResponse{ActionResponseState: &state}}
+	}
+
+	for _, uuid := range []string{"a", "b", "c", "d"} {
+		history.start(newActionItem(uuid))
+	}
+	action := turboaction.NewTurboActionBuilder("default", "a").Create()
+	action.Status = turboaction.Executed
+	history.update(&action)
+	// The record keeps a copy of the turbo action as it is when updated.
+	action.Status = turboaction.Success
+
+	for _, uuid := range []string{"a", "b", "c"} {
+		history.finish(uuid, newResult(proto.ActionResponseState_SUCCEEDED))
+	}
+	inFlight, recent := history.List()
+	if len(inFlight) != 1 || inFlight[0].UUID != "d" {
+		t.Errorf("Expected action d in flight, got %v", inFlight)
+	}
+	// Only the 2 most recent finished actions are kept.
+	if len(recent) != 2 || recent[0].UUID != "b" || recent[1].UUID != "c" {
+		t.Fatalf("Expected actions b and c finished recently, got %v", recent)
+	}
+	if recent[0].State != proto.ActionResponseState_SUCCEEDED.String() || recent[0].FinishedTime == nil {
+		t.Errorf("Expected action b succeeded, got %+v", recent[0])
+	}
+
+	history = NewActionHistory(2)
+	history.start(newActionItem("a"))
+	history.update(&action)
+	action.Status = turboaction.Fail
+	inFlight, _ = history.List()
+	if inFlight[0].TurboAction == nil || inFlight[0].TurboAction.Status != turboaction.Success {
+		t.Errorf("Expected the turbo action of a copied with status success, got %+v", inFlight[0].TurboAction)
+	}
+}
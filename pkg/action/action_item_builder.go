@@ -0,0 +1,83 @@
+package action
+
+import (
+	api "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+)
+
+// Build the action items of the pods and nodes of the cluster the same way as Turbo does, so that an action can be
+// executed without Turbo.
+
+// Build the action item of moving a pod to a node. The node is found by its addresses.
+func NewMoveActionItem(uuid string, pod *api.Pod, node *api.Node) *proto.ActionItemDTO {
+	var ipAddresses []string
+	for _, address := range node.Status.Addresses {
+		ipAddresses = append(ipAddresses, address.Address)
+	}
+	actionType := proto.ActionItemDTO_MOVE
+	return &proto.ActionItemDTO{
+		ActionType: &actionType,
+		Uuid:       &uuid,
+		TargetSE:   newPodEntityDTO(pod),
+		CurrentSE:  newEntityDTO(proto.EntityDTO_VIRTUAL_MACHINE, pod.Spec.NodeName, pod.Spec.NodeName),
+		NewSE: &proto.EntityDTO{
+			EntityType:  newEntityType(proto.EntityDTO_VIRTUAL_MACHINE),
+			Id:          newString(string(node.UID)),
+			DisplayName: newString(node.Name),
+			EntityData: &proto.EntityDTO_VirtualMachineData_{
+				VirtualMachineData: &proto.EntityDTO_VirtualMachineData{IpAddress: ipAddresses},
+			},
+		},
+	}
+}
+
+// Build the action item of adding a replica of the pod to the replication controller or deployment of the pod.
+func NewProvisionActionItem(uuid string, pod *api.Pod) *proto.ActionItemDTO {
+	actionType := proto.ActionItemDTO_PROVISION
+	return &proto.ActionItemDTO{
+		ActionType: &actionType,
+		Uuid:       &uuid,
+		TargetSE:   newPodEntityDTO(pod),
+	}
+}
+
+// Build the action item of removing a replica from the replication controller or deployment of the pod. Turbo sends
+// it as moving the virtual application off the application of the pod.
+func NewUnbindActionItem(uuid string, pod *api.Pod) *proto.ActionItemDTO {
+	actionType := proto.ActionItemDTO_MOVE
+	podId := string(pod.UID)
+	application := newEntityDTO(proto.EntityDTO_APPLICATION, "App-"+podId, "App-"+pod.Namespace+"/"+pod.Name)
+	application.CommoditiesBought = []*proto.EntityDTO_CommodityBought{
+		{
+			ProviderId:   &podId,
+			ProviderType: newEntityType(proto.EntityDTO_CONTAINER_POD),
+		},
+	}
+	return &proto.ActionItemDTO{
+		ActionType: &actionType,
+		Uuid:       &uuid,
+		TargetSE:   newEntityDTO(proto.EntityDTO_VIRTUAL_APPLICATION, "vApp-"+podId, "vApp-"+pod.Name),
+		CurrentSE:  application,
+	}
+}
+
+func newPodEntityDTO(pod *api.Pod) *proto.EntityDTO {
+	return newEntityDTO(proto.EntityDTO_CONTAINER_POD, string(pod.UID), pod.Namespace+"/"+pod.Name)
+}
+
+func newEntityDTO(entityType proto.EntityDTO_EntityType, id, displayName string) *proto.EntityDTO {
+	return &proto.EntityDTO{
+		EntityType:  &entityType,
+		Id:          &id,
+		DisplayName: &displayName,
+	}
+}
+
+func newEntityType(entityType proto.EntityDTO_EntityType) *proto.EntityDTO_EntityType {
+	return &entityType
+}
+
+func newString(s string) *string {
+	return &s
+}
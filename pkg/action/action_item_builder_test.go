@@ -0,0 +1,51 @@
+package action
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	api "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/turbonomic/kubeturbo/pkg/action/turboaction"
+
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+)
+
+func TestActionItemBuilders(t *testing.T) {
+	pod := &api.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx-1", Namespace: "default", UID: "pod-uid"},
+		Spec:       api.PodSpec{NodeName: "node-1"},
+	}
+	node := &api.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-2", UID: "node-uid"},
+		Status: api.NodeStatus{
+			Addresses: []api.NodeAddress{{Type: api.NodeInternalIP, Address: "10.0.0.2"}},
+		},
+	}
+
+	table := []struct {
+		actionItem         *proto.ActionItemDTO
+		expectedActionType turboaction.TurboActionType
+	}{
+		{NewMoveActionItem("move", pod, node), turboaction.ActionMove},
+		{NewProvisionActionItem("provision", pod), turboaction.ActionProvision},
+		{NewUnbindActionItem("unbind", pod), turboaction.ActionUnbind},
+	}
+	for _, item := range table {
+		actionType, err := getActionTypeFromActionItemDTO(item.actionItem)
+		if err != nil || actionType != item.expectedActionType {
+			t.Errorf("Action item %s: expected %s, got %s: %v", item.actionItem.GetUuid(),
+				item.expectedActionType, actionType, err)
+		}
+	}
+
+	// The executors find the destination node by IP, and the pod of an unbind action by the commodities bought.
+	moveItem := NewMoveActionItem("move", pod, node)
+	if ips := moveItem.GetNewSE().GetVirtualMachineData().GetIpAddress(); len(ips) != 1 || ips[0] != "10.0.0.2" {
+		t.Errorf("Expected the IP of node-2 in the move destination, got %v", ips)
+	}
+	unbindItem := NewUnbindActionItem("unbind", pod)
+	if bought := unbindItem.GetCurrentSE().GetCommoditiesBought(); len(bought) != 1 || bought[0].GetProviderId() != "pod-uid" {
+		t.Errorf("Expected the application to buy from pod-uid, got %v", bought)
+	}
+}
@@ -0,0 +1,49 @@
+package action
+
+import (
+	"fmt"
+
+	sdkprobe "github.com/turbonomic/turbo-go-sdk/pkg/probe"
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+)
+
+// ActionRouter routes the actions of a probe discovering several clusters to the action handler of the cluster the
+// action is for. The cluster is identified by the account value of the given identifying field.
+type ActionRouter struct {
+	identifyingField string
+
+	// key: target identifier; value: the action handler of the cluster discovered as the target.
+	handlers map[string]sdkprobe.TurboActionExecutorClient
+}
+
+func NewActionRouter(identifyingField string) *ActionRouter {
+	return &ActionRouter{
+		identifyingField: identifyingField,
+		handlers:         make(map[string]sdkprobe.TurboActionExecutorClient),
+	}
+}
+
+// Route the actions of the given target to the given handler.
+func (r *ActionRouter) WithHandler(targetIdentifier string, handler sdkprobe.TurboActionExecutorClient) *ActionRouter {
+	r.handlers[targetIdentifier] = handler
+	return r
+}
+
+// Implement ActionExecutorClient interface defined in Go SDK.
+// Execute the action by the action handler of the target in the account values.
+func (r *ActionRouter) ExecuteAction(actionExecutionDTO *proto.ActionExecutionDTO,
+	accountValues []*proto.AccountValue,
+	progressTracker sdkprobe.ActionProgressTracker) (*proto.ActionResult, error) {
+	var targetIdentifier string
+	for _, accountValue := range accountValues {
+		if accountValue.GetKey() == r.identifyingField {
+			targetIdentifier = accountValue.GetStringValue()
+			break
+		}
+	}
+	handler, exist := r.handlers[targetIdentifier]
+	if !exist {
+		return nil, fmt.Errorf("no cluster is discovered as target %q", targetIdentifier)
+	}
+	return handler.ExecuteAction(actionExecutionDTO, accountValues, progressTracker)
+}
@@ -0,0 +1,50 @@
+package action
+
+import (
+	"testing"
+
+	sdkprobe "github.com/turbonomic/turbo-go-sdk/pkg/probe"
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+)
+
+type fakeActionHandler struct {
+	executed int
+}
+
+func (h *fakeActionHandler) ExecuteAction(actionExecutionDTO *proto.ActionExecutionDTO,
+	accountValues []*proto.AccountValue,
+	progressTracker sdkprobe.ActionProgressTracker) (*proto.ActionResult, error) {
+	h.executed++
+	return &proto.ActionResult{}, nil
+}
+
+func TestActionRouter(t *testing.T) {
+	handlerA, handlerB := &fakeActionHandler{}, &fakeActionHandler{}
+	router := NewActionRouter("address").WithHandler("cluster-a", handlerA).WithHandler("cluster-b", handlerB)
+
+	accountValues := func(key, value string) []*proto.AccountValue {
+		return []*proto.AccountValue{{Key: &key, StringValue: &value}}
+	}
+	table := []struct {
+		accountValues     []*proto.AccountValue
+		expectedErr       bool
+		expectedExecutedA int
+		expectedExecutedB int
+	}{
+		{accountValues("address", "cluster-a"), false, 1, 0},
+		{accountValues("address", "cluster-b"), false, 1, 1},
+		{accountValues("address", "cluster-c"), true, 1, 1},
+		{accountValues("username", "cluster-a"), true, 1, 1},
+		{nil, true, 1, 1},
+	}
+	for i, item := range table {
+		_, err := router.ExecuteAction(&proto.ActionExecutionDTO{}, item.accountValues, nil)
+		if (err != nil) != item.expectedErr {
+			t.Errorf("Test case %d failed: expected error %t, got %v", i, item.expectedErr, err)
+		}
+		if handlerA.executed != item.expectedExecutedA || handlerB.executed != item.expectedExecutedB {
+			t.Errorf("Test case %d failed: expected %d and %d actions executed, got %d and %d", i,
+				item.expectedExecutedA, item.expectedExecutedB, handlerA.executed, handlerB.executed)
+		}
+	}
+}
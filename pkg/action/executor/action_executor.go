@@ -14,6 +14,9 @@ const (
 
 	// This is the maximum timeout for action executor.
 	secondPhaseTimeoutLimit time.Duration = time.Minute * 5
+
+	// The maximum time to wait for the new pod of a create-before-delete move to be ready.
+	podReadyTimeout time.Duration = time.Minute * 5
 )
 
 type TurboActionExecutor interface {
@@ -0,0 +1,272 @@
+package executor
+
+import (
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	client "k8s.io/client-go/kubernetes"
+	appsclient "k8s.io/client-go/kubernetes/typed/apps/v1beta1"
+	coreclient "k8s.io/client-go/kubernetes/typed/core/v1"
+	extensionsclient "k8s.io/client-go/kubernetes/typed/extensions/v1beta1"
+	api "k8s.io/client-go/pkg/api/v1"
+	apps "k8s.io/client-go/pkg/apis/apps/v1beta1"
+	extensions "k8s.io/client-go/pkg/apis/extensions/v1beta1"
+)
+
+// An in-memory clientset serving the ReplicationControllers, ReplicaSets, Deployments and Pods used by the executors.
+// The objects are stored by namespace/name; calling any other method panics.
+type fakeClientset struct {
+	client.Interface
+
+	rcs         map[string]*api.ReplicationController
+	rss         map[string]*extensions.ReplicaSet
+	deployments map[string]*apps.Deployment
+	pods        map[string]*api.Pod
+	// The namespace/name of the deleted pods, in order.
+	deletedPods []string
+	// The grace period of each deleted pod, by namespace/name. It is nil if the pod is deleted with its default one.
+	deletionGracePeriods map[string]*int64
+	// The status given to the pods created.
+	createdPodStatus api.PodStatus
+	// The number of the pod names generated.
+	generatedNames int
+}
+
+func newFakeClientset() *fakeClientset {
+	return &fakeClientset{
+		rcs:         make(map[string]*api.ReplicationController),
+		rss:         make(map[string]*extensions.ReplicaSet),
+		deployments: make(map[string]*apps.Deployment),
+		pods:        make(map[string]*api.Pod),
+
+		deletionGracePeriods: make(map[string]*int64),
+	}
+}
+
+func (c *fakeClientset) addReplicationController(rc *api.ReplicationController) {
+	c.rcs[objectKey(rc.Namespace, rc.Name)] = rc
+}
+
+func (c *fakeClientset) addReplicaSet(rs *extensions.ReplicaSet) {
+	c.rss[objectKey(rs.Namespace, rs.Name)] = rs
+}
+
+func (c *fakeClientset) addDeployment(deployment *apps.Deployment) {
+	c.deployments[objectKey(deployment.Namespace, deployment.Name)] = deployment
+}
+
+func (c *fakeClientset) addPod(pod *api.Pod) {
+	c.pods[objectKey(pod.Namespace, pod.Name)] = pod
+}
+
+func (c *fakeClientset) CoreV1() coreclient.CoreV1Interface {
+	return &fakeCoreV1{clientset: c}
+}
+
+func (c *fakeClientset) ExtensionsV1beta1() extensionsclient.ExtensionsV1beta1Interface {
+	return &fakeExtensionsV1beta1{clientset: c}
+}
+
+func (c *fakeClientset) AppsV1beta1() appsclient.AppsV1beta1Interface {
+	return &fakeAppsV1beta1{clientset: c}
+}
+
+func objectKey(namespace, name string) string {
+	return fmt.Sprintf("%s/%s", namespace, name)
+}
+
+// Check whether an object in the given namespace is listed from the namespace to list.
+func inNamespace(namespace, listNamespace string) bool {
+	return listNamespace == api.NamespaceAll || namespace == listNamespace
+}
+
+func notFound(resource, namespace, name string) error {
+	return apierrors.NewNotFound(schema.GroupResource{Resource: resource}, objectKey(namespace, name))
+}
+
+type fakeCoreV1 struct {
+	coreclient.CoreV1Interface
+	clientset *fakeClientset
+}
+
+func (c *fakeCoreV1) ReplicationControllers(namespace string) coreclient.ReplicationControllerInterface {
+	return &fakeReplicationControllers{clientset: c.clientset, namespace: namespace}
+}
+
+func (c *fakeCoreV1) Pods(namespace string) coreclient.PodInterface {
+	return &fakePods{clientset: c.clientset, namespace: namespace}
+}
+
+type fakeReplicationControllers struct {
+	coreclient.ReplicationControllerInterface
+	clientset *fakeClientset
+	namespace string
+}
+
+func (c *fakeReplicationControllers) Get(name string, options metav1.GetOptions) (*api.ReplicationController, error) {
+	rc, exist := c.clientset.rcs[objectKey(c.namespace, name)]
+	if !exist {
+		return nil, notFound("replicationcontrollers", c.namespace, name)
+	}
+	return rc, nil
+}
+
+func (c *fakeReplicationControllers) List(opts metav1.ListOptions) (*api.ReplicationControllerList, error) {
+	list := &api.ReplicationControllerList{}
+	for _, rc := range c.clientset.rcs {
+		if inNamespace(rc.Namespace, c.namespace) {
+			list.Items = append(list.Items, *rc)
+		}
+	}
+	return list, nil
+}
+
+func (c *fakeReplicationControllers) Update(rc *api.ReplicationController) (*api.ReplicationController, error) {
+	if _, err := c.Get(rc.Name, metav1.GetOptions{}); err != nil {
+		return nil, err
+	}
+	c.clientset.addReplicationController(rc)
+	return rc, nil
+}
+
+type fakePods struct {
+	coreclient.PodInterface
+	clientset *fakeClientset
+	namespace string
+}
+
+// Create the pod with the status of the created pods. Its name is generated if it only has a generate name.
+func (c *fakePods) Create(pod *api.Pod) (*api.Pod, error) {
+	created := *pod
+	created.Namespace = c.namespace
+	if created.Name == "" {
+		created.Name = fmt.Sprintf("%s%d", created.GenerateName, c.clientset.generatedNames)
+		c.clientset.generatedNames++
+	}
+	if _, exist := c.clientset.pods[objectKey(created.Namespace, created.Name)]; exist {
+		return nil, apierrors.NewAlreadyExists(schema.GroupResource{Resource: "pods"}, created.Name)
+	}
+	created.Status = c.clientset.createdPodStatus
+	c.clientset.addPod(&created)
+	return &created, nil
+}
+
+func (c *fakePods) Get(name string, options metav1.GetOptions) (*api.Pod, error) {
+	pod, exist := c.clientset.pods[objectKey(c.namespace, name)]
+	if !exist {
+		return nil, notFound("pods", c.namespace, name)
+	}
+	return pod, nil
+}
+
+// List the pods in the namespace. Only the field selector of the pending pods is supported.
+func (c *fakePods) List(opts metav1.ListOptions) (*api.PodList, error) {
+	list := &api.PodList{}
+	for _, pod := range c.clientset.pods {
+		if !inNamespace(pod.Namespace, c.namespace) {
+			continue
+		}
+		if opts.FieldSelector == "spec.nodeName=" && pod.Spec.NodeName != "" {
+			continue
+		}
+		list.Items = append(list.Items, *pod)
+	}
+	return list, nil
+}
+
+func (c *fakePods) Delete(name string, options *metav1.DeleteOptions) error {
+	key := objectKey(c.namespace, name)
+	if _, exist := c.clientset.pods[key]; !exist {
+		return notFound("pods", c.namespace, name)
+	}
+	delete(c.clientset.pods, key)
+	c.clientset.deletedPods = append(c.clientset.deletedPods, key)
+	if options != nil {
+		c.clientset.deletionGracePeriods[key] = options.GracePeriodSeconds
+	}
+	return nil
+}
+
+type fakeExtensionsV1beta1 struct {
+	extensionsclient.ExtensionsV1beta1Interface
+	clientset *fakeClientset
+}
+
+func (c *fakeExtensionsV1beta1) ReplicaSets(namespace string) extensionsclient.ReplicaSetInterface {
+	return &fakeReplicaSets{clientset: c.clientset, namespace: namespace}
+}
+
+type fakeReplicaSets struct {
+	extensionsclient.ReplicaSetInterface
+	clientset *fakeClientset
+	namespace string
+}
+
+func (c *fakeReplicaSets) Get(name string, options metav1.GetOptions) (*extensions.ReplicaSet, error) {
+	rs, exist := c.clientset.rss[objectKey(c.namespace, name)]
+	if !exist {
+		return nil, notFound("replicasets", c.namespace, name)
+	}
+	return rs, nil
+}
+
+func (c *fakeReplicaSets) List(opts metav1.ListOptions) (*extensions.ReplicaSetList, error) {
+	list := &extensions.ReplicaSetList{}
+	for _, rs := range c.clientset.rss {
+		if inNamespace(rs.Namespace, c.namespace) {
+			list.Items = append(list.Items, *rs)
+		}
+	}
+	return list, nil
+}
+
+func (c *fakeReplicaSets) Update(rs *extensions.ReplicaSet) (*extensions.ReplicaSet, error) {
+	if _, err := c.Get(rs.Name, metav1.GetOptions{}); err != nil {
+		return nil, err
+	}
+	c.clientset.addReplicaSet(rs)
+	return rs, nil
+}
+
+type fakeAppsV1beta1 struct {
+	appsclient.AppsV1beta1Interface
+	clientset *fakeClientset
+}
+
+func (c *fakeAppsV1beta1) Deployments(namespace string) appsclient.DeploymentInterface {
+	return &fakeDeployments{clientset: c.clientset, namespace: namespace}
+}
+
+type fakeDeployments struct {
+	appsclient.DeploymentInterface
+	clientset *fakeClientset
+	namespace string
+}
+
+func (c *fakeDeployments) Get(name string, options metav1.GetOptions) (*apps.Deployment, error) {
+	deployment, exist := c.clientset.deployments[objectKey(c.namespace, name)]
+	if !exist {
+		return nil, notFound("deployments", c.namespace, name)
+	}
+	return deployment, nil
+}
+
+func (c *fakeDeployments) List(opts metav1.ListOptions) (*apps.DeploymentList, error) {
+	list := &apps.DeploymentList{}
+	for _, deployment := range c.clientset.deployments {
+		if inNamespace(deployment.Namespace, c.namespace) {
+			list.Items = append(list.Items, *deployment)
+		}
+	}
+	return list, nil
+}
+
+func (c *fakeDeployments) Update(deployment *apps.Deployment) (*apps.Deployment, error) {
+	if _, err := c.Get(deployment.Name, metav1.GetOptions{}); err != nil {
+		return nil, err
+	}
+	c.clientset.addDeployment(deployment)
+	return deployment, nil
+}
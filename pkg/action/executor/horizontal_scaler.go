@@ -202,11 +202,17 @@ func (h *HorizontalScaler) getProviderPod(actionItem *proto.ActionItemDTO) (*api
 
 		// TODO the following is a temporary fix.
 		// Here we need to find the ID of the provider pod from commodity bought of application.
+		// An application buys from the containers of the pod, and the ID of a container contains the pod ID.
 		commoditiesBought := currentSE.GetCommoditiesBought()
 		var podID string
 		for _, cb := range commoditiesBought {
-			if cb.GetProviderType() == proto.EntityDTO_CONTAINER_POD {
+			switch cb.GetProviderType() {
+			case proto.EntityDTO_CONTAINER_POD:
 				podID = cb.GetProviderId()
+			case proto.EntityDTO_CONTAINER:
+				if id, err := discutil.PodIDFromContainerID(cb.GetProviderId()); err == nil {
+					podID = id
+				}
 			}
 		}
 		if podID == "" {
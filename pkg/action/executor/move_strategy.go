@@ -0,0 +1,304 @@
+package executor
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	client "k8s.io/client-go/kubernetes"
+	api "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// The annotation selecting how a pod is moved. It is looked up on the pod, then on its ReplicationController or
+	// ReplicaSet, which has the annotations of its Deployment, then on its namespace.
+	MoveStrategyAnnotation = "kubeturbo.io/move-strategy"
+
+	// Delete the original pod immediately, then create the new pod on the destination. It is the default.
+	MoveStrategyDeleteBeforeCreate = "delete-before-create"
+	// Create the new pod on the destination and wait for it to be ready, then delete the original pod gracefully.
+	MoveStrategyCreateBeforeDelete = "create-before-delete"
+
+	// The annotation recording the number of replicas added to a controller by the create-before-delete moves in
+	// progress, so that they can be removed if kubeturbo dies in the middle of a move.
+	SurgeReplicasAnnotation = "kubeturbo.io/surge-replicas"
+
+	KindDeployment = "Deployment"
+
+	podReadyCheckInterval    = 2 * time.Second
+	maxUpdateConflictRetries = 5
+)
+
+// Get the move strategy of the pod from the annotation of the pod, its controller or its namespace, in that order.
+func getMoveStrategy(client client.Interface, pod *api.Pod, parentKind, parentName string) string {
+	id := fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)
+	if strategy, ok := parseMoveStrategy(pod.Annotations, "pod-"+id); ok {
+		return strategy
+	}
+
+	var parentMeta *metav1.ObjectMeta
+	switch parentKind {
+	case KindReplicationController:
+		rc, err := client.CoreV1().ReplicationControllers(pod.Namespace).Get(parentName, metav1.GetOptions{})
+		if err != nil {
+			glog.Warningf("Failed to get the move strategy of ReplicationController-%s/%s: %v", pod.Namespace,
+				parentName, err)
+		} else {
+			parentMeta = &rc.ObjectMeta
+		}
+	case KindReplicaSet:
+		rs, err := client.ExtensionsV1beta1().ReplicaSets(pod.Namespace).Get(parentName, metav1.GetOptions{})
+		if err != nil {
+			glog.Warningf("Failed to get the move strategy of ReplicaSet-%s/%s: %v", pod.Namespace, parentName, err)
+		} else {
+			parentMeta = &rs.ObjectMeta
+		}
+	}
+	if parentMeta != nil {
+		owner := fmt.Sprintf("%s-%s/%s", parentKind, pod.Namespace, parentName)
+		if strategy, ok := parseMoveStrategy(parentMeta.Annotations, owner); ok {
+			return strategy
+		}
+	}
+
+	ns, err := client.CoreV1().Namespaces().Get(pod.Namespace, metav1.GetOptions{})
+	if err != nil {
+		glog.Warningf("Failed to get the move strategy of namespace %s: %v", pod.Namespace, err)
+	} else if strategy, ok := parseMoveStrategy(ns.Annotations, "namespace "+pod.Namespace); ok {
+		return strategy
+	}
+	return MoveStrategyDeleteBeforeCreate
+}
+
+// Parse the move strategy in the annotations of the given owner. It returns false if the strategy is not set or
+// invalid.
+func parseMoveStrategy(annotations map[string]string, owner string) (string, bool) {
+	strategy, exist := annotations[MoveStrategyAnnotation]
+	if !exist {
+		return "", false
+	}
+	switch strategy {
+	case MoveStrategyDeleteBeforeCreate, MoveStrategyCreateBeforeDelete:
+		return strategy, true
+	}
+	glog.Warningf("Invalid move strategy %q of %s in annotation %s, it must be %s or %s.", strategy, owner,
+		MoveStrategyAnnotation, MoveStrategyDeleteBeforeCreate, MoveStrategyCreateBeforeDelete)
+	return "", false
+}
+
+// Move the pod by creating a copy on the destination node, waiting for it to be ready, then deleting the original
+// pod gracefully. A replica is added to the controller of the pod in the meantime, so that the controller keeps
+// both pods, and removed once the original pod is deleted or the move fails.
+func (r *ReScheduler) movePodCreateBeforeDelete(pod *api.Pod, parentKind, parentName,
+	nodeName string) (*api.Pod, error) {
+	podClient := r.kubeClient.CoreV1().Pods(pod.Namespace)
+	id := fmt.Sprintf("%v/%v", pod.Namespace, pod.Name)
+	glog.V(2).Infof("move-pod: begin to move %v from %v to %v, creating the new pod first", id, pod.Spec.NodeName,
+		nodeName)
+
+	//1. add a replica to the controller
+	if parentKind != "" {
+		scaleKind, scaleName, err := getScaleTarget(r.kubeClient, pod.Namespace, parentKind, parentName)
+		if err != nil {
+			err = fmt.Errorf("move-failed: failed to get the controller to scale for pod-%v: %v", id, err)
+			glog.Error(err.Error())
+			return nil, err
+		}
+		scaleId := fmt.Sprintf("%s-%s/%s", scaleKind, pod.Namespace, scaleName)
+		r.startMoving(scaleKind, pod.Namespace, scaleName)
+		defer r.finishMoving(scaleKind, pod.Namespace, scaleName)
+		if err := surgeReplicas(r.kubeClient, pod.Namespace, scaleKind, scaleName, 1); err != nil {
+			err = fmt.Errorf("move-failed: failed to add a replica to %v: %v", scaleId, err)
+			glog.Error(err.Error())
+			return nil, err
+		}
+		defer func() {
+			if err := surgeReplicas(r.kubeClient, pod.Namespace, scaleKind, scaleName, -1); err != nil {
+				glog.Errorf("Failed to remove the replica added to %v, leaving it to the scheduler name "+
+					"reconciler: %v", scaleId, err)
+			}
+		}()
+	}
+
+	//2. create the new pod on the destination, with another name as the original pod still exists
+	npod := &api.Pod{}
+	copyPodInfo(pod, npod)
+	npod.Name = ""
+	if npod.GenerateName == "" {
+		npod.GenerateName = pod.Name + "-"
+	}
+	npod.Spec.NodeName = nodeName
+	npod, err := podClient.Create(npod)
+	if err != nil {
+		err = fmt.Errorf("move-failed: failed to create new pod for pod-%v: %v", id, err)
+		glog.Error(err.Error())
+		return nil, err
+	}
+	nid := fmt.Sprintf("%v/%v", npod.Namespace, npod.Name)
+
+	//3. wait for the new pod to be ready; the original pod is kept if it is not
+	grace := podDeletionGracePeriod
+	if err := waitPodReady(r.kubeClient, npod.Namespace, npod.Name, r.podReadyTimeout); err != nil {
+		err = fmt.Errorf("move-failed: new pod-%v for pod-%v is not ready: %v", nid, id, err)
+		glog.Error(err.Error())
+		if err := podClient.Delete(npod.Name, &metav1.DeleteOptions{GracePeriodSeconds: &grace}); err != nil {
+			glog.Errorf("Failed to delete new pod-%v: %v", nid, err)
+		}
+		return nil, err
+	}
+
+	//4. delete the original pod with its termination grace period
+	delOption := &metav1.DeleteOptions{GracePeriodSeconds: pod.Spec.TerminationGracePeriodSeconds}
+	if err := podClient.Delete(pod.Name, delOption); err != nil {
+		err = fmt.Errorf("move-failed: failed to delete original pod-%v: %v", id, err)
+		glog.Error(err.Error())
+		if err := podClient.Delete(npod.Name, &metav1.DeleteOptions{GracePeriodSeconds: &grace}); err != nil {
+			glog.Errorf("Failed to delete new pod-%v: %v", nid, err)
+		}
+		return nil, err
+	}
+
+	glog.V(2).Infof("move-finished: %v from %v to %v as %v", id, pod.Spec.NodeName, nodeName, nid)
+	return npod, nil
+}
+
+// Wait up to the timeout for the pod to be ready. It fails early if the pod terminates.
+func waitPodReady(client client.Interface, namespace, name string, timeout time.Duration) error {
+	return wait.PollImmediate(podReadyCheckInterval, timeout, func() (bool, error) {
+		pod, err := client.CoreV1().Pods(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return false, err
+			}
+			glog.Warningf("Failed to get pod-%s/%s: %v", namespace, name, err)
+			return false, nil
+		}
+		if pod.Status.Phase == api.PodFailed || pod.Status.Phase == api.PodSucceeded {
+			return false, fmt.Errorf("pod is %s", pod.Status.Phase)
+		}
+		return isPodReady(pod), nil
+	})
+}
+
+func isPodReady(pod *api.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == api.PodReady {
+			return condition.Status == api.ConditionTrue
+		}
+	}
+	return false
+}
+
+// Get the object whose replicas are changed for a pod of the given controller. It is the Deployment of a ReplicaSet
+// created by a Deployment, as the Deployment would revert the replicas of the ReplicaSet.
+func getScaleTarget(client client.Interface, namespace, kind, name string) (string, string, error) {
+	if kind != KindReplicaSet {
+		return kind, name, nil
+	}
+	rs, err := client.ExtensionsV1beta1().ReplicaSets(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return "", "", err
+	}
+	if deployment := getControllingDeployment(&rs.ObjectMeta); deployment != "" {
+		return KindDeployment, deployment, nil
+	}
+	return kind, name, nil
+}
+
+// Get the name of the Deployment controlling an object, or an empty string if it is not controlled by a Deployment.
+func getControllingDeployment(meta *metav1.ObjectMeta) string {
+	for _, owner := range meta.OwnerReferences {
+		if owner.Controller != nil && *owner.Controller && owner.Kind == KindDeployment {
+			return owner.Name
+		}
+	}
+	return ""
+}
+
+// Add delta replicas to the ReplicationController, ReplicaSet or Deployment, and to the surge annotation in the
+// same update.
+func surgeReplicas(client client.Interface, namespace, kind, name string, delta int32) error {
+	return retryOnConflict(func() error {
+		switch kind {
+		case KindReplicationController:
+			rcClient := client.CoreV1().ReplicationControllers(namespace)
+			rc, err := rcClient.Get(name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			rc.Spec.Replicas = addSurgeReplicas(&rc.ObjectMeta, rc.Spec.Replicas, delta)
+			_, err = rcClient.Update(rc)
+			return err
+		case KindReplicaSet:
+			rsClient := client.ExtensionsV1beta1().ReplicaSets(namespace)
+			rs, err := rsClient.Get(name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			rs.Spec.Replicas = addSurgeReplicas(&rs.ObjectMeta, rs.Spec.Replicas, delta)
+			_, err = rsClient.Update(rs)
+			return err
+		case KindDeployment:
+			deploymentClient := client.AppsV1beta1().Deployments(namespace)
+			deployment, err := deploymentClient.Get(name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			deployment.Spec.Replicas = addSurgeReplicas(&deployment.ObjectMeta, deployment.Spec.Replicas, delta)
+			_, err = deploymentClient.Update(deployment)
+			return err
+		default:
+			return fmt.Errorf("unsupported controller kind %s", kind)
+		}
+	})
+}
+
+// Add delta to the surge annotation and to the given replicas, which default to 1, and return the new replicas.
+func addSurgeReplicas(meta *metav1.ObjectMeta, replicas *int32, delta int32) *int32 {
+	if surge := getSurgeReplicas(meta) + delta; surge > 0 {
+		if meta.Annotations == nil {
+			meta.Annotations = make(map[string]string)
+		}
+		meta.Annotations[SurgeReplicasAnnotation] = strconv.Itoa(int(surge))
+	} else {
+		delete(meta.Annotations, SurgeReplicasAnnotation)
+	}
+
+	newReplicas := int32(1)
+	if replicas != nil {
+		newReplicas = *replicas
+	}
+	if newReplicas += delta; newReplicas < 0 {
+		newReplicas = 0
+	}
+	return &newReplicas
+}
+
+// Get the number of replicas added to a controller by the create-before-delete moves in progress.
+func getSurgeReplicas(meta *metav1.ObjectMeta) int32 {
+	value, exist := meta.Annotations[SurgeReplicasAnnotation]
+	if !exist {
+		return 0
+	}
+	surge, err := strconv.Atoi(value)
+	if err != nil || surge < 0 {
+		glog.Warningf("Invalid surge replicas %q of %s/%s in annotation %s.", value, meta.Namespace, meta.Name,
+			SurgeReplicasAnnotation)
+		return 0
+	}
+	return int32(surge)
+}
+
+// Call the update function again while it fails with a conflict, up to maxUpdateConflictRetries times.
+func retryOnConflict(update func() error) error {
+	err := update()
+	for i := 0; i < maxUpdateConflictRetries && apierrors.IsConflict(err); i++ {
+		err = update()
+	}
+	return err
+}
@@ -0,0 +1,157 @@
+package executor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	api "k8s.io/client-go/pkg/api/v1"
+)
+
+func TestParseMoveStrategy(t *testing.T) {
+	table := []struct {
+		annotations      map[string]string
+		expectedStrategy string
+		expectedOk       bool
+	}{
+		{nil, "", false},
+		{map[string]string{"a": "b"}, "", false},
+		{map[string]string{MoveStrategyAnnotation: MoveStrategyCreateBeforeDelete}, MoveStrategyCreateBeforeDelete, true},
+		{map[string]string{MoveStrategyAnnotation: MoveStrategyDeleteBeforeCreate}, MoveStrategyDeleteBeforeCreate, true},
+		{map[string]string{MoveStrategyAnnotation: "rolling"}, "", false},
+	}
+	for i, item := range table {
+		strategy, ok := parseMoveStrategy(item.annotations, "pod-default/web")
+		if strategy != item.expectedStrategy || ok != item.expectedOk {
+			t.Errorf("Test case %d failed: expected %q, %v, got %q, %v", i, item.expectedStrategy,
+				item.expectedOk, strategy, ok)
+		}
+	}
+}
+
+func TestAddSurgeReplicas(t *testing.T) {
+	replicas := func(n int32) *int32 { return &n }
+	table := []struct {
+		annotations        map[string]string
+		replicas           *int32
+		delta              int32
+		expectedReplicas   int32
+		expectedAnnotation string
+	}{
+		{nil, replicas(3), 1, 4, "1"},
+		{nil, nil, 1, 2, "1"},
+		{map[string]string{SurgeReplicasAnnotation: "1"}, replicas(4), 1, 5, "2"},
+		{map[string]string{SurgeReplicasAnnotation: "2"}, replicas(5), -1, 4, "1"},
+		{map[string]string{SurgeReplicasAnnotation: "1"}, replicas(4), -1, 3, ""},
+		{map[string]string{SurgeReplicasAnnotation: "2"}, replicas(1), -2, 0, ""},
+		{map[string]string{SurgeReplicasAnnotation: "invalid"}, replicas(3), 1, 4, "1"},
+	}
+	for i, item := range table {
+		meta := &metav1.ObjectMeta{Annotations: item.annotations}
+		newReplicas := addSurgeReplicas(meta, item.replicas, item.delta)
+		if *newReplicas != item.expectedReplicas {
+			t.Errorf("Test case %d failed: expected replicas %d, got %d", i, item.expectedReplicas, *newReplicas)
+		}
+		if annotation := meta.Annotations[SurgeReplicasAnnotation]; annotation != item.expectedAnnotation {
+			t.Errorf("Test case %d failed: expected annotation %q, got %q", i, item.expectedAnnotation, annotation)
+		}
+	}
+}
+
+func TestIsPodReady(t *testing.T) {
+	condition := func(conditionType api.PodConditionType, status api.ConditionStatus) api.PodCondition {
+		return api.PodCondition{Type: conditionType, Status: status}
+	}
+	table := []struct {
+		conditions []api.PodCondition
+		expected   bool
+	}{
+		{nil, false},
+		{[]api.PodCondition{condition(api.PodScheduled, api.ConditionTrue)}, false},
+		{[]api.PodCondition{condition(api.PodScheduled, api.ConditionTrue), condition(api.PodReady, api.ConditionFalse)},
+			false},
+		{[]api.PodCondition{condition(api.PodScheduled, api.ConditionTrue), condition(api.PodReady, api.ConditionTrue)},
+			true},
+	}
+	for i, item := range table {
+		pod := &api.Pod{Status: api.PodStatus{Conditions: item.conditions}}
+		if ready := isPodReady(pod); ready != item.expected {
+			t.Errorf("Test case %d failed: expected ready %v, got %v", i, item.expected, ready)
+		}
+	}
+}
+
+func TestMovePodCreateBeforeDelete(t *testing.T) {
+	readyStatus := api.PodStatus{
+		Phase:      api.PodRunning,
+		Conditions: []api.PodCondition{{Type: api.PodReady, Status: api.ConditionTrue}},
+	}
+	table := []struct {
+		createdPodStatus api.PodStatus
+		expectedErr      bool
+		// The pod deleted by the move, and the grace period it is deleted with.
+		expectedDeletedPod  string
+		expectedGracePeriod int64
+	}{
+		// The original pod is deleted with its termination grace period once the new pod is ready.
+		{readyStatus, false, "default/web-1", 30},
+		// The new pod is deleted immediately if it is not ready in time, keeping the original pod.
+		{api.PodStatus{Phase: api.PodPending}, true, "default/web-1-0", podDeletionGracePeriod},
+	}
+	for i, item := range table {
+		kubeClient := newFakeClientset()
+		kubeClient.createdPodStatus = item.createdPodStatus
+		kubeClient.addDeployment(newTestDeployment("web", 3, nil))
+		rs := newTestReplicaSet("web-rs", api.DefaultSchedulerName, 3, nil)
+		controller := true
+		rs.OwnerReferences = []metav1.OwnerReference{{Kind: KindDeployment, Name: "web", Controller: &controller}}
+		kubeClient.addReplicaSet(rs)
+		pod := newTestControlledPod("web-1", api.DefaultSchedulerName, "node-1", KindReplicaSet, "web-rs")
+		gracePeriod := int64(30)
+		pod.Spec.TerminationGracePeriodSeconds = &gracePeriod
+		kubeClient.addPod(pod)
+
+		r := NewReScheduler(kubeClient, nil)
+		r.podReadyTimeout = 10 * time.Millisecond
+		npod, err := r.movePodCreateBeforeDelete(pod, KindReplicaSet, "web-rs", "node-2")
+		if item.expectedErr {
+			if err == nil || npod != nil {
+				t.Errorf("Test case %d failed: expected the move to fail, got %v, %v", i, npod, err)
+			}
+			if _, exist := kubeClient.pods["default/web-1"]; !exist {
+				t.Errorf("Test case %d failed: the original pod is deleted", i)
+			}
+		} else {
+			if err != nil {
+				t.Errorf("Test case %d failed: unexpected error %v", i, err)
+			} else if npod.Name != "web-1-0" || npod.Spec.NodeName != "node-2" {
+				t.Errorf("Test case %d failed: expected new pod web-1-0 on node-2, got %s on %s", i, npod.Name,
+					npod.Spec.NodeName)
+			}
+		}
+
+		if expected := []string{item.expectedDeletedPod}; !reflect.DeepEqual(kubeClient.deletedPods, expected) {
+			t.Errorf("Test case %d failed: expected deleted pods %v, got %v", i, expected,
+				kubeClient.deletedPods)
+		} else if grace := kubeClient.deletionGracePeriods[item.expectedDeletedPod]; grace == nil ||
+			*grace != item.expectedGracePeriod {
+			t.Errorf("Test case %d failed: expected pod %s deleted with grace period %d, got %v", i,
+				item.expectedDeletedPod, item.expectedGracePeriod, grace)
+		}
+
+		// The replica added to the Deployment is removed, and the ReplicaSet is left to the Deployment.
+		deployment := kubeClient.deployments["default/web"]
+		if *deployment.Spec.Replicas != 3 || getSurgeReplicas(&deployment.ObjectMeta) != 0 {
+			t.Errorf("Test case %d failed: expected 3 replicas of the Deployment without surge, got %d, %v", i,
+				*deployment.Spec.Replicas, deployment.Annotations)
+		}
+		if rs := kubeClient.rss["default/web-rs"]; *rs.Spec.Replicas != 3 || len(rs.Annotations) != 0 {
+			t.Errorf("Test case %d failed: the ReplicaSet is changed to %d replicas, %v", i, *rs.Spec.Replicas,
+				rs.Annotations)
+		}
+		if r.isMoving(KindDeployment, "default", "web") {
+			t.Errorf("Test case %d failed: the Deployment is still moving", i)
+		}
+	}
+}
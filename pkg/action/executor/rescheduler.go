@@ -4,7 +4,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	//"time"
+	"sync"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	client "k8s.io/client-go/kubernetes"
@@ -26,20 +27,62 @@ const (
 	DefaultNoneExistSchedulerName       = "turbo-none-exist-scheduler"
 	KindReplicationController           = "ReplicationController"
 	KindReplicaSet                      = "ReplicaSet"
+
+	// The annotation recording the scheduler name of a controller before it is changed to the placeholder scheduler,
+	// so that it can be restored if kubeturbo dies in the middle of a move.
+	OriginalSchedulerNameAnnotation = "kubeturbo.io/original-scheduler-name"
 )
 
 type ReScheduler struct {
-	kubeClient *client.Clientset
+	kubeClient client.Interface
 	broker     turbostore.Broker
+
+	// The max time to wait for the new pod of a create-before-delete move to be ready.
+	podReadyTimeout time.Duration
+
+	// The controllers of the pods being moved, whose scheduler name may be changed to the placeholder scheduler.
+	// <kind/namespace/name : number of the moves>
+	movingControllers     map[string]int
+	movingControllersLock sync.Mutex
 }
 
-func NewReScheduler(client *client.Clientset, broker turbostore.Broker) *ReScheduler {
+func NewReScheduler(client client.Interface, broker turbostore.Broker) *ReScheduler {
 	return &ReScheduler{
 		kubeClient: client,
 		broker:     broker,
+
+		podReadyTimeout: podReadyTimeout,
+
+		movingControllers: make(map[string]int),
+	}
+}
+
+// Check whether a pod of the given controller is being moved.
+func (r *ReScheduler) isMoving(kind, namespace, name string) bool {
+	r.movingControllersLock.Lock()
+	defer r.movingControllersLock.Unlock()
+	return r.movingControllers[controllerKey(kind, namespace, name)] > 0
+}
+
+func (r *ReScheduler) startMoving(kind, namespace, name string) {
+	r.movingControllersLock.Lock()
+	defer r.movingControllersLock.Unlock()
+	r.movingControllers[controllerKey(kind, namespace, name)]++
+}
+
+func (r *ReScheduler) finishMoving(kind, namespace, name string) {
+	r.movingControllersLock.Lock()
+	defer r.movingControllersLock.Unlock()
+	key := controllerKey(kind, namespace, name)
+	if r.movingControllers[key]--; r.movingControllers[key] <= 0 {
+		delete(r.movingControllers, key)
 	}
 }
 
+func controllerKey(kind, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+}
+
 func (r *ReScheduler) Execute(actionItem *proto.ActionItemDTO) (*turboaction.TurboAction, error) {
 	if actionItem == nil {
 		return nil, errors.New("ActionItem passed in is nil")
@@ -196,11 +239,11 @@ func (r *ReScheduler) reSchedule(action *turboaction.TurboAction) (*turboaction.
 		return nil, fmt.Errorf("move-abort: cannot get pod-%v parent info: %v", fullName, err.Error())
 	}
 
-	var f func(*client.Clientset, string, string, string, string) (string, error)
+	var f func(client.Interface, string, string, string, string) (string, error)
 	switch parentKind {
 	case "":
 		glog.V(3).Infof("pod-%v is a standalone Pod, move it directly.", fullName)
-		f = func(c *client.Clientset, ns, pname, cname, sname string) (string, error) { return "", nil }
+		f = func(c client.Interface, ns, pname, cname, sname string) (string, error) { return "", nil }
 	case KindReplicationController:
 		glog.V(3).Infof("pod-%v parent is a ReplicationController-%v", fullName, parentName)
 		f = updateRCscheduler
@@ -213,6 +256,11 @@ func (r *ReScheduler) reSchedule(action *turboaction.TurboAction) (*turboaction.
 		return nil, err
 	}
 
+	// The scheduler reconciler leaves the controller alone until the move finishes.
+	if parentKind != "" {
+		r.startMoving(parentKind, namespace, parentName)
+		defer r.finishMoving(parentKind, namespace, parentName)
+	}
 	preScheduler, err := f(r.kubeClient, namespace, parentName, "", DefaultNoneExistSchedulerName)
 	restore := func() {
 		if preScheduler != "" {
@@ -227,7 +275,12 @@ func (r *ReScheduler) reSchedule(action *turboaction.TurboAction) (*turboaction.
 	}
 
 	//3. move the Pod
-	npod, err := movePod(r.kubeClient, pod, nodeName)
+	var npod *api.Pod
+	if getMoveStrategy(r.kubeClient, pod, parentKind, parentName) == MoveStrategyCreateBeforeDelete {
+		npod, err = r.movePodCreateBeforeDelete(pod, parentKind, parentName, nodeName)
+	} else {
+		npod, err = movePod(r.kubeClient, pod, nodeName)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("re-schedule failed: failed to create new Pod: %v\n%v", fullName, err.Error())
 	}
@@ -244,7 +297,7 @@ func (r *ReScheduler) reSchedule(action *turboaction.TurboAction) (*turboaction.
 //update the schedulerName of a ReplicaSet to schedulerName.
 // if condName is not empty, then only current schedulerName is same to condName, then will do the update.
 // return the previous schedulerName
-func updateRSscheduler(client *client.Clientset, nameSpace, rsName, condName, schedulerName string) (string, error) {
+func updateRSscheduler(client client.Interface, nameSpace, rsName, condName, schedulerName string) (string, error) {
 	currentName := ""
 
 	rsClient := client.ExtensionsV1beta1().ReplicaSets(nameSpace)
@@ -277,6 +330,7 @@ func updateRSscheduler(client *client.Clientset, nameSpace, rsName, condName, sc
 
 	//3. update schedulerName
 	rs.Spec.Template.Spec.SchedulerName = schedulerName
+	updateOriginalSchedulerAnnotation(&rs.ObjectMeta, currentName, schedulerName)
 	_, err = rsClient.Update(rs)
 	if err != nil {
 		err = fmt.Errorf("failed to update RC-%v:%v\n", id, err.Error())
@@ -306,7 +360,7 @@ func updateRSscheduler(client *client.Clientset, nameSpace, rsName, condName, sc
 //update the schedulerName of a ReplicationController
 // if condName is not empty, then only current schedulerName is same to condName, then will do the update.
 // return the previous schedulerName
-func updateRCscheduler(client *client.Clientset, nameSpace, rcName, condName, schedulerName string) (string, error) {
+func updateRCscheduler(client client.Interface, nameSpace, rcName, condName, schedulerName string) (string, error) {
 	currentName := ""
 
 	id := fmt.Sprintf("%v/%v", nameSpace, rcName)
@@ -335,6 +389,7 @@ func updateRCscheduler(client *client.Clientset, nameSpace, rcName, condName, sc
 
 	//3. update
 	rc.Spec.Template.Spec.SchedulerName = schedulerName
+	updateOriginalSchedulerAnnotation(&rc.ObjectMeta, currentName, schedulerName)
 	rc, err = rcClient.Update(rc)
 	if err != nil {
 		err = fmt.Errorf("failed to update RC-%v:%v\n", id, err.Error())
@@ -362,8 +417,21 @@ func updateRCscheduler(client *client.Clientset, nameSpace, rcName, condName, sc
 	return currentName, nil
 }
 
+// Record the current scheduler name of a controller in the annotation when it is changed to the placeholder
+// scheduler, and remove the annotation when it is changed back.
+func updateOriginalSchedulerAnnotation(meta *metav1.ObjectMeta, currentName, schedulerName string) {
+	if schedulerName != DefaultNoneExistSchedulerName {
+		delete(meta.Annotations, OriginalSchedulerNameAnnotation)
+		return
+	}
+	if meta.Annotations == nil {
+		meta.Annotations = make(map[string]string)
+	}
+	meta.Annotations[OriginalSchedulerNameAnnotation] = currentName
+}
+
 // move pod nameSpace/podName to node nodeName
-func movePod(client *client.Clientset, pod *api.Pod, nodeName string) (*api.Pod, error) {
+func movePod(client client.Interface, pod *api.Pod, nodeName string) (*api.Pod, error) {
 	podClient := client.CoreV1().Pods(pod.Namespace)
 	if podClient == nil {
 		err := fmt.Errorf("cannot get Pod client for nameSpace:%v", pod.Namespace)
@@ -0,0 +1,171 @@
+package executor
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	client "k8s.io/client-go/kubernetes"
+	api "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/golang/glog"
+)
+
+// SchedulerNameReconciler restores the controllers left on the placeholder scheduler, e.g. if kubeturbo dies in the
+// middle of a move, and deletes the pods they created meanwhile, which would otherwise stay pending forever, as the
+// scheduler name of a pod cannot be changed. It also removes the replicas left by create-before-delete moves.
+type SchedulerNameReconciler struct {
+	kubeClient client.Interface
+	// The controllers being moved by the re-scheduler are left alone.
+	reScheduler *ReScheduler
+}
+
+func NewSchedulerNameReconciler(client client.Interface, reScheduler *ReScheduler) *SchedulerNameReconciler {
+	return &SchedulerNameReconciler{
+		kubeClient:  client,
+		reScheduler: reScheduler,
+	}
+}
+
+// Reconcile restores the scheduler name of the ReplicationControllers and ReplicaSets on the placeholder scheduler,
+// then deletes the pending pods on the placeholder scheduler of the restored controllers, so that they are recreated
+// with the original scheduler. The replicas added to the ReplicationControllers, ReplicaSets and Deployments by the
+// create-before-delete moves not in progress are removed as well, except from the ReplicaSets controlled by a
+// Deployment, which are scaled through the Deployment.
+func (r *SchedulerNameReconciler) Reconcile() {
+	// The controllers failed to be restored or being moved, whose pods are not deleted.
+	// <kind/namespace/name : true>
+	skipped := make(map[string]bool)
+
+	rcList, err := r.kubeClient.CoreV1().ReplicationControllers(api.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		glog.Errorf("Failed to list ReplicationControllers to reconcile scheduler names: %s", err)
+		return
+	}
+	for i := range rcList.Items {
+		rc := &rcList.Items[i]
+		r.removeSurgeReplicas(KindReplicationController, &rc.ObjectMeta)
+		if rc.Spec.Template == nil || rc.Spec.Template.Spec.SchedulerName != DefaultNoneExistSchedulerName {
+			continue
+		}
+		if !r.restore(KindReplicationController, &rc.ObjectMeta, updateRCscheduler) {
+			skipped[controllerKey(KindReplicationController, rc.Namespace, rc.Name)] = true
+		}
+	}
+
+	rsList, err := r.kubeClient.ExtensionsV1beta1().ReplicaSets(api.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		glog.Errorf("Failed to list ReplicaSets to reconcile scheduler names: %s", err)
+		return
+	}
+	for i := range rsList.Items {
+		rs := &rsList.Items[i]
+		// The replicas added by moves are on the Deployment of a ReplicaSet it controls, and the Deployment copies its
+		// annotations, including the surge one, onto the ReplicaSet.
+		if getControllingDeployment(&rs.ObjectMeta) == "" {
+			r.removeSurgeReplicas(KindReplicaSet, &rs.ObjectMeta)
+		}
+		if rs.Spec.Template.Spec.SchedulerName != DefaultNoneExistSchedulerName {
+			continue
+		}
+		if !r.restore(KindReplicaSet, &rs.ObjectMeta, updateRSscheduler) {
+			skipped[controllerKey(KindReplicaSet, rs.Namespace, rs.Name)] = true
+		}
+	}
+
+	deploymentList, err := r.kubeClient.AppsV1beta1().Deployments(api.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		glog.Errorf("Failed to list Deployments to reconcile surge replicas: %s", err)
+	} else {
+		for i := range deploymentList.Items {
+			r.removeSurgeReplicas(KindDeployment, &deploymentList.Items[i].ObjectMeta)
+		}
+	}
+
+	r.deletePendingPods(skipped)
+}
+
+// Remove the replicas added to a controller by the create-before-delete moves, unless it is being moved.
+func (r *SchedulerNameReconciler) removeSurgeReplicas(kind string, meta *metav1.ObjectMeta) {
+	surge := getSurgeReplicas(meta)
+	if surge == 0 || r.reScheduler.isMoving(kind, meta.Namespace, meta.Name) {
+		return
+	}
+	id := fmt.Sprintf("%s-%s/%s", kind, meta.Namespace, meta.Name)
+	glog.V(2).Infof("%s is left with %d replicas added by moves, removing them.", id, surge)
+	if err := surgeReplicas(r.kubeClient, meta.Namespace, kind, meta.Name, -surge); err != nil {
+		glog.Errorf("Failed to remove the replicas added to %s: %s", id, err)
+	}
+}
+
+// Restore the scheduler name of a controller on the placeholder scheduler with the given update function, unless it
+// is being moved. It returns whether the controller is restored.
+func (r *SchedulerNameReconciler) restore(kind string, meta *metav1.ObjectMeta,
+	update func(client.Interface, string, string, string, string) (string, error)) bool {
+	id := fmt.Sprintf("%s-%s/%s", kind, meta.Namespace, meta.Name)
+	if r.reScheduler.isMoving(kind, meta.Namespace, meta.Name) {
+		glog.V(3).Infof("%s is being moved, skip restoring its scheduler name.", id)
+		return false
+	}
+
+	schedulerName := originalSchedulerName(meta)
+	glog.V(2).Infof("%s is left on scheduler %s, restoring it to %s.", id, DefaultNoneExistSchedulerName,
+		schedulerName)
+	if _, err := update(r.kubeClient, meta.Namespace, meta.Name, DefaultNoneExistSchedulerName,
+		schedulerName); err != nil {
+		glog.Errorf("Failed to restore the scheduler name of %s: %s", id, err)
+		return false
+	}
+	return true
+}
+
+// Delete the pending pods on the placeholder scheduler, whose controllers are not skipped.
+func (r *SchedulerNameReconciler) deletePendingPods(skipped map[string]bool) {
+	listOption := metav1.ListOptions{FieldSelector: "spec.nodeName="}
+	podList, err := r.kubeClient.CoreV1().Pods(api.NamespaceAll).List(listOption)
+	if err != nil {
+		glog.Errorf("Failed to list pending pods to reconcile scheduler names: %s", err)
+		return
+	}
+
+	grace := podDeletionGracePeriod
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.Spec.SchedulerName != DefaultNoneExistSchedulerName || pod.DeletionTimestamp != nil {
+			continue
+		}
+		id := fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)
+
+		kind, name, err := getParentInfo(pod)
+		if err != nil {
+			glog.Errorf("Failed to get the parent of pending pod-%s: %s", id, err)
+			continue
+		}
+		if kind != KindReplicationController && kind != KindReplicaSet {
+			glog.V(3).Infof("Pending pod-%s on scheduler %s is not created by a ReplicationController or "+
+				"ReplicaSet, skip it.", id, DefaultNoneExistSchedulerName)
+			continue
+		}
+		if skipped[controllerKey(kind, pod.Namespace, name)] || r.reScheduler.isMoving(kind, pod.Namespace, name) {
+			continue
+		}
+
+		glog.V(2).Infof("Deleting pending pod-%s on scheduler %s so that %s-%s recreates it.", id,
+			DefaultNoneExistSchedulerName, kind, name)
+		delOption := &metav1.DeleteOptions{GracePeriodSeconds: &grace}
+		if err := r.kubeClient.CoreV1().Pods(pod.Namespace).Delete(pod.Name, delOption); err != nil {
+			glog.Errorf("Failed to delete pending pod-%s: %s", id, err)
+		}
+	}
+}
+
+// Get the scheduler name of a controller before it was changed to the placeholder scheduler. Without a valid
+// annotation, e.g. the controller was changed by an older kubeturbo, it is the default scheduler.
+func originalSchedulerName(meta *metav1.ObjectMeta) string {
+	schedulerName := meta.Annotations[OriginalSchedulerNameAnnotation]
+	if schedulerName == "" || schedulerName == DefaultNoneExistSchedulerName {
+		glog.Warningf("No valid original scheduler name of %s/%s in annotation %s, using %s.", meta.Namespace,
+			meta.Name, OriginalSchedulerNameAnnotation, api.DefaultSchedulerName)
+		return api.DefaultSchedulerName
+	}
+	return schedulerName
+}
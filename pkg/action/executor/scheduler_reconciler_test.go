@@ -0,0 +1,211 @@
+package executor
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	api "k8s.io/client-go/pkg/api/v1"
+	apps "k8s.io/client-go/pkg/apis/apps/v1beta1"
+	extensions "k8s.io/client-go/pkg/apis/extensions/v1beta1"
+)
+
+func TestOriginalSchedulerName(t *testing.T) {
+	table := []struct {
+		currentName          string
+		annotations          map[string]string
+		expectedAnnotation   string
+		expectedOriginalName string
+	}{
+		// Changed to the placeholder scheduler from the default one.
+		{"default-scheduler", nil, "default-scheduler", "default-scheduler"},
+		// Changed to the placeholder scheduler from a custom one, keeping the other annotations.
+		{"my-scheduler", map[string]string{"a": "b"}, "my-scheduler", "my-scheduler"},
+		// Overwriting an annotation left by an interrupted move.
+		{"my-scheduler", map[string]string{OriginalSchedulerNameAnnotation: "other"}, "my-scheduler",
+			"my-scheduler"},
+		// A controller without a scheduler name.
+		{"", nil, "", api.DefaultSchedulerName},
+	}
+	for i, item := range table {
+		meta := &metav1.ObjectMeta{Annotations: item.annotations}
+		updateOriginalSchedulerAnnotation(meta, item.currentName, DefaultNoneExistSchedulerName)
+		annotation, exist := meta.Annotations[OriginalSchedulerNameAnnotation]
+		if !exist || annotation != item.expectedAnnotation {
+			t.Errorf("Test case %d failed: expected annotation %q, got %q", i, item.expectedAnnotation,
+				annotation)
+		}
+		if name := originalSchedulerName(meta); name != item.expectedOriginalName {
+			t.Errorf("Test case %d failed: expected original scheduler name %s, got %s", i,
+				item.expectedOriginalName, name)
+		}
+
+		// Restoring the scheduler name removes the annotation.
+		updateOriginalSchedulerAnnotation(meta, DefaultNoneExistSchedulerName, item.currentName)
+		if _, exist := meta.Annotations[OriginalSchedulerNameAnnotation]; exist {
+			t.Errorf("Test case %d failed: annotation %s is not removed", i, OriginalSchedulerNameAnnotation)
+		}
+		if name := originalSchedulerName(meta); name != api.DefaultSchedulerName {
+			t.Errorf("Test case %d failed: expected original scheduler name %s without annotation, got %s", i,
+				api.DefaultSchedulerName, name)
+		}
+	}
+}
+
+func TestReSchedulerMovingControllers(t *testing.T) {
+	r := NewReScheduler(nil, nil)
+	r.startMoving(KindReplicaSet, "default", "web")
+	r.startMoving(KindReplicaSet, "default", "web")
+	if !r.isMoving(KindReplicaSet, "default", "web") {
+		t.Errorf("ReplicaSet default/web is not moving after starting two moves")
+	}
+	if r.isMoving(KindReplicationController, "default", "web") {
+		t.Errorf("ReplicationController default/web is moving without any move")
+	}
+	r.finishMoving(KindReplicaSet, "default", "web")
+	if !r.isMoving(KindReplicaSet, "default", "web") {
+		t.Errorf("ReplicaSet default/web is not moving after finishing one of two moves")
+	}
+	r.finishMoving(KindReplicaSet, "default", "web")
+	if r.isMoving(KindReplicaSet, "default", "web") {
+		t.Errorf("ReplicaSet default/web is moving after finishing all moves")
+	}
+}
+
+func newTestObjectMeta(name string, annotations map[string]string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Namespace: "default", Name: name, Annotations: annotations}
+}
+
+func newTestReplicationController(name, schedulerName string, replicas int32,
+	annotations map[string]string) *api.ReplicationController {
+	return &api.ReplicationController{
+		ObjectMeta: newTestObjectMeta(name, annotations),
+		Spec: api.ReplicationControllerSpec{
+			Replicas: &replicas,
+			Template: &api.PodTemplateSpec{Spec: api.PodSpec{SchedulerName: schedulerName}},
+		},
+	}
+}
+
+func newTestReplicaSet(name, schedulerName string, replicas int32,
+	annotations map[string]string) *extensions.ReplicaSet {
+	return &extensions.ReplicaSet{
+		ObjectMeta: newTestObjectMeta(name, annotations),
+		Spec: extensions.ReplicaSetSpec{
+			Replicas: &replicas,
+			Template: api.PodTemplateSpec{Spec: api.PodSpec{SchedulerName: schedulerName}},
+		},
+	}
+}
+
+func newTestDeployment(name string, replicas int32, annotations map[string]string) *apps.Deployment {
+	return &apps.Deployment{
+		ObjectMeta: newTestObjectMeta(name, annotations),
+		Spec:       apps.DeploymentSpec{Replicas: &replicas},
+	}
+}
+
+// Build a pod of the given scheduler and node, controlled by the given parent if its kind is not empty.
+func newTestControlledPod(name, schedulerName, nodeName, parentKind, parentName string) *api.Pod {
+	pod := &api.Pod{
+		ObjectMeta: newTestObjectMeta(name, nil),
+		Spec:       api.PodSpec{SchedulerName: schedulerName, NodeName: nodeName},
+	}
+	if parentKind != "" {
+		controller := true
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: parentKind, Name: parentName, Controller: &controller}}
+	}
+	return pod
+}
+
+func TestSchedulerNameReconcilerReconcile(t *testing.T) {
+	surge := func(n string) map[string]string { return map[string]string{SurgeReplicasAnnotation: n} }
+	original := map[string]string{OriginalSchedulerNameAnnotation: "my-scheduler"}
+
+	kubeClient := newFakeClientset()
+	kubeClient.addReplicationController(newTestReplicationController("rc-left", DefaultNoneExistSchedulerName, 2,
+		original))
+	kubeClient.addReplicationController(newTestReplicationController("rc-surge", api.DefaultSchedulerName, 5,
+		surge("2")))
+	kubeClient.addReplicaSet(newTestReplicaSet("rs-left", DefaultNoneExistSchedulerName, 2, nil))
+	kubeClient.addReplicaSet(newTestReplicaSet("rs-moving", DefaultNoneExistSchedulerName, 3, surge("1")))
+	kubeClient.addDeployment(newTestDeployment("deployment-surge", 3, surge("1")))
+	// The Deployment copies its surge annotation onto the ReplicaSet it controls.
+	deploymentRS := newTestReplicaSet("deployment-surge-rs", api.DefaultSchedulerName, 3, surge("1"))
+	controller := true
+	deploymentRS.OwnerReferences = []metav1.OwnerReference{{Kind: KindDeployment, Name: "deployment-surge",
+		Controller: &controller}}
+	kubeClient.addReplicaSet(deploymentRS)
+	kubeClient.addPod(newTestControlledPod("rc-left-pending", DefaultNoneExistSchedulerName, "",
+		KindReplicationController, "rc-left"))
+	kubeClient.addPod(newTestControlledPod("rc-left-recreated", "my-scheduler", "",
+		KindReplicationController, "rc-left"))
+	kubeClient.addPod(newTestControlledPod("rs-left-pending", DefaultNoneExistSchedulerName, "",
+		KindReplicaSet, "rs-left"))
+	kubeClient.addPod(newTestControlledPod("rs-left-bound", DefaultNoneExistSchedulerName, "node-1",
+		KindReplicaSet, "rs-left"))
+	kubeClient.addPod(newTestControlledPod("rs-moving-pending", DefaultNoneExistSchedulerName, "",
+		KindReplicaSet, "rs-moving"))
+	kubeClient.addPod(newTestControlledPod("standalone-pending", DefaultNoneExistSchedulerName, "", "", ""))
+
+	reScheduler := NewReScheduler(nil, nil)
+	reScheduler.startMoving(KindReplicaSet, "default", "rs-moving")
+	NewSchedulerNameReconciler(kubeClient, reScheduler).Reconcile()
+
+	table := []struct {
+		kind                  string
+		name                  string
+		expectedSchedulerName string
+		expectedReplicas      int32
+		expectedAnnotations   map[string]string
+	}{
+		// Restored to the scheduler in the annotation, which is removed.
+		{KindReplicationController, "rc-left", "my-scheduler", 2, map[string]string{}},
+		// The surge replicas are removed.
+		{KindReplicationController, "rc-surge", api.DefaultSchedulerName, 3, map[string]string{}},
+		// Restored to the default scheduler without annotation.
+		{KindReplicaSet, "rs-left", api.DefaultSchedulerName, 2, nil},
+		// Left alone while being moved.
+		{KindReplicaSet, "rs-moving", DefaultNoneExistSchedulerName, 3, surge("1")},
+		{KindDeployment, "deployment-surge", "", 2, map[string]string{}},
+		// Left to the Deployment, whose surge replicas are removed.
+		{KindReplicaSet, "deployment-surge-rs", api.DefaultSchedulerName, 3, surge("1")},
+	}
+	for i, item := range table {
+		var meta metav1.ObjectMeta
+		var schedulerName string
+		var replicas *int32
+		key := objectKey("default", item.name)
+		switch item.kind {
+		case KindReplicationController:
+			rc := kubeClient.rcs[key]
+			meta, schedulerName, replicas = rc.ObjectMeta, rc.Spec.Template.Spec.SchedulerName, rc.Spec.Replicas
+		case KindReplicaSet:
+			rs := kubeClient.rss[key]
+			meta, schedulerName, replicas = rs.ObjectMeta, rs.Spec.Template.Spec.SchedulerName, rs.Spec.Replicas
+		case KindDeployment:
+			deployment := kubeClient.deployments[key]
+			meta, replicas = deployment.ObjectMeta, deployment.Spec.Replicas
+		}
+		if schedulerName != item.expectedSchedulerName {
+			t.Errorf("Test case %d failed: expected scheduler name %s of %s-%s, got %s", i,
+				item.expectedSchedulerName, item.kind, key, schedulerName)
+		}
+		if *replicas != item.expectedReplicas {
+			t.Errorf("Test case %d failed: expected %d replicas of %s-%s, got %d", i, item.expectedReplicas,
+				item.kind, key, *replicas)
+		}
+		if !reflect.DeepEqual(meta.Annotations, item.expectedAnnotations) {
+			t.Errorf("Test case %d failed: expected annotations %v of %s-%s, got %v", i,
+				item.expectedAnnotations, item.kind, key, meta.Annotations)
+		}
+	}
+
+	// Only the pending pods on the placeholder scheduler of the restored controllers are deleted.
+	expectedDeleted := []string{"default/rc-left-pending", "default/rs-left-pending"}
+	sort.Strings(kubeClient.deletedPods)
+	if !reflect.DeepEqual(kubeClient.deletedPods, expectedDeleted) {
+		t.Errorf("Expected deleted pods %v, got %v", expectedDeleted, kubeClient.deletedPods)
+	}
+}
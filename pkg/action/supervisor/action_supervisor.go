@@ -61,17 +61,50 @@ func (s *ActionSupervisor) Start() {
 	go wait.Until(s.getNextExecutedTurboAction, 0, s.config.StopEverything)
 }
 
+// Stop stops watching the executed actions. The action being checked is still checked until it succeeds or expires.
+func (s *ActionSupervisor) Stop() {
+	close(s.config.StopEverything)
+}
+
 func (s *ActionSupervisor) getNextExecutedTurboAction() {
 	action := <-s.config.executedActionChan
 	glog.V(3).Infof("Executed action is %v", action)
+	checkFunc := s.getCheckFunc(action)
+	if checkFunc == nil {
+		return
+	}
+	if s.updateAction(action, checkFunc) {
+		s.config.succeededActionChan <- action
+	} else {
+		s.config.failedActionChan <- action
+	}
+}
+
+// Supervise checks an executed action until it succeeds or expires, and updates its status to success or fail.
+// Unlike the actions sent to the executed action channel, the result is returned to the caller directly.
+func (s *ActionSupervisor) Supervise(action *turboaction.TurboAction) error {
+	checkFunc := s.getCheckFunc(action)
+	if checkFunc == nil {
+		action.Status = turboaction.Fail
+		return fmt.Errorf("cannot check %s action", action.Content.ActionType)
+	}
+	if !s.updateAction(action, checkFunc) {
+		return fmt.Errorf("%s action on %s-%s is not done in time", action.Content.ActionType,
+			action.Content.TargetObject.TargetObjectType, action.Content.TargetObject.TargetObjectName)
+	}
+	return nil
+}
+
+func (s *ActionSupervisor) getCheckFunc(action *turboaction.TurboAction) CheckActionFunc {
 	switch {
 	case action.Content.ActionType == "move":
-		s.updateAction(action, s.checkMoveAction)
+		return s.checkMoveAction
 	case action.Content.ActionType == "provision":
-		s.updateAction(action, s.checkProvisionAction)
+		return s.checkProvisionAction
 	case action.Content.ActionType == "unbind":
-		s.updateAction(action, s.checkUnbindAction)
+		return s.checkUnbindAction
 	}
+	return nil
 }
 
 func (s *ActionSupervisor) checkMoveAction(action *turboaction.TurboAction) (bool, error) {
@@ -159,10 +192,13 @@ func (s *ActionSupervisor) checkScaleAction(action *turboaction.TurboAction) (bo
 	return false, nil
 }
 
-func (s *ActionSupervisor) updateAction(action *turboaction.TurboAction, checkFunc CheckActionFunc) {
+// Check the action until it succeeds or expires, and return whether it succeeds.
+func (s *ActionSupervisor) updateAction(action *turboaction.TurboAction, checkFunc CheckActionFunc) bool {
 	// Check if the event has expired. If true, update the status to fail and return;
 	// Otherwise, only update the LastTimestamp.
-	for !checkExpired(action) {
+	// The action is checked at least once, as an executor may take longer than the expiration, e.g. waiting for the
+	// new pod of a create-before-delete move to be ready.
+	for {
 		successful, err := checkFunc(action)
 		if err != nil {
 			// TODO: do we want to return?
@@ -170,8 +206,10 @@ func (s *ActionSupervisor) updateAction(action *turboaction.TurboAction, checkFu
 		}
 		if successful {
 			action.Status = turboaction.Success
-			s.config.succeededActionChan <- action
-			return
+			return true
+		}
+		if checkExpired(action) {
+			break
 		}
 
 		time.Sleep(time.Second * 1)
@@ -181,8 +219,7 @@ func (s *ActionSupervisor) updateAction(action *turboaction.TurboAction, checkFu
 	glog.Errorf("Timeout processing when %s action on %s-%s", action.Content.ActionType,
 		action.Content.TargetObject.TargetObjectType, action.Content.TargetObject.TargetObjectName)
 	action.Status = turboaction.Fail
-	s.config.failedActionChan <- action
-	return
+	return false
 }
 
 func checkExpired(action *turboaction.TurboAction) bool {
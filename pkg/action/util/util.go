@@ -10,6 +10,7 @@ import (
 	"k8s.io/client-go/pkg/apis/apps/v1beta1"
 
 	"github.com/turbonomic/kubeturbo/pkg/discovery/dtofactory/property"
+	discutil "github.com/turbonomic/kubeturbo/pkg/discovery/util"
 
 	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
 
@@ -124,7 +125,7 @@ func findRCBasedOnPodLabel(rcList []api.ReplicationController, labels map[string
 }
 
 // Get all nodes currently in K8s.
-func GetAllNodes(kubeClient *client.Clientset) ([]api.Node, error) {
+func GetAllNodes(kubeClient client.Interface) ([]api.Node, error) {
 	nodeList, err := kubeClient.CoreV1().Nodes().List(listOption)
 	if err != nil {
 		return nil, fmt.Errorf("Error when getting all the nodes :%s", err)
@@ -134,7 +135,7 @@ func GetAllNodes(kubeClient *client.Clientset) ([]api.Node, error) {
 
 // Iterate all nodes to find the name of the node which has the provided IP address.
 // TODO. We can also create a IP->NodeName map to save time. But it consumes space.
-func GetNodeNameFromIP(kubeClient *client.Clientset, machineIPs []string) (string, error) {
+func GetNodeNameFromIP(kubeClient client.Interface, machineIPs []string) (string, error) {
 	ipAddresses := machineIPs
 	allNodes, err := GetAllNodes(kubeClient)
 	if err != nil {
@@ -174,7 +175,7 @@ func GetPodFromProperties(kubeClient *client.Clientset, entityType proto.EntityD
 
 // Get a pod instance from the uuid of a pod. Since there is no support for uuid lookup, we have to get all the pods
 // and then find the correct pod based on uuid match.
-func GetPodFromUUID(kubeClient *client.Clientset, podUUID string) (*api.Pod, error) {
+func GetPodFromUUID(kubeClient client.Interface, podUUID string) (*api.Pod, error) {
 	namespace := api.NamespaceAll
 	podList, err := kubeClient.CoreV1().Pods(namespace).List(listOption)
 	if err != nil {
@@ -198,9 +199,18 @@ func FindApplicationPodProvider(kubeClient *client.Clientset, providers []*proto
 		if providerInfo == nil {
 			continue
 		}
-		if providerInfo.GetEntityType() == proto.EntityDTO_CONTAINER_POD {
+		providerType := providerInfo.GetEntityType()
+		if providerType == proto.EntityDTO_CONTAINER_POD || providerType == proto.EntityDTO_CONTAINER {
 			providerIDs := providerInfo.GetIds()
 			for _, id := range providerIDs {
+				if providerType == proto.EntityDTO_CONTAINER {
+					podID, err := discutil.PodIDFromContainerID(id)
+					if err != nil {
+						glog.Errorf("Error getting pod provider from container identifier %s: %s", id, err)
+						continue
+					}
+					id = podID
+				}
 				podProvider, err := GetPodFromUUID(kubeClient, id)
 				if err != nil {
 					glog.Errorf("Error getting pod provider from pod identifier %s", id)
@@ -1,15 +1,23 @@
 package cluster
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"sync"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	client "k8s.io/client-go/kubernetes"
 	api "k8s.io/client-go/pkg/api/v1"
+	extensions "k8s.io/client-go/pkg/apis/extensions/v1beta1"
 	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 
 	"github.com/golang/glog"
 )
@@ -18,6 +26,16 @@ const (
 	k8sDefaultNamespace = "default"
 
 	kubernetesServiceName = "kubernetes"
+
+	// Name of the pod index keyed by the name of the hosting node.
+	podNodeNameIndex = "nodeName"
+
+	// The informers only rely on watch events. No periodical resync is required.
+	defaultResyncPeriod time.Duration = 0
+
+	// The max time to wait for the informer caches to be populated.
+	defaultCacheSyncTimeout time.Duration = time.Minute * 2
+	cacheSyncPollPeriod     time.Duration = time.Millisecond * 100
 )
 
 var (
@@ -25,8 +43,26 @@ var (
 	fieldSelectEverything = fields.Everything().String()
 )
 
+// ClusterScraper provides access to the objects in a Kubernetes cluster.
+// Nodes, pods, services, endpoints and controllers are served from a local cache backed by shared informers once
+// the cache has been started and the informer of the kind of objects has synced. Before that, requests go directly
+// to the API server, so that e.g. a forbidden ReplicaSet informer does not keep nodes and pods from the cache.
 type ClusterScraper struct {
 	*client.Clientset
+
+	nodeInformer      cache.SharedIndexInformer
+	podInformer       cache.SharedIndexInformer
+	serviceInformer   cache.SharedIndexInformer
+	endpointsInformer cache.SharedIndexInformer
+	rcInformer        cache.SharedIndexInformer
+	rsInformer        cache.SharedIndexInformer
+
+	startOnce sync.Once
+	// The max time Start waits for the informers to sync.
+	cacheSyncTimeout time.Duration
+
+	// the scope of the snapshots. The other methods always return all the objects in the cluster.
+	scope *DiscoveryScope
 }
 
 func NewClusterInfoScraper(kubeConfig *restclient.Config) (*ClusterScraper, error) {
@@ -35,12 +71,137 @@ func NewClusterInfoScraper(kubeConfig *restclient.Config) (*ClusterScraper, erro
 		return nil, fmt.Errorf("failed to create kubeClient: %s", err)
 	}
 
+	return NewClusterScraper(kubeClient), nil
+}
+
+// Create a new ClusterScraper based on the given kubeClient. The local cache is not started until Start() is called.
+func NewClusterScraper(kubeClient *client.Clientset) *ClusterScraper {
+	coreClient := kubeClient.CoreV1().RESTClient()
+	extClient := kubeClient.ExtensionsV1beta1().RESTClient()
+
 	return &ClusterScraper{
 		Clientset: kubeClient,
-	}, nil
+
+		nodeInformer: newInformer(coreClient, "nodes", &api.Node{}, cache.Indexers{}),
+		podInformer: newInformer(coreClient, "pods", &api.Pod{}, cache.Indexers{
+			cache.NamespaceIndex: cache.MetaNamespaceIndexFunc,
+			podNodeNameIndex:     podNodeNameIndexFunc,
+		}),
+		serviceInformer:   newInformer(coreClient, "services", &api.Service{}, namespaceIndexers()),
+		endpointsInformer: newInformer(coreClient, "endpoints", &api.Endpoints{}, namespaceIndexers()),
+		rcInformer:        newInformer(coreClient, "replicationcontrollers", &api.ReplicationController{}, namespaceIndexers()),
+		rsInformer:        newInformer(extClient, "replicasets", &extensions.ReplicaSet{}, namespaceIndexers()),
+
+		cacheSyncTimeout: defaultCacheSyncTimeout,
+	}
+}
+
+// Limit the snapshots taken by the scraper to the given discovery scope.
+func (s *ClusterScraper) WithDiscoveryScope(scope *DiscoveryScope) *ClusterScraper {
+	s.scope = scope
+	return s
+}
+
+func newInformer(c cache.Getter, resource string, objType runtime.Object, indexers cache.Indexers) cache.SharedIndexInformer {
+	lw := cache.NewListWatchFromClient(c, resource, api.NamespaceAll, fields.Everything())
+	return cache.NewSharedIndexInformer(lw, objType, defaultResyncPeriod, indexers)
+}
+
+func namespaceIndexers() cache.Indexers {
+	return cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}
+}
+
+// Index pods based on the name of the node they are running on.
+func podNodeNameIndexFunc(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*api.Pod)
+	if !ok {
+		return nil, fmt.Errorf("object is not a pod: %v", obj)
+	}
+	if pod.Spec.NodeName == "" {
+		return []string{}, nil
+	}
+	return []string{pod.Spec.NodeName}, nil
+}
+
+// Start all the informers and wait until their caches are populated. Start only takes effect the first time it is
+// called. Closing stopCh stops all the informers.
+// If some informers are not synced in time, e.g. their objects are forbidden, an error is returned but all the
+// informers keep running: each kind of objects is served from the cache as soon as its informer is synced.
+func (s *ClusterScraper) Start(stopCh <-chan struct{}) error {
+	var err error
+	s.startOnce.Do(func() {
+		informers := s.informers()
+		for _, informer := range informers {
+			go informer.Run(stopCh)
+		}
+
+		var notSynced []string
+		err = wait.PollImmediate(cacheSyncPollPeriod, s.cacheSyncTimeout, func() (bool, error) {
+			select {
+			case <-stopCh:
+				return false, errors.New("cluster cache is stopped before it gets synced")
+			default:
+			}
+			notSynced = notSyncedResources(informers)
+			return len(notSynced) == 0, nil
+		})
+		if err != nil {
+			err = fmt.Errorf("failed to sync the cluster cache of %v: %s", notSynced, err)
+			go waitForCacheSync(informers, stopCh)
+			return
+		}
+		glog.V(2).Infof("Cluster cache has been synced.")
+	})
+	return err
+}
+
+// Wait in the background until all the informers are synced or stopped.
+func waitForCacheSync(informers map[string]cache.SharedIndexInformer, stopCh <-chan struct{}) {
+	err := wait.PollUntil(cacheSyncPollPeriod, func() (bool, error) {
+		return len(notSyncedResources(informers)) == 0, nil
+	}, stopCh)
+	if err == nil {
+		glog.V(2).Infof("Cluster cache has been synced.")
+	}
+}
+
+// Get the sorted resources of the given informers which are not synced yet.
+func notSyncedResources(informers map[string]cache.SharedIndexInformer) []string {
+	var resources []string
+	for resource, informer := range informers {
+		if !informer.HasSynced() {
+			resources = append(resources, resource)
+		}
+	}
+	sort.Strings(resources)
+	return resources
+}
+
+// The informers keyed by the resource they list and watch.
+func (s *ClusterScraper) informers() map[string]cache.SharedIndexInformer {
+	return map[string]cache.SharedIndexInformer{
+		"nodes":                  s.nodeInformer,
+		"pods":                   s.podInformer,
+		"services":               s.serviceInformer,
+		"endpoints":              s.endpointsInformer,
+		"replicationcontrollers": s.rcInformer,
+		"replicasets":            s.rsInformer,
+	}
+}
+
+// Check if the objects of the given informer can be served from the local cache.
+func cacheSynced(informer cache.SharedIndexInformer) bool {
+	return informer.HasSynced()
 }
 
 func (s *ClusterScraper) GetAllNodes() ([]*api.Node, error) {
+	if cacheSynced(s.nodeInformer) {
+		var nodes []*api.Node
+		err := cache.ListAll(s.nodeInformer.GetIndexer(), labels.Everything(), func(obj interface{}) {
+			nodes = append(nodes, obj.(*api.Node))
+		})
+		return nodes, err
+	}
 	listOption := metav1.ListOptions{
 		LabelSelector: labelSelectEverything,
 		FieldSelector: fieldSelectEverything,
@@ -61,6 +222,14 @@ func (s *ClusterScraper) GetNodes(opts metav1.ListOptions) ([]*api.Node, error)
 }
 
 func (s *ClusterScraper) GetAllPods() ([]*api.Pod, error) {
+	if cacheSynced(s.podInformer) {
+		var pods []*api.Pod
+		err := cache.ListAllByNamespace(s.podInformer.GetIndexer(), api.NamespaceAll, labels.Everything(),
+			func(obj interface{}) {
+				pods = append(pods, obj.(*api.Pod))
+			})
+		return pods, err
+	}
 	listOption := metav1.ListOptions{
 		LabelSelector: labelSelectEverything,
 		FieldSelector: fieldSelectEverything,
@@ -82,6 +251,14 @@ func (s *ClusterScraper) GetPods(namespaces string, opts metav1.ListOptions) ([]
 }
 
 func (s *ClusterScraper) GetAllServices() ([]*api.Service, error) {
+	if cacheSynced(s.serviceInformer) {
+		var services []*api.Service
+		err := cache.ListAllByNamespace(s.serviceInformer.GetIndexer(), api.NamespaceAll, labels.Everything(),
+			func(obj interface{}) {
+				services = append(services, obj.(*api.Service))
+			})
+		return services, err
+	}
 	listOption := metav1.ListOptions{
 		LabelSelector: labelSelectEverything,
 	}
@@ -116,13 +293,74 @@ func (s *ClusterScraper) GetEndpoints(namespaces string, opts metav1.ListOptions
 }
 
 func (s *ClusterScraper) GetAllEndpoints() ([]*api.Endpoints, error) {
+	if cacheSynced(s.endpointsInformer) {
+		var endpoints []*api.Endpoints
+		err := cache.ListAllByNamespace(s.endpointsInformer.GetIndexer(), api.NamespaceAll, labels.Everything(),
+			func(obj interface{}) {
+				endpoints = append(endpoints, obj.(*api.Endpoints))
+			})
+		return endpoints, err
+	}
 	listOption := metav1.ListOptions{
 		LabelSelector: labelSelectEverything,
 	}
 	return s.GetEndpoints(api.NamespaceAll, listOption)
 }
 
+func (s *ClusterScraper) GetAllReplicationControllers() ([]*api.ReplicationController, error) {
+	if cacheSynced(s.rcInformer) {
+		var rcs []*api.ReplicationController
+		err := cache.ListAllByNamespace(s.rcInformer.GetIndexer(), api.NamespaceAll, labels.Everything(),
+			func(obj interface{}) {
+				rcs = append(rcs, obj.(*api.ReplicationController))
+			})
+		return rcs, err
+	}
+	rcList, err := s.CoreV1().ReplicationControllers(api.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	rcs := make([]*api.ReplicationController, len(rcList.Items))
+	for i := 0; i < len(rcList.Items); i++ {
+		rcs[i] = &rcList.Items[i]
+	}
+	return rcs, nil
+}
+
+func (s *ClusterScraper) GetAllReplicaSets() ([]*extensions.ReplicaSet, error) {
+	if cacheSynced(s.rsInformer) {
+		var rss []*extensions.ReplicaSet
+		err := cache.ListAllByNamespace(s.rsInformer.GetIndexer(), api.NamespaceAll, labels.Everything(),
+			func(obj interface{}) {
+				rss = append(rss, obj.(*extensions.ReplicaSet))
+			})
+		return rss, err
+	}
+	rsList, err := s.ExtensionsV1beta1().ReplicaSets(api.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	rss := make([]*extensions.ReplicaSet, len(rsList.Items))
+	for i := 0; i < len(rsList.Items); i++ {
+		rss[i] = &rsList.Items[i]
+	}
+	return rss, nil
+}
+
 func (s *ClusterScraper) GetKubernetesServiceID() (svcID string, err error) {
+	if cacheSynced(s.serviceInformer) {
+		obj, exist, getErr := s.serviceInformer.GetIndexer().GetByKey(k8sDefaultNamespace + "/" + kubernetesServiceName)
+		if getErr != nil {
+			err = getErr
+			return
+		}
+		if !exist {
+			err = fmt.Errorf("service %s/%s does not exist", k8sDefaultNamespace, kubernetesServiceName)
+			return
+		}
+		svcID = string(obj.(*api.Service).UID)
+		return
+	}
 	svc, err := s.CoreV1().Services(k8sDefaultNamespace).Get(kubernetesServiceName, metav1.GetOptions{})
 	if err != nil {
 		return
@@ -144,8 +382,21 @@ func (s *ClusterScraper) GetRunningPodsOnNodes(nodeList []*api.Node) []*api.Pod
 	return pods
 }
 
-// TODO, create a local pod, node cache to avoid too many API request.
 func (s *ClusterScraper) findRunningPodsOnNode(nodeName string) ([]*api.Pod, error) {
+	if cacheSynced(s.podInformer) {
+		objs, err := s.podInformer.GetIndexer().ByIndex(podNodeNameIndex, nodeName)
+		if err != nil {
+			return nil, err
+		}
+		var pods []*api.Pod
+		for _, obj := range objs {
+			pod := obj.(*api.Pod)
+			if pod.Status.Phase == api.PodRunning {
+				pods = append(pods, pod)
+			}
+		}
+		return pods, nil
+	}
 	fieldSelector, err := fields.ParseSelector("spec.nodeName=" + nodeName + ",status.phase=" +
 		string(api.PodRunning))
 	if err != nil {
@@ -0,0 +1,70 @@
+package cluster
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// An informer which never lists nor watches. Its objects are added to the indexer directly.
+type fakeInformer struct {
+	cache.SharedIndexInformer
+	indexer cache.Indexer
+	synced  bool
+}
+
+func newFakeInformer(indexers cache.Indexers, synced bool, objs ...interface{}) *fakeInformer {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, indexers)
+	for _, obj := range objs {
+		indexer.Add(obj)
+	}
+	return &fakeInformer{indexer: indexer, synced: synced}
+}
+
+func (i *fakeInformer) Run(stopCh <-chan struct{}) {
+	<-stopCh
+}
+
+func (i *fakeInformer) HasSynced() bool {
+	return i.synced
+}
+
+func (i *fakeInformer) GetIndexer() cache.Indexer {
+	return i.indexer
+}
+
+func TestClusterScraperPartiallySynced(t *testing.T) {
+	// The ReplicaSet informer never syncs, e.g. listing ReplicaSets is forbidden.
+	scraper := &ClusterScraper{
+		nodeInformer: newFakeInformer(cache.Indexers{}, true, newNode("node-1"), newNode("node-2")),
+		podInformer: newFakeInformer(cache.Indexers{podNodeNameIndex: podNodeNameIndexFunc}, true,
+			newPod("pod-1", "node-1", "Running")),
+		serviceInformer:   newFakeInformer(namespaceIndexers(), true),
+		endpointsInformer: newFakeInformer(namespaceIndexers(), true),
+		rcInformer:        newFakeInformer(namespaceIndexers(), true),
+		rsInformer:        newFakeInformer(namespaceIndexers(), false),
+
+		cacheSyncTimeout: cacheSyncPollPeriod,
+	}
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	err := scraper.Start(stopCh)
+	if err == nil || !strings.Contains(err.Error(), "[replicasets]") {
+		t.Errorf("Expected an error for the ReplicaSet informer only, got %v", err)
+	}
+
+	// The nodes and pods are served from the cache without the API server.
+	nodes, err := scraper.GetAllNodes()
+	if err != nil || len(nodes) != 2 {
+		t.Errorf("Expected 2 nodes from the cache, got %v: %v", nodes, err)
+	}
+	pods := scraper.GetRunningPodsOnNodes(nodes)
+	if len(pods) != 1 || pods[0].Name != "pod-1" {
+		t.Errorf("Expected pod-1 running on the nodes from the cache, got %v", pods)
+	}
+	if cacheSynced(scraper.rsInformer) {
+		t.Errorf("ReplicaSets are served from the cache before it is synced")
+	}
+}
@@ -0,0 +1,173 @@
+package cluster
+
+import (
+	"fmt"
+
+	api "k8s.io/client-go/pkg/api/v1"
+	extensions "k8s.io/client-go/pkg/apis/extensions/v1beta1"
+
+	"github.com/golang/glog"
+)
+
+// ClusterSnapshot is a point-in-time view of the objects in a Kubernetes cluster.
+// All the phases of one discovery cycle read from the same snapshot, so they see a consistent topology.
+// A snapshot shares objects with the local cache. Objects in a snapshot must not be modified.
+// If the snapshot has a discovery scope, only the pods, services and controllers in the scope are kept, while the
+// running pods out of the scope are still indexed, because they count toward the usage of the nodes.
+type ClusterSnapshot struct {
+	Nodes                  []*api.Node
+	Pods                   []*api.Pod
+	Services               []*api.Service
+	Endpoints              []*api.Endpoints
+	ReplicationControllers []*api.ReplicationController
+	ReplicaSets            []*extensions.ReplicaSet
+
+	// index of running pods, including the pods out of the scope. key: node name; value: running pods on the node.
+	runningPodsByNode map[string][]*api.Pod
+
+	// the default/kubernetes service, which identifies the cluster even if its namespace is out of the scope.
+	kubernetesService *api.Service
+
+	scope *DiscoveryScope
+}
+
+// Build a snapshot from the given objects.
+func NewClusterSnapshot(nodes []*api.Node, pods []*api.Pod, services []*api.Service,
+	endpoints []*api.Endpoints) *ClusterSnapshot {
+	snapshot := &ClusterSnapshot{
+		Nodes:     nodes,
+		Pods:      pods,
+		Services:  services,
+		Endpoints: endpoints,
+	}
+	snapshot.buildIndex()
+	return snapshot
+}
+
+func (cs *ClusterSnapshot) buildIndex() {
+	cs.runningPodsByNode = make(map[string][]*api.Pod)
+	for _, pod := range cs.Pods {
+		if pod.Spec.NodeName == "" || pod.Status.Phase != api.PodRunning {
+			continue
+		}
+		cs.runningPodsByNode[pod.Spec.NodeName] = append(cs.runningPodsByNode[pod.Spec.NodeName], pod)
+	}
+	for _, svc := range cs.Services {
+		if svc.Namespace == k8sDefaultNamespace && svc.Name == kubernetesServiceName {
+			cs.kubernetesService = svc
+		}
+	}
+}
+
+// Keep only the pods, services and controllers in the given scope. The running pods out of the scope are still
+// returned by GetRunningPodsOnNodes.
+func (cs *ClusterSnapshot) WithScope(scope *DiscoveryScope) *ClusterSnapshot {
+	cs.scope = scope
+	if scope.IsEverything() {
+		return cs
+	}
+
+	var pods []*api.Pod
+	for _, pod := range cs.Pods {
+		if scope.PodInScope(pod) {
+			pods = append(pods, pod)
+		}
+	}
+	var services []*api.Service
+	for _, service := range cs.Services {
+		if scope.NamespaceInScope(service.Namespace) {
+			services = append(services, service)
+		}
+	}
+	var endpoints []*api.Endpoints
+	for _, ep := range cs.Endpoints {
+		if scope.NamespaceInScope(ep.Namespace) {
+			endpoints = append(endpoints, ep)
+		}
+	}
+	var rcs []*api.ReplicationController
+	for _, rc := range cs.ReplicationControllers {
+		if scope.NamespaceInScope(rc.Namespace) {
+			rcs = append(rcs, rc)
+		}
+	}
+	var rss []*extensions.ReplicaSet
+	for _, rs := range cs.ReplicaSets {
+		if scope.NamespaceInScope(rs.Namespace) {
+			rss = append(rss, rs)
+		}
+	}
+	glog.V(3).Infof("Discovery scope %s keeps %d of %d pods and %d of %d services.", scope, len(pods), len(cs.Pods),
+		len(services), len(cs.Services))
+
+	cs.Pods = pods
+	cs.Services = services
+	cs.Endpoints = endpoints
+	cs.ReplicationControllers = rcs
+	cs.ReplicaSets = rss
+	return cs
+}
+
+// Get all the running pods on the given nodes, including the pods out of the discovery scope.
+func (cs *ClusterSnapshot) GetRunningPodsOnNodes(nodes []*api.Node) []*api.Pod {
+	pods := []*api.Pod{}
+	for _, node := range nodes {
+		pods = append(pods, cs.runningPodsByNode[node.Name]...)
+	}
+	return pods
+}
+
+// Get the running pods in the discovery scope on the given nodes.
+func (cs *ClusterSnapshot) GetRunningPodsInScope(nodes []*api.Node) []*api.Pod {
+	pods := []*api.Pod{}
+	for _, pod := range cs.GetRunningPodsOnNodes(nodes) {
+		if cs.scope.PodInScope(pod) {
+			pods = append(pods, pod)
+		}
+	}
+	return pods
+}
+
+// Get the UID of the default/kubernetes service, which is used as the ID of the cluster.
+func (cs *ClusterSnapshot) GetKubernetesServiceID() (string, error) {
+	if cs.kubernetesService != nil {
+		return string(cs.kubernetesService.UID), nil
+	}
+	return "", fmt.Errorf("service %s/%s does not exist", k8sDefaultNamespace, kubernetesServiceName)
+}
+
+// Take a snapshot of the current cluster in the discovery scope of the scraper. If the local cache has been synced, no
+// request is sent to the API server.
+func (s *ClusterScraper) TakeSnapshot() (*ClusterSnapshot, error) {
+	nodes, err := s.GetAllNodes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all nodes: %s", err)
+	}
+	pods, err := s.GetAllPods()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all pods: %s", err)
+	}
+	services, err := s.GetAllServices()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all services: %s", err)
+	}
+	endpoints, err := s.GetAllEndpoints()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all endpoints: %s", err)
+	}
+	snapshot := NewClusterSnapshot(nodes, pods, services, endpoints)
+
+	// Controllers are not required by every phase. Failing to get them does not invalidate the snapshot.
+	if rcs, err := s.GetAllReplicationControllers(); err != nil {
+		glog.Warningf("Failed to get all replication controllers: %s", err)
+	} else {
+		snapshot.ReplicationControllers = rcs
+	}
+	if rss, err := s.GetAllReplicaSets(); err != nil {
+		glog.Warningf("Failed to get all replica sets: %s", err)
+	} else {
+		snapshot.ReplicaSets = rss
+	}
+
+	return snapshot.WithScope(s.scope), nil
+}
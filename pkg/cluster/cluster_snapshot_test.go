@@ -0,0 +1,133 @@
+package cluster
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	api "k8s.io/client-go/pkg/api/v1"
+)
+
+func TestGetRunningPodsOnNodes(t *testing.T) {
+	nodes := []*api.Node{newNode("node-1"), newNode("node-2"), newNode("node-3")}
+	pods := []*api.Pod{
+		newPod("pod-1", "node-1", api.PodRunning),
+		newPod("pod-2", "node-1", api.PodPending),
+		newPod("pod-3", "node-2", api.PodRunning),
+		newPod("pod-4", "", api.PodPending),
+		newPod("pod-5", "node-3", api.PodSucceeded),
+	}
+	snapshot := NewClusterSnapshot(nodes, pods, nil, nil)
+
+	table := []struct {
+		nodes        []*api.Node
+		expectedPods []string
+	}{
+		{
+			nodes:        nodes[:1],
+			expectedPods: []string{"pod-1"},
+		},
+		{
+			nodes:        nodes,
+			expectedPods: []string{"pod-1", "pod-3"},
+		},
+		{
+			nodes:        nodes[2:],
+			expectedPods: []string{},
+		},
+	}
+	for i, item := range table {
+		runningPods := snapshot.GetRunningPodsOnNodes(item.nodes)
+		if len(runningPods) != len(item.expectedPods) {
+			t.Errorf("Test case %d failed: expected %d running pods, got %d", i, len(item.expectedPods), len(runningPods))
+			continue
+		}
+		for j, pod := range runningPods {
+			if pod.Name != item.expectedPods[j] {
+				t.Errorf("Test case %d failed: expected pod %s, got %s", i, item.expectedPods[j], pod.Name)
+			}
+		}
+	}
+}
+
+func TestGetKubernetesServiceID(t *testing.T) {
+	services := []*api.Service{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: kubernetesServiceName, UID: "wrong"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: k8sDefaultNamespace, Name: kubernetesServiceName, UID: "cluster-id"}},
+	}
+	snapshot := NewClusterSnapshot(nil, nil, services, nil)
+	id, err := snapshot.GetKubernetesServiceID()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if id != "cluster-id" {
+		t.Errorf("Expected cluster ID cluster-id, got %s", id)
+	}
+
+	emptySnapshot := NewClusterSnapshot(nil, nil, services[:1], nil)
+	if _, err := emptySnapshot.GetKubernetesServiceID(); err == nil {
+		t.Error("Expected error when kubernetes service does not exist, got nil")
+	}
+}
+
+func newNode(name string) *api.Node {
+	return &api.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			UID:  types.UID(name),
+		},
+	}
+}
+
+func newPod(name, nodeName string, phase api.PodPhase) *api.Pod {
+	return &api.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      name,
+			UID:       types.UID(name),
+		},
+		Spec: api.PodSpec{
+			NodeName: nodeName,
+		},
+		Status: api.PodStatus{
+			Phase: phase,
+		},
+	}
+}
+
+func TestSnapshotWithScope(t *testing.T) {
+	nodes := []*api.Node{newNode("node-1")}
+	web := newPod("web", "node-1", api.PodRunning)
+	web.Labels = map[string]string{"team": "a"}
+	batch := newPod("batch", "node-1", api.PodRunning)
+	batch.Labels = map[string]string{"team": "b"}
+	system := newPod("dns", "node-1", api.PodRunning)
+	system.Namespace = "kube-system"
+	services := []*api.Service{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: k8sDefaultNamespace, Name: kubernetesServiceName, UID: "cluster-id"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "kube-dns"}},
+	}
+
+	scope, err := NewDiscoveryScope(nil, []string{"kube-system"}, "team=a")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	snapshot := NewClusterSnapshot(nodes, []*api.Pod{web, batch, system}, services, nil).WithScope(scope)
+
+	if len(snapshot.Pods) != 1 || snapshot.Pods[0].Name != "web" {
+		t.Errorf("Expected only pod web in scope, got %d pods", len(snapshot.Pods))
+	}
+	if inScope := snapshot.GetRunningPodsInScope(nodes); len(inScope) != 1 {
+		t.Errorf("Expected 1 running pod in scope, got %d", len(inScope))
+	}
+	// All the pods count toward the usage of the node.
+	if running := snapshot.GetRunningPodsOnNodes(nodes); len(running) != 3 {
+		t.Errorf("Expected 3 running pods on the node, got %d", len(running))
+	}
+	if len(snapshot.Services) != 1 {
+		t.Errorf("Expected 1 service in scope, got %d", len(snapshot.Services))
+	}
+	if id, err := snapshot.GetKubernetesServiceID(); err != nil || id != "cluster-id" {
+		t.Errorf("Expected cluster ID cluster-id, got %s, %v", id, err)
+	}
+}
@@ -0,0 +1,82 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/labels"
+	api "k8s.io/client-go/pkg/api/v1"
+)
+
+// DiscoveryScope limits the pods, applications and services discovered to some namespaces and to the pods matching a
+// label selector. A nil DiscoveryScope includes everything.
+type DiscoveryScope struct {
+	// the namespaces to discover. All namespaces are included if it is empty.
+	includeNamespaces map[string]bool
+	// the namespaces not to discover, even if they are included.
+	excludeNamespaces map[string]bool
+
+	podSelector labels.Selector
+}
+
+// Create a DiscoveryScope from the given namespace lists and pod label selector. An empty include list includes all
+// namespaces, and an empty selector selects all pods.
+func NewDiscoveryScope(includeNamespaces, excludeNamespaces []string, podLabelSelector string) (*DiscoveryScope, error) {
+	podSelector, err := labels.Parse(podLabelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pod label selector %q: %s", podLabelSelector, err)
+	}
+	scope := &DiscoveryScope{
+		includeNamespaces: make(map[string]bool),
+		excludeNamespaces: make(map[string]bool),
+		podSelector:       podSelector,
+	}
+	for _, namespace := range includeNamespaces {
+		scope.includeNamespaces[namespace] = true
+	}
+	for _, namespace := range excludeNamespaces {
+		scope.excludeNamespaces[namespace] = true
+	}
+	return scope, nil
+}
+
+// Check if the scope includes everything in the cluster.
+func (s *DiscoveryScope) IsEverything() bool {
+	return s == nil || (len(s.includeNamespaces) == 0 && len(s.excludeNamespaces) == 0 && s.podSelector.Empty())
+}
+
+// Check if the objects in the given namespace are discovered.
+func (s *DiscoveryScope) NamespaceInScope(namespace string) bool {
+	if s == nil {
+		return true
+	}
+	if len(s.includeNamespaces) > 0 && !s.includeNamespaces[namespace] {
+		return false
+	}
+	return !s.excludeNamespaces[namespace]
+}
+
+// Check if the given pod is discovered.
+func (s *DiscoveryScope) PodInScope(pod *api.Pod) bool {
+	if s == nil {
+		return true
+	}
+	return s.NamespaceInScope(pod.Namespace) && s.podSelector.Matches(labels.Set(pod.Labels))
+}
+
+func (s *DiscoveryScope) String() string {
+	if s.IsEverything() {
+		return "everything"
+	}
+	return fmt.Sprintf("namespaces included %v, excluded %v, pod selector %q", keys(s.includeNamespaces),
+		keys(s.excludeNamespaces), s.podSelector.String())
+}
+
+func keys(set map[string]bool) []string {
+	var keys []string
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
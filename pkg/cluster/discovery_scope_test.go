@@ -0,0 +1,51 @@
+package cluster
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	api "k8s.io/client-go/pkg/api/v1"
+)
+
+func TestDiscoveryScope(t *testing.T) {
+	table := []struct {
+		include       []string
+		exclude       []string
+		selector      string
+		namespace     string
+		labels        map[string]string
+		expectedInNS  bool
+		expectedInPod bool
+	}{
+		{nil, nil, "", "default", nil, true, true},
+		{[]string{"team-a"}, nil, "", "default", nil, false, false},
+		{[]string{"team-a"}, nil, "", "team-a", nil, true, true},
+		{[]string{"team-a"}, []string{"team-a"}, "", "team-a", nil, false, false},
+		{nil, []string{"kube-system"}, "", "kube-system", nil, false, false},
+		{nil, nil, "app=web", "default", map[string]string{"app": "db"}, true, false},
+		{nil, nil, "app in (web, db)", "default", map[string]string{"app": "db"}, true, true},
+		{nil, nil, "!canary", "default", map[string]string{"canary": "true"}, true, false},
+	}
+	for i, item := range table {
+		scope, err := NewDiscoveryScope(item.include, item.exclude, item.selector)
+		if err != nil {
+			t.Errorf("Test case %d failed: unexpected error %s", i, err)
+			continue
+		}
+		if inScope := scope.NamespaceInScope(item.namespace); inScope != item.expectedInNS {
+			t.Errorf("Test case %d failed: expected namespace in scope %t, got %t", i, item.expectedInNS, inScope)
+		}
+		pod := &api.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: item.namespace, Name: "pod", Labels: item.labels}}
+		if inScope := scope.PodInScope(pod); inScope != item.expectedInPod {
+			t.Errorf("Test case %d failed: expected pod in scope %t, got %t", i, item.expectedInPod, inScope)
+		}
+	}
+
+	if _, err := NewDiscoveryScope(nil, nil, "app in (web"); err == nil {
+		t.Error("Expected an error for an invalid selector")
+	}
+	var everything *DiscoveryScope
+	if !everything.IsEverything() || !everything.PodInScope(&api.Pod{}) {
+		t.Error("Expected a nil scope to include everything")
+	}
+}
@@ -0,0 +1,181 @@
+package kubeturbo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/turbonomic/kubeturbo/pkg/action"
+	"github.com/turbonomic/kubeturbo/pkg/discovery"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/configs"
+
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+
+	"github.com/golang/glog"
+)
+
+const (
+	redactedValue = "<redacted>"
+)
+
+var (
+	// The config keys containing any of these are secrets.
+	secretKeywords = []string{"password", "secret", "token"}
+)
+
+// InstallDebugHandlers installs the read-only JSON endpoints for inspecting the state of the service: /debug/discovery
+// for the summary of the last discovery of each target, /debug/entities?id=<id> or ?name=<display_name> for the
+// entityDTOs of the last discoveries, /debug/actions for the in-flight and recent actions of each target, and
+// /debug/config for the effective configuration with the secrets redacted. The entities can be limited to one target
+// by target=<target_identifier>. The given flags are shown as part of the configuration.
+func (v *KubeturboService) InstallDebugHandlers(mux *http.ServeMux, flags interface{}) {
+	mux.HandleFunc("/debug/discovery", v.serveDiscovery)
+	mux.HandleFunc("/debug/entities", v.serveEntities)
+	mux.HandleFunc("/debug/actions", v.serveActions)
+	mux.HandleFunc("/debug/config", func(w http.ResponseWriter, req *http.Request) {
+		v.serveConfig(w, req, flags)
+	})
+}
+
+func (v *KubeturboService) serveDiscovery(w http.ResponseWriter, req *http.Request) {
+	if !checkGet(w, req) {
+		return
+	}
+	statuses := make(map[string]*discovery.DiscoveryStatus)
+	for target, discoveryClient := range v.k8sTAPService.discoveryClients {
+		statuses[target] = discoveryClient.LastDiscovery()
+	}
+	writeDebugJSON(w, http.StatusOK, statuses)
+}
+
+type targetEntityDTO struct {
+	Target    string           `json:"target"`
+	EntityDTO *proto.EntityDTO `json:"entityDTO"`
+}
+
+func (v *KubeturboService) serveEntities(w http.ResponseWriter, req *http.Request) {
+	if !checkGet(w, req) {
+		return
+	}
+	query := req.URL.Query()
+	id, name, targetFilter := query.Get("id"), query.Get("name"), query.Get("target")
+	if (id == "") == (name == "") {
+		http.Error(w, "either id or name must be given", http.StatusBadRequest)
+		return
+	}
+
+	var found []targetEntityDTO
+	for target, discoveryClient := range v.k8sTAPService.discoveryClients {
+		status := discoveryClient.LastDiscovery()
+		if status == nil || (targetFilter != "" && target != targetFilter) {
+			continue
+		}
+		if id != "" {
+			if entityDTO := status.FindEntityDTO(id); entityDTO != nil {
+				found = append(found, targetEntityDTO{target, entityDTO})
+			}
+			continue
+		}
+		for _, entityDTO := range status.FindEntityDTOsByName(name) {
+			found = append(found, targetEntityDTO{target, entityDTO})
+		}
+	}
+	if len(found) == 0 {
+		http.Error(w, "no entity is found in the last discoveries", http.StatusNotFound)
+		return
+	}
+	writeDebugJSON(w, http.StatusOK, found)
+}
+
+type actionList struct {
+	InFlight []action.ActionRecord `json:"inFlight"`
+	Recent   []action.ActionRecord `json:"recent"`
+}
+
+func (v *KubeturboService) serveActions(w http.ResponseWriter, req *http.Request) {
+	if !checkGet(w, req) {
+		return
+	}
+	actions := make(map[string]actionList)
+	for _, cluster := range v.clusters {
+		inFlight, recent := cluster.actionHandler.ActionHistory().List()
+		actions[cluster.config.targetConfig.TargetIdentifier] = actionList{inFlight, recent}
+	}
+	writeDebugJSON(w, http.StatusOK, actions)
+}
+
+func (v *KubeturboService) serveConfig(w http.ResponseWriter, req *http.Request, flags interface{}) {
+	if !checkGet(w, req) {
+		return
+	}
+	var targets []*configs.K8sTargetConfig
+	for _, cluster := range v.clusters {
+		targets = append(targets, cluster.config.targetConfig)
+	}
+	config := map[string]interface{}{
+		"flags":       flags,
+		"turboconfig": v.clusters[0].config.tapSpec,
+		"targets":     targets,
+	}
+
+	// Redact the secrets in the generic form of the config, which covers the config blocks of the monitoring sources.
+	data, err := json.Marshal(config)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to marshal config: %s", err), http.StatusInternalServerError)
+		return
+	}
+	var genericConfig interface{}
+	if err := json.Unmarshal(data, &genericConfig); err != nil {
+		http.Error(w, fmt.Sprintf("failed to unmarshal config: %s", err), http.StatusInternalServerError)
+		return
+	}
+	writeDebugJSON(w, http.StatusOK, redactSecrets(genericConfig))
+}
+
+// Replace the non-empty values of the secret keys in a generic JSON value.
+func redactSecrets(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if isSecretKey(key) && field != nil && field != "" {
+				v[key] = redactedValue
+			} else {
+				v[key] = redactSecrets(field)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactSecrets(item)
+		}
+	}
+	return value
+}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, keyword := range secretKeywords {
+		if strings.Contains(key, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkGet(w http.ResponseWriter, req *http.Request) bool {
+	if req.Method != http.MethodGet {
+		http.Error(w, req.Method+" is not supported", http.StatusMethodNotAllowed)
+		return false
+	}
+	return true
+}
+
+func writeDebugJSON(w http.ResponseWriter, statusCode int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		glog.Errorf("Failed to write debug response: %s", err)
+	}
+}
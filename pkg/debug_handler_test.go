@@ -0,0 +1,292 @@
+package kubeturbo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	api "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/turbonomic/kubeturbo/pkg/action"
+	"github.com/turbonomic/kubeturbo/pkg/discovery"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/configs"
+	"github.com/turbonomic/kubeturbo/pkg/turbostore"
+
+	"github.com/turbonomic/turbo-go-sdk/pkg/mediationcontainer"
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+	"github.com/turbonomic/turbo-go-sdk/pkg/service"
+)
+
+func TestRedactSecrets(t *testing.T) {
+	table := []struct {
+		config         string
+		expectedConfig string
+	}{
+		{
+			`{"restAPIConfig": {"opsManagerUsername": "admin", "opsManagerPassword": "secret"}}`,
+			`{"restAPIConfig": {"opsManagerUsername": "admin", "opsManagerPassword": "<redacted>"}}`,
+		},
+		// The config blocks of the monitoring sources are redacted by key as well.
+		{
+			`{"monitoringSources": [{"source": "prometheus", "config": {"bearerToken": "abc", "ClientSecret": "def"}}]}`,
+			`{"monitoringSources": [{"source": "prometheus", "config": {"bearerToken": "<redacted>", "ClientSecret": "<redacted>"}}]}`,
+		},
+		// Empty secrets are kept to show they are not set.
+		{
+			`{"password": "", "websocketPassword": null, "address": "cluster-a"}`,
+			`{"password": "", "websocketPassword": null, "address": "cluster-a"}`,
+		},
+	}
+	for i, item := range table {
+		var config, expectedConfig interface{}
+		if err := json.Unmarshal([]byte(item.config), &config); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal([]byte(item.expectedConfig), &expectedConfig); err != nil {
+			t.Fatal(err)
+		}
+		if redacted := redactSecrets(config); !reflect.DeepEqual(redacted, expectedConfig) {
+			t.Errorf("Test case %d: expected %v, got %v", i, expectedConfig, redacted)
+		}
+	}
+}
+
+type fakeLastDiscovery struct {
+	status *discovery.DiscoveryStatus
+}
+
+func (d *fakeLastDiscovery) LastDiscovery() *discovery.DiscoveryStatus {
+	return d.status
+}
+
+func newTestDiscoveryStatus(podIDs ...string) *discovery.DiscoveryStatus {
+	var entityDTOs []*proto.EntityDTO
+	for _, id := range podIDs {
+		id, name, entityType := id, "default/web", proto.EntityDTO_CONTAINER_POD
+		entityDTOs = append(entityDTOs, &proto.EntityDTO{Id: &id, DisplayName: &name, EntityType: &entityType})
+	}
+	return discovery.NewDiscoveryStatus(time.Now(), entityDTOs, nil, nil)
+}
+
+// Create a service of the targets cluster-a and cluster-b, which have discovered a pod named default/web each, and
+// cluster-c, which has not been discovered yet. The debug endpoints are served by the returned handler.
+func newDebugTestService() (*KubeturboService, http.Handler) {
+	tapSpec := &K8sTAPServiceSpec{
+		TurboCommunicationConfig: &service.TurboCommunicationConfig{
+			ServerMeta: mediationcontainer.ServerMeta{TurboServer: "https://turbo"},
+			WebSocketConfig: mediationcontainer.WebSocketConfig{WebSocketUsername: "ws-user",
+				WebSocketPassword: "ws-secret"},
+			RestAPIConfig: service.RestAPIConfig{OpsManagerUsername: "admin", OpsManagerPassword: "api-secret"},
+		},
+	}
+	v := &KubeturboService{
+		k8sTAPService: &K8sTAPService{
+			discoveryClients: map[string]lastDiscoveryGetter{
+				"cluster-a": &fakeLastDiscovery{newTestDiscoveryStatus("pod-a")},
+				"cluster-b": &fakeLastDiscovery{newTestDiscoveryStatus("pod-b")},
+				"cluster-c": &fakeLastDiscovery{},
+			},
+		},
+	}
+	for _, target := range []string{"cluster-a", "cluster-b", "cluster-c"} {
+		actionHandler := action.NewActionHandler(action.NewActionHandlerConfig(nil, turbostore.NewPodBroker()), nil)
+		v.clusters = append(v.clusters, &clusterService{
+			config: &Config{
+				tapSpec:      tapSpec,
+				targetConfig: &configs.K8sTargetConfig{TargetIdentifier: target},
+			},
+			actionHandler: actionHandler,
+		})
+	}
+
+	mux := http.NewServeMux()
+	v.InstallDebugHandlers(mux, map[string]string{"Master": "https://cluster-a"})
+	return v, mux
+}
+
+func stopDebugTestService(v *KubeturboService) {
+	for _, cluster := range v.clusters {
+		cluster.actionHandler.Stop()
+	}
+}
+
+func serveDebugRequest(handler http.Handler, method, url string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, url, nil))
+	return recorder
+}
+
+func TestDebugHandlersMethod(t *testing.T) {
+	v, handler := newDebugTestService()
+	defer stopDebugTestService(v)
+
+	for _, path := range []string{"/debug/discovery", "/debug/entities?id=pod-a", "/debug/actions", "/debug/config"} {
+		if code := serveDebugRequest(handler, http.MethodGet, path).Code; code != http.StatusOK {
+			t.Errorf("Expected status %d of GET %s, got %d", http.StatusOK, path, code)
+		}
+		for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodDelete} {
+			if code := serveDebugRequest(handler, method, path).Code; code != http.StatusMethodNotAllowed {
+				t.Errorf("Expected status %d of %s %s, got %d", http.StatusMethodNotAllowed, method, path, code)
+			}
+		}
+	}
+}
+
+func TestDebugHandlersDiscovery(t *testing.T) {
+	v, handler := newDebugTestService()
+	defer stopDebugTestService(v)
+
+	recorder := serveDebugRequest(handler, http.MethodGet, "/debug/discovery")
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("Expected content type application/json, got %s", contentType)
+	}
+	var statuses map[string]*discovery.DiscoveryStatus
+	if err := json.Unmarshal(recorder.Body.Bytes(), &statuses); err != nil {
+		t.Fatalf("Failed to decode %s: %s", recorder.Body, err)
+	}
+	if len(statuses) != 3 || statuses["cluster-c"] != nil {
+		t.Errorf("Expected the statuses of 3 targets, cluster-c not discovered, got %s", recorder.Body)
+	}
+	for _, target := range []string{"cluster-a", "cluster-b"} {
+		status := statuses[target]
+		if status == nil || status.EntityCounts[proto.EntityDTO_CONTAINER_POD.String()] != 1 {
+			t.Errorf("Expected 1 pod discovered in %s, got %+v", target, status)
+		}
+	}
+}
+
+func TestDebugHandlersEntities(t *testing.T) {
+	v, handler := newDebugTestService()
+	defer stopDebugTestService(v)
+
+	table := []struct {
+		query           string
+		expectedCode    int
+		expectedTargets []string
+	}{
+		// Either id or name is required.
+		{"", http.StatusBadRequest, nil},
+		{"?target=cluster-a", http.StatusBadRequest, nil},
+		{"?id=pod-a&name=default/web", http.StatusBadRequest, nil},
+
+		{"?id=pod-a", http.StatusOK, []string{"cluster-a"}},
+		{"?name=default/web", http.StatusOK, []string{"cluster-a", "cluster-b"}},
+		{"?name=default/web&target=cluster-b", http.StatusOK, []string{"cluster-b"}},
+
+		{"?id=pod-c", http.StatusNotFound, nil},
+		{"?id=pod-a&target=cluster-b", http.StatusNotFound, nil},
+		{"?name=default/web&target=cluster-c", http.StatusNotFound, nil},
+	}
+	for i, item := range table {
+		recorder := serveDebugRequest(handler, http.MethodGet, "/debug/entities"+item.query)
+		if recorder.Code != item.expectedCode {
+			t.Errorf("Test case %d failed: expected status %d, got %d: %s", i, item.expectedCode, recorder.Code,
+				recorder.Body)
+			continue
+		}
+		if recorder.Code != http.StatusOK {
+			continue
+		}
+		var found []struct {
+			Target    string           `json:"target"`
+			EntityDTO *proto.EntityDTO `json:"entityDTO"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &found); err != nil {
+			t.Errorf("Test case %d failed: cannot decode %s: %s", i, recorder.Body, err)
+			continue
+		}
+		var targets []string
+		for _, entity := range found {
+			if entity.EntityDTO.GetDisplayName() != "default/web" {
+				t.Errorf("Test case %d failed: unexpected entity %+v", i, entity.EntityDTO)
+			}
+			targets = append(targets, entity.Target)
+		}
+		sort.Strings(targets)
+		if !reflect.DeepEqual(targets, item.expectedTargets) {
+			t.Errorf("Test case %d failed: expected entities of targets %v, got %v", i, item.expectedTargets,
+				targets)
+		}
+	}
+}
+
+func TestDebugHandlersActions(t *testing.T) {
+	v, handler := newDebugTestService()
+	defer stopDebugTestService(v)
+
+	// An action received while draining is rejected without being executed, and recorded as finished.
+	actionHandler := v.clusters[0].actionHandler
+	actionHandler.Drain(0)
+	pod := &api.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"}}
+	node := &api.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	actionItem := action.NewMoveActionItem("action-1", pod, node)
+	actionHandler.ExecuteAction(&proto.ActionExecutionDTO{ActionItem: []*proto.ActionItemDTO{actionItem}}, nil, nil)
+
+	recorder := serveDebugRequest(handler, http.MethodGet, "/debug/actions")
+	var actions map[string]actionList
+	if err := json.Unmarshal(recorder.Body.Bytes(), &actions); err != nil {
+		t.Fatalf("Failed to decode %s: %s", recorder.Body, err)
+	}
+	if len(actions) != 3 {
+		t.Errorf("Expected the actions of 3 targets, got %s", recorder.Body)
+	}
+	recent := actions["cluster-a"].Recent
+	if len(actions["cluster-a"].InFlight) != 0 || len(recent) != 1 || recent[0].UUID != "action-1" ||
+		recent[0].State != proto.ActionResponseState_FAILED.String() {
+		t.Errorf("Expected the failed action-1 of cluster-a, got %+v", actions["cluster-a"])
+	}
+	if len(actions["cluster-b"].Recent) != 0 {
+		t.Errorf("Expected no action of cluster-b, got %+v", actions["cluster-b"])
+	}
+}
+
+func TestDebugHandlersConfig(t *testing.T) {
+	v, handler := newDebugTestService()
+	defer stopDebugTestService(v)
+
+	recorder := serveDebugRequest(handler, http.MethodGet, "/debug/config")
+	body := recorder.Body.String()
+	for _, secret := range []string{"ws-secret", "api-secret"} {
+		if strings.Contains(body, secret) {
+			t.Errorf("Secret %s is not redacted: %s", secret, body)
+		}
+	}
+
+	var config struct {
+		Flags       map[string]string `json:"flags"`
+		Turboconfig struct {
+			CommunicationConfig struct {
+				WebSocketConfig map[string]string `json:"websocketConfig"`
+				RestAPIConfig   map[string]string `json:"restAPIConfig"`
+			} `json:"communicationConfig"`
+		} `json:"turboconfig"`
+		Targets []*configs.K8sTargetConfig `json:"targets"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &config); err != nil {
+		t.Fatalf("Failed to decode %s: %s", body, err)
+	}
+	table := []struct {
+		value    string
+		expected string
+	}{
+		{config.Turboconfig.CommunicationConfig.WebSocketConfig["websocketPassword"], redactedValue},
+		{config.Turboconfig.CommunicationConfig.WebSocketConfig["websocketUsername"], "ws-user"},
+		{config.Turboconfig.CommunicationConfig.RestAPIConfig["opsManagerPassword"], redactedValue},
+		{config.Turboconfig.CommunicationConfig.RestAPIConfig["opsManagerUsername"], "admin"},
+		{config.Flags["Master"], "https://cluster-a"},
+	}
+	for i, item := range table {
+		if item.value != item.expected {
+			t.Errorf("Test case %d failed: expected %q, got %q", i, item.expected, item.value)
+		}
+	}
+	if len(config.Targets) != 3 || config.Targets[0].TargetIdentifier != "cluster-a" {
+		t.Errorf("Expected the configs of 3 targets, got %s", body)
+	}
+}
@@ -0,0 +1,150 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+
+	"github.com/golang/glog"
+)
+
+const (
+	discoveryResultAgeEvent = "DiscoveryResultAge"
+	discoveryCategory       = "Discovery"
+)
+
+// The result of a complete discovery cycle.
+type discoveryResult struct {
+	response *proto.DiscoveryResponse
+
+	// when the discovery cycle finished.
+	timestamp time.Time
+}
+
+// asyncDiscoverer discovers the cluster in the background on a schedule, and keeps the freshest complete result, so
+// that a discovery request from the server is answered without waiting for the cluster to be scraped.
+type asyncDiscoverer struct {
+	// the interval between the starts of two discovery cycles.
+	interval time.Duration
+
+	discover func(ctx context.Context) (*proto.DiscoveryResponse, error)
+
+	lock sync.RWMutex
+	// the result of the last successful cycle, nil if no cycle has succeeded yet.
+	lastGood *discoveryResult
+	// the error of the last cycle, nil if it succeeded.
+	lastErr error
+
+	// closed once the first cycle finishes, successfully or not.
+	firstCycleDone chan struct{}
+	firstCycleOnce sync.Once
+}
+
+func newAsyncDiscoverer(interval time.Duration,
+	discover func(ctx context.Context) (*proto.DiscoveryResponse, error)) *asyncDiscoverer {
+	return &asyncDiscoverer{
+		interval:       interval,
+		discover:       discover,
+		firstCycleDone: make(chan struct{}),
+	}
+}
+
+// Discover the cluster every interval until stopCh is closed. The ongoing cycle is cancelled once stopCh is closed.
+// A cycle which takes longer than the interval delays the next one instead of piling them up.
+func (d *asyncDiscoverer) Run(stopCh <-chan struct{}) {
+	glog.V(2).Infof("Start discovering in the background every %s.", d.interval)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		d.runCycle(ctx)
+		select {
+		case <-stopCh:
+			glog.V(2).Infof("Stop discovering in the background.")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run one discovery cycle and keep its result if it succeeds.
+func (d *asyncDiscoverer) runCycle(ctx context.Context) {
+	start := time.Now()
+	response, err := d.discover(ctx)
+
+	d.lock.Lock()
+	if err != nil {
+		glog.Errorf("Background discovery failed: %s", err)
+		d.lastErr = err
+	} else {
+		glog.V(2).Infof("Background discovery finished in %s.", time.Since(start))
+		d.lastGood = &discoveryResult{response: response, timestamp: time.Now()}
+		d.lastErr = nil
+	}
+	d.lock.Unlock()
+
+	d.firstCycleOnce.Do(func() { close(d.firstCycleDone) })
+}
+
+// Get the freshest complete discovery result. If the last cycle failed, the last good result is returned along with a
+// warning. It waits for the first cycle to finish, or fails once ctx is done.
+func (d *asyncDiscoverer) GetDiscoveryResponse(ctx context.Context) (*proto.DiscoveryResponse, error) {
+	select {
+	case <-d.firstCycleDone:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("no discovery result is available yet: %s", ctx.Err())
+	}
+
+	d.lock.RLock()
+	lastGood, lastErr := d.lastGood, d.lastErr
+	d.lock.RUnlock()
+
+	if lastGood == nil {
+		if lastErr == nil {
+			lastErr = errors.New("unknown error")
+		}
+		return nil, fmt.Errorf("no discovery has succeeded yet: %s", lastErr)
+	}
+
+	// The cached response is shared by the requests, so only a copy is amended.
+	response := *lastGood.response
+	age := time.Since(lastGood.timestamp)
+	notificationSeverity := proto.NotificationDTO_NORMAL
+	response.ErrorDTO = append([]*proto.ErrorDTO{}, lastGood.response.ErrorDTO...)
+	if lastErr != nil {
+		notificationSeverity = proto.NotificationDTO_MINOR
+		response.ErrorDTO = append(response.ErrorDTO, newErrorDTO(proto.ErrorDTO_WARNING,
+			fmt.Sprintf("The latest discovery failed: %s. The result of the last successful discovery %s ago is "+
+				"reported.", lastErr, age)))
+	}
+	response.Notification = append(append([]*proto.NotificationDTO{}, lastGood.response.Notification...),
+		newNotificationDTO(discoveryResultAgeEvent, notificationSeverity,
+			fmt.Sprintf("The discovery result was completed at %s, %s ago.", lastGood.timestamp.Format(time.RFC3339),
+				age)))
+	glog.V(2).Infof("Report the discovery result completed %s ago.", age)
+
+	return &response, nil
+}
+
+func newNotificationDTO(event string, severity proto.NotificationDTO_Severity, description string) *proto.NotificationDTO {
+	category := discoveryCategory
+	return &proto.NotificationDTO{
+		Event:       &event,
+		Category:    &category,
+		Severity:    severity.Enum(),
+		Description: &description,
+	}
+}
@@ -0,0 +1,69 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+)
+
+func TestAsyncDiscovererFallback(t *testing.T) {
+	var nextErr error
+	discover := func(ctx context.Context) (*proto.DiscoveryResponse, error) {
+		if nextErr != nil {
+			return nil, nextErr
+		}
+		return &proto.DiscoveryResponse{EntityDTO: []*proto.EntityDTO{{}}}, nil
+	}
+	d := newAsyncDiscoverer(0, discover)
+
+	// No result before the first cycle finishes.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := d.GetDiscoveryResponse(ctx); err == nil {
+		t.Error("Expected an error before the first cycle finishes")
+	}
+
+	// No result if the first cycle fails.
+	nextErr = errors.New("failed")
+	d.runCycle(context.Background())
+	if _, err := d.GetDiscoveryResponse(context.Background()); err == nil {
+		t.Error("Expected an error if no cycle has succeeded")
+	}
+
+	table := []struct {
+		err              error
+		expectedWarnings int
+		expectedSeverity proto.NotificationDTO_Severity
+	}{
+		{nil, 0, proto.NotificationDTO_NORMAL},
+		// The last good result is reported with a warning.
+		{errors.New("failed"), 1, proto.NotificationDTO_MINOR},
+		{errors.New("failed again"), 1, proto.NotificationDTO_MINOR},
+		{nil, 0, proto.NotificationDTO_NORMAL},
+	}
+	for i, item := range table {
+		nextErr = item.err
+		d.runCycle(context.Background())
+		response, err := d.GetDiscoveryResponse(context.Background())
+		if err != nil {
+			t.Errorf("Test case %d failed: unexpected error %s", i, err)
+			continue
+		}
+		if len(response.GetEntityDTO()) != 1 {
+			t.Errorf("Test case %d failed: expected 1 entityDTO, got %d", i, len(response.GetEntityDTO()))
+		}
+		if len(response.GetErrorDTO()) != item.expectedWarnings {
+			t.Errorf("Test case %d failed: expected %d errorDTOs, got %d", i, item.expectedWarnings,
+				len(response.GetErrorDTO()))
+		}
+		if len(response.GetNotification()) != 1 {
+			t.Errorf("Test case %d failed: expected 1 notification, got %d", i, len(response.GetNotification()))
+			continue
+		}
+		if severity := response.GetNotification()[0].GetSeverity(); severity != item.expectedSeverity {
+			t.Errorf("Test case %d failed: expected severity %s, got %s", i, item.expectedSeverity, severity)
+		}
+	}
+}
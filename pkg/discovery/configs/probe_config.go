@@ -1,11 +1,24 @@
 package configs
 
-
 import (
+	"time"
+
 	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/types"
 	"github.com/turbonomic/kubeturbo/pkg/discovery/stitching"
 )
 
+const (
+	// The max time a monitoring worker may take to finish one task, unless a timeout is set for its source.
+	DefaultMonitoringTimeout = time.Minute * 5
+
+	SnapshotFormatJSON     SnapshotFormat = "json"
+	SnapshotFormatProtobuf SnapshotFormat = "protobuf"
+)
+
+// The format of the discovery snapshots written to disk. Only protobuf snapshots can be replayed.
+type SnapshotFormat string
+
 type ProbeConfig struct {
 	CadvisorPort int
 
@@ -13,4 +26,38 @@ type ProbeConfig struct {
 	StitchingPropertyType stitching.StitchingPropertyType
 
 	MonitoringConfigs []monitoring.MonitorWorkerConfig
+
+	// The max time a monitoring worker of a source may take to finish one task.
+	// The default timeout is used for the sources not in the map.
+	MonitoringTimeouts map[types.MonitoringSource]time.Duration
+	// The default timeout. DefaultMonitoringTimeout is used if it is not positive.
+	DefaultMonitoringTimeout time.Duration
+
+	// The interval of sampling resource usage between discoveries. Sampling is disabled if it is not positive.
+	SamplingInterval time.Duration
+	// Samples older than the window are dropped.
+	SamplingWindow time.Duration
+	// The percentile of the sampled usage reported along with the peak, in (0, 100].
+	UsagePercentile float64
+
+	// The number of discovery workers. It is derived from the size of the cluster if it is not positive.
+	DiscoveryWorkerCount int
+
+	// The interval of discovering in the background. A discovery request is answered with the freshest result of the
+	// background discovery. Discovery is done on request if it is not positive.
+	AsyncDiscoveryInterval time.Duration
+
+	// Discovery fails if the fraction of the nodes failed to be discovered is more than this, in [0, 1].
+	MaxFailedNodeFraction float64
+
+	// The directory every discovery response is written to as a snapshot. No snapshot is written if it is empty.
+	SnapshotDir    string
+	SnapshotFormat SnapshotFormat
+	// The max number and the max total size of the snapshots kept for a target. The oldest are removed first. There
+	// is no limit if it is not positive.
+	MaxSnapshots     int
+	MaxSnapshotBytes int64
+
+	// The protobuf snapshot served instead of discovering the cluster. The cluster is discovered if it is empty.
+	ReplaySnapshot string
 }
@@ -1,6 +1,10 @@
 package configs
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/turbonomic/kubeturbo/pkg/cluster"
+)
 
 const (
 	// When user doesn't specify username and password, the default username and password will be used.
@@ -14,6 +18,13 @@ type K8sTargetConfig struct {
 	TargetIdentifier string `json:"address,omitempty"`
 	TargetUsername   string `json:"username,omitempty"`
 	TargetPassword   string `json:"password,omitempty"`
+
+	// The scope of discovery. Only the pods in the included namespaces, which are not excluded and match the label
+	// selector, are discovered along with their applications. Services and affinity rules are limited to the same
+	// scope. All the pods count toward the usage of the nodes.
+	IncludeNamespaces []string `json:"includeNamespaces,omitempty"`
+	ExcludeNamespaces []string `json:"excludeNamespaces,omitempty"`
+	PodLabelSelector  string   `json:"podLabelSelector,omitempty"`
 }
 
 func NewK8sTargetConfig(probeCategory, targetType, id, username, password string) *K8sTargetConfig {
@@ -39,5 +50,13 @@ func (config *K8sTargetConfig) ValidateK8sTargetConfig() error {
 	if config.TargetPassword == "" {
 		config.TargetPassword = defaultPassword
 	}
+	if _, err := config.DiscoveryScope(); err != nil {
+		return err
+	}
 	return nil
 }
+
+// Get the scope of discovery.
+func (config *K8sTargetConfig) DiscoveryScope() (*cluster.DiscoveryScope, error) {
+	return cluster.NewDiscoveryScope(config.IncludeNamespaces, config.ExcludeNamespaces, config.PodLabelSelector)
+}
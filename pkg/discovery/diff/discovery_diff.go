@@ -0,0 +1,226 @@
+package diff
+
+import (
+	"math"
+	"sort"
+
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+)
+
+// An entity in a discovery result.
+type Entity struct {
+	EntityType  string `json:"entityType"`
+	Id          string `json:"id"`
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+// The change of the capacity or the used value of a commodity sold, or bought from a provider, by an entity.
+type CommodityChange struct {
+	// Empty if the commodity is sold by the entity.
+	ProviderId    string  `json:"providerId,omitempty"`
+	CommodityType string  `json:"commodityType"`
+	Key           string  `json:"key,omitempty"`
+	OldCapacity   float64 `json:"oldCapacity"`
+	NewCapacity   float64 `json:"newCapacity"`
+	OldUsed       float64 `json:"oldUsed"`
+	NewUsed       float64 `json:"newUsed"`
+}
+
+// The changes of an entity discovered in both results.
+type EntityChange struct {
+	Entity
+
+	AddedProviders   []string          `json:"addedProviders,omitempty"`
+	RemovedProviders []string          `json:"removedProviders,omitempty"`
+	CommodityChanges []CommodityChange `json:"commodityChanges,omitempty"`
+}
+
+// The difference between two discovery results. The entities are sorted by type and ID.
+type Report struct {
+	// The relative change of a commodity value above which it is reported.
+	Threshold float64 `json:"threshold"`
+
+	Added   []Entity       `json:"added,omitempty"`
+	Removed []Entity       `json:"removed,omitempty"`
+	Changed []EntityChange `json:"changed,omitempty"`
+}
+
+// Check if the two results are the same, as far as the report is concerned.
+func (r *Report) IsEmpty() bool {
+	return len(r.Added) == 0 && len(r.Removed) == 0 && len(r.Changed) == 0
+}
+
+// Compare the entities of two discovery results. An entity is identified by its type and ID. The commodity capacity
+// and used values are reported if their relative change is more than the threshold.
+func Compare(oldEntityDTOs, newEntityDTOs []*proto.EntityDTO, threshold float64) *Report {
+	report := &Report{Threshold: threshold}
+	oldGroup := GroupByEntityType(oldEntityDTOs)
+	newGroup := GroupByEntityType(newEntityDTOs)
+
+	for entityType, oldEntities := range oldGroup {
+		newEntities := newGroup[entityType]
+		for id, oldEntity := range oldEntities {
+			newEntity, exist := newEntities[id]
+			if !exist {
+				report.Removed = append(report.Removed, newReportEntity(oldEntity))
+				continue
+			}
+			if change, changed := compareEntity(oldEntity, newEntity, threshold); changed {
+				report.Changed = append(report.Changed, change)
+			}
+		}
+	}
+	for entityType, newEntities := range newGroup {
+		oldEntities := oldGroup[entityType]
+		for id, newEntity := range newEntities {
+			if _, exist := oldEntities[id]; !exist {
+				report.Added = append(report.Added, newReportEntity(newEntity))
+			}
+		}
+	}
+
+	sortEntities(report.Added)
+	sortEntities(report.Removed)
+	sort.Slice(report.Changed, func(i, j int) bool {
+		return lessEntity(report.Changed[i].Entity, report.Changed[j].Entity)
+	})
+	return report
+}
+
+func compareEntity(oldEntity, newEntity *proto.EntityDTO, threshold float64) (EntityChange, bool) {
+	change := EntityChange{Entity: newReportEntity(newEntity)}
+
+	oldBought := GroupCommoditiesBought(oldEntity.GetCommoditiesBought())
+	newBought := GroupCommoditiesBought(newEntity.GetCommoditiesBought())
+	for providerId := range newBought {
+		if _, exist := oldBought[providerId]; !exist {
+			change.AddedProviders = append(change.AddedProviders, providerId)
+		}
+	}
+	for providerId, oldCommodities := range oldBought {
+		newCommodities, exist := newBought[providerId]
+		if !exist {
+			change.RemovedProviders = append(change.RemovedProviders, providerId)
+			continue
+		}
+		change.CommodityChanges = append(change.CommodityChanges,
+			compareCommodities(providerId, oldCommodities, newCommodities, threshold)...)
+	}
+	change.CommodityChanges = append(change.CommodityChanges,
+		compareCommodities("", oldEntity.GetCommoditiesSold(), newEntity.GetCommoditiesSold(), threshold)...)
+
+	sort.Strings(change.AddedProviders)
+	sort.Strings(change.RemovedProviders)
+	sort.Slice(change.CommodityChanges, func(i, j int) bool {
+		ci, cj := change.CommodityChanges[i], change.CommodityChanges[j]
+		if ci.ProviderId != cj.ProviderId {
+			return ci.ProviderId < cj.ProviderId
+		}
+		if ci.CommodityType != cj.CommodityType {
+			return ci.CommodityType < cj.CommodityType
+		}
+		return ci.Key < cj.Key
+	})
+	changed := len(change.AddedProviders) > 0 || len(change.RemovedProviders) > 0 || len(change.CommodityChanges) > 0
+	return change, changed
+}
+
+// Compare the commodities of the same type and key in both lists.
+func compareCommodities(providerId string, oldCommodities, newCommodities []*proto.CommodityDTO,
+	threshold float64) []CommodityChange {
+	var changes []CommodityChange
+	newGroup := GroupCommodities(newCommodities)
+	for commodityType, oldSubGroup := range GroupCommodities(oldCommodities) {
+		for key, oldCommodity := range oldSubGroup {
+			newCommodity, exist := newGroup[commodityType][key]
+			if !exist {
+				continue
+			}
+			if WithinTolerance(oldCommodity.GetCapacity(), newCommodity.GetCapacity(), threshold) &&
+				WithinTolerance(oldCommodity.GetUsed(), newCommodity.GetUsed(), threshold) {
+				continue
+			}
+			changes = append(changes, CommodityChange{
+				ProviderId:    providerId,
+				CommodityType: commodityType.String(),
+				Key:           key,
+				OldCapacity:   oldCommodity.GetCapacity(),
+				NewCapacity:   newCommodity.GetCapacity(),
+				OldUsed:       oldCommodity.GetUsed(),
+				NewUsed:       newCommodity.GetUsed(),
+			})
+		}
+	}
+	return changes
+}
+
+func newReportEntity(entityDTO *proto.EntityDTO) Entity {
+	return Entity{
+		EntityType:  entityDTO.GetEntityType().String(),
+		Id:          entityDTO.GetId(),
+		DisplayName: entityDTO.GetDisplayName(),
+	}
+}
+
+func sortEntities(entities []Entity) {
+	sort.Slice(entities, func(i, j int) bool {
+		return lessEntity(entities[i], entities[j])
+	})
+}
+
+func lessEntity(e1, e2 Entity) bool {
+	if e1.EntityType != e2.EntityType {
+		return e1.EntityType < e2.EntityType
+	}
+	return e1.Id < e2.Id
+}
+
+// <entity_type : <entity_id : entityDTO>>
+func GroupByEntityType(entityDTOs []*proto.EntityDTO) map[proto.EntityDTO_EntityType]map[string]*proto.EntityDTO {
+	grouped := make(map[proto.EntityDTO_EntityType]map[string]*proto.EntityDTO)
+	for _, entityDTO := range entityDTOs {
+		mm, exist := grouped[entityDTO.GetEntityType()]
+		if !exist {
+			mm = make(map[string]*proto.EntityDTO)
+		}
+		mm[entityDTO.GetId()] = entityDTO
+		grouped[entityDTO.GetEntityType()] = mm
+	}
+	return grouped
+}
+
+// <provider_id : commodities bought from the provider>
+func GroupCommoditiesBought(commoditiesBought []*proto.EntityDTO_CommodityBought) map[string][]*proto.CommodityDTO {
+	providerCommBoughtMap := make(map[string][]*proto.CommodityDTO)
+	for _, commodityBought := range commoditiesBought {
+		providerID := commodityBought.GetProviderId()
+		providerCommBoughtMap[providerID] = append(providerCommBoughtMap[providerID], commodityBought.GetBought()...)
+	}
+	return providerCommBoughtMap
+}
+
+// <commodity_type : <commodity_key : commodityDTO>>
+func GroupCommodities(commodities []*proto.CommodityDTO) map[proto.CommodityDTO_CommodityType]map[string]*proto.CommodityDTO {
+	grouped := make(map[proto.CommodityDTO_CommodityType]map[string]*proto.CommodityDTO)
+	for _, comm := range commodities {
+		cType := comm.GetCommodityType()
+		subMap, exist := grouped[cType]
+		if !exist {
+			subMap = make(map[string]*proto.CommodityDTO)
+		}
+		key := comm.GetKey()
+		subMap[key] = comm
+		grouped[cType] = subMap
+	}
+
+	return grouped
+}
+
+// Check if the relative difference between the new value and the old value is within the tolerance. Any change from
+// zero is beyond the tolerance.
+func WithinTolerance(oldValue, newValue, tolerance float64) bool {
+	if oldValue == 0 {
+		return newValue == 0
+	}
+	return math.Abs(oldValue-newValue)/math.Abs(oldValue) <= tolerance
+}
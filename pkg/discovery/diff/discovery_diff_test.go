@@ -0,0 +1,101 @@
+package diff
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+)
+
+func newEntityDTO(entityType proto.EntityDTO_EntityType, id string, cpuCapacity, cpuUsed float64,
+	providers ...string) *proto.EntityDTO {
+	commodity := func() *proto.CommodityDTO {
+		return &proto.CommodityDTO{
+			CommodityType: proto.CommodityDTO_VCPU.Enum(),
+			Capacity:      &cpuCapacity,
+			Used:          &cpuUsed,
+		}
+	}
+	entityDTO := &proto.EntityDTO{
+		EntityType:      entityType.Enum(),
+		Id:              &id,
+		CommoditiesSold: []*proto.CommodityDTO{commodity()},
+	}
+	for i := range providers {
+		entityDTO.CommoditiesBought = append(entityDTO.CommoditiesBought, &proto.EntityDTO_CommodityBought{
+			ProviderId: &providers[i],
+			Bought:     []*proto.CommodityDTO{commodity()},
+		})
+	}
+	return entityDTO
+}
+
+func TestCompare(t *testing.T) {
+	oldEntityDTOs := []*proto.EntityDTO{
+		newEntityDTO(proto.EntityDTO_VIRTUAL_MACHINE, "node-1", 4000, 1000),
+		newEntityDTO(proto.EntityDTO_VIRTUAL_MACHINE, "node-2", 4000, 1000),
+		newEntityDTO(proto.EntityDTO_CONTAINER_POD, "pod-1", 1000, 100, "node-1"),
+		newEntityDTO(proto.EntityDTO_CONTAINER_POD, "pod-2", 1000, 100, "node-2"),
+	}
+	newEntityDTOs := []*proto.EntityDTO{
+		// The used value changes within the threshold.
+		newEntityDTO(proto.EntityDTO_VIRTUAL_MACHINE, "node-1", 4000, 1050),
+		newEntityDTO(proto.EntityDTO_VIRTUAL_MACHINE, "node-3", 4000, 1000),
+		// Moved to another node, with the capacity doubled.
+		newEntityDTO(proto.EntityDTO_CONTAINER_POD, "pod-1", 2000, 100, "node-3"),
+		// The used value changes beyond the threshold.
+		newEntityDTO(proto.EntityDTO_CONTAINER_POD, "pod-2", 1000, 200, "node-2"),
+		// The same ID as a removed entity of another type.
+		newEntityDTO(proto.EntityDTO_CONTAINER, "node-2", 1000, 100),
+	}
+
+	report := Compare(oldEntityDTOs, newEntityDTOs, 0.1)
+	if len(report.Added) != 2 || report.Added[0].Id != "node-2" || report.Added[1].Id != "node-3" {
+		t.Errorf("Expected node-2 container and node-3 added, got %+v", report.Added)
+	}
+	if len(report.Removed) != 1 || report.Removed[0].Id != "node-2" {
+		t.Errorf("Expected node-2 removed, got %+v", report.Removed)
+	}
+	if len(report.Changed) != 2 {
+		t.Fatalf("Expected 2 entities changed, got %+v", report.Changed)
+	}
+
+	pod1 := report.Changed[0]
+	if pod1.Id != "pod-1" || len(pod1.AddedProviders) != 1 || len(pod1.RemovedProviders) != 1 ||
+		len(pod1.CommodityChanges) != 1 || pod1.CommodityChanges[0].NewCapacity != 2000 {
+		t.Errorf("Expected pod-1 moved with a sold commodity changed, got %+v", pod1)
+	}
+	pod2 := report.Changed[1]
+	if pod2.Id != "pod-2" || len(pod2.AddedProviders) != 0 || len(pod2.CommodityChanges) != 2 {
+		t.Errorf("Expected pod-2 with a sold and a bought commodity changed, got %+v", pod2)
+	}
+
+	var text bytes.Buffer
+	if err := report.WriteText(&text); err != nil {
+		t.Errorf("Unexpected error writing text: %s", err)
+	}
+
+	if report := Compare(oldEntityDTOs, oldEntityDTOs, 0); !report.IsEmpty() {
+		t.Errorf("Expected no difference between the same results, got %+v", report)
+	}
+}
+
+func TestWithinTolerance(t *testing.T) {
+	table := []struct {
+		oldValue  float64
+		newValue  float64
+		tolerance float64
+		expected  bool
+	}{
+		{0, 0, 0, true},
+		{0, 1, 10, false},
+		{100, 110, 0.1, true},
+		{100, 111, 0.1, false},
+		{-100, -90, 0.1, true},
+	}
+	for i, item := range table {
+		if within := WithinTolerance(item.oldValue, item.newValue, item.tolerance); within != item.expected {
+			t.Errorf("Test case %d failed: expected %t, got %t", i, item.expected, within)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package diff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Write the report as human readable text.
+func (r *Report) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	writeEntities(bw, "Added", "+", r.Added)
+	writeEntities(bw, "Removed", "-", r.Removed)
+	if len(r.Changed) > 0 {
+		fmt.Fprintf(bw, "Changed entities (%d):\n", len(r.Changed))
+		for _, change := range r.Changed {
+			fmt.Fprintf(bw, "  ~ %s\n", formatEntity(change.Entity))
+			for _, providerId := range change.AddedProviders {
+				fmt.Fprintf(bw, "      provider added: %s\n", providerId)
+			}
+			for _, providerId := range change.RemovedProviders {
+				fmt.Fprintf(bw, "      provider removed: %s\n", providerId)
+			}
+			for _, commodityChange := range change.CommodityChanges {
+				fmt.Fprintf(bw, "      %s: capacity %s, used %s\n", formatCommodity(commodityChange),
+					formatDelta(commodityChange.OldCapacity, commodityChange.NewCapacity),
+					formatDelta(commodityChange.OldUsed, commodityChange.NewUsed))
+			}
+		}
+	}
+	fmt.Fprintf(bw, "Summary: %d added, %d removed, %d changed (commodity threshold %.1f%%)\n", len(r.Added),
+		len(r.Removed), len(r.Changed), r.Threshold*100)
+	return bw.Flush()
+}
+
+func writeEntities(w io.Writer, title, mark string, entities []Entity) {
+	if len(entities) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s entities (%d):\n", title, len(entities))
+	for _, entity := range entities {
+		fmt.Fprintf(w, "  %s %s\n", mark, formatEntity(entity))
+	}
+}
+
+func formatEntity(entity Entity) string {
+	return fmt.Sprintf("%s %s [%s]", entity.EntityType, entity.DisplayName, entity.Id)
+}
+
+func formatCommodity(change CommodityChange) string {
+	s := change.CommodityType
+	if change.Key != "" {
+		s = fmt.Sprintf("%s(%s)", s, change.Key)
+	}
+	if change.ProviderId == "" {
+		return "sold " + s
+	}
+	return fmt.Sprintf("bought %s from %s", s, change.ProviderId)
+}
+
+func formatDelta(oldValue, newValue float64) string {
+	if oldValue == newValue {
+		return fmt.Sprintf("%g", newValue)
+	}
+	if oldValue == 0 {
+		return fmt.Sprintf("%g -> %g", oldValue, newValue)
+	}
+	return fmt.Sprintf("%g -> %g (%+.1f%%)", oldValue, newValue, (newValue-oldValue)/oldValue*100)
+}
@@ -0,0 +1,85 @@
+package discovery
+
+import (
+	"fmt"
+
+	"github.com/turbonomic/kubeturbo/pkg/discovery/task"
+
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+)
+
+const (
+	// The max number of ErrorDTOs reported for the same source and entity type. A node which cannot be scraped fails
+	// the pods, containers and applications on it as well, so the rest are summarized in a single ErrorDTO.
+	maxErrorDTOsPerKind = 20
+)
+
+var (
+	entityTypeMapping = map[task.DiscoveredEntityType]proto.EntityDTO_EntityType{
+		task.NodeType:        proto.EntityDTO_VIRTUAL_MACHINE,
+		task.PodType:         proto.EntityDTO_CONTAINER_POD,
+		task.ContainerType:   proto.EntityDTO_CONTAINER,
+		task.ApplicationType: proto.EntityDTO_APPLICATION,
+		task.ServiceType:     proto.EntityDTO_VIRTUAL_APPLICATION,
+	}
+)
+
+// Build a warning ErrorDTO for every discovery error, so that the server knows the topology is incomplete.
+func buildDiscoveryErrorDTOs(discoveryErrors []*task.DiscoveryError) []*proto.ErrorDTO {
+	type errorKind struct {
+		source     string
+		entityType task.DiscoveredEntityType
+	}
+	var kinds []errorKind
+	counts := make(map[errorKind]int)
+
+	var errorDTOs []*proto.ErrorDTO
+	for _, discoveryError := range discoveryErrors {
+		kind := errorKind{discoveryError.Source, discoveryError.EntityType}
+		if counts[kind] == 0 {
+			kinds = append(kinds, kind)
+		}
+		counts[kind]++
+		if counts[kind] > maxErrorDTOsPerKind {
+			continue
+		}
+
+		errorDTO := newErrorDTO(proto.ErrorDTO_WARNING, discoveryError.Error())
+		if discoveryError.EntityUID != "" {
+			uid := discoveryError.EntityUID
+			errorDTO.EntityUuid = &uid
+		}
+		if eType, exist := entityTypeMapping[discoveryError.EntityType]; exist {
+			entityType := eType.String()
+			errorDTO.EntityType = &entityType
+		}
+		errorDTOs = append(errorDTOs, errorDTO)
+	}
+
+	for _, kind := range kinds {
+		if more := counts[kind] - maxErrorDTOsPerKind; more > 0 {
+			errorDTOs = append(errorDTOs, newErrorDTO(proto.ErrorDTO_WARNING,
+				fmt.Sprintf("%s: %d more %s entities failed to be discovered.", kind.source, more, kind.entityType)))
+		}
+	}
+	return errorDTOs
+}
+
+// Check if the fraction of the nodes failed to be discovered is more than the given max fraction.
+func checkFailedNodes(discoveryErrors []*task.DiscoveryError, nodeCount int, maxFailedNodeFraction float64) error {
+	if nodeCount == 0 {
+		return nil
+	}
+	failedNodes := make(map[string]bool)
+	for _, discoveryError := range discoveryErrors {
+		if discoveryError.EntityType == task.NodeType {
+			failedNodes[discoveryError.EntityName] = true
+		}
+	}
+	fraction := float64(len(failedNodes)) / float64(nodeCount)
+	if fraction > maxFailedNodeFraction {
+		return fmt.Errorf("%d of %d nodes failed to be discovered, which is more than the max fraction %v",
+			len(failedNodes), nodeCount, maxFailedNodeFraction)
+	}
+	return nil
+}
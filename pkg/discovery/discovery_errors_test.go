@@ -0,0 +1,70 @@
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/turbonomic/kubeturbo/pkg/discovery/task"
+
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+)
+
+func TestBuildDiscoveryErrorDTOs(t *testing.T) {
+	var discoveryErrors []*task.DiscoveryError
+	discoveryErrors = append(discoveryErrors, task.NewDiscoveryError("Kubelet", errors.New("connection refused")).
+		WithEntity(task.NodeType, "node-1", "uid-1"))
+	for i := 0; i < maxErrorDTOsPerKind+5; i++ {
+		discoveryErrors = append(discoveryErrors, task.NewDiscoveryError("EntityDTOBuilder", errors.New("no cpu frequency")).
+			WithEntity(task.PodType, fmt.Sprintf("default/pod-%d", i), fmt.Sprintf("pod-uid-%d", i)))
+	}
+	discoveryErrors = append(discoveryErrors, task.NewDiscoveryError("ServiceDiscovery", errors.New("no endpoints")))
+
+	errorDTOs := buildDiscoveryErrorDTOs(discoveryErrors)
+	// One node error, the max pod errors, one service discovery error and a summary of the rest pod errors.
+	if len(errorDTOs) != maxErrorDTOsPerKind+3 {
+		t.Fatalf("Expected %d ErrorDTOs, got %d", maxErrorDTOsPerKind+3, len(errorDTOs))
+	}
+	if errorDTOs[0].GetEntityType() != proto.EntityDTO_VIRTUAL_MACHINE.String() || errorDTOs[0].GetEntityUuid() != "uid-1" {
+		t.Errorf("Unexpected entity of node ErrorDTO: %s", errorDTOs[0])
+	}
+	last := errorDTOs[len(errorDTOs)-1]
+	if last.GetEntityType() != "" || last.GetDescription() != "EntityDTOBuilder: 5 more Pod entities failed to be discovered." {
+		t.Errorf("Unexpected summary ErrorDTO: %s", last)
+	}
+	for _, errorDTO := range errorDTOs {
+		if errorDTO.GetSeverity() != proto.ErrorDTO_WARNING {
+			t.Errorf("Unexpected severity of ErrorDTO: %s", errorDTO)
+		}
+	}
+}
+
+func TestCheckFailedNodes(t *testing.T) {
+	nodeError := func(name string) *task.DiscoveryError {
+		return task.NewDiscoveryError("Kubelet", errors.New("timeout")).WithEntity(task.NodeType, name, "")
+	}
+	podError := task.NewDiscoveryError("EntityDTOBuilder", errors.New("no provider")).
+		WithEntity(task.PodType, "default/pod", "")
+
+	table := []struct {
+		discoveryErrors []*task.DiscoveryError
+		nodeCount       int
+		maxFraction     float64
+		expectErr       bool
+	}{
+		{nil, 4, 0, false},
+		{[]*task.DiscoveryError{podError}, 4, 0, false},
+		{[]*task.DiscoveryError{nodeError("a")}, 4, 0, true},
+		// The same node failed by different sources is counted once.
+		{[]*task.DiscoveryError{nodeError("a"), nodeError("a"), podError}, 4, 0.25, false},
+		{[]*task.DiscoveryError{nodeError("a"), nodeError("b")}, 4, 0.25, true},
+		{[]*task.DiscoveryError{nodeError("a"), nodeError("b"), nodeError("c"), nodeError("d")}, 4, 1, false},
+		{nil, 0, 0, false},
+	}
+	for i, item := range table {
+		err := checkFailedNodes(item.discoveryErrors, item.nodeCount, item.maxFraction)
+		if item.expectErr != (err != nil) {
+			t.Errorf("Test case %d failed: expect error %t, got %v", i, item.expectErr, err)
+		}
+	}
+}
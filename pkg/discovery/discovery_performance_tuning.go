@@ -1,6 +1,7 @@
 package discovery
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -67,8 +68,11 @@ func (dc *K8sDiscoveryClient) discTimeCompare() {
 			discFunc: dc.discoveryWithOldFramework,
 		},
 		{
-			name:     "New Framework",
-			discFunc: dc.discoverWithNewFramework,
+			name: "New Framework",
+			discFunc: func() ([]*proto.EntityDTO, error) {
+				entityDTOs, _, err := dc.discoverWithNewFramework(context.Background())
+				return entityDTOs, err
+			},
 		},
 		{
 			name:     "New Framework Without Compliance",
@@ -96,17 +100,20 @@ func (dc *K8sDiscoveryClient) discoveryWithOldFramework() ([]*proto.EntityDTO, e
 }
 
 func (dc *K8sDiscoveryClient) discoverWithNewFrameworkWithoutCompliance() ([]*proto.EntityDTO, error) {
-	nodes, err := dc.config.k8sClusterScraper.GetAllNodes()
+	clusterSnapshot, err := dc.config.k8sClusterScraper.TakeSnapshot()
 	if err != nil {
-		return nil, fmt.Errorf("Failed to get all nodes in the cluster: %s", err)
+		return nil, fmt.Errorf("Failed to take a snapshot of the cluster: %s", err)
 	}
 
-	workerCount := dc.dispatcher.Dispatch(nodes)
-	entityDTOs := dc.resultCollector.Collect(workerCount)
+	workerCount := dc.dispatcher.Dispatch(context.Background(), clusterSnapshot)
+	entityDTOs, _ := dc.resultCollector.Collect(workerCount)
 	glog.V(3).Infof("Discovery workers have finished discovery work with %d entityDTOs built. Now performing service discovery...", len(entityDTOs))
 
-	svcWorkerConfig := worker.NewK8sServiceDiscoveryWorkerConfig(dc.config.k8sClusterScraper)
+	svcWorkerConfig := worker.NewK8sServiceDiscoveryWorkerConfig(clusterSnapshot)
 	svcDiscWorker, err := worker.NewK8sServiceDiscoveryWorker(svcWorkerConfig)
+	if err != nil {
+		return nil, err
+	}
 	svcDiscResult := svcDiscWorker.Do(entityDTOs)
 	if svcDiscResult.Err() != nil {
 		glog.Errorf("Failed to discover services from current Kubernetes cluster with the new discovery framework: %s", svcDiscResult.Err())
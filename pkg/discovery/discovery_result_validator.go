@@ -2,9 +2,10 @@ package discovery
 
 import (
 	"fmt"
-	"math"
 	"reflect"
 
+	"github.com/turbonomic/kubeturbo/pkg/discovery/diff"
+
 	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
 
 	"github.com/golang/glog"
@@ -14,8 +15,8 @@ func compareDiscoveryResults(discResFromOldFramework, discResFromNewFramework []
 
 	glog.Infof("Start validation")
 
-	oldGroup := groupByEntityDtoType(discResFromOldFramework)
-	newGroup := groupByEntityDtoType(discResFromNewFramework)
+	oldGroup := diff.GroupByEntityType(discResFromOldFramework)
+	newGroup := diff.GroupByEntityType(discResFromNewFramework)
 
 	for entityType, entitiesFromOld := range oldGroup {
 		glog.Infof("Now examine %s", entityType)
@@ -85,19 +86,6 @@ func showEntityDTOListDifference(oldEntityDTOs, newEntityDTOs map[string]*proto.
 	}
 }
 
-func groupByEntityDtoType(entityDTOs []*proto.EntityDTO) map[proto.EntityDTO_EntityType]map[string]*proto.EntityDTO {
-	grouped := make(map[proto.EntityDTO_EntityType]map[string]*proto.EntityDTO)
-	for _, entityDTO := range entityDTOs {
-		mm, exist := grouped[entityDTO.GetEntityType()]
-		if !exist {
-			mm = make(map[string]*proto.EntityDTO)
-		}
-		mm[entityDTO.GetId()] = entityDTO
-		grouped[entityDTO.GetEntityType()] = mm
-	}
-	return grouped
-}
-
 func compareEntityDTOs(oldEntity, newEntity *proto.EntityDTO) bool {
 	isClear := true
 
@@ -217,8 +205,8 @@ func compareEntityDTOs(oldEntity, newEntity *proto.EntityDTO) bool {
 	}
 
 	// commodity bought
-	oldCommoditiesBoughtGroup := groupCommoditiesBought(oldEntity.GetCommoditiesBought())
-	newCommoditiesBoughtGroup := groupCommoditiesBought(newEntity.GetCommoditiesBought())
+	oldCommoditiesBoughtGroup := diff.GroupCommoditiesBought(oldEntity.GetCommoditiesBought())
+	newCommoditiesBoughtGroup := diff.GroupCommoditiesBought(newEntity.GetCommoditiesBought())
 	for provider, commoditiesBoughtOld := range oldCommoditiesBoughtGroup {
 		commoditiesBoughtNew, find := newCommoditiesBoughtGroup[provider]
 		if !find {
@@ -270,40 +258,9 @@ func compareEntityProperties(properties1, properties2 []*proto.EntityDTO_EntityP
 	return true
 }
 
-func groupCommoditiesBought(commoditiesBought []*proto.EntityDTO_CommodityBought) map[string][]*proto.CommodityDTO {
-	providerCommBoughtMap := make(map[string][]*proto.CommodityDTO)
-	for _, commodityBought := range commoditiesBought {
-		providerID := commodityBought.GetProviderId()
-		commodities, exists := providerCommBoughtMap[providerID]
-		if !exists {
-			commodities = []*proto.CommodityDTO{}
-		}
-		commodities = append(commodities, commodityBought.GetBought()...)
-		providerCommBoughtMap[providerID] = commodities
-	}
-	return providerCommBoughtMap
-}
-
-// <commodity_type : <commodity_key : commodityDTO>>
-func groupCommodities(commodities []*proto.CommodityDTO) map[proto.CommodityDTO_CommodityType]map[string]*proto.CommodityDTO {
-	grouped := make(map[proto.CommodityDTO_CommodityType]map[string]*proto.CommodityDTO)
-	for _, comm := range commodities {
-		cType := comm.GetCommodityType()
-		subMap, exist := grouped[cType]
-		if !exist {
-			subMap = make(map[string]*proto.CommodityDTO)
-		}
-		key := comm.GetKey()
-		subMap[key] = comm
-		grouped[cType] = subMap
-	}
-
-	return grouped
-}
-
 func compareCommodities(commoditiesFromOld, commoditiesFromNew []*proto.CommodityDTO) bool {
-	oldGroup := groupCommodities(commoditiesFromOld)
-	newGroup := groupCommodities(commoditiesFromNew)
+	oldGroup := diff.GroupCommodities(commoditiesFromOld)
+	newGroup := diff.GroupCommodities(commoditiesFromNew)
 
 	isClear := true
 	for cType, subOld := range oldGroup {
@@ -383,19 +340,19 @@ func compareCommodity(commodityOld, commodityNew *proto.CommodityDTO) bool {
 	}
 
 	// used value
-	if !compareValue(commodityOld.GetUsed(), commodityNew.GetUsed()) {
+	if !diff.WithinTolerance(commodityOld.GetUsed(), commodityNew.GetUsed(), toleration) {
 		glog.Errorf("Old has a %s commodity used value set as %f, new has it as %f", cType, commodityOld.GetUsed(), commodityNew.GetUsed())
 		isClear = false
 	}
 
 	// capacity value
-	if !compareValue(commodityOld.GetCapacity(), commodityNew.GetCapacity()) {
+	if !diff.WithinTolerance(commodityOld.GetCapacity(), commodityNew.GetCapacity(), toleration) {
 		glog.Errorf("Old has a %s commodity capacity value set as %f, new has it as %f", cType, commodityOld.GetCapacity(), commodityNew.GetCapacity())
 		isClear = false
 	}
 
 	// reservation value
-	if !compareValue(commodityOld.GetReservation(), commodityNew.GetReservation()) {
+	if !diff.WithinTolerance(commodityOld.GetReservation(), commodityNew.GetReservation(), toleration) {
 		glog.Errorf("Old has a %s commodity reservation value set as %f, new has it as %f", cType, commodityOld.GetReservation(), commodityNew.GetReservation())
 		isClear = false
 	}
@@ -403,15 +360,3 @@ func compareCommodity(commodityOld, commodityNew *proto.CommodityDTO) bool {
 }
 
 const toleration float64 = 0.3
-
-// if the difference between two value is greater than toleration rate, then return false.
-func compareValue(v1, v2 float64) bool {
-	if v1 == 0 {
-		if v2 == 0 {
-			return true
-		} else {
-			return false
-		}
-	}
-	return math.Abs(v1-v2)/math.Abs(v1) <= toleration
-}
@@ -0,0 +1,168 @@
+package discovery
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/turbonomic/kubeturbo/pkg/discovery/configs"
+
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+
+	"github.com/golang/glog"
+	goproto "github.com/golang/protobuf/proto"
+)
+
+const (
+	snapshotFilePrefix = "discovery-"
+	// The timestamp in the file name, which sorts the snapshots of a target from the oldest to the newest.
+	snapshotTimeFormat = "20060102T150405.000000000Z"
+
+	jsonSnapshotExt     = ".json"
+	protobufSnapshotExt = ".pb"
+)
+
+var unsafeFileNameChars = regexp.MustCompile("[^A-Za-z0-9._-]")
+
+// snapshotRecorder writes the discovery responses of a target to a directory, and keeps at most a max number and a
+// max total size of the snapshots by removing the oldest ones.
+type snapshotRecorder struct {
+	dir    string
+	format configs.SnapshotFormat
+	// all the snapshots of the target start with the prefix.
+	prefix string
+
+	maxSnapshots int
+	maxBytes     int64
+
+	// serializes the writing and rotation of the snapshots.
+	lock sync.Mutex
+}
+
+func newSnapshotRecorder(dir string, format configs.SnapshotFormat, targetIdentifier string, maxSnapshots int,
+	maxBytes int64) (*snapshotRecorder, error) {
+	switch format {
+	case configs.SnapshotFormatJSON, configs.SnapshotFormatProtobuf:
+	default:
+		return nil, fmt.Errorf("snapshot format %q is not supported", format)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory %s: %s", dir, err)
+	}
+	return &snapshotRecorder{
+		dir:          dir,
+		format:       format,
+		prefix:       snapshotFilePrefix + unsafeFileNameChars.ReplaceAllString(targetIdentifier, "_") + "-",
+		maxSnapshots: maxSnapshots,
+		maxBytes:     maxBytes,
+	}, nil
+}
+
+// Write the discovery response as a new snapshot, and remove the oldest snapshots beyond the limits. The new snapshot
+// is always kept. It returns the path of the new snapshot.
+func (r *snapshotRecorder) record(response *proto.DiscoveryResponse, timestamp time.Time) (string, error) {
+	var data []byte
+	var err error
+	ext := protobufSnapshotExt
+	if r.format == configs.SnapshotFormatJSON {
+		ext = jsonSnapshotExt
+		data, err = json.MarshalIndent(response, "", "  ")
+	} else {
+		data, err = goproto.Marshal(response)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize discovery response: %s", err)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	// Write to a temporary file first, so that a partial snapshot is never left with the name of a snapshot.
+	path := filepath.Join(r.dir, r.prefix+timestamp.UTC().Format(snapshotTimeFormat)+ext)
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to write snapshot %s: %s", path, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to write snapshot %s: %s", path, err)
+	}
+
+	if err := r.rotate(); err != nil {
+		glog.Warningf("Failed to remove old discovery snapshots: %s", err)
+	}
+	return path, nil
+}
+
+// Remove the oldest snapshots of the target until both the number and the total size are within the limits.
+func (r *snapshotRecorder) rotate() error {
+	files, err := ioutil.ReadDir(r.dir)
+	if err != nil {
+		return err
+	}
+	var snapshots []os.FileInfo
+	for _, file := range files {
+		if file.Mode().IsRegular() && r.isSnapshot(file.Name()) {
+			snapshots = append(snapshots, file)
+		}
+	}
+	// Newest first.
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Name() > snapshots[j].Name()
+	})
+
+	var totalBytes int64
+	for i, snapshot := range snapshots {
+		totalBytes += snapshot.Size()
+		if i == 0 {
+			continue
+		}
+		if (r.maxSnapshots > 0 && i >= r.maxSnapshots) || (r.maxBytes > 0 && totalBytes > r.maxBytes) {
+			path := filepath.Join(r.dir, snapshot.Name())
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			glog.V(3).Infof("Removed discovery snapshot %s.", path)
+		}
+	}
+	return nil
+}
+
+// Check if the file is a snapshot of the target. The prefix of a target may be the prefix of another target, so the
+// rest of the name must be a timestamp.
+func (r *snapshotRecorder) isSnapshot(name string) bool {
+	if !strings.HasPrefix(name, r.prefix) {
+		return false
+	}
+	timestamp := strings.TrimPrefix(name, r.prefix)
+	if ext := filepath.Ext(timestamp); ext == jsonSnapshotExt || ext == protobufSnapshotExt {
+		_, err := time.Parse(snapshotTimeFormat, strings.TrimSuffix(timestamp, ext))
+		return err == nil
+	}
+	return false
+}
+
+// Read a discovery response from a protobuf snapshot.
+func LoadSnapshot(path string) (*proto.DiscoveryResponse, error) {
+	if strings.HasSuffix(path, jsonSnapshotExt) {
+		return nil, fmt.Errorf("JSON snapshot %s cannot be replayed, record snapshots in %s format instead", path,
+			configs.SnapshotFormatProtobuf)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %s", err)
+	}
+	response := &proto.DiscoveryResponse{}
+	if err := goproto.Unmarshal(data, response); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %s: %s", path, err)
+	}
+	return response, nil
+}
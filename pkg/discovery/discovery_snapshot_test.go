@@ -0,0 +1,86 @@
+package discovery
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/turbonomic/kubeturbo/pkg/discovery/configs"
+
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+)
+
+func TestSnapshotRecorderRotation(t *testing.T) {
+	table := []struct {
+		maxSnapshots int
+		maxBytes     int64
+		records      int
+		// the expected number of snapshots kept after each record.
+		expectedKept int
+	}{
+		{0, 0, 5, 5},
+		{3, 0, 5, 3},
+		// The newest snapshot is kept even if it exceeds the size limit.
+		{0, 1, 3, 1},
+		{10, 1 << 20, 5, 5},
+	}
+	for i, item := range table {
+		dir, err := ioutil.TempDir("", "snapshots")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		recorder, err := newSnapshotRecorder(dir, configs.SnapshotFormatProtobuf, "https://10.0.0.1:6443",
+			item.maxSnapshots, item.maxBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Snapshots of other targets are not rotated, even if their names start with the same prefix.
+		other, err := newSnapshotRecorder(dir, configs.SnapshotFormatJSON, "https://10.0.0.1:6443-b", 1, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := other.record(&proto.DiscoveryResponse{}, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+
+		start := time.Now()
+		var lastPath string
+		for j := 0; j < item.records; j++ {
+			entityDTO := &proto.EntityDTO{EntityType: proto.EntityDTO_CONTAINER_POD.Enum(), Id: &dir}
+			lastPath, err = recorder.record(&proto.DiscoveryResponse{EntityDTO: []*proto.EntityDTO{entityDTO}},
+				start.Add(time.Duration(j)*time.Second))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		kept, others := 0, 0
+		for _, file := range files {
+			if recorder.isSnapshot(file.Name()) {
+				kept++
+			} else if other.isSnapshot(file.Name()) {
+				others++
+			}
+		}
+		if kept != item.expectedKept {
+			t.Errorf("Test case %d failed: expected %d snapshots kept, got %d", i, item.expectedKept, kept)
+		}
+		if others != 1 {
+			t.Errorf("Test case %d failed: expected 1 snapshot of the other target, got %d", i, others)
+		}
+
+		response, err := LoadSnapshot(lastPath)
+		if err != nil {
+			t.Errorf("Test case %d failed: unexpected error replaying the last snapshot: %s", i, err)
+		} else if len(response.GetEntityDTO()) != 1 {
+			t.Errorf("Test case %d failed: expected 1 entityDTO replayed, got %d", i, len(response.GetEntityDTO()))
+		}
+	}
+}
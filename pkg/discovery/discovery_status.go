@@ -0,0 +1,93 @@
+package discovery
+
+import (
+	"sync"
+	"time"
+
+	"github.com/turbonomic/kubeturbo/pkg/discovery/task"
+
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+)
+
+// DiscoveryStatus summarizes the last discovery of a target, for debugging.
+type DiscoveryStatus struct {
+	Time     time.Time `json:"time"`
+	Duration string    `json:"duration"`
+	// The error failing the whole discovery, if any.
+	Error string `json:"error,omitempty"`
+
+	// <entity_type : number of entities>
+	EntityCounts map[string]int `json:"entityCounts,omitempty"`
+	// <node_name : errors of discovering the node>
+	NodeErrors map[string][]string `json:"nodeErrors,omitempty"`
+	// The errors not related to a node, e.g. of a pod or a discovery phase.
+	OtherErrors []string `json:"otherErrors,omitempty"`
+
+	entityDTOs []*proto.EntityDTO
+}
+
+// NewDiscoveryStatus summarizes a discovery started at the given time, with its entityDTOs and errors.
+func NewDiscoveryStatus(start time.Time, entityDTOs []*proto.EntityDTO, discoveryErrors []*task.DiscoveryError,
+	err error) *DiscoveryStatus {
+	status := &DiscoveryStatus{
+		Time:         start,
+		Duration:     time.Since(start).String(),
+		EntityCounts: make(map[string]int),
+		NodeErrors:   make(map[string][]string),
+		entityDTOs:   entityDTOs,
+	}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	for _, entityDTO := range entityDTOs {
+		status.EntityCounts[entityDTO.GetEntityType().String()]++
+	}
+	for _, discoveryError := range discoveryErrors {
+		if discoveryError.EntityType == task.NodeType {
+			status.NodeErrors[discoveryError.EntityName] = append(status.NodeErrors[discoveryError.EntityName],
+				discoveryError.Error())
+		} else {
+			status.OtherErrors = append(status.OtherErrors, discoveryError.Error())
+		}
+	}
+	return status
+}
+
+// Find the entityDTO of the given id.
+func (s *DiscoveryStatus) FindEntityDTO(id string) *proto.EntityDTO {
+	for _, entityDTO := range s.entityDTOs {
+		if entityDTO.GetId() == id {
+			return entityDTO
+		}
+	}
+	return nil
+}
+
+// Find the entityDTOs of the given display name, e.g. namespace/name for a pod.
+func (s *DiscoveryStatus) FindEntityDTOsByName(displayName string) []*proto.EntityDTO {
+	var entityDTOs []*proto.EntityDTO
+	for _, entityDTO := range s.entityDTOs {
+		if entityDTO.GetDisplayName() == displayName {
+			entityDTOs = append(entityDTOs, entityDTO)
+		}
+	}
+	return entityDTOs
+}
+
+// Keeps the status of the last discovery. It is safe for concurrent use.
+type discoveryStatusHolder struct {
+	lock   sync.RWMutex
+	status *DiscoveryStatus
+}
+
+func (h *discoveryStatusHolder) set(status *DiscoveryStatus) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.status = status
+}
+
+func (h *discoveryStatusHolder) get() *DiscoveryStatus {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	return h.status
+}
@@ -19,6 +19,9 @@ import (
 
 const (
 	AppPrefix string = "App-"
+
+	// The index of the container hosting the application of a pod.
+	hostingContainerIndex = 0
 )
 
 var (
@@ -59,19 +62,17 @@ func (builder *applicationEntityDTOBuilder) BuildEntityDTOs(pods []*api.Pod) ([]
 		commoditiesSold, err := builder.getApplicationCommoditiesSold(pod)
 		if err != nil {
 			glog.Errorf("Failed to create commodities sold by application %s: %s", displayName, err)
+			builder.addDiscoveryError(task.ApplicationType, displayName, appID, fmt.Errorf("failed to create commodities sold: %s", err))
 			continue
 		}
 		entityDTOBuilder.SellsCommodities(commoditiesSold)
 
-		// commodity bought
-		provider := sdkbuilder.CreateProvider(proto.EntityDTO_CONTAINER_POD, string(pod.UID))
-		entityDTOBuilder = entityDTOBuilder.Provider(provider)
-		commoditiesBought, err := builder.getApplicationCommoditiesBought(pod)
-		if err != nil {
+		// commodity bought. An application buys from the hosting container of the pod.
+		if err := builder.buyFromHostingContainer(entityDTOBuilder, pod); err != nil {
 			glog.Errorf("Failed to create commodities bought by application %s: %s", displayName, err)
+			builder.addDiscoveryError(task.ApplicationType, displayName, appID, fmt.Errorf("failed to create commodities bought: %s", err))
 			continue
 		}
-		entityDTOBuilder.BuysCommodities(commoditiesBought)
 
 		// entities' properties.
 		properties := builder.getApplicationProperties(pod)
@@ -90,6 +91,7 @@ func (builder *applicationEntityDTOBuilder) BuildEntityDTOs(pods []*api.Pod) ([]
 		entityDTO, err := entityDTOBuilder.Create()
 		if err != nil {
 			glog.Errorf("Failed to build Application entityDTO based on application %s: %s", displayName, err)
+			builder.addDiscoveryError(task.ApplicationType, displayName, appID, fmt.Errorf("failed to build entityDTO: %s", err))
 			continue
 		}
 
@@ -139,24 +141,43 @@ func FindTransactionCommodityKey(pod *api.Pod, clusterID string) string {
 	return appType + "-" + clusterID
 }
 
-// Build the bought commodities by each application.
-// An application buys vCPU, vMem and Application commodity from a pod.
-func (builder *applicationEntityDTOBuilder) getApplicationCommoditiesBought(pod *api.Pod) ([]*proto.CommodityDTO, error) {
-	var commoditiesBought []*proto.CommodityDTO
-	// As all the application commodities are actually retrieved from pods, so the key is also from pod.
-	key := util.PodKeyFunc(pod)
+// Add the hosting container of the pod as the provider of the application. It is the first container in the pod
+// spec, which by convention runs the application, while the others are its sidecars. An application has a single
+// hosting provider.
+func (builder *applicationEntityDTOBuilder) buyFromHostingContainer(entityDTOBuilder *sdkbuilder.EntityDTOBuilder, pod *api.Pod) error {
+	if len(pod.Spec.Containers) == 0 {
+		return fmt.Errorf("pod %s has no container", util.GetPodClusterID(pod))
+	}
 
 	// get cpu frequency
 	cpuFrequencyUID := metrics.GenerateEntityStateMetricUID(task.NodeType, util.NodeKeyFromPodFunc(pod), metrics.CpuFrequency)
 	cpuFrequencyMetric, err := builder.metricsSink.GetMetric(cpuFrequencyUID)
 	if err != nil {
 		// TODO acceptable return? To get cpu, frequency is required.
-		return nil, fmt.Errorf("Failed to get cpu frequency from sink for node %s: %s", util.NodeKeyFromPodFunc(pod), err)
+		return fmt.Errorf("Failed to get cpu frequency from sink for node %s: %s", util.NodeKeyFromPodFunc(pod), err)
 	}
 	cpuFrequency := cpuFrequencyMetric.GetValue().(float64)
-	// cpu and cpu provisioned needs to be converted from number of cores to frequency.
+	// cpu needs to be converted from number of cores to frequency.
 	converter := NewConverter().Set(func(input float64) float64 { return input * cpuFrequency }, metrics.CPU)
 
+	containerID := util.ContainerIDFunc(pod, hostingContainerIndex)
+	containerKey := util.ContainerKeyFunc(pod, pod.Spec.Containers[hostingContainerIndex].Name)
+	commoditiesBought, err := builder.getApplicationCommoditiesBought(containerKey, containerID, converter)
+	if err != nil {
+		return err
+	}
+	provider := sdkbuilder.CreateProvider(proto.EntityDTO_CONTAINER, containerID)
+	entityDTOBuilder.Provider(provider)
+	entityDTOBuilder.BuysCommodities(commoditiesBought)
+	return nil
+}
+
+// Build the commodities bought by an application from one container.
+// An application buys vCPU, vMem and Application commodity from a container.
+func (builder *applicationEntityDTOBuilder) getApplicationCommoditiesBought(key, containerID string,
+	converter *converter) ([]*proto.CommodityDTO, error) {
+	var commoditiesBought []*proto.CommodityDTO
+
 	// Resource commodities.
 	resourceCommoditiesBought, err := builder.getResourceCommoditiesBought(task.ApplicationType, key, applicationResourceCommodityBought, converter, nil)
 	if err != nil {
@@ -166,7 +187,7 @@ func (builder *applicationEntityDTOBuilder) getApplicationCommoditiesBought(pod
 
 	// Application commodity
 	applicationCommBought, err := sdkbuilder.NewCommodityDTOBuilder(proto.CommodityDTO_APPLICATION).
-		Key(string(pod.UID)).
+		Key(containerID).
 		Create()
 	if err != nil {
 		return nil, err
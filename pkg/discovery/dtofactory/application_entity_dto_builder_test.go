@@ -0,0 +1,58 @@
+package dtofactory
+
+import (
+	"testing"
+
+	api "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/turbonomic/kubeturbo/pkg/discovery/metrics"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/task"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/util"
+
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+)
+
+func TestApplicationEntityDTOBuilderHostingContainer(t *testing.T) {
+	pod := newTestPod("app", "sidecar")
+	sink := newTestContainerSink(pod)
+	sink.AddNewMetricEntries(metrics.NewEntityStateMetric(task.ClusterType, "", metrics.Cluster, "cluster-id"))
+
+	builder := NewApplicationEntityDTOBuilder(sink)
+	entityDTOs, err := builder.BuildEntityDTOs([]*api.Pod{pod})
+	if err != nil {
+		t.Fatalf("Failed to build application entityDTOs: %s", err)
+	}
+	if len(entityDTOs) != 1 {
+		t.Fatalf("Expected 1 application entityDTO, got %d", len(entityDTOs))
+	}
+
+	bought := entityDTOs[0].GetCommoditiesBought()
+	if len(bought) != 1 {
+		t.Fatalf("Expected the application to buy from a single container, got %d providers", len(bought))
+	}
+	if expected := util.ContainerIDFunc(pod, 0); bought[0].GetProviderId() != expected {
+		t.Errorf("Expected the application to be hosted by container %s, got %s", expected,
+			bought[0].GetProviderId())
+	}
+	application := findCommodity(bought[0].GetBought(), proto.CommodityDTO_APPLICATION)
+	if application == nil || application.GetKey() != bought[0].GetProviderId() {
+		t.Errorf("Expected application commodity bought with key %s, got %+v", bought[0].GetProviderId(),
+			application)
+	}
+}
+
+func TestApplicationEntityDTOBuilderWithoutContainer(t *testing.T) {
+	pod := newTestPod()
+	sink := newTestContainerSink(pod)
+	sink.AddNewMetricEntries(metrics.NewEntityStateMetric(task.ClusterType, "", metrics.Cluster, "cluster-id"))
+
+	builder := NewApplicationEntityDTOBuilder(sink)
+	entityDTOs, err := builder.BuildEntityDTOs([]*api.Pod{pod})
+	if err != nil {
+		t.Fatalf("Failed to build application entityDTOs: %s", err)
+	}
+	if len(entityDTOs) != 0 || len(builder.GetDiscoveryErrors()) != 1 {
+		t.Errorf("Expected a discovery error instead of an application without container, got %d entityDTOs "+
+			"and errors %v", len(entityDTOs), builder.GetDiscoveryErrors())
+	}
+}
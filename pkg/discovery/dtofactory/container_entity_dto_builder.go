@@ -0,0 +1,192 @@
+package dtofactory
+
+import (
+	"fmt"
+
+	api "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/turbonomic/kubeturbo/pkg/discovery/dtofactory/property"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/metrics"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/task"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/util"
+
+	sdkbuilder "github.com/turbonomic/turbo-go-sdk/pkg/builder"
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+
+	"github.com/golang/glog"
+)
+
+var (
+	containerResourceCommoditySold = []metrics.ResourceType{
+		metrics.CPU,
+		metrics.Memory,
+	}
+
+	containerResourceCommodityBought = []metrics.ResourceType{
+		metrics.CPU,
+		metrics.Memory,
+	}
+)
+
+type containerEntityDTOBuilder struct {
+	generalBuilder
+}
+
+func NewContainerEntityDTOBuilder(sink *metrics.EntityMetricSink) *containerEntityDTOBuilder {
+	return &containerEntityDTOBuilder{
+		generalBuilder: newGeneralBuilder(sink),
+	}
+}
+
+// Build entityDTOs for all the containers of the given pods.
+func (builder *containerEntityDTOBuilder) BuildEntityDTOs(pods []*api.Pod) ([]*proto.EntityDTO, error) {
+	var result []*proto.EntityDTO
+	for _, pod := range pods {
+		// get cpu frequency
+		cpuFrequencyUID := metrics.GenerateEntityStateMetricUID(task.NodeType, util.NodeKeyFromPodFunc(pod), metrics.CpuFrequency)
+		cpuFrequencyMetric, err := builder.metricsSink.GetMetric(cpuFrequencyUID)
+		if err != nil {
+			glog.Errorf("Failed to get cpu frequency from sink for node %s: %s", util.NodeKeyFromPodFunc(pod), err)
+			for _, container := range pod.Spec.Containers {
+				builder.addDiscoveryError(task.ContainerType, util.GetPodClusterID(pod)+"/"+container.Name, "",
+					fmt.Errorf("failed to get cpu frequency of node %s: %s", util.NodeKeyFromPodFunc(pod), err))
+			}
+			continue
+		}
+		cpuFrequency := cpuFrequencyMetric.GetValue().(float64)
+		// cpu needs to be converted from number of cores to frequency.
+		converter := NewConverter().Set(func(input float64) float64 { return input * cpuFrequency }, metrics.CPU)
+
+		for i, container := range pod.Spec.Containers {
+			// id.
+			containerID := util.ContainerIDFunc(pod, i)
+			entityDTOBuilder := sdkbuilder.NewEntityDTOBuilder(proto.EntityDTO_CONTAINER, containerID)
+
+			// display name.
+			displayName := util.GetPodClusterID(pod) + "/" + container.Name
+			entityDTOBuilder.DisplayName(displayName)
+
+			key := util.ContainerKeyFunc(pod, container.Name)
+
+			// commodities sold.
+			commoditiesSold, err := builder.getContainerCommoditiesSold(key, containerID, converter)
+			if err != nil {
+				glog.Errorf("Error when create commoditiesSold for container %s: %s", displayName, err)
+				builder.addDiscoveryError(task.ContainerType, displayName, containerID, fmt.Errorf("failed to create commodities sold: %s", err))
+				continue
+			}
+			entityDTOBuilder.SellsCommodities(commoditiesSold)
+
+			// commodities bought.
+			provider := sdkbuilder.CreateProvider(proto.EntityDTO_CONTAINER_POD, string(pod.UID))
+			entityDTOBuilder = entityDTOBuilder.Provider(provider)
+			commoditiesBought, err := builder.getContainerCommoditiesBought(pod, key, converter)
+			if err != nil {
+				glog.Errorf("Error when create commoditiesBought for container %s: %s", displayName, err)
+				builder.addDiscoveryError(task.ContainerType, displayName, containerID, fmt.Errorf("failed to create commodities bought: %s", err))
+				continue
+			}
+			entityDTOBuilder.BuysCommodities(commoditiesBought)
+
+			// entities' properties.
+			properties := property.BuildContainerProperties(pod.Namespace, pod.Name, container.Name)
+			entityDTOBuilder.WithProperties(properties)
+
+			if !util.Monitored(pod) {
+				entityDTOBuilder.Monitored(false)
+			}
+
+			// build entityDTO.
+			entityDTO, err := entityDTOBuilder.Create()
+			if err != nil {
+				glog.Errorf("Failed to build Container entityDTO based on container %s: %s", displayName, err)
+				builder.addDiscoveryError(task.ContainerType, displayName, containerID, fmt.Errorf("failed to build entityDTO: %s", err))
+				continue
+			}
+
+			result = append(result, entityDTO)
+		}
+	}
+
+	return result, nil
+}
+
+// Build the sold commodityDTO by each container. They are:
+// vCPU, vMem, ApplicationCommodity.
+// Capacities of vCPU and vMem are the limits of the container, and reservations are the requests.
+func (builder *containerEntityDTOBuilder) getContainerCommoditiesSold(key, containerID string, converter *converter) ([]*proto.CommodityDTO, error) {
+	var commoditiesSold []*proto.CommodityDTO
+
+	// attr
+	attributeSetter := NewCommodityAttrSetter()
+	// Containers are not resized, as there is no executor for resize actions.
+	attributeSetter.Add(func(commBuilder *sdkbuilder.CommodityDTOBuilder) { commBuilder.Resizable(false) }, metrics.CPU, metrics.Memory)
+	for _, rType := range containerResourceCommoditySold {
+		reservation, err := builder.getReservation(key, rType, converter)
+		if err != nil {
+			glog.V(4).Infof("Don't find %s reservation for container %s: %s", rType, key, err)
+			continue
+		}
+		attributeSetter.Add(func(commBuilder *sdkbuilder.CommodityDTOBuilder) { commBuilder.Reservation(reservation) }, rType)
+	}
+
+	// Resource Commodities
+	resourceCommoditiesSold, err := builder.getResourceCommoditiesSold(task.ContainerType, key, containerResourceCommoditySold, converter, attributeSetter)
+	if err != nil {
+		return nil, err
+	}
+	commoditiesSold = append(commoditiesSold, resourceCommoditiesSold...)
+
+	// Application commodity
+	applicationComm, err := sdkbuilder.NewCommodityDTOBuilder(proto.CommodityDTO_APPLICATION).
+		Key(containerID).
+		Capacity(applicationCommodityDefaultCapacity).
+		Create()
+	if err != nil {
+		return nil, err
+	}
+	commoditiesSold = append(commoditiesSold, applicationComm)
+
+	return commoditiesSold, nil
+}
+
+// Get the reservation of the given resource type of a container.
+func (builder *containerEntityDTOBuilder) getReservation(key string, rType metrics.ResourceType, converter *converter) (float64, error) {
+	reservationUID := metrics.GenerateEntityResourceMetricUID(task.ContainerType, key, rType, metrics.Reservation)
+	reservationMetric, err := builder.metricsSink.GetMetric(reservationUID)
+	if err != nil {
+		return 0, err
+	}
+	reservation, ok := reservationMetric.GetValue().(float64)
+	if !ok {
+		return 0, fmt.Errorf("%s reservation is not a float64", rType)
+	}
+	if converter != nil && converter.Convertible(rType) {
+		reservation = converter.Convert(rType, reservation)
+	}
+	return reservation, nil
+}
+
+// Build the bought commodityDTO by each container. They are:
+// vCPU, vMem and Application commodity from the hosting pod.
+func (builder *containerEntityDTOBuilder) getContainerCommoditiesBought(pod *api.Pod, key string, converter *converter) ([]*proto.CommodityDTO, error) {
+	var commoditiesBought []*proto.CommodityDTO
+
+	// Resource Commodities.
+	resourceCommoditiesBought, err := builder.getResourceCommoditiesBought(task.ContainerType, key, containerResourceCommodityBought, converter, nil)
+	if err != nil {
+		return nil, err
+	}
+	commoditiesBought = append(commoditiesBought, resourceCommoditiesBought...)
+
+	// Application commodity
+	applicationCommBought, err := sdkbuilder.NewCommodityDTOBuilder(proto.CommodityDTO_APPLICATION).
+		Key(string(pod.UID)).
+		Create()
+	if err != nil {
+		return nil, err
+	}
+	commoditiesBought = append(commoditiesBought, applicationCommBought)
+
+	return commoditiesBought, nil
+}
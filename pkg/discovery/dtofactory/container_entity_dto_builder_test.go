@@ -0,0 +1,194 @@
+package dtofactory
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	api "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/turbonomic/kubeturbo/pkg/discovery/metrics"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/task"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/util"
+
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+)
+
+const testCPUFrequency = 2000.0
+
+func newTestPod(containerNames ...string) *api.Pod {
+	pod := &api.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: types.UID("pod-uid")},
+		Spec:       api.PodSpec{NodeName: "node-1"},
+	}
+	for _, name := range containerNames {
+		pod.Spec.Containers = append(pod.Spec.Containers, api.Container{Name: name})
+	}
+	return pod
+}
+
+// Build a sink with the cpu frequency of the node of the pod, and the used, capacity and reservation of the cpu and
+// memory of each container.
+func newTestContainerSink(pod *api.Pod) *metrics.EntityMetricSink {
+	sink := metrics.NewEntityMetricSink()
+	sink.AddNewMetricEntries(metrics.NewEntityStateMetric(task.NodeType, pod.Spec.NodeName, metrics.CpuFrequency,
+		testCPUFrequency))
+	for _, container := range pod.Spec.Containers {
+		key := util.ContainerKeyFunc(pod, container.Name)
+		sink.AddNewMetricEntries(
+			metrics.NewEntityResourceMetric(task.ContainerType, key, metrics.CPU, metrics.Used, 0.5),
+			metrics.NewEntityResourceMetric(task.ContainerType, key, metrics.CPU, metrics.Capacity, 2),
+			metrics.NewEntityResourceMetric(task.ContainerType, key, metrics.CPU, metrics.Reservation, 1),
+			metrics.NewEntityResourceMetric(task.ContainerType, key, metrics.Memory, metrics.Used, 1024),
+			metrics.NewEntityResourceMetric(task.ContainerType, key, metrics.Memory, metrics.Capacity, 4096),
+		)
+	}
+	return sink
+}
+
+func findCommodity(commodities []*proto.CommodityDTO, cType proto.CommodityDTO_CommodityType) *proto.CommodityDTO {
+	for _, commodity := range commodities {
+		if commodity.GetCommodityType() == cType {
+			return commodity
+		}
+	}
+	return nil
+}
+
+func TestContainerEntityDTOBuilder(t *testing.T) {
+	pod := newTestPod("app", "sidecar")
+	builder := NewContainerEntityDTOBuilder(newTestContainerSink(pod))
+	entityDTOs, err := builder.BuildEntityDTOs([]*api.Pod{pod})
+	if err != nil {
+		t.Fatalf("Failed to build container entityDTOs: %s", err)
+	}
+	if len(entityDTOs) != 2 {
+		t.Fatalf("Expected 2 container entityDTOs, got %d", len(entityDTOs))
+	}
+
+	for i, entityDTO := range entityDTOs {
+		if expected := util.ContainerIDFunc(pod, i); entityDTO.GetId() != expected {
+			t.Errorf("Container %d: expected ID %s, got %s", i, expected, entityDTO.GetId())
+		}
+		if entityDTO.GetEntityType() != proto.EntityDTO_CONTAINER {
+			t.Errorf("Container %d: expected entity type %s, got %s", i, proto.EntityDTO_CONTAINER,
+				entityDTO.GetEntityType())
+		}
+
+		sold := entityDTO.GetCommoditiesSold()
+		vCPU := findCommodity(sold, proto.CommodityDTO_VCPU)
+		if vCPU == nil {
+			t.Fatalf("Container %d: no vCPU sold", i)
+		}
+		if vCPU.GetUsed() != 0.5*testCPUFrequency || vCPU.GetCapacity() != 2*testCPUFrequency ||
+			vCPU.GetReservation() != testCPUFrequency {
+			t.Errorf("Container %d: vCPU used %f, capacity %f and reservation %f are not converted to MHz", i,
+				vCPU.GetUsed(), vCPU.GetCapacity(), vCPU.GetReservation())
+		}
+		vMem := findCommodity(sold, proto.CommodityDTO_VMEM)
+		if vMem == nil || vMem.GetUsed() != 1024 || vMem.GetCapacity() != 4096 {
+			t.Errorf("Container %d: unexpected vMem sold %+v", i, vMem)
+		}
+		for _, commodity := range []*proto.CommodityDTO{vCPU, vMem} {
+			if commodity != nil && commodity.GetResizable() {
+				t.Errorf("Container %d: %s is resizable", i, commodity.GetCommodityType())
+			}
+		}
+		application := findCommodity(sold, proto.CommodityDTO_APPLICATION)
+		if application == nil || application.GetKey() != entityDTO.GetId() {
+			t.Errorf("Container %d: expected application commodity sold with key %s, got %+v", i,
+				entityDTO.GetId(), application)
+		}
+
+		bought := entityDTO.GetCommoditiesBought()
+		if len(bought) != 1 || bought[0].GetProviderId() != string(pod.UID) {
+			t.Fatalf("Container %d: expected to buy from pod %s only, got %+v", i, pod.UID, bought)
+		}
+		application = findCommodity(bought[0].GetBought(), proto.CommodityDTO_APPLICATION)
+		if application == nil || application.GetKey() != string(pod.UID) {
+			t.Errorf("Container %d: expected application commodity bought with key %s, got %+v", i, pod.UID,
+				application)
+		}
+	}
+	if errs := builder.GetDiscoveryErrors(); len(errs) != 0 {
+		t.Errorf("Expected no discovery error, got %v", errs)
+	}
+}
+
+func TestContainerEntityDTOBuilderWithoutCPUFrequency(t *testing.T) {
+	pod := newTestPod("app", "sidecar")
+	sink := metrics.NewEntityMetricSink()
+	builder := NewContainerEntityDTOBuilder(sink)
+	entityDTOs, err := builder.BuildEntityDTOs([]*api.Pod{pod})
+	if err != nil {
+		t.Fatalf("Failed to build container entityDTOs: %s", err)
+	}
+	if len(entityDTOs) != 0 {
+		t.Errorf("Expected no container entityDTO without the cpu frequency of the node, got %d",
+			len(entityDTOs))
+	}
+	if errs := builder.GetDiscoveryErrors(); len(errs) != 2 {
+		t.Errorf("Expected a discovery error for each container, got %v", errs)
+	}
+}
+
+func TestContainerEntityDTOBuilderPeakAndPercentile(t *testing.T) {
+	pod := newTestPod("app", "sidecar")
+	sink := newTestContainerSink(pod)
+	// Only the usage of the first container has been sampled.
+	key := util.ContainerKeyFunc(pod, "app")
+	sink.AddNewMetricEntries(
+		metrics.NewEntityResourceMetric(task.ContainerType, key, metrics.CPU, metrics.Peak, 1.5),
+		metrics.NewEntityResourceMetric(task.ContainerType, key, metrics.CPU, metrics.Percentile, 1.2),
+		metrics.NewEntityResourceMetric(task.ContainerType, key, metrics.Memory, metrics.Peak, 2048),
+		metrics.NewEntityResourceMetric(task.ContainerType, key, metrics.Memory, metrics.Percentile, 1536))
+	entityDTOs, err := NewContainerEntityDTOBuilder(sink).BuildEntityDTOs([]*api.Pod{pod})
+	if err != nil {
+		t.Fatalf("Failed to build container entityDTOs: %s", err)
+	}
+	if len(entityDTOs) != 2 {
+		t.Fatalf("Expected 2 container entityDTOs, got %d", len(entityDTOs))
+	}
+
+	table := []struct {
+		container          int
+		cType              proto.CommodityDTO_CommodityType
+		expectedPeak       *float64
+		expectedPercentile []string
+	}{
+		// The peak and percentile cpu are converted to MHz.
+		{0, proto.CommodityDTO_VCPU, floatPtr(1.5 * testCPUFrequency), []string{"2400"}},
+		{0, proto.CommodityDTO_VMEM, floatPtr(2048), []string{"1536"}},
+		{1, proto.CommodityDTO_VCPU, nil, nil},
+		{1, proto.CommodityDTO_VMEM, nil, nil},
+	}
+	for i, item := range table {
+		commodity := findCommodity(entityDTOs[item.container].GetCommoditiesSold(), item.cType)
+		if commodity == nil {
+			t.Errorf("Test case %d failed: no %s sold", i, item.cType)
+			continue
+		}
+		if item.expectedPeak == nil {
+			if commodity.Peak != nil {
+				t.Errorf("Test case %d failed: expected no peak, got %f", i, commodity.GetPeak())
+			}
+		} else if commodity.Peak == nil || commodity.GetPeak() != *item.expectedPeak {
+			t.Errorf("Test case %d failed: expected peak %f, got %v", i, *item.expectedPeak, commodity.Peak)
+		}
+		var percentile []string
+		for _, prop := range commodity.GetPropMap() {
+			if prop.GetName() == usedPercentilePropertyName {
+				percentile = prop.GetValues()
+			}
+		}
+		if !reflect.DeepEqual(percentile, item.expectedPercentile) {
+			t.Errorf("Test case %d failed: expected percentile property %v, got %v", i, item.expectedPercentile,
+				percentile)
+		}
+	}
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
@@ -1,6 +1,8 @@
 package dtofactory
 
 import (
+	"strconv"
+
 	"github.com/turbonomic/kubeturbo/pkg/discovery/metrics"
 	"github.com/turbonomic/kubeturbo/pkg/discovery/task"
 	sdkbuilder "github.com/turbonomic/turbo-go-sdk/pkg/builder"
@@ -19,9 +21,25 @@ var (
 		metrics.CPUProvisioned:    proto.CommodityDTO_CPU_PROVISIONED,
 		metrics.MemoryProvisioned: proto.CommodityDTO_MEM_PROVISIONED,
 		metrics.Transaction:       proto.CommodityDTO_TRANSACTION,
+		metrics.EphemeralStorage:  proto.CommodityDTO_VSTORAGE,
+		metrics.ImageStorage:      proto.CommodityDTO_VSTORAGE,
+		metrics.VolumeStorage:     proto.CommodityDTO_STORAGE_AMOUNT,
+		metrics.NetThroughput:     proto.CommodityDTO_NET_THROUGHPUT,
 	}
 )
 
+const (
+	// Name of the commodity property which holds the percentile of the sampled used values.
+	usedPercentilePropertyName = "UsedPercentile"
+
+	// Source of the discovery errors of the entityDTO builders.
+	entityDTOBuilderSource = "EntityDTOBuilder"
+
+	// Keys of the vStorage commodities, as a node sells one for the root filesystem and one for the image filesystem.
+	ephemeralStorageCommodityKey = "ephemeral-storage"
+	imageStorageCommodityKey     = "image-storage"
+)
+
 type ValueConversionFunc func(input float64) float64
 
 type converter struct {
@@ -76,6 +94,15 @@ func (s *attributeSetter) Add(setFunc commodityAttrSetter, rTypes ...metrics.Res
 	return s
 }
 
+// Set the keys of the vStorage commodities.
+func (s *attributeSetter) AddStorageKeys() *attributeSetter {
+	s.Add(func(commBuilder *sdkbuilder.CommodityDTOBuilder) { commBuilder.Key(ephemeralStorageCommodityKey) },
+		metrics.EphemeralStorage)
+	s.Add(func(commBuilder *sdkbuilder.CommodityDTOBuilder) { commBuilder.Key(imageStorageCommodityKey) },
+		metrics.ImageStorage)
+	return s
+}
+
 func (s *attributeSetter) Settable(rType metrics.ResourceType) bool {
 	_, exist := s.attrSetterFunc[rType]
 	return exist
@@ -89,14 +116,28 @@ func (s *attributeSetter) Set(rType metrics.ResourceType, commBuilder *sdkbuilde
 
 type generalBuilder struct {
 	metricsSink *metrics.EntityMetricSink
+
+	// the entities whose entityDTOs cannot be built.
+	discoveryErrors *task.DiscoveryErrors
 }
 
 func newGeneralBuilder(sink *metrics.EntityMetricSink) generalBuilder {
 	return generalBuilder{
-		metricsSink: sink,
+		metricsSink:     sink,
+		discoveryErrors: task.NewDiscoveryErrors(),
 	}
 }
 
+// Get the errors of the entities whose entityDTOs cannot be built.
+func (builder generalBuilder) GetDiscoveryErrors() []*task.DiscoveryError {
+	return builder.discoveryErrors.List()
+}
+
+// Record that the entityDTO of the given entity cannot be built.
+func (builder generalBuilder) addDiscoveryError(eType task.DiscoveredEntityType, name, uid string, err error) {
+	builder.discoveryErrors.Add(task.NewDiscoveryError(entityDTOBuilderSource, err).WithEntity(eType, name, uid))
+}
+
 // TODO cpuFrequency is passed in as a parameter. We need special handling for cpu related metric as the value collected by Kubernetes is in number of cores. We need to convert it to MHz.
 func (builder generalBuilder) getResourceCommoditiesSold(entityType task.DiscoveredEntityType, entityID string,
 	resourceTypesList []metrics.ResourceType, converter *converter, commodityAttrSetter *attributeSetter) ([]*proto.CommodityDTO, error) {
@@ -151,6 +192,7 @@ func (builder generalBuilder) getResourceCommoditiesSold(entityType task.Discove
 			glog.Errorf("Failed to build commodity sold: %s", err)
 			continue
 		}
+		builder.setPeakAndPercentile(commSold, entityType, entityID, rType, converter)
 		resourceCommoditiesSold = append(resourceCommoditiesSold, commSold)
 	}
 	return resourceCommoditiesSold, nil
@@ -207,7 +249,36 @@ func (builder generalBuilder) getResourceCommoditiesBought(entityType task.Disco
 			glog.Errorf("Failed to build commodity bought: %s", err)
 			continue
 		}
+		builder.setPeakAndPercentile(commSold, entityType, entityID, rType, converter)
 		resourceCommoditiesSold = append(resourceCommoditiesSold, commSold)
 	}
 	return resourceCommoditiesSold, nil
 }
+
+// Set the peak and the percentile of the used value of a commodity, if the usage has been sampled.
+// The commodity builder does not expose peak and properties, so they are set on the built commodity.
+func (builder generalBuilder) setPeakAndPercentile(commDTO *proto.CommodityDTO, entityType task.DiscoveredEntityType,
+	entityID string, rType metrics.ResourceType, converter *converter) {
+	getValue := func(mProp metrics.MetricProp) (float64, bool) {
+		m, err := builder.metricsSink.GetMetric(metrics.GenerateEntityResourceMetricUID(entityType, entityID, rType, mProp))
+		if err != nil {
+			return 0, false
+		}
+		value := m.GetValue().(float64)
+		if converter != nil && converter.Convertible(rType) {
+			value = converter.Convert(rType, value)
+		}
+		return value, true
+	}
+
+	if peak, exist := getValue(metrics.Peak); exist {
+		commDTO.Peak = &peak
+	}
+	if percentile, exist := getValue(metrics.Percentile); exist {
+		name := usedPercentilePropertyName
+		commDTO.PropMap = append(commDTO.PropMap, &proto.CommodityDTO_PropertiesList{
+			Name:   &name,
+			Values: []string{strconv.FormatFloat(percentile, 'f', -1, 64)},
+		})
+	}
+}
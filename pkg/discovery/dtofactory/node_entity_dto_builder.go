@@ -28,6 +28,9 @@ var (
 	nodeResourceCommoditiesSold = []metrics.ResourceType{
 		metrics.CPU,
 		metrics.Memory,
+		metrics.EphemeralStorage,
+		metrics.ImageStorage,
+		metrics.NetThroughput,
 		//metrics.CPUProvisioned,
 		//metrics.MemoryProvisioned,
 	}
@@ -62,6 +65,7 @@ func (builder *nodeEntityDTOBuilder) BuildEntityDTOs(nodes []*api.Node) ([]*prot
 		commoditiesSold, err := builder.getNodeCommoditiesSold(node)
 		if err != nil {
 			glog.Errorf("Error when create commoditiesSold for %s: %s", node.Name, err)
+			builder.addDiscoveryError(task.NodeType, displayName, nodeID, fmt.Errorf("failed to create commodities sold: %s", err))
 			continue
 		}
 		entityDTOBuilder.SellsCommodities(commoditiesSold)
@@ -70,6 +74,7 @@ func (builder *nodeEntityDTOBuilder) BuildEntityDTOs(nodes []*api.Node) ([]*prot
 		properties, err := builder.getNodeProperties(node)
 		if err != nil {
 			glog.Errorf("Failed to get node properties: %s", err)
+			builder.addDiscoveryError(task.NodeType, displayName, nodeID, fmt.Errorf("failed to get properties: %s", err))
 			continue
 		}
 		entityDTOBuilder = entityDTOBuilder.WithProperties(properties)
@@ -78,6 +83,7 @@ func (builder *nodeEntityDTOBuilder) BuildEntityDTOs(nodes []*api.Node) ([]*prot
 		metaData, err := builder.stitchingManager.GenerateReconciliationMetaData()
 		if err != nil {
 			glog.Errorf("Failed to build reconciling metadata for node %s: %s", displayName, err)
+			builder.addDiscoveryError(task.NodeType, displayName, nodeID, fmt.Errorf("failed to build reconciling metadata: %s", err))
 			continue
 		}
 		entityDTOBuilder = entityDTOBuilder.ReplacedBy(metaData)
@@ -89,6 +95,7 @@ func (builder *nodeEntityDTOBuilder) BuildEntityDTOs(nodes []*api.Node) ([]*prot
 		entityDto, err := entityDTOBuilder.Create()
 		if err != nil {
 			glog.Errorf("Failed to build VM entityDTO: %s", err)
+			builder.addDiscoveryError(task.NodeType, displayName, nodeID, fmt.Errorf("failed to build entityDTO: %s", err))
 			continue
 		}
 
@@ -99,7 +106,7 @@ func (builder *nodeEntityDTOBuilder) BuildEntityDTOs(nodes []*api.Node) ([]*prot
 }
 
 // Build the sold commodityDTO by each node. They are include:
-// VCPU, VMem, CPUProvisioned, MemProvisioned;
+// VCPU, VMem, CPUProvisioned, MemProvisioned, VStorage, NetThroughput;
 // VMPMAccessCommodity, ApplicationCommodity, ClusterCommodity.
 func (builder *nodeEntityDTOBuilder) getNodeCommoditiesSold(node *api.Node) ([]*proto.CommodityDTO, error) {
 	var commoditiesSold []*proto.CommodityDTO
@@ -121,8 +128,11 @@ func (builder *nodeEntityDTOBuilder) getNodeCommoditiesSold(node *api.Node) ([]*
 		},
 		metrics.CPU, metrics.CPUProvisioned)
 
+	// attr
+	attributeSetter := NewCommodityAttrSetter().AddStorageKeys()
+
 	// Resource Commodities
-	resourceCommoditiesSold, err := builder.getResourceCommoditiesSold(task.NodeType, key, nodeResourceCommoditiesSold, converter, nil)
+	resourceCommoditiesSold, err := builder.getResourceCommoditiesSold(task.NodeType, key, nodeResourceCommoditiesSold, converter, attributeSetter)
 	if err != nil {
 		return nil, err
 	}
@@ -25,11 +25,14 @@ var (
 	podResourceCommoditySold = []metrics.ResourceType{
 		metrics.CPU,
 		metrics.Memory,
+		metrics.EphemeralStorage,
 	}
 
 	podResourceCommodityBought = []metrics.ResourceType{
 		metrics.CPU,
 		metrics.Memory,
+		metrics.EphemeralStorage,
+		metrics.NetThroughput,
 		// TODO, add back provisioned commodity later.
 		//metrics.CPUProvisioned,
 		//metrics.MemoryProvisioned,
@@ -67,6 +70,7 @@ func (builder *podEntityDTOBuilder) BuildEntityDTOs(pods []*api.Pod) ([]*proto.E
 		commoditiesSold, err := builder.getPodCommoditiesSold(pod)
 		if err != nil {
 			glog.Errorf("Error when create commoditiesSold for pod %s: %s", displayName, err)
+			builder.addDiscoveryError(task.PodType, displayName, podID, fmt.Errorf("failed to create commodities sold: %s", err))
 			continue
 		}
 		entityDTOBuilder.SellsCommodities(commoditiesSold)
@@ -75,12 +79,14 @@ func (builder *podEntityDTOBuilder) BuildEntityDTOs(pods []*api.Pod) ([]*proto.E
 		commoditiesBought, err := builder.getPodCommoditiesBought(pod)
 		if err != nil {
 			glog.Errorf("Error when create commoditiesBought for pod %s: %s", displayName, err)
+			builder.addDiscoveryError(task.PodType, displayName, podID, fmt.Errorf("failed to create commodities bought: %s", err))
 			continue
 		}
 		providerNodeUID, exist := builder.nodeNameUIDMap[pod.Spec.NodeName]
 		if !exist {
 			glog.Errorf("Error when create commoditiesBought for pod %s: Cannot find uuid for provider "+
-				"node.", displayName, err)
+				"node %s.", displayName, pod.Spec.NodeName)
+			builder.addDiscoveryError(task.PodType, displayName, podID, fmt.Errorf("provider node %s is not found", pod.Spec.NodeName))
 			continue
 		}
 		provider := sdkbuilder.CreateProvider(proto.EntityDTO_VIRTUAL_MACHINE, providerNodeUID)
@@ -91,6 +97,7 @@ func (builder *podEntityDTOBuilder) BuildEntityDTOs(pods []*api.Pod) ([]*proto.E
 		properties, err := builder.getPodProperties(pod)
 		if err != nil {
 			glog.Errorf("Failed to get required pod properties: %s", err)
+			builder.addDiscoveryError(task.PodType, displayName, podID, fmt.Errorf("failed to get properties: %s", err))
 			continue
 		}
 		entityDTOBuilder = entityDTOBuilder.WithProperties(properties)
@@ -103,6 +110,7 @@ func (builder *podEntityDTOBuilder) BuildEntityDTOs(pods []*api.Pod) ([]*proto.E
 		entityDto, err := entityDTOBuilder.Create()
 		if err != nil {
 			glog.Errorf("Failed to build Pod entityDTO: %s", err)
+			builder.addDiscoveryError(task.PodType, displayName, podID, fmt.Errorf("failed to build entityDTO: %s", err))
 			continue
 		}
 
@@ -113,7 +121,7 @@ func (builder *podEntityDTOBuilder) BuildEntityDTOs(pods []*api.Pod) ([]*proto.E
 }
 
 // Build the sold commodityDTO by each pod. They are:
-// vCPU, vMem, ApplicationCommodity.
+// vCPU, vMem, vStorage, StorageAmount of each persistent volume, ApplicationCommodity.
 func (builder *podEntityDTOBuilder) getPodCommoditiesSold(pod *api.Pod) ([]*proto.CommodityDTO, error) {
 	var commoditiesSold []*proto.CommodityDTO
 	key := util.PodKeyFunc(pod)
@@ -133,6 +141,7 @@ func (builder *podEntityDTOBuilder) getPodCommoditiesSold(pod *api.Pod) ([]*prot
 	// attr
 	attributeSetter := NewCommodityAttrSetter()
 	attributeSetter.Add(func(commBuilder *sdkbuilder.CommodityDTOBuilder) { commBuilder.Resizable(true) }, metrics.CPU, metrics.Memory)
+	attributeSetter.AddStorageKeys()
 
 	// Resource Commodities
 	resourceCommoditiesSold, err := builder.getResourceCommoditiesSold(task.PodType, key, podResourceCommoditySold, converter, attributeSetter)
@@ -141,6 +150,22 @@ func (builder *podEntityDTOBuilder) getPodCommoditiesSold(pod *api.Pod) ([]*prot
 	}
 	commoditiesSold = append(commoditiesSold, resourceCommoditiesSold...)
 
+	// Storage amount commodities of the persistent volumes, keyed by the claims.
+	for _, volume := range pod.Spec.Volumes {
+		if volume.PersistentVolumeClaim == nil {
+			continue
+		}
+		claimKey := pod.Namespace + "/" + volume.PersistentVolumeClaim.ClaimName
+		volumeAttrSetter := NewCommodityAttrSetter()
+		volumeAttrSetter.Add(func(commBuilder *sdkbuilder.CommodityDTOBuilder) { commBuilder.Key(claimKey) }, metrics.VolumeStorage)
+		volumeCommoditiesSold, err := builder.getResourceCommoditiesSold(task.PodType, util.PodVolumeKeyFunc(pod, volume.Name),
+			[]metrics.ResourceType{metrics.VolumeStorage}, nil, volumeAttrSetter)
+		if err != nil {
+			return nil, err
+		}
+		commoditiesSold = append(commoditiesSold, volumeCommoditiesSold...)
+	}
+
 	// Application commodity
 	applicationComm, err := sdkbuilder.NewCommodityDTOBuilder(proto.CommodityDTO_APPLICATION).
 		Key(string(pod.UID)).
@@ -155,7 +180,7 @@ func (builder *podEntityDTOBuilder) getPodCommoditiesSold(pod *api.Pod) ([]*prot
 }
 
 // Build the bought commodityDTO by each pod. They are:
-// vCPU, vMem, cpuProvisioned, memProvisioned, access, cluster.
+// vCPU, vMem, vStorage, netThroughput, cpuProvisioned, memProvisioned, access, cluster.
 func (builder *podEntityDTOBuilder) getPodCommoditiesBought(pod *api.Pod) ([]*proto.CommodityDTO, error) {
 	var commoditiesBought []*proto.CommodityDTO
 	key := util.PodKeyFunc(pod)
@@ -173,6 +198,7 @@ func (builder *podEntityDTOBuilder) getPodCommoditiesBought(pod *api.Pod) ([]*pr
 	// attr
 	attributeSetter := NewCommodityAttrSetter()
 	attributeSetter.Add(func(commBuilder *sdkbuilder.CommodityDTOBuilder) { commBuilder.Resizable(true) }, metrics.CPU, metrics.Memory)
+	attributeSetter.AddStorageKeys()
 
 	// Resource Commodities.
 	resourceCommoditiesBought, err := builder.getResourceCommoditiesBought(task.PodType, key, podResourceCommodityBought, converter, attributeSetter)
@@ -0,0 +1,27 @@
+package property
+
+import (
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+)
+
+const (
+	containerPropertyNameContainerName = "Kubernetes-Container-Name"
+)
+
+// Build properties of a container. The namespace and name of the hosting pod, and the name of the container in
+// the pod spec are stored in the properties.
+func BuildContainerProperties(podNamespace, podName, containerName string) []*proto.EntityDTO_EntityProperty {
+	properties := BuildAppProperties(podNamespace, podName)
+
+	propertyNamespace := appPropertyNamespace
+	containerNamePropertyName := containerPropertyNameContainerName
+	containerNamePropertyValue := containerName
+	nameProperty := &proto.EntityDTO_EntityProperty{
+		Namespace: &propertyNamespace,
+		Name:      &containerNamePropertyName,
+		Value:     &containerNamePropertyValue,
+	}
+	properties = append(properties, nameProperty)
+
+	return properties
+}
@@ -0,0 +1,46 @@
+package discovery
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+)
+
+// WriteEntityTable writes the entities as a human readable table, each followed by the commodities it sells and
+// buys.
+func WriteEntityTable(w io.Writer, entityDTOs []*proto.EntityDTO) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "ENTITY TYPE\tDISPLAY NAME\tID\tCOMMODITY\tKEY\tCAPACITY\tUSED\tPROVIDER")
+	for _, entityDTO := range entityDTOs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t\t\t\t\t\n", entityDTO.GetEntityType(), entityDTO.GetDisplayName(),
+			entityDTO.GetId())
+		for _, commodity := range entityDTO.GetCommoditiesSold() {
+			writeCommodityRow(tw, commodity, "-")
+		}
+		for _, commodityBought := range entityDTO.GetCommoditiesBought() {
+			for _, commodity := range commodityBought.GetBought() {
+				writeCommodityRow(tw, commodity, commodityBought.GetProviderId())
+			}
+		}
+	}
+	fmt.Fprintf(tw, "Total: %d entities\n", len(entityDTOs))
+	return tw.Flush()
+}
+
+func writeCommodityRow(w io.Writer, commodity *proto.CommodityDTO, provider string) {
+	capacity := "-"
+	if commodity.Capacity != nil {
+		capacity = fmt.Sprintf("%g", commodity.GetCapacity())
+	}
+	used := "-"
+	if commodity.Used != nil {
+		used = fmt.Sprintf("%g", commodity.GetUsed())
+	}
+	key := commodity.GetKey()
+	if key == "" {
+		key = "-"
+	}
+	fmt.Fprintf(w, "\t\t\t%s\t%s\t%s\t%s\t%s\n", commodity.GetCommodityType(), key, capacity, used, provider)
+}
@@ -0,0 +1,54 @@
+package discovery
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/turbonomic/turbo-go-sdk/pkg/builder"
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+)
+
+func TestWriteEntityTable(t *testing.T) {
+	vcpu, err := builder.NewCommodityDTOBuilder(proto.CommodityDTO_VCPU).Capacity(4000).Used(1000).Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cluster, err := builder.NewCommodityDTOBuilder(proto.CommodityDTO_CLUSTER).Key("cluster-1").Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod, err := builder.NewEntityDTOBuilder(proto.EntityDTO_CONTAINER_POD, "pod-1").
+		DisplayName("default/nginx-1").
+		SellsCommodities([]*proto.CommodityDTO{vcpu}).
+		Provider(builder.CreateProvider(proto.EntityDTO_VIRTUAL_MACHINE, "node-1")).
+		BuysCommodities([]*proto.CommodityDTO{cluster}).
+		Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteEntityTable(&buf, []*proto.EntityDTO{pod}); err != nil {
+		t.Fatal(err)
+	}
+	var rows [][]string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		rows = append(rows, strings.Fields(line))
+	}
+	expectedRows := [][]string{
+		{"ENTITY", "TYPE", "DISPLAY", "NAME", "ID", "COMMODITY", "KEY", "CAPACITY", "USED", "PROVIDER"},
+		{"CONTAINER_POD", "default/nginx-1", "pod-1"},
+		{"VCPU", "-", "4000", "1000", "-"},
+		{"CLUSTER", "cluster-1", "-", "-", "node-1"},
+		{"Total:", "1", "entities"},
+	}
+	if len(rows) != len(expectedRows) {
+		t.Fatalf("Expected %d rows, got:\n%s", len(expectedRows), buf.String())
+	}
+	for i := range rows {
+		if strings.Join(rows[i], " ") != strings.Join(expectedRows[i], " ") {
+			t.Errorf("Row %d: expected %v, got %v", i, expectedRows[i], rows[i])
+		}
+	}
+}
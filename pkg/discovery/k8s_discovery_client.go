@@ -1,16 +1,21 @@
 package discovery
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
 
 	kubeClient "k8s.io/client-go/kubernetes"
+	api "k8s.io/client-go/pkg/api/v1"
 
 	"github.com/turbonomic/kubeturbo/pkg/cluster"
 	"github.com/turbonomic/kubeturbo/pkg/discovery/configs"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/sampling"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/task"
 	"github.com/turbonomic/kubeturbo/pkg/discovery/worker"
 	"github.com/turbonomic/kubeturbo/pkg/discovery/worker/compliance"
+	"github.com/turbonomic/kubeturbo/pkg/metrics"
 	"github.com/turbonomic/kubeturbo/pkg/registration"
 
 	sdkprobe "github.com/turbonomic/turbo-go-sdk/pkg/probe"
@@ -20,8 +25,17 @@ import (
 )
 
 const (
-	// TODO make this number programmatically.
-	workerCount int = 4
+	// The bounds of the number of discovery workers derived from the size of the cluster.
+	minWorkerCount int = 4
+	maxWorkerCount int = 32
+	// The number of nodes a derived discovery worker is expected to discover.
+	nodesPerWorker int = 20
+	// The max time to wait for the nodes to derive the number of discovery workers from.
+	nodeCountTimeout = time.Second * 30
+
+	// Sources of the discovery errors of the discovery phases after the discovery workers.
+	affinityProcessorSource = "AffinityProcessor"
+	serviceDiscoverySource  = "ServiceDiscovery"
 )
 
 type DiscoveryClientConfig struct {
@@ -30,13 +44,23 @@ type DiscoveryClientConfig struct {
 	probeConfig *configs.ProbeConfig
 
 	targetConfig *configs.K8sTargetConfig
+
+	// Close this to stop the local cluster cache.
+	StopEverything chan struct{}
 }
 
 func NewDiscoveryConfig(kubeClient *kubeClient.Clientset, probeConfig *configs.ProbeConfig, targetConfig *configs.K8sTargetConfig) *DiscoveryClientConfig {
+	// The target config has been validated, so the scope is valid.
+	scope, err := targetConfig.DiscoveryScope()
+	if err != nil {
+		glog.Errorf("Invalid discovery scope, discover the whole cluster: %s", err)
+	}
+	glog.V(2).Infof("Discovery scope: %s", scope)
 	return &DiscoveryClientConfig{
-		k8sClusterScraper: &cluster.ClusterScraper{kubeClient},
+		k8sClusterScraper: cluster.NewClusterScraper(kubeClient).WithDiscoveryScope(scope),
 		probeConfig:       probeConfig,
 		targetConfig:      targetConfig,
+		StopEverything:    make(chan struct{}),
 	}
 }
 
@@ -46,23 +70,133 @@ type K8sDiscoveryClient struct {
 	dispatcher      *worker.Dispatcher
 	resultCollector *worker.ResultCollector
 
+	validator *targetValidator
+
+	// discovers in the background if asynchronous discovery is enabled, otherwise nil.
+	asyncDiscoverer *asyncDiscoverer
+
+	// writes every discovery response to disk if snapshots are enabled, otherwise nil.
+	snapshotRecorder *snapshotRecorder
+
+	lastDiscovery discoveryStatusHolder
+
 	wg sync.WaitGroup
 }
 
-func NewK8sDiscoveryClient(config *DiscoveryClientConfig) *K8sDiscoveryClient {
+func NewK8sDiscoveryClient(config *DiscoveryClientConfig) (*K8sDiscoveryClient, error) {
+	// The cluster is not accessed when a snapshot is replayed.
+	if config.probeConfig.ReplaySnapshot != "" {
+		glog.V(2).Infof("Replay discovery snapshot %s instead of discovering the cluster.",
+			config.probeConfig.ReplaySnapshot)
+		return &K8sDiscoveryClient{config: config}, nil
+	}
+
+	workerCount := getWorkerCount(config)
+
 	// make maxWorkerCount of result collector twice the worker count.
 	resultCollector := worker.NewResultCollector(workerCount * 2)
 
-	dispatcherConfig := worker.NewDispatcherConfig(config.k8sClusterScraper, config.probeConfig, workerCount)
+	dispatcherConfig := worker.NewDispatcherConfig(config.probeConfig, workerCount)
+
+	// Sample resource usage in the background, so that discovery can report the peak and percentile of the usage.
+	var sampler *sampling.MetricSampler
+	if config.probeConfig.SamplingInterval > 0 {
+		samplerConfig := sampling.NewMetricSamplerConfig(config.k8sClusterScraper, config.probeConfig.MonitoringConfigs,
+			config.probeConfig.SamplingInterval).WithWindow(config.probeConfig.SamplingWindow)
+		var err error
+		sampler, err = sampling.NewMetricSampler(samplerConfig)
+		if err != nil {
+			glog.Errorf("Peak and percentile of usage will not be reported: %s", err)
+		} else {
+			dispatcherConfig.WithMetricHistory(sampler.GetHistory())
+		}
+	}
+
 	dispatcher := worker.NewDispatcher(dispatcherConfig)
-	dispatcher.Init(resultCollector)
+	if err := dispatcher.Init(resultCollector); err != nil {
+		return nil, err
+	}
+	if sampler != nil {
+		go sampler.Run(config.StopEverything)
+	}
+
+	// Populate the local cluster cache in the background. Before it is synced, discovery reads from the API server.
+	go func() {
+		if err := config.k8sClusterScraper.Start(config.StopEverything); err != nil {
+			glog.Errorf("Cluster objects will be fetched from API server directly until their cache is synced: %s", err)
+		}
+	}()
 
 	dc := &K8sDiscoveryClient{
 		config:          config,
 		dispatcher:      dispatcher,
 		resultCollector: resultCollector,
+		validator:       newTargetValidator(config.k8sClusterScraper, config.probeConfig.MonitoringConfigs),
 	}
-	return dc
+
+	if config.probeConfig.SnapshotDir != "" {
+		recorder, err := newSnapshotRecorder(config.probeConfig.SnapshotDir, config.probeConfig.SnapshotFormat,
+			config.targetConfig.TargetIdentifier, config.probeConfig.MaxSnapshots, config.probeConfig.MaxSnapshotBytes)
+		if err != nil {
+			glog.Errorf("Discovery snapshots will not be written: %s", err)
+		} else {
+			dc.snapshotRecorder = recorder
+		}
+	}
+
+	// Discover in the background, so that a discovery request is answered with the freshest result immediately.
+	if config.probeConfig.AsyncDiscoveryInterval > 0 {
+		dc.asyncDiscoverer = newAsyncDiscoverer(config.probeConfig.AsyncDiscoveryInterval, dc.discover)
+		go dc.asyncDiscoverer.Run(config.StopEverything)
+	}
+	return dc, nil
+}
+
+// Get the number of discovery workers. Unless it is configured, it is derived from the number of nodes in the cluster.
+func getWorkerCount(config *DiscoveryClientConfig) int {
+	if config.probeConfig.DiscoveryWorkerCount > 0 {
+		return config.probeConfig.DiscoveryWorkerCount
+	}
+	nodeCount, err := countNodes(config.k8sClusterScraper.GetAllNodes, nodeCountTimeout)
+	if err != nil {
+		glog.Errorf("Failed to get the number of nodes, use %d discovery workers: %s", minWorkerCount, err)
+		return minWorkerCount
+	}
+	workerCount := deriveWorkerCount(nodeCount)
+	glog.V(2).Infof("Use %d discovery workers for %d nodes.", workerCount, nodeCount)
+	return workerCount
+}
+
+// Count the nodes got by the given function, unless it does not return within the timeout, e.g. the API server of the
+// cluster is not reachable, which would otherwise block the creation of the discovery client.
+func countNodes(getNodes func() ([]*api.Node, error), timeout time.Duration) (int, error) {
+	type result struct {
+		nodes []*api.Node
+		err   error
+	}
+	// Buffered, so that the goroutine does not block if it returns after the timeout.
+	results := make(chan result, 1)
+	go func() {
+		nodes, err := getNodes()
+		results <- result{nodes: nodes, err: err}
+	}()
+	select {
+	case r := <-results:
+		return len(r.nodes), r.err
+	case <-time.After(timeout):
+		return 0, fmt.Errorf("timed out after %s", timeout)
+	}
+}
+
+func deriveWorkerCount(nodeCount int) int {
+	workerCount := (nodeCount + nodesPerWorker - 1) / nodesPerWorker
+	if workerCount < minWorkerCount {
+		return minWorkerCount
+	}
+	if workerCount > maxWorkerCount {
+		return maxWorkerCount
+	}
+	return workerCount
 }
 
 func (dc *K8sDiscoveryClient) GetAccountValues() *sdkprobe.TurboTargetInfo {
@@ -97,23 +231,83 @@ func (dc *K8sDiscoveryClient) GetAccountValues() *sdkprobe.TurboTargetInfo {
 // Validate the Target
 func (dc *K8sDiscoveryClient) Validate(accountValues []*proto.AccountValue) (*proto.ValidationResponse, error) {
 	glog.V(2).Infof("Validating Kubernetes target...")
+	metrics.TurboRequestReceived()
+
+	if dc.config.probeConfig.ReplaySnapshot != "" {
+		glog.V(2).Infof("Kubernetes target is not validated, as a snapshot is replayed.")
+		return &proto.ValidationResponse{}, nil
+	}
 
-	// TODO: connect to the client and get validation response
-	validationResponse := &proto.ValidationResponse{}
+	errorDTOs := dc.validator.Validate()
+	for _, errorDTO := range errorDTOs {
+		glog.Errorf("Validation of Kubernetes target failed: [%s] %s", errorDTO.GetSeverity(), errorDTO.GetDescription())
+	}
+	if len(errorDTOs) == 0 {
+		glog.V(2).Infof("Kubernetes target is valid.")
+	}
+	validationResponse := &proto.ValidationResponse{
+		ErrorDTO: errorDTOs,
+	}
 
 	return validationResponse, nil
 }
 
-// DiscoverTopology receives a discovery request from server and start probing the k8s.
+// DiscoverTopology receives a discovery request from server and start probing the k8s. If asynchronous discovery is
+// enabled, the freshest result of the background discovery is returned instead. If a snapshot is replayed, the
+// recorded response is returned as is.
 func (dc *K8sDiscoveryClient) Discover(accountValues []*proto.AccountValue) (*proto.DiscoveryResponse, error) {
+	metrics.TurboRequestReceived()
+	if dc.config.probeConfig.ReplaySnapshot != "" {
+		return LoadSnapshot(dc.config.probeConfig.ReplaySnapshot)
+	}
+
+	ctx, cancel := dc.newDiscoveryContext()
+	defer cancel()
+	var discoveryResponse *proto.DiscoveryResponse
+	var err error
+	if dc.asyncDiscoverer != nil {
+		discoveryResponse, err = dc.asyncDiscoverer.GetDiscoveryResponse(ctx)
+	} else {
+		discoveryResponse, err = dc.discover(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if dc.snapshotRecorder != nil {
+		path, err := dc.snapshotRecorder.record(discoveryResponse, time.Now())
+		if err != nil {
+			glog.Errorf("Failed to write discovery snapshot: %s", err)
+		} else {
+			glog.V(2).Infof("Discovery snapshot is written to %s.", path)
+		}
+	}
+	return discoveryResponse, nil
+}
+
+// Get the status of the last discovery of the cluster, or nil if the cluster has not been discovered yet. A replayed
+// snapshot is not a discovery.
+func (dc *K8sDiscoveryClient) LastDiscovery() *DiscoveryStatus {
+	return dc.lastDiscovery.get()
+}
+
+// Discover the cluster and build the discovery response.
+func (dc *K8sDiscoveryClient) discover(ctx context.Context) (*proto.DiscoveryResponse, error) {
 	currentTime := time.Now()
-	newDiscoveryResultDTOs, err := dc.discoverWithNewFramework()
+	newDiscoveryResultDTOs, discoveryErrors, err := dc.discoverWithNewFramework(ctx)
+	status := NewDiscoveryStatus(currentTime, newDiscoveryResultDTOs, discoveryErrors, err)
+	dc.lastDiscovery.set(status)
 	if err != nil {
 		glog.Errorf("Failed to use the new framework to discover current Kubernetes cluster: %s", err)
+		return nil, err
 	}
+	target := dc.config.targetConfig.TargetIdentifier
+	metrics.DiscoveryPhaseLatency.WithLabelValues(target, metrics.DiscoveryPhaseTotal).Observe(metrics.SinceInSeconds(currentTime))
+	metrics.SetDiscoveredEntities(target, status.EntityCounts)
 
 	discoveryResponse := &proto.DiscoveryResponse{
 		EntityDTO: newDiscoveryResultDTOs,
+		ErrorDTO:  buildDiscoveryErrorDTOs(discoveryErrors),
 	}
 
 	newFrameworkDiscTime := time.Now().Sub(currentTime).Nanoseconds()
@@ -122,33 +316,77 @@ func (dc *K8sDiscoveryClient) Discover(accountValues []*proto.AccountValue) (*pr
 	return discoveryResponse, nil
 }
 
-func (dc *K8sDiscoveryClient) discoverWithNewFramework() ([]*proto.EntityDTO, error) {
-	nodes, err := dc.config.k8sClusterScraper.GetAllNodes()
+// Get a context for a discovery, which is cancelled once StopEverything is closed.
+func (dc *K8sDiscoveryClient) newDiscoveryContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-dc.config.StopEverything:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// Discover the cluster. The parts of the cluster failed to be discovered are returned as discovery errors, unless too
+// many nodes fail, in which case the whole discovery fails. The discovery fails if ctx is done before the discovery
+// workers finish.
+func (dc *K8sDiscoveryClient) discoverWithNewFramework(ctx context.Context) ([]*proto.EntityDTO, []*task.DiscoveryError, error) {
+	target := dc.config.targetConfig.TargetIdentifier
+	// All the discovery phases work on the same snapshot of the cluster.
+	phaseStart := time.Now()
+	clusterSnapshot, err := dc.config.k8sClusterScraper.TakeSnapshot()
 	if err != nil {
-		return nil, fmt.Errorf("Failed to get all nodes in the cluster: %s", err)
+		return nil, nil, fmt.Errorf("Failed to take a snapshot of the cluster: %s", err)
 	}
+	metrics.DiscoveryPhaseLatency.WithLabelValues(target, metrics.DiscoveryPhaseSnapshot).Observe(metrics.SinceInSeconds(phaseStart))
 
-	workerCount := dc.dispatcher.Dispatch(nodes)
-	entityDTOs := dc.resultCollector.Collect(workerCount)
+	phaseStart = time.Now()
+	workerCount := dc.dispatcher.Dispatch(ctx, clusterSnapshot)
+	entityDTOs, discoveryErrors := dc.resultCollector.Collect(workerCount)
+	if err := ctx.Err(); err != nil {
+		return nil, nil, fmt.Errorf("Discovery is cancelled: %s", err)
+	}
+	metrics.DiscoveryPhaseLatency.WithLabelValues(target, metrics.DiscoveryPhaseWorkers).Observe(metrics.SinceInSeconds(phaseStart))
 	glog.V(3).Infof("Discovery workers have finished discovery work with %d entityDTOs built. Now performing service discovery...", len(entityDTOs))
 
+	err = checkFailedNodes(discoveryErrors, len(clusterSnapshot.Nodes), dc.config.probeConfig.MaxFailedNodeFraction)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// affinity process
-	affinityProcessorConfig := compliance.NewAffinityProcessorConfig(dc.config.k8sClusterScraper)
+	phaseStart = time.Now()
+	affinityProcessorConfig := compliance.NewAffinityProcessorConfig(clusterSnapshot)
 	affinityProcessor, err := compliance.NewAffinityProcessor(affinityProcessorConfig)
 	if err != nil {
 		glog.Errorf("Failed during process affinity rules: %s", err)
+		discoveryErrors = append(discoveryErrors, task.NewDiscoveryError(affinityProcessorSource, err))
 	} else {
 		entityDTOs = affinityProcessor.ProcessAffinityRules(entityDTOs)
 	}
+	metrics.DiscoveryPhaseLatency.WithLabelValues(target, metrics.DiscoveryPhaseAffinity).Observe(metrics.SinceInSeconds(phaseStart))
 
-	svcWorkerConfig := worker.NewK8sServiceDiscoveryWorkerConfig(dc.config.k8sClusterScraper)
+	phaseStart = time.Now()
+	defer func() {
+		metrics.DiscoveryPhaseLatency.WithLabelValues(target, metrics.DiscoveryPhaseServices).Observe(metrics.SinceInSeconds(phaseStart))
+	}()
+
+	svcWorkerConfig := worker.NewK8sServiceDiscoveryWorkerConfig(clusterSnapshot)
 	svcDiscWorker, err := worker.NewK8sServiceDiscoveryWorker(svcWorkerConfig)
+	if err != nil {
+		glog.Errorf("Failed to create service discovery worker: %s", err)
+		discoveryErrors = append(discoveryErrors, task.NewDiscoveryError(serviceDiscoverySource, err))
+		return entityDTOs, discoveryErrors, nil
+	}
 	svcDiscResult := svcDiscWorker.Do(entityDTOs)
 	if svcDiscResult.Err() != nil {
 		glog.Errorf("Failed to discover services from current Kubernetes cluster with the new discovery framework: %s", svcDiscResult.Err())
+		discoveryErrors = append(discoveryErrors, task.NewDiscoveryError(serviceDiscoverySource, svcDiscResult.Err()))
 	} else {
 		entityDTOs = append(entityDTOs, svcDiscResult.Content()...)
 	}
 
-	return entityDTOs, nil
+	return entityDTOs, discoveryErrors, nil
 }
@@ -0,0 +1,49 @@
+package discovery
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	api "k8s.io/client-go/pkg/api/v1"
+)
+
+func TestDeriveWorkerCount(t *testing.T) {
+	table := []struct {
+		nodeCount int
+		expected  int
+	}{
+		{0, minWorkerCount},
+		{3, minWorkerCount},
+		{80, 4},
+		{81, 5},
+		{200, 10},
+		{5000, maxWorkerCount},
+	}
+	for i, item := range table {
+		if got := deriveWorkerCount(item.nodeCount); got != item.expected {
+			t.Errorf("Test case %d failed: expected %d workers for %d nodes, got %d", i, item.expected, item.nodeCount, got)
+		}
+	}
+}
+
+func TestCountNodes(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	table := []struct {
+		getNodes      func() ([]*api.Node, error)
+		expectedCount int
+		expectErr     bool
+	}{
+		{func() ([]*api.Node, error) { return []*api.Node{{}, {}}, nil }, 2, false},
+		{func() ([]*api.Node, error) { return nil, errors.New("unauthorized") }, 0, true},
+		{func() ([]*api.Node, error) { <-block; return nil, nil }, 0, true},
+	}
+	for i, item := range table {
+		count, err := countNodes(item.getNodes, time.Millisecond*100)
+		if count != item.expectedCount || item.expectErr != (err != nil) {
+			t.Errorf("Test case %d failed: expected %d nodes and error %t, got %d and %v", i, item.expectedCount,
+				item.expectErr, count, err)
+		}
+	}
+}
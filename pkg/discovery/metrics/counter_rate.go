@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// Counters which have not been updated for this long are dropped, e.g. those of deleted pods.
+	defaultCounterExpiry = time.Hour
+)
+
+type counterSample struct {
+	value     uint64
+	timestamp time.Time
+
+	// The rate computed when the sample was recorded, if any.
+	rate    float64
+	hasRate bool
+}
+
+// CounterRateTracker computes the rates of cumulative counters, e.g. the bytes received by a network interface,
+// from the deltas between the samples of each counter. It keeps the last sample of every counter across discoveries,
+// and is safe for concurrent use.
+type CounterRateTracker struct {
+	expiry time.Duration
+
+	lock      sync.Mutex
+	samples   map[string]counterSample
+	lastPrune time.Time
+}
+
+func NewCounterRateTracker() *CounterRateTracker {
+	return &CounterRateTracker{
+		expiry:  defaultCounterExpiry,
+		samples: make(map[string]counterSample),
+	}
+}
+
+// Record the value of the counter at the given time, and return its rate per second since the previous sample.
+// A sample taken at the same time as the previous one, e.g. when the same stats are scraped twice, returns the rate
+// computed last time. There is no rate for the first sample of a counter, nor after the counter is reset.
+func (t *CounterRateTracker) Rate(key string, value uint64, timestamp time.Time) (float64, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.prune(timestamp)
+
+	prev, exist := t.samples[key]
+	if exist && !timestamp.After(prev.timestamp) {
+		return prev.rate, prev.hasRate
+	}
+
+	sample := counterSample{value: value, timestamp: timestamp}
+	if exist && value >= prev.value {
+		sample.rate = float64(value-prev.value) / timestamp.Sub(prev.timestamp).Seconds()
+		sample.hasRate = true
+	}
+	t.samples[key] = sample
+	return sample.rate, sample.hasRate
+}
+
+// Drop the expired counters, at most once per expiry period. Must be called with the lock held.
+func (t *CounterRateTracker) prune(now time.Time) {
+	if now.Sub(t.lastPrune) < t.expiry {
+		return
+	}
+	for key, sample := range t.samples {
+		if now.Sub(sample.timestamp) > t.expiry {
+			delete(t.samples, key)
+		}
+	}
+	t.lastPrune = now
+}
@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCounterRateTracker(t *testing.T) {
+	tracker := NewCounterRateTracker()
+	start := time.Now()
+
+	table := []struct {
+		value      uint64
+		offset     time.Duration
+		expectRate bool
+		expected   float64
+	}{
+		// The first sample has no rate.
+		{1000, 0, false, 0},
+		{3000, time.Second * 10, true, 200},
+		// The same stats scraped again.
+		{3000, time.Second * 10, true, 200},
+		{3600, time.Second * 20, true, 60},
+		// The counter is reset.
+		{100, time.Second * 30, false, 0},
+		{400, time.Second * 40, true, 30},
+		// The counter has expired.
+		{500, time.Second*40 + time.Hour*2, false, 0},
+	}
+	for i, item := range table {
+		rate, ok := tracker.Rate("node-1", item.value, start.Add(item.offset))
+		if ok != item.expectRate || rate != item.expected {
+			t.Errorf("Test case %d failed: expected (%f, %t), got (%f, %t)", i, item.expected, item.expectRate, rate, ok)
+		}
+	}
+}
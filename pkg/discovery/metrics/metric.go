@@ -11,6 +11,15 @@ const (
 	MemoryProvisioned ResourceType = "MemoryProvisioned"
 	Transaction       ResourceType = "Transaction"
 
+	// Storage of the root filesystem of a node used by pods, i.e. container writable layers, logs and emptyDir volumes.
+	EphemeralStorage ResourceType = "EphemeralStorage"
+	// Storage of the filesystem of a node where the container runtime keeps images.
+	ImageStorage ResourceType = "ImageStorage"
+	// Storage of a persistent volume mounted by a pod.
+	VolumeStorage ResourceType = "VolumeStorage"
+	// Bytes received and transmitted per second.
+	NetThroughput ResourceType = "NetThroughput"
+
 	Access       ResourceType = "Access"
 	Cluster      ResourceType = "Cluster"
 	Schedulable  ResourceType = "Schedulable"
@@ -23,6 +32,10 @@ const (
 	Capacity    MetricProp = "Capacity"
 	Used        MetricProp = "Used"
 	Reservation MetricProp = "Reservation"
+
+	// The highest and the percentile of used values sampled in a rolling window.
+	Peak       MetricProp = "Peak"
+	Percentile MetricProp = "Percentile"
 )
 
 type Metric interface {
@@ -74,6 +87,22 @@ func (m EntityResourceMetric) GetValue() interface{} {
 	return m.value
 }
 
+func (m EntityResourceMetric) GetEntityType() task.DiscoveredEntityType {
+	return m.entityType
+}
+
+func (m EntityResourceMetric) GetEntityID() string {
+	return m.entityID
+}
+
+func (m EntityResourceMetric) GetResourceType() ResourceType {
+	return m.resourceType
+}
+
+func (m EntityResourceMetric) GetMetricProp() MetricProp {
+	return m.metricProp
+}
+
 // Generate the UID for each metric entry based on entityType, entityID, resourceType and metricType.
 func GenerateEntityResourceMetricUID(eType task.DiscoveredEntityType, id string, rType ResourceType, mType MetricProp) string {
 	return string(eType) + "-" + id + "-" + string(rType) + "-" + string(mType)
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+type usedSample struct {
+	timestamp time.Time
+	value     float64
+}
+
+// MetricHistory keeps the used values of resource metrics sampled in a rolling window.
+// It is safe for concurrent use.
+type MetricHistory struct {
+	window time.Duration
+
+	lock sync.RWMutex
+	// key: UID of the used metric; value: samples in time order.
+	samples map[string][]usedSample
+}
+
+func NewMetricHistory(window time.Duration) *MetricHistory {
+	return &MetricHistory{
+		window:  window,
+		samples: make(map[string][]usedSample),
+	}
+}
+
+// Record the used values of all the resource metrics in the sink, and drop the samples out of the window.
+func (h *MetricHistory) AddSamples(sink *EntityMetricSink, timestamp time.Time) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for _, m := range sink.GetAllMetrics() {
+		rm, ok := m.(EntityResourceMetric)
+		if !ok || rm.GetMetricProp() != Used {
+			continue
+		}
+		h.samples[rm.GetUID()] = append(h.samples[rm.GetUID()], usedSample{timestamp: timestamp, value: rm.value})
+	}
+	h.prune(timestamp)
+}
+
+// Drop the samples out of the window. Must be called with the lock held.
+func (h *MetricHistory) prune(now time.Time) {
+	start := now.Add(-h.window)
+	for uid, samples := range h.samples {
+		i := sort.Search(len(samples), func(i int) bool { return !samples[i].timestamp.Before(start) })
+		if i == len(samples) {
+			delete(h.samples, uid)
+		} else if i > 0 {
+			h.samples[uid] = append([]usedSample(nil), samples[i:]...)
+		}
+	}
+}
+
+// For every used resource metric in the sink, add the peak and the given percentile of the values in the window,
+// including the current used value, to the sink.
+func (h *MetricHistory) AddAggregatedMetrics(sink *EntityMetricSink, percentile float64) {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	var aggregated []Metric
+	for _, m := range sink.GetAllMetrics() {
+		rm, ok := m.(EntityResourceMetric)
+		if !ok || rm.GetMetricProp() != Used {
+			continue
+		}
+		values := []float64{rm.value}
+		for _, sample := range h.samples[rm.GetUID()] {
+			values = append(values, sample.value)
+		}
+		sort.Float64s(values)
+		aggregated = append(aggregated,
+			NewEntityResourceMetric(rm.entityType, rm.entityID, rm.resourceType, Peak, values[len(values)-1]),
+			NewEntityResourceMetric(rm.entityType, rm.entityID, rm.resourceType, Percentile,
+				percentileOfSorted(values, percentile)))
+	}
+	sink.AddNewMetricEntries(aggregated...)
+}
+
+// Get the percentile of the sorted values with the nearest-rank method. The percentile is in (0, 100].
+func percentileOfSorted(sorted []float64, percentile float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(percentile / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
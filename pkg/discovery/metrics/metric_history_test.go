@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/turbonomic/kubeturbo/pkg/discovery/task"
+)
+
+func TestPercentileOfSorted(t *testing.T) {
+	table := []struct {
+		values     []float64
+		percentile float64
+		expected   float64
+	}{
+		{[]float64{}, 95, 0},
+		{[]float64{1}, 95, 1},
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 50, 5},
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 95, 10},
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 90, 9},
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 100, 10},
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 1, 1},
+	}
+	for i, item := range table {
+		if got := percentileOfSorted(item.values, item.percentile); got != item.expected {
+			t.Errorf("Test case %d failed: expected %f, got %f", i, item.expected, got)
+		}
+	}
+}
+
+func TestMetricHistory(t *testing.T) {
+	history := NewMetricHistory(time.Minute * 10)
+	start := time.Now()
+	for i, used := range []float64{8, 2, 3, 4} {
+		sink := NewEntityMetricSink()
+		sink.AddNewMetricEntries(
+			NewEntityResourceMetric(task.PodType, "default/web", CPU, Used, used),
+			NewEntityResourceMetric(task.PodType, "default/web", CPU, Capacity, 10))
+		history.AddSamples(sink, start.Add(time.Duration(i)*time.Minute*4))
+	}
+
+	// The first sample of 8 is out of the window.
+	sink := NewEntityMetricSink()
+	sink.AddNewMetricEntries(NewEntityResourceMetric(task.PodType, "default/web", CPU, Used, 1))
+	history.AddAggregatedMetrics(sink, 50)
+
+	table := []struct {
+		prop     MetricProp
+		expected float64
+	}{
+		{Used, 1},
+		{Peak, 4},
+		{Percentile, 2},
+	}
+	for _, item := range table {
+		m, err := sink.GetMetric(GenerateEntityResourceMetricUID(task.PodType, "default/web", CPU, item.prop))
+		if err != nil {
+			t.Errorf("Failed to get %s: %s", item.prop, err)
+			continue
+		}
+		if got := m.GetValue().(float64); got != item.expected {
+			t.Errorf("Expected %s %f, got %f", item.prop, item.expected, got)
+		}
+	}
+
+	// Only used metrics are aggregated.
+	if _, err := sink.GetMetric(GenerateEntityResourceMetricUID(task.PodType, "default/web", CPU, Capacity)); err == nil {
+		t.Error("Unexpected capacity metric")
+	}
+}
+
+func TestMetricHistoryWindow(t *testing.T) {
+	history := NewMetricHistory(time.Minute * 10)
+	start := time.Now()
+	sink := NewEntityMetricSink()
+	sink.AddNewMetricEntries(NewEntityResourceMetric(task.PodType, "default/web", CPU, Used, 8))
+	history.AddSamples(sink, start)
+
+	table := []struct {
+		elapsed  time.Duration
+		used     float64
+		expected float64
+	}{
+		// The sample at the start of the window is kept.
+		{time.Minute * 10, 1, 8},
+		// The current used value is the peak if it is the highest.
+		{time.Minute * 10, 9, 9},
+		// Once every sample is out of the window, only the current used value is left.
+		{time.Minute * 11, 1, 1},
+	}
+	for i, item := range table {
+		// Sampling an empty sink only drops the samples out of the window.
+		history.AddSamples(NewEntityMetricSink(), start.Add(item.elapsed))
+		sink := NewEntityMetricSink()
+		sink.AddNewMetricEntries(NewEntityResourceMetric(task.PodType, "default/web", CPU, Used, item.used))
+		history.AddAggregatedMetrics(sink, 50)
+		m, err := sink.GetMetric(GenerateEntityResourceMetricUID(task.PodType, "default/web", CPU, Peak))
+		if err != nil {
+			t.Errorf("Test case %d failed: %s", i, err)
+			continue
+		}
+		if got := m.GetValue().(float64); got != item.expected {
+			t.Errorf("Test case %d failed: expected peak %f, got %f", i, item.expected, got)
+		}
+	}
+}
@@ -41,6 +41,19 @@ func (s *EntityMetricSink) GetMetric(metricUID string) (Metric, error) {
 	}
 }
 
+// Get all the metrics in the sink.
+func (s *EntityMetricSink) GetAllMetrics() []Metric {
+	var all []Metric
+	for _, key := range s.data.AllKeys() {
+		if m, exist := s.data.Get(key); exist {
+			if metric, ok := m.(Metric); ok {
+				all = append(all, metric)
+			}
+		}
+	}
+	return all
+}
+
 func (s *EntityMetricSink) PrintAllKeys() {
 	for _, key := range s.data.AllKeys() {
 		glog.V(3).Infof("Current sink has an entry with key: %s", key)
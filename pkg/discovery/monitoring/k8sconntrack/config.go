@@ -15,6 +15,10 @@ type K8sConntrackMonitorConfig struct {
 
 	// http or https.
 	enableHttps bool
+
+	// whether the source is enabled by the user, rather than by default. K8sConntrack is optional, so its agents are
+	// only checked during target validation if it is enabled explicitly.
+	explicitlyEnabled bool
 }
 
 func NewK8sConntrackMonitorConfig() *K8sConntrackMonitorConfig {
@@ -35,6 +39,15 @@ func (kcm *K8sConntrackMonitorConfig) EnableHttps() *K8sConntrackMonitorConfig {
 	return kcm
 }
 
+func (kcm *K8sConntrackMonitorConfig) EnableExplicitly() *K8sConntrackMonitorConfig {
+	kcm.explicitlyEnabled = true
+	return kcm
+}
+
+func (kcm *K8sConntrackMonitorConfig) IsExplicitlyEnabled() bool {
+	return kcm.explicitlyEnabled
+}
+
 // Implement MonitoringWorkerConfig interface.
 func (kcm *K8sConntrackMonitorConfig) GetMonitorType() types.MonitorType {
 	return types.ResourceMonitor
@@ -1,6 +1,7 @@
 package k8sconntrack
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -45,7 +46,7 @@ func (c *K8sConntrackClient) GetPort() int64 {
 }
 
 // Get transaction data from provided host.
-func (c *K8sConntrackClient) GetTransactionData(host Host) (transactions []Transaction, err error) {
+func (c *K8sConntrackClient) GetTransactionData(ctx context.Context, host Host) (transactions []Transaction, err error) {
 	requestURL := url.URL{
 		Scheme: c.config.schema,
 		Host:   fmt.Sprintf("%s:%d", host.IP, host.Port),
@@ -55,6 +56,7 @@ func (c *K8sConntrackClient) GetTransactionData(host Host) (transactions []Trans
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(ctx)
 	var transactionsList []Transaction
 	if err = httputil.PostRequestAndGetValue(c.client, req, &transactionsList); err != nil {
 		glog.Errorf("Error getting Json Data for transactions: %s", err)
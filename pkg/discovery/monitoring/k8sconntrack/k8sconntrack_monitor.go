@@ -1,7 +1,9 @@
 package k8sconntrack
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"sync"
 
 	api "k8s.io/client-go/pkg/api/v1"
@@ -33,8 +35,6 @@ type K8sConntrackMonitor struct {
 	metricSink *metrics.EntityMetricSink
 
 	wg sync.WaitGroup
-
-	stopCh chan struct{}
 }
 
 func NewK8sConntrackMonitor(config *K8sConntrackMonitorConfig) (*K8sConntrackMonitor, error) {
@@ -50,13 +50,11 @@ func NewK8sConntrackMonitor(config *K8sConntrackMonitorConfig) (*K8sConntrackMon
 		config:             config,
 		k8sConntrackClient: NewK8sConntrackClient(k8sConntrackClientConfig),
 		metricSink:         metrics.NewEntityMetricSink(),
-		stopCh:             make(chan struct{}, 1),
 	}, nil
 }
 
 func (m *K8sConntrackMonitor) reset() {
 	m.metricSink = metrics.NewEntityMetricSink()
-	m.stopCh = make(chan struct{}, 1)
 }
 
 // Implement MonitoringWorker interface.
@@ -72,14 +70,10 @@ func (m *K8sConntrackMonitor) ReceiveTask(task *task.Task) {
 	m.nodePodMap = util.GroupPodsByNode(task.PodList())
 }
 
-func (m *K8sConntrackMonitor) Stop() {
-	m.stopCh <- struct{}{}
-}
-
 // Implement MonitoringWorker interface.
-func (m *K8sConntrackMonitor) Do() *metrics.EntityMetricSink {
+func (m *K8sConntrackMonitor) Do(ctx context.Context) *metrics.EntityMetricSink {
 	glog.V(4).Infof("%s has started task.", m.GetMonitoringSource())
-	err := m.RetrieveResourceStat()
+	err := m.RetrieveResourceStat(ctx)
 	if err != nil {
 		glog.Errorf("Failed to execute task: %s", err)
 	}
@@ -87,12 +81,9 @@ func (m *K8sConntrackMonitor) Do() *metrics.EntityMetricSink {
 	return m.metricSink
 }
 
-// Start to retrieve resource stats for the received list of nodes.
-func (m *K8sConntrackMonitor) RetrieveResourceStat() error {
-	defer func() {
-		close(m.stopCh)
-	}()
-
+// Start to retrieve resource stats for the received list of nodes. The nodes not scraped yet are skipped and the
+// ongoing requests are cancelled once ctx is done.
+func (m *K8sConntrackMonitor) RetrieveResourceStat(ctx context.Context) error {
 	if m.nodeList == nil || len(m.nodeList) == 0 {
 		return errors.New("Invalid nodeList or empty nodeList. Finish Immediately...")
 	}
@@ -102,35 +93,46 @@ func (m *K8sConntrackMonitor) RetrieveResourceStat() error {
 		go func(n *api.Node) {
 			defer m.wg.Done()
 
-			select {
-			case <-m.stopCh:
+			if ctx.Err() != nil {
 				return
-			default:
-				m.scrapeK8sConntrack(node)
 			}
+			m.scrapeK8sConntrack(ctx, n)
 		}(node)
 	}
 
 	m.wg.Wait()
 
-	return nil
+	return ctx.Err()
 }
 
 // Get transaction value from a single host.
-func (m *K8sConntrackMonitor) getTransactionFromNode(ip string) ([]Transaction, error) {
+func (m *K8sConntrackMonitor) getTransactionFromNode(ctx context.Context, ip string) ([]Transaction, error) {
 	host := Host{
 		IP:   ip,
 		Port: m.k8sConntrackClient.GetPort(),
 	}
-	transactions, err := m.k8sConntrackClient.GetTransactionData(host)
+	transactions, err := m.k8sConntrackClient.GetTransactionData(ctx, host)
 	if err != nil {
 		return transactions, err
 	}
 	return transactions, nil
 }
 
+// Check if the K8sConntrack agent on the given node can be reached with the configured port and scheme.
+func (m *K8sConntrackMonitor) CheckNodeAgent(ctx context.Context, node *api.Node) error {
+	ip, err := util.GetNodeIPForMonitor(node, types.K8sConntrackSource)
+	if err != nil {
+		return err
+	}
+	if _, err := m.getTransactionFromNode(ctx, ip); err != nil {
+		return fmt.Errorf("failed to reach %s://%s:%d: %s", m.k8sConntrackClient.config.schema, ip,
+			m.k8sConntrackClient.GetPort(), err)
+	}
+	return nil
+}
+
 // Retrieve resource metrics for the given node.
-func (m *K8sConntrackMonitor) scrapeK8sConntrack(node *api.Node) {
+func (m *K8sConntrackMonitor) scrapeK8sConntrack(ctx context.Context, node *api.Node) {
 	// build pod IP map.
 	runningPods, exist := m.nodePodMap[node.Name]
 	if !exist {
@@ -147,7 +149,7 @@ func (m *K8sConntrackMonitor) scrapeK8sConntrack(node *api.Node) {
 	}
 
 	// get transaction data from node
-	transactionData, err := m.getTransactionFromNode(ip)
+	transactionData, err := m.getTransactionFromNode(ctx, ip)
 	if err != nil {
 		glog.Errorf("Failed to get transaction data from %s: %s", node.Name, err)
 	}
@@ -1,10 +1,13 @@
 package kubelet
 
 import (
+	"net/http"
+
 	restclient "k8s.io/client-go/rest"
 
 	kubeletclient "k8s.io/kubernetes/pkg/kubelet/client"
 
+	"github.com/turbonomic/kubeturbo/pkg/discovery/metrics"
 	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/types"
 )
 
@@ -16,10 +19,25 @@ const (
 	defaultUseServiceAccount       = false
 	defaultServiceAccountFile      = "/var/run/secrets/kubernetes.io/serviceaccount/token"
 	defaultInClusterConfig         = true
+
+	// The network throughput capacity of a node in Mbit/s, unless it is specified by the node annotation.
+	DefaultNetThroughputCapacity float64 = 1000
+	// The node annotation which specifies the network throughput capacity of the node in Mbit/s.
+	NetThroughputCapacityAnnotation = "kubeturbo.io/net-throughput-capacity"
 )
 
 type KubeletMonitorConfig struct {
 	*kubeletclient.KubeletClientConfig
+
+	// network throughput capacity of a node in Mbit/s if the node is not annotated.
+	netThroughputCapacity float64
+
+	// The network rates are computed from the counters scraped by all the monitors built from this config,
+	// so they share the last counter values.
+	netRateTracker *metrics.CounterRateTracker
+
+	// If it is set, the kubelets are accessed through this transport instead of the network.
+	transport http.RoundTripper
 }
 
 // Implement MonitoringWorkerConfig interface.
@@ -41,7 +59,9 @@ func NewKubeletMonitorConfig(kubeConfig *restclient.Config) *KubeletMonitorConfi
 	}
 
 	return &KubeletMonitorConfig{
-		KubeletClientConfig: kubeletConfig,
+		KubeletClientConfig:   kubeletConfig,
+		netThroughputCapacity: DefaultNetThroughputCapacity,
+		netRateTracker:        metrics.NewCounterRateTracker(),
 	}
 }
 
@@ -54,3 +74,17 @@ func (kc *KubeletMonitorConfig) EnableHttps(enable bool) *KubeletMonitorConfig {
 	kc.KubeletClientConfig.EnableHttps = enable
 	return kc
 }
+
+// Assign a different network throughput capacity in Mbit/s if it is not the default one.
+func (kc *KubeletMonitorConfig) WithNetThroughputCapacity(capacity float64) *KubeletMonitorConfig {
+	if capacity > 0 {
+		kc.netThroughputCapacity = capacity
+	}
+	return kc
+}
+
+// Access the kubelets through the given transport, e.g. to serve the responses of simulated kubelets.
+func (kc *KubeletMonitorConfig) WithTransport(transport http.RoundTripper) *KubeletMonitorConfig {
+	kc.transport = transport
+	return kc
+}
@@ -1,6 +1,7 @@
 package kubelet
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -33,7 +34,7 @@ func (kc *kubeletClient) GetPort() int {
 	return int(kc.config.Port)
 }
 
-func (kc *kubeletClient) GetSummary(host Host) (*stats.Summary, error) {
+func (kc *kubeletClient) GetSummary(ctx context.Context, host Host) (*stats.Summary, error) {
 	requestURL := url.URL{
 		Scheme: "http",
 		Host:   fmt.Sprintf("%s:%d", host.IP, host.Port),
@@ -47,13 +48,14 @@ func (kc *kubeletClient) GetSummary(host Host) (*stats.Summary, error) {
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(ctx)
 	summary := &stats.Summary{}
 	client := kc.client
 	err = httputil.PostRequestAndGetValue(client, req, summary)
 	return summary, err
 }
 
-func (kc *kubeletClient) GetMachineInfo(host Host) (*cadvisorapi.MachineInfo, error) {
+func (kc *kubeletClient) GetMachineInfo(ctx context.Context, host Host) (*cadvisorapi.MachineInfo, error) {
 	requestURL := url.URL{
 		Scheme: "http",
 		Host:   fmt.Sprintf("%s:%d", host.IP, host.Port),
@@ -66,6 +68,7 @@ func (kc *kubeletClient) GetMachineInfo(host Host) (*cadvisorapi.MachineInfo, er
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(ctx)
 	var minfo cadvisorapi.MachineInfo
 	err = httputil.PostRequestAndGetValue(kc.client, req, &minfo)
 	return &minfo, err
@@ -1,8 +1,10 @@
 package kubelet
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"sync"
 
 	api "k8s.io/client-go/pkg/api/v1"
@@ -18,35 +20,52 @@ import (
 	"github.com/golang/glog"
 )
 
+const (
+	// Name of the ephemeral storage resource since Kubernetes 1.8. It is the successor of the alpha
+	// api.ResourceStorageScratch.
+	resourceEphemeralStorage api.ResourceName = "ephemeral-storage"
+)
+
 // KubeletMonitor is a resource monitoring worker.
 type KubeletMonitor struct {
 	nodeList []*api.Node
 
+	// key: pod key; value: the pod. Used to find the ephemeral storage limits and the volume sources of pods.
+	podMap map[string]*api.Pod
+
 	kubeletClient *kubeletClient
 
 	metricSink *metrics.EntityMetricSink
 
-	stopCh chan struct{}
+	// the nodes failed to be scraped in the last task.
+	discoveryErrors *task.DiscoveryErrors
+
+	// network throughput capacity of a node in Mbit/s if the node is not annotated.
+	netThroughputCapacity float64
+
+	netRateTracker *metrics.CounterRateTracker
 
 	wg sync.WaitGroup
 }
 
 func NewKubeletMonitor(config *KubeletMonitorConfig) (*KubeletMonitor, error) {
-	kubeletClient, err := NewKubeletClient(config.KubeletClientConfig)
+	kubeletClient, err := newKubeletClientFromConfig(config)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to create Kubelet client based on given config: %s", err)
+		return nil, err
 	}
 
 	return &KubeletMonitor{
-		kubeletClient: kubeletClient,
-		metricSink:    metrics.NewEntityMetricSink(),
-		stopCh:        make(chan struct{}, 1),
+		kubeletClient:         kubeletClient,
+		metricSink:            metrics.NewEntityMetricSink(),
+		discoveryErrors:       task.NewDiscoveryErrors(),
+		netThroughputCapacity: config.netThroughputCapacity,
+		netRateTracker:        config.netRateTracker,
 	}, nil
 }
 
 func (m *KubeletMonitor) reset() {
 	m.metricSink = metrics.NewEntityMetricSink()
-	m.stopCh = make(chan struct{}, 1)
+	m.discoveryErrors = task.NewDiscoveryErrors()
 }
 
 func (m *KubeletMonitor) GetMonitoringSource() types.MonitoringSource {
@@ -57,15 +76,20 @@ func (m *KubeletMonitor) ReceiveTask(task *task.Task) {
 	m.reset()
 
 	m.nodeList = task.NodeList()
+	m.podMap = make(map[string]*api.Pod)
+	for _, pod := range task.PodList() {
+		m.podMap[util.PodKeyFunc(pod)] = pod
+	}
 }
 
-func (m *KubeletMonitor) Stop() {
-	m.stopCh <- struct{}{}
+// Get the nodes failed to be scraped in the last task.
+func (m *KubeletMonitor) GetDiscoveryErrors() []*task.DiscoveryError {
+	return m.discoveryErrors.List()
 }
 
-func (m *KubeletMonitor) Do() *metrics.EntityMetricSink {
+func (m *KubeletMonitor) Do(ctx context.Context) *metrics.EntityMetricSink {
 	glog.V(4).Infof("%s has started task.", m.GetMonitoringSource())
-	err := m.RetrieveResourceStat()
+	err := m.RetrieveResourceStat(ctx)
 	if err != nil {
 		glog.Errorf("Failed to execute task: %s", err)
 	}
@@ -73,11 +97,9 @@ func (m *KubeletMonitor) Do() *metrics.EntityMetricSink {
 	return m.metricSink
 }
 
-// Start to retrieve resource stats for the received list of nodes.
-func (m *KubeletMonitor) RetrieveResourceStat() error {
-	defer func() {
-		close(m.stopCh)
-	}()
+// Start to retrieve resource stats for the received list of nodes. Once ctx is done, the ongoing requests are
+// cancelled, and the nodes not scraped are reported as failed.
+func (m *KubeletMonitor) RetrieveResourceStat(ctx context.Context) error {
 	if m.nodeList == nil || len(m.nodeList) == 0 {
 		return errors.New("Invalid nodeList or empty nodeList. Finish Immediately...")
 	}
@@ -86,59 +108,123 @@ func (m *KubeletMonitor) RetrieveResourceStat() error {
 	for _, node := range m.nodeList {
 		go func(n *api.Node) {
 			defer m.wg.Done()
-			select {
-			case <-m.stopCh:
-				return
-			default:
-				m.scrapeKubelet(n)
+			err := ctx.Err()
+			if err == nil {
+				err = m.scrapeKubelet(ctx, n)
+			}
+			if err != nil {
+				glog.Errorf("Failed to get resource metrics from %s: %s", n.Name, err)
+				m.discoveryErrors.Add(task.NewDiscoveryError(string(types.KubeletSource), err).
+					WithEntity(task.NodeType, n.Name, string(n.UID)))
 			}
 		}(node)
 	}
 
 	m.wg.Wait()
 
-	return nil
+	return ctx.Err()
 }
 
 // Retrieve resource metrics for the given node.
-func (m *KubeletMonitor) scrapeKubelet(node *api.Node) {
+func (m *KubeletMonitor) scrapeKubelet(ctx context.Context, node *api.Node) error {
 	ip, err := util.GetNodeIPForMonitor(node, types.KubeletSource)
 	if err != nil {
-		glog.Errorf("Failed to get resource metrics from %s: %s", node.Name, err)
-		return
+		return err
 	}
 	host := Host{
 		IP:   ip,
 		Port: m.kubeletClient.GetPort(),
 	}
 	// get machine information
-	machineInfo, err := m.kubeletClient.GetMachineInfo(host)
+	machineInfo, err := m.kubeletClient.GetMachineInfo(ctx, host)
 	if err != nil {
-		glog.Errorf("Failed to get machine information from %s: %s", node.Name, err)
-		return
+		return fmt.Errorf("failed to get machine information: %s", err)
 	}
 	glog.V(4).Infof("Machine info of %s is %++v", node.Name, machineInfo)
 	m.parseNodeInfo(node, machineInfo)
 
 	// get summary information about the given node and the pods running on it.
-	summary, err := m.kubeletClient.GetSummary(host)
+	summary, err := m.kubeletClient.GetSummary(ctx, host)
 	if err != nil {
-		glog.Errorf("Failed to get resource metrics summary from %s: %s", node.Name, err)
-		return
+		return fmt.Errorf("failed to get resource metrics summary: %s", err)
 	}
 	m.parseNodeStats(summary.Node)
 	m.parsePodStats(summary.Pods)
+	m.parseNodeStorageStats(summary.Node)
+	m.parsePodStorageStats(summary.Pods, summary.Node.Fs)
+	m.parseNodeNetworkStats(node, summary.Node)
+	m.parsePodNetworkStats(summary.Pods)
 
 	glog.V(4).Infof("Finished scrape node %s.", node.Name)
+	return nil
+}
 
+// Check if the kubelet on the given node can be reached with the configured port and scheme.
+func (m *KubeletMonitor) CheckNodeAgent(ctx context.Context, node *api.Node) error {
+	ip, err := util.GetNodeIPForMonitor(node, types.KubeletSource)
+	if err != nil {
+		return err
+	}
+	scheme := "http"
+	if m.kubeletClient.config.EnableHttps {
+		scheme = "https"
+	}
+	if _, err := m.kubeletClient.GetMachineInfo(ctx, Host{IP: ip, Port: m.kubeletClient.GetPort()}); err != nil {
+		return fmt.Errorf("failed to reach %s://%s:%d: %s", scheme, ip, m.kubeletClient.GetPort(), err)
+	}
+	return nil
 }
 
 func (m *KubeletMonitor) parseNodeInfo(node *api.Node, machineInfo *cadvisorapi.MachineInfo) {
-	cpuFrequencyMHz := float64(machineInfo.CpuFrequency) / util.MegaToKilo
-	cpuFrequencyMetric := metrics.NewEntityStateMetric(task.NodeType, util.NodeKeyFunc(node), metrics.CpuFrequency, cpuFrequencyMHz)
+	cpuFrequencyMetric := metrics.NewEntityStateMetric(task.NodeType, util.NodeKeyFunc(node), metrics.CpuFrequency,
+		cpuFrequencyMHz(machineInfo))
 	m.metricSink.AddNewMetricEntries(cpuFrequencyMetric)
 }
 
+// Get the cpu frequency in MHz from the machine info, where it is in KHz.
+func cpuFrequencyMHz(machineInfo *cadvisorapi.MachineInfo) float64 {
+	return float64(machineInfo.CpuFrequency) / util.MegaToKilo
+}
+
+// CpuFrequencyGetter gets the cpu frequency of the nodes from the machine info of their kubelets, for the resource
+// monitors which cannot always get it from their own source, e.g. Prometheus.
+type CpuFrequencyGetter struct {
+	kubeletClient *kubeletClient
+}
+
+func NewCpuFrequencyGetter(config *KubeletMonitorConfig) (*CpuFrequencyGetter, error) {
+	kubeletClient, err := newKubeletClientFromConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &CpuFrequencyGetter{kubeletClient: kubeletClient}, nil
+}
+
+// Get the cpu frequency of the node in MHz.
+func (g *CpuFrequencyGetter) GetCpuFrequency(ctx context.Context, node *api.Node) (float64, error) {
+	ip, err := util.GetNodeIPForMonitor(node, types.KubeletSource)
+	if err != nil {
+		return 0, err
+	}
+	machineInfo, err := g.kubeletClient.GetMachineInfo(ctx, Host{IP: ip, Port: g.kubeletClient.GetPort()})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get machine information: %s", err)
+	}
+	return cpuFrequencyMHz(machineInfo), nil
+}
+
+// Create the client of the kubelets with the given config.
+func newKubeletClientFromConfig(config *KubeletMonitorConfig) (*kubeletClient, error) {
+	kubeletClient, err := NewKubeletClient(config.KubeletClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create Kubelet client based on given config: %s", err)
+	}
+	if config.transport != nil {
+		kubeletClient.client.Transport = config.transport
+	}
+	return kubeletClient, nil
+}
+
 // Parse node stats and put it into sink.
 func (m *KubeletMonitor) parseNodeStats(nodeStats stats.NodeStats) {
 	// cpu
@@ -163,12 +249,19 @@ func (m *KubeletMonitor) parsePodStats(podStats []stats.PodStats) {
 		var cpuUsageNanoCoreSum uint64
 		var memoryUsageBytesSum uint64
 		for _, containerStat := range podStat.Containers {
+			var cpuUsageNanoCore uint64
+			var memoryUsageBytes uint64
 			if containerStat.CPU != nil && containerStat.CPU.UsageNanoCores != nil {
-				cpuUsageNanoCoreSum += *containerStat.CPU.UsageNanoCores
+				cpuUsageNanoCore = *containerStat.CPU.UsageNanoCores
 			}
 			if containerStat.Memory != nil && containerStat.Memory.UsageBytes != nil {
-				memoryUsageBytesSum += *containerStat.Memory.UsageBytes
+				memoryUsageBytes = *containerStat.Memory.UsageBytes
 			}
+			cpuUsageNanoCoreSum += cpuUsageNanoCore
+			memoryUsageBytesSum += memoryUsageBytes
+
+			m.parseContainerStats(podStat, containerStat, float64(cpuUsageNanoCore)/util.NanoToUnit,
+				float64(memoryUsageBytes)/util.KilobytesToBytes)
 		}
 		glog.V(4).Infof("Cpu usage of pod %s is %f core", util.PodStatsKeyFunc(podStat),
 			float64(cpuUsageNanoCoreSum)/util.NanoToUnit)
@@ -180,17 +273,190 @@ func (m *KubeletMonitor) parsePodStats(podStats []stats.PodStats) {
 		podMemoryUsageCoreMetrics := metrics.NewEntityResourceMetric(task.PodType, util.PodStatsKeyFunc(podStat),
 			metrics.Memory, metrics.Used, float64(memoryUsageBytesSum)/util.KilobytesToBytes)
 
-		// application cpu and mem used are the same as pod's.
-		applicationCpuUsageCoreMetrics := metrics.NewEntityResourceMetric(task.ApplicationType,
-			util.PodStatsKeyFunc(podStat), metrics.CPU, metrics.Used,
-			float64(cpuUsageNanoCoreSum)/util.NanoToUnit)
-		applicationMemoryUsageCoreMetrics := metrics.NewEntityResourceMetric(task.ApplicationType,
-			util.PodStatsKeyFunc(podStat), metrics.Memory, metrics.Used,
-			float64(memoryUsageBytesSum)/util.KilobytesToBytes)
+		m.metricSink.AddNewMetricEntries(podCpuUsageCoreMetrics, podMemoryUsageCoreMetrics)
+	}
+}
+
+// Parse the cpu and memory usage of a single container.
+// The application consumes the resources of the container it runs in, so it has the same usage as the container.
+func (m *KubeletMonitor) parseContainerStats(podStat stats.PodStats, containerStat stats.ContainerStats,
+	cpuUsageCore, memoryUsageKiloBytes float64) {
+	key := util.ContainerStatsKeyFunc(podStat, containerStat)
+
+	glog.V(4).Infof("Cpu usage of container %s is %f core", key, cpuUsageCore)
+	containerCpuUsageCoreMetrics := metrics.NewEntityResourceMetric(task.ContainerType, key,
+		metrics.CPU, metrics.Used, cpuUsageCore)
+	glog.V(4).Infof("Memory usage of container %s is %f Kb", key, memoryUsageKiloBytes)
+	containerMemoryUsageKiloBytesMetrics := metrics.NewEntityResourceMetric(task.ContainerType, key,
+		metrics.Memory, metrics.Used, memoryUsageKiloBytes)
 
-		m.metricSink.AddNewMetricEntries(podCpuUsageCoreMetrics,
-			podMemoryUsageCoreMetrics,
-			applicationCpuUsageCoreMetrics,
-			applicationMemoryUsageCoreMetrics)
+	applicationCpuUsageCoreMetrics := metrics.NewEntityResourceMetric(task.ApplicationType, key,
+		metrics.CPU, metrics.Used, cpuUsageCore)
+	applicationMemoryUsageKiloBytesMetrics := metrics.NewEntityResourceMetric(task.ApplicationType, key,
+		metrics.Memory, metrics.Used, memoryUsageKiloBytes)
+
+	m.metricSink.AddNewMetricEntries(containerCpuUsageCoreMetrics,
+		containerMemoryUsageKiloBytesMetrics,
+		applicationCpuUsageCoreMetrics,
+		applicationMemoryUsageKiloBytesMetrics)
+}
+
+// Parse the storage stats of the node. The root filesystem is shared by the pods as ephemeral storage,
+// and the image filesystem holds the images of the container runtime.
+func (m *KubeletMonitor) parseNodeStorageStats(nodeStats stats.NodeStats) {
+	key := util.NodeStatsKeyFunc(nodeStats)
+	m.addFsMetrics(task.NodeType, key, metrics.EphemeralStorage, nodeStats.Fs, true)
+	if nodeStats.Runtime != nil {
+		m.addFsMetrics(task.NodeType, key, metrics.ImageStorage, nodeStats.Runtime.ImageFs, true)
+	}
+}
+
+// Parse the ephemeral storage and the persistent volume usage of every pod.
+// Like the kubelet does for eviction, the ephemeral storage used by a pod is the sum of the writable layers and logs
+// of its containers and of its emptyDir volumes that are not backed by memory. The capacity is the ephemeral storage
+// limit of the pod if all its containers have one, otherwise the capacity of the root filesystem of the node.
+func (m *KubeletMonitor) parsePodStorageStats(podStats []stats.PodStats, nodeFs *stats.FsStats) {
+	for _, podStat := range podStats {
+		key := util.PodStatsKeyFunc(podStat)
+		pod, exist := m.podMap[key]
+		if !exist {
+			glog.V(3).Infof("Pod %s is not in the task, skip its storage stats.", key)
+			continue
+		}
+
+		var usedBytes uint64
+		for _, containerStat := range podStat.Containers {
+			usedBytes += fsUsedBytes(containerStat.Rootfs) + fsUsedBytes(containerStat.Logs)
+		}
+
+		volumes := make(map[string]*api.Volume)
+		for i := range pod.Spec.Volumes {
+			volumes[pod.Spec.Volumes[i].Name] = &pod.Spec.Volumes[i]
+		}
+		for i := range podStat.VolumeStats {
+			volumeStat := &podStat.VolumeStats[i]
+			volume, exist := volumes[volumeStat.Name]
+			if !exist {
+				continue
+			}
+			if volume.EmptyDir != nil && volume.EmptyDir.Medium != api.StorageMediumMemory {
+				usedBytes += fsUsedBytes(&volumeStat.FsStats)
+			} else if volume.PersistentVolumeClaim != nil {
+				m.addFsMetrics(task.PodType, util.PodVolumeStatsKeyFunc(podStat, *volumeStat), metrics.VolumeStorage,
+					&volumeStat.FsStats, true)
+			}
+		}
+
+		usedMB := float64(usedBytes) / util.MegabytesToBytes
+		glog.V(4).Infof("Ephemeral storage usage of pod %s is %f MB", key, usedMB)
+		m.metricSink.AddNewMetricEntries(metrics.NewEntityResourceMetric(task.PodType, key,
+			metrics.EphemeralStorage, metrics.Used, usedMB))
+
+		if limitBytes, exist := podEphemeralStorageLimit(pod); exist {
+			m.metricSink.AddNewMetricEntries(metrics.NewEntityResourceMetric(task.PodType, key,
+				metrics.EphemeralStorage, metrics.Capacity, limitBytes/util.MegabytesToBytes))
+		} else {
+			m.addFsMetrics(task.PodType, key, metrics.EphemeralStorage, nodeFs, false)
+		}
+	}
+}
+
+// Add the capacity of the filesystem, and its used value if withUsed is true, in MB.
+func (m *KubeletMonitor) addFsMetrics(eType task.DiscoveredEntityType, key string, rType metrics.ResourceType,
+	fs *stats.FsStats, withUsed bool) {
+	if fs == nil {
+		return
+	}
+	if fs.CapacityBytes != nil {
+		capacityMB := float64(*fs.CapacityBytes) / util.MegabytesToBytes
+		glog.V(4).Infof("%s capacity of %s %s is %f MB", rType, eType, key, capacityMB)
+		m.metricSink.AddNewMetricEntries(metrics.NewEntityResourceMetric(eType, key, rType, metrics.Capacity,
+			capacityMB))
+	}
+	if withUsed && fs.UsedBytes != nil {
+		usedMB := float64(*fs.UsedBytes) / util.MegabytesToBytes
+		glog.V(4).Infof("%s usage of %s %s is %f MB", rType, eType, key, usedMB)
+		m.metricSink.AddNewMetricEntries(metrics.NewEntityResourceMetric(eType, key, rType, metrics.Used, usedMB))
 	}
 }
+
+func fsUsedBytes(fs *stats.FsStats) uint64 {
+	if fs == nil || fs.UsedBytes == nil {
+		return 0
+	}
+	return *fs.UsedBytes
+}
+
+// Get the ephemeral storage limit of the pod in bytes, which is the sum of the limits of its containers.
+// The pod has no limit if any of its containers has none.
+func podEphemeralStorageLimit(pod *api.Pod) (float64, bool) {
+	if len(pod.Spec.Containers) == 0 {
+		return 0, false
+	}
+	var limit float64
+	for _, container := range pod.Spec.Containers {
+		quantity, exist := container.Resources.Limits[resourceEphemeralStorage]
+		if !exist {
+			quantity, exist = container.Resources.Limits[api.ResourceStorageScratch]
+		}
+		if !exist {
+			return 0, false
+		}
+		limit += float64(quantity.Value())
+	}
+	return limit, true
+}
+
+// Parse the network throughput of the node, in KB/s. The capacity is taken from the node annotation if there is one,
+// otherwise the configured capacity is used.
+func (m *KubeletMonitor) parseNodeNetworkStats(node *api.Node, nodeStats stats.NodeStats) {
+	key := util.NodeStatsKeyFunc(nodeStats)
+
+	capacity := m.netThroughputCapacity
+	if value, exist := node.Annotations[NetThroughputCapacityAnnotation]; exist {
+		annotated, err := strconv.ParseFloat(value, 64)
+		if err != nil || annotated <= 0 {
+			glog.Warningf("Invalid annotation %s=%q of node %s, use the default capacity %f Mbit/s.",
+				NetThroughputCapacityAnnotation, value, node.Name, capacity)
+		} else {
+			capacity = annotated
+		}
+	}
+	m.metricSink.AddNewMetricEntries(metrics.NewEntityResourceMetric(task.NodeType, key, metrics.NetThroughput,
+		metrics.Capacity, mbpsToKBps(capacity)))
+
+	if rate, exist := m.getNetworkRate(string(task.NodeType)+"/"+key, nodeStats.Network); exist {
+		glog.V(4).Infof("Network throughput of node %s is %f KB/s", key, rate)
+		m.metricSink.AddNewMetricEntries(metrics.NewEntityResourceMetric(task.NodeType, key, metrics.NetThroughput,
+			metrics.Used, rate))
+	}
+}
+
+// Parse the network throughput of every pod, in KB/s.
+func (m *KubeletMonitor) parsePodNetworkStats(podStats []stats.PodStats) {
+	for _, podStat := range podStats {
+		key := util.PodStatsKeyFunc(podStat)
+		// A pod re-created with the same name has new counters, so the counters are tracked by the uid of the pod.
+		if rate, exist := m.getNetworkRate(string(task.PodType)+"/"+podStat.PodRef.UID, podStat.Network); exist {
+			glog.V(4).Infof("Network throughput of pod %s is %f KB/s", key, rate)
+			m.metricSink.AddNewMetricEntries(metrics.NewEntityResourceMetric(task.PodType, key, metrics.NetThroughput,
+				metrics.Used, rate))
+		}
+	}
+}
+
+// Get the rate of the bytes received and transmitted in KB/s, from the delta since the counters were last scraped.
+// There is no rate until the counters have been scraped twice.
+func (m *KubeletMonitor) getNetworkRate(counterKey string, networkStats *stats.NetworkStats) (float64, bool) {
+	if networkStats == nil || networkStats.RxBytes == nil || networkStats.TxBytes == nil {
+		return 0, false
+	}
+	rate, exist := m.netRateTracker.Rate(counterKey, *networkStats.RxBytes+*networkStats.TxBytes,
+		networkStats.Time.Time)
+	return rate / util.KilobytesToBytes, exist
+}
+
+// Convert Mbit/s to KB/s.
+func mbpsToKBps(mbps float64) float64 {
+	return mbps * 1e6 / 8 / util.KilobytesToBytes
+}
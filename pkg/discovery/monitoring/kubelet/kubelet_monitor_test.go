@@ -0,0 +1,313 @@
+package kubelet
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	api "k8s.io/client-go/pkg/api/v1"
+	restclient "k8s.io/client-go/rest"
+	stats "k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
+
+	"github.com/turbonomic/kubeturbo/pkg/discovery/metrics"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/task"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/util"
+
+	cadvisorapi "github.com/google/cadvisor/info/v1"
+)
+
+const mb = uint64(util.MegabytesToBytes)
+
+func bytesPtr(megabytes uint64) *uint64 {
+	b := megabytes * mb
+	return &b
+}
+
+func newFsStats(capacityMB, usedMB *uint64) *stats.FsStats {
+	return &stats.FsStats{CapacityBytes: capacityMB, UsedBytes: usedMB}
+}
+
+func newTestMonitor(pods ...*api.Pod) *KubeletMonitor {
+	m := &KubeletMonitor{
+		metricSink: metrics.NewEntityMetricSink(),
+		podMap:     make(map[string]*api.Pod),
+	}
+	for _, pod := range pods {
+		m.podMap[util.PodKeyFunc(pod)] = pod
+	}
+	return m
+}
+
+func newStoragePod(name string, limits ...string) *api.Pod {
+	pod := &api.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name}}
+	for _, limit := range limits {
+		container := api.Container{}
+		if limit != "" {
+			container.Resources.Limits = api.ResourceList{resourceEphemeralStorage: resource.MustParse(limit)}
+		}
+		pod.Spec.Containers = append(pod.Spec.Containers, container)
+	}
+	return pod
+}
+
+// Check that the sink has exactly the expected metrics, by UID.
+func checkSink(t *testing.T, caseName string, sink *metrics.EntityMetricSink, expected map[string]float64) {
+	all := sink.GetAllMetrics()
+	if len(all) != len(expected) {
+		t.Errorf("%s: expected %d metrics, got %d", caseName, len(expected), len(all))
+	}
+	for uid, value := range expected {
+		m, err := sink.GetMetric(uid)
+		if err != nil {
+			t.Errorf("%s: expected metric %s, got error: %s", caseName, uid, err)
+			continue
+		}
+		if got := m.GetValue().(float64); got != value {
+			t.Errorf("%s: expected %f for %s, got %f", caseName, value, uid, got)
+		}
+	}
+}
+
+func TestParseNodeStorageStats(t *testing.T) {
+	uid := func(rType metrics.ResourceType, mProp metrics.MetricProp) string {
+		return metrics.GenerateEntityResourceMetricUID(task.NodeType, "node-1", rType, mProp)
+	}
+	table := []struct {
+		name      string
+		nodeStats stats.NodeStats
+		expected  map[string]float64
+	}{
+		{"no filesystem", stats.NodeStats{NodeName: "node-1"}, map[string]float64{}},
+		{
+			"no usage",
+			stats.NodeStats{NodeName: "node-1", Fs: newFsStats(bytesPtr(100), nil)},
+			map[string]float64{uid(metrics.EphemeralStorage, metrics.Capacity): 100},
+		},
+		{
+			"no image filesystem",
+			stats.NodeStats{NodeName: "node-1", Fs: newFsStats(bytesPtr(100), bytesPtr(40)),
+				Runtime: &stats.RuntimeStats{}},
+			map[string]float64{
+				uid(metrics.EphemeralStorage, metrics.Capacity): 100,
+				uid(metrics.EphemeralStorage, metrics.Used):     40,
+			},
+		},
+		{
+			"both filesystems",
+			stats.NodeStats{NodeName: "node-1", Fs: newFsStats(bytesPtr(100), bytesPtr(40)),
+				Runtime: &stats.RuntimeStats{ImageFs: newFsStats(bytesPtr(50), bytesPtr(10))}},
+			map[string]float64{
+				uid(metrics.EphemeralStorage, metrics.Capacity): 100,
+				uid(metrics.EphemeralStorage, metrics.Used):     40,
+				uid(metrics.ImageStorage, metrics.Capacity):     50,
+				uid(metrics.ImageStorage, metrics.Used):         10,
+			},
+		},
+	}
+	for _, item := range table {
+		m := newTestMonitor()
+		m.parseNodeStorageStats(item.nodeStats)
+		checkSink(t, item.name, m.metricSink, item.expected)
+	}
+}
+
+func TestParsePodStorageStats(t *testing.T) {
+	uid := func(key string, rType metrics.ResourceType, mProp metrics.MetricProp) string {
+		return metrics.GenerateEntityResourceMetricUID(task.PodType, key, rType, mProp)
+	}
+	podStats := func(name string, containers []stats.ContainerStats, volumes ...stats.VolumeStats) stats.PodStats {
+		return stats.PodStats{
+			PodRef:      stats.PodReference{Namespace: "default", Name: name},
+			Containers:  containers,
+			VolumeStats: volumes,
+		}
+	}
+	volumeStats := func(name string, usedMB uint64) stats.VolumeStats {
+		return stats.VolumeStats{Name: name, FsStats: *newFsStats(bytesPtr(500), bytesPtr(usedMB))}
+	}
+
+	limited := newStoragePod("limited", "1Mi", "2Mi")
+	limited.Spec.Volumes = []api.Volume{
+		{Name: "cache", VolumeSource: api.VolumeSource{EmptyDir: &api.EmptyDirVolumeSource{}}},
+		{Name: "shm", VolumeSource: api.VolumeSource{EmptyDir: &api.EmptyDirVolumeSource{Medium: api.StorageMediumMemory}}},
+		{Name: "data", VolumeSource: api.VolumeSource{PersistentVolumeClaim: &api.PersistentVolumeClaimVolumeSource{}}},
+	}
+	unlimited := newStoragePod("unlimited", "1Mi", "")
+
+	table := []struct {
+		name     string
+		pods     []*api.Pod
+		podStats stats.PodStats
+		nodeFs   *stats.FsStats
+		expected map[string]float64
+	}{
+		{
+			"limited pod with volumes",
+			[]*api.Pod{limited},
+			podStats("limited",
+				[]stats.ContainerStats{
+					{Rootfs: newFsStats(nil, bytesPtr(1)), Logs: newFsStats(nil, bytesPtr(2))},
+					{Rootfs: newFsStats(nil, bytesPtr(3))},
+				},
+				volumeStats("cache", 4), volumeStats("shm", 8), volumeStats("data", 16), volumeStats("unknown", 32)),
+			newFsStats(bytesPtr(100), bytesPtr(50)),
+			map[string]float64{
+				uid("default/limited", metrics.EphemeralStorage, metrics.Used):       10,
+				uid("default/limited", metrics.EphemeralStorage, metrics.Capacity):   3,
+				uid("default/limited/data", metrics.VolumeStorage, metrics.Used):     16,
+				uid("default/limited/data", metrics.VolumeStorage, metrics.Capacity): 500,
+			},
+		},
+		{
+			"unlimited pod without container stats",
+			[]*api.Pod{unlimited},
+			podStats("unlimited", []stats.ContainerStats{{}, {Rootfs: &stats.FsStats{}}}),
+			newFsStats(bytesPtr(100), bytesPtr(50)),
+			map[string]float64{
+				uid("default/unlimited", metrics.EphemeralStorage, metrics.Used):     0,
+				uid("default/unlimited", metrics.EphemeralStorage, metrics.Capacity): 100,
+			},
+		},
+		{
+			"unlimited pod without node filesystem",
+			[]*api.Pod{unlimited},
+			podStats("unlimited", []stats.ContainerStats{{Rootfs: newFsStats(nil, bytesPtr(1))}}),
+			nil,
+			map[string]float64{
+				uid("default/unlimited", metrics.EphemeralStorage, metrics.Used): 1,
+			},
+		},
+		{
+			"pod not in the task",
+			[]*api.Pod{limited},
+			podStats("other", []stats.ContainerStats{{Rootfs: newFsStats(nil, bytesPtr(1))}}),
+			newFsStats(bytesPtr(100), bytesPtr(50)),
+			map[string]float64{},
+		},
+	}
+	for _, item := range table {
+		m := newTestMonitor(item.pods...)
+		m.parsePodStorageStats([]stats.PodStats{item.podStats}, item.nodeFs)
+		checkSink(t, item.name, m.metricSink, item.expected)
+	}
+}
+
+func TestPodEphemeralStorageLimit(t *testing.T) {
+	scratch := newStoragePod("scratch", "")
+	scratch.Spec.Containers[0].Resources.Limits = api.ResourceList{api.ResourceStorageScratch: resource.MustParse("1Ki")}
+
+	table := []struct {
+		pod           *api.Pod
+		expectedLimit float64
+		expectedOk    bool
+	}{
+		{newStoragePod("no-container"), 0, false},
+		{newStoragePod("no-limit", ""), 0, false},
+		{newStoragePod("partial-limit", "1Ki", ""), 0, false},
+		{newStoragePod("limited", "1Ki", "2Ki"), 3072, true},
+		{scratch, 1024, true},
+	}
+	for _, item := range table {
+		limit, ok := podEphemeralStorageLimit(item.pod)
+		if limit != item.expectedLimit || ok != item.expectedOk {
+			t.Errorf("Pod %s: expected %f, %v, got %f, %v", item.pod.Name, item.expectedLimit, item.expectedOk,
+				limit, ok)
+		}
+	}
+}
+
+// A transport to fake kubelets, which serves the machine info and the usage of the node, except that the
+// kubelets on the slow hosts do not respond until the request is cancelled.
+type fakeKubeletTransport struct {
+	// <host : name of the node>
+	nodeNames map[string]string
+	slowHosts map[string]bool
+}
+
+func (f *fakeKubeletTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := strings.Split(req.URL.Host, ":")[0]
+	if f.slowHosts[host] {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}
+	var body interface{}
+	switch {
+	case strings.HasPrefix(req.URL.Path, specPath):
+		body = cadvisorapi.MachineInfo{CpuFrequency: 2000000}
+	case strings.HasPrefix(req.URL.Path, summaryPath):
+		usageNanoCores, usageBytes := uint64(1500000000), uint64(2048*1024)
+		body = stats.Summary{Node: stats.NodeStats{
+			NodeName: f.nodeNames[host],
+			CPU:      &stats.CPUStats{UsageNanoCores: &usageNanoCores},
+			Memory:   &stats.MemoryStats{UsageBytes: &usageBytes},
+		}}
+	}
+	recorder := httptest.NewRecorder()
+	if body == nil {
+		http.NotFound(recorder, req)
+	} else if err := json.NewEncoder(recorder).Encode(body); err != nil {
+		return nil, err
+	}
+	return recorder.Result(), nil
+}
+
+func TestKubeletMonitorTimeout(t *testing.T) {
+	newNode := func(name, ip string) *api.Node {
+		return &api.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     api.NodeStatus{Addresses: []api.NodeAddress{{Type: api.NodeInternalIP, Address: ip}}},
+		}
+	}
+	transport := &fakeKubeletTransport{
+		nodeNames: map[string]string{"10.0.0.1": "node-1", "10.0.0.2": "node-2"},
+		slowHosts: map[string]bool{"10.0.0.2": true},
+	}
+	monitor, err := NewKubeletMonitor(NewKubeletMonitorConfig(&restclient.Config{}).WithTransport(transport))
+	if err != nil {
+		t.Fatalf("Failed to create Kubelet monitor: %s", err)
+	}
+	monitor.ReceiveTask(task.NewTask().WithNodes([]*api.Node{newNode("node-1", "10.0.0.1"),
+		newNode("node-2", "10.0.0.2")}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*200)
+	defer cancel()
+	done := make(chan *metrics.EntityMetricSink)
+	go func() {
+		done <- monitor.Do(ctx)
+	}()
+	var sink *metrics.EntityMetricSink
+	select {
+	case sink = <-done:
+	case <-time.After(time.Second * 10):
+		t.Fatal("Kubelet monitor did not return after the timeout")
+	}
+
+	// The metrics of the node scraped before the timeout are kept.
+	uid := metrics.GenerateEntityStateMetricUID(task.NodeType, "node-1", metrics.CpuFrequency)
+	if m, err := sink.GetMetric(uid); err != nil {
+		t.Errorf("Expected cpu frequency of node-1, got error: %s", err)
+	} else if value := m.GetValue().(float64); value != 2000 {
+		t.Errorf("Expected cpu frequency 2000 of node-1, got %f", value)
+	}
+	uid = metrics.GenerateEntityResourceMetricUID(task.NodeType, "node-1", metrics.CPU, metrics.Used)
+	if m, err := sink.GetMetric(uid); err != nil {
+		t.Errorf("Expected cpu usage of node-1, got error: %s", err)
+	} else if value := m.GetValue().(float64); value != 1.5 {
+		t.Errorf("Expected cpu usage 1.5 of node-1, got %f", value)
+	}
+	uid = metrics.GenerateEntityStateMetricUID(task.NodeType, "node-2", metrics.CpuFrequency)
+	if _, err := sink.GetMetric(uid); err == nil {
+		t.Error("Unexpected cpu frequency of node-2, which timed out")
+	}
+
+	errs := monitor.GetDiscoveryErrors()
+	if len(errs) != 1 || errs[0].EntityName != "node-2" {
+		t.Errorf("Expected a discovery error for node-2, got %v", errs)
+	}
+}
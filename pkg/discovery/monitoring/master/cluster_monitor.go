@@ -1,6 +1,7 @@
 package master
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -24,15 +25,12 @@ type ClusterMonitor struct {
 	nodePodMap map[string][]*api.Pod
 
 	nodeResourceCapacities map[string]*nodeInfo
-
-	stopCh chan struct{}
 }
 
 func NewClusterMonitor(config *ClusterMonitorConfig) (*ClusterMonitor, error) {
 
 	return &ClusterMonitor{
 		config: config,
-		stopCh: make(chan struct{}, 1),
 	}, nil
 }
 
@@ -47,13 +45,9 @@ func (m *ClusterMonitor) ReceiveTask(task *task.Task) {
 	m.nodePodMap = util.GroupPodsByNode(task.PodList())
 }
 
-func (m *ClusterMonitor) Stop() {
-	m.stopCh <- struct{}{}
-}
-
-func (m *ClusterMonitor) Do() *metrics.EntityMetricSink {
+func (m *ClusterMonitor) Do(ctx context.Context) *metrics.EntityMetricSink {
 	glog.V(4).Infof("%s has started task.", m.GetMonitoringSource())
-	err := m.RetrieveClusterStat()
+	err := m.RetrieveClusterStat(ctx)
 	if err != nil {
 		glog.Errorf("Failed to execute task: %s", err)
 	}
@@ -61,39 +55,31 @@ func (m *ClusterMonitor) Do() *metrics.EntityMetricSink {
 	return m.sink
 }
 
-// Start to retrieve resource stats for the received list of nodes.
-func (m *ClusterMonitor) RetrieveClusterStat() error {
-	defer func() {
-		close(m.stopCh)
-	}()
-
+// Start to retrieve resource stats for the received list of nodes. Stop early with what has been retrieved if ctx is
+// done.
+func (m *ClusterMonitor) RetrieveClusterStat(ctx context.Context) error {
 	if m.nodeList == nil {
 		return errors.New("Invalid nodeList or empty nodeList. Nothing to monitor.")
 	}
-	select {
-	case <-m.stopCh:
-		return nil
-	default:
-		err := m.findClusterID()
-		if err != nil {
-			return fmt.Errorf("Failed to find cluster ID based on Kubernetes service: %v", err)
-		}
-		select {
-		case <-m.stopCh:
-			return nil
-		default:
-			m.findNodeStates()
-			m.findPodStates()
-		}
-
-		return nil
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	err := m.findClusterID()
+	if err != nil {
+		return fmt.Errorf("Failed to find cluster ID based on Kubernetes service: %v", err)
 	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	m.findNodeStates()
+	m.findPodStates()
+
+	return nil
 }
 
 func (m *ClusterMonitor) reset() {
 	m.sink = metrics.NewEntityMetricSink()
 	m.nodeResourceCapacities = make(map[string]*nodeInfo)
-	m.stopCh = make(chan struct{}, 1)
 }
 
 // ----------------------------------------------- Cluster State -------------------------------------------------
@@ -235,9 +221,7 @@ func (m *ClusterMonitor) findPodStates() {
 //	CPUProvisioned 		used
 //	memoryProvisioned 	used
 //
-// As we create one application per pod, here we also get the resource metrics for applications:
-// 	CPU			capacity
-//	memory			capacity
+// Here we also get the resource metrics of the containers inside the pod.
 func (m *ClusterMonitor) getPodResourceMetric(pod *api.Pod) ([]metrics.Metric, error) {
 	key := util.PodKeyFunc(pod)
 
@@ -305,17 +289,44 @@ func (m *ClusterMonitor) getPodResourceMetric(pod *api.Pod) ([]metrics.Metric, e
 		metrics.MemoryProvisioned, metrics.Used, memoryProvisionedUsedKiloBytes)
 	podAndAppResourceMetrics = append(podAndAppResourceMetrics, podMemoryProvisionedUsedCoreMetrics)
 
-	// Cpu and Memory capacity of an application is the same as hosting pod.
-	// application cpu capacity
-	glog.V(4).Infof("Cpu capacity of Application App-%s is %f core", key, cpuCapacityCore)
-	applicationCpuCapacityCoreMetrics := metrics.NewEntityResourceMetric(task.ApplicationType, key,
-		metrics.CPU, metrics.Capacity, cpuCapacityCore)
-	podAndAppResourceMetrics = append(podAndAppResourceMetrics, applicationCpuCapacityCoreMetrics)
-	// application memory capacity
-	glog.V(4).Infof("Memory capacity of Application App-%s is %f Kb", key, memoryCapacityKiloBytes)
-	applicationMemoryCapacityKiloBytesMetrics := metrics.NewEntityResourceMetric(task.ApplicationType, key,
-		metrics.Memory, metrics.Capacity, memoryCapacityKiloBytes)
-	podAndAppResourceMetrics = append(podAndAppResourceMetrics, applicationMemoryCapacityKiloBytesMetrics)
+	// Containers
+	for _, container := range pod.Spec.Containers {
+		podAndAppResourceMetrics = append(podAndAppResourceMetrics, m.getContainerResourceMetrics(pod, container)...)
+	}
 
 	return podAndAppResourceMetrics, nil
 }
+
+// ----------------------------------------------- Container State -------------------------------------------------
+
+// Get resource metrics of a single container:
+// 	CPU 			capacity, reservation
+// 	memory 			capacity, reservation
+//
+// Capacity is the limit of the container. If there is no limit, the container can use up to the capacity of the node.
+// Reservation is the request of the container.
+func (m *ClusterMonitor) getContainerResourceMetrics(pod *api.Pod, container api.Container) []metrics.Metric {
+	key := util.ContainerKeyFunc(pod, container.Name)
+
+	cpuCapacityCore, memoryCapacityKiloBytes := util.GetCpuAndMemoryValues(container.Resources.Limits)
+	if nodeCapacity, exist := m.nodeResourceCapacities[pod.Spec.NodeName]; exist {
+		if cpuCapacityCore == 0 {
+			cpuCapacityCore = nodeCapacity.cpuCapacity
+		}
+		if memoryCapacityKiloBytes == 0 {
+			memoryCapacityKiloBytes = nodeCapacity.memoryCapacity
+		}
+	}
+	cpuReservationCore, memoryReservationKiloBytes := util.GetCpuAndMemoryValues(container.Resources.Requests)
+
+	glog.V(4).Infof("Cpu capacity of container %s is %f core", key, cpuCapacityCore)
+	glog.V(4).Infof("Memory capacity of container %s is %f Kb", key, memoryCapacityKiloBytes)
+	glog.V(4).Infof("Cpu reservation of container %s is %f core", key, cpuReservationCore)
+	glog.V(4).Infof("Memory reservation of container %s is %f Kb", key, memoryReservationKiloBytes)
+	return []metrics.Metric{
+		metrics.NewEntityResourceMetric(task.ContainerType, key, metrics.CPU, metrics.Capacity, cpuCapacityCore),
+		metrics.NewEntityResourceMetric(task.ContainerType, key, metrics.Memory, metrics.Capacity, memoryCapacityKiloBytes),
+		metrics.NewEntityResourceMetric(task.ContainerType, key, metrics.CPU, metrics.Reservation, cpuReservationCore),
+		metrics.NewEntityResourceMetric(task.ContainerType, key, metrics.Memory, metrics.Reservation, memoryReservationKiloBytes),
+	}
+}
@@ -1,13 +1,22 @@
 package monitoring
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	api "k8s.io/client-go/pkg/api/v1"
+	restclient "k8s.io/client-go/rest"
 
 	"github.com/turbonomic/kubeturbo/pkg/discovery/metrics"
 	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/k8sconntrack"
 	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/kubelet"
 	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/master"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/prometheus"
 	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/types"
 	"github.com/turbonomic/kubeturbo/pkg/discovery/task"
 )
@@ -18,47 +27,248 @@ type MonitorWorkerConfig interface {
 }
 
 type MonitoringWorker interface {
-	Do() *metrics.EntityMetricSink
-	Stop()
+	// Do the received task and return the metrics collected. Once ctx is done, the worker stops as soon as possible
+	// and returns the partial metrics collected so far.
+	Do(ctx context.Context) *metrics.EntityMetricSink
 	ReceiveTask(task *task.Task)
 	GetMonitoringSource() types.MonitoringSource
 }
 
 type ResourceMonitoringWorker interface {
 	MonitoringWorker
-	RetrieveResourceStat() error
+	RetrieveResourceStat(ctx context.Context) error
 }
 
 type StateMonitoringWorker interface {
 	MonitoringWorker
-	RetrieveClusterStat() error
+	RetrieveClusterStat(ctx context.Context) error
 }
 
-func BuildMonitorWorker(source types.MonitoringSource, config MonitorWorkerConfig) (MonitoringWorker, error) {
-	// Build monitoring client
-	switch source {
-	case types.KubeletSource:
-		kubeletConfig, ok := config.(*kubelet.KubeletMonitorConfig)
-		if !ok {
-			return nil, errors.New("failed to build a Kubelet monitoring client as the provided config was not a KubeletMonitorConfig")
+// ErrorReportingMonitoringWorker is a monitoring worker which reports the failures of its last task, e.g. the nodes
+// it failed to scrape.
+type ErrorReportingMonitoringWorker interface {
+	MonitoringWorker
+	GetDiscoveryErrors() []*task.DiscoveryError
+}
+
+// NodeAgentMonitoringWorker is a monitoring worker which scrapes an agent running on every node, e.g. Kubelet.
+type NodeAgentMonitoringWorker interface {
+	MonitoringWorker
+	// Check if the agent on the given node can be reached with the configured port and scheme.
+	CheckNodeAgent(ctx context.Context, node *api.Node) error
+}
+
+func init() {
+	builtinSources := map[types.MonitoringSource]*monitoringSourceFactory{
+		types.KubeletSource:      {buildKubeletMonitorConfig, buildKubeletMonitor},
+		types.ClusterSource:      {buildClusterMonitorConfig, buildClusterMonitor},
+		types.K8sConntrackSource: {buildK8sConntrackMonitorConfig, buildK8sConntrackMonitor},
+		types.PrometheusSource:   {buildPrometheusMonitorConfig, buildPrometheusMonitor},
+	}
+	for source, factory := range builtinSources {
+		if err := RegisterMonitoringSource(source, factory.configFactory, factory.workerFactory); err != nil {
+			panic(err)
 		}
-		return kubelet.NewKubeletMonitor(kubeletConfig)
-	case types.ClusterSource:
-		clusterMonitorConfig, ok := config.(*master.ClusterMonitorConfig)
-		if !ok {
-			return nil, errors.New("Failed to build a cluster monitoring client as the provided config was not a ClusterMonitorConfig")
+	}
+}
+
+// Decode the config block of a monitoring source. Unknown fields are rejected, so that a typo does not go unnoticed.
+func decodeConfig(config json.RawMessage, v interface{}) error {
+	if len(config) == 0 {
+		return nil
+	}
+	if err := checkUnknownFields(config, reflect.TypeOf(v)); err != nil {
+		return err
+	}
+	return json.Unmarshal(config, v)
+}
+
+// Check that every key of a JSON object is a field of the given struct type, and so on for the nested objects.
+// Values of the wrong JSON type are left to json.Unmarshal to reject.
+func checkUnknownFields(config json.RawMessage, t reflect.Type) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(config, &object); err != nil {
+		return nil
+	}
+	fields := jsonFields(t)
+	for key, value := range object {
+		// Keys are matched case-insensitively, as json.Unmarshal does.
+		fieldType, exist := fields[strings.ToLower(key)]
+		if !exist {
+			return fmt.Errorf("unknown field %q", key)
 		}
-		return master.NewClusterMonitor(clusterMonitorConfig)
-	case types.K8sConntrackSource:
-		k8sconntrackMonitoring, ok := config.(*k8sconntrack.K8sConntrackMonitorConfig)
-		if !ok {
-			return nil, errors.New("Failed to build a k8sconntrack monitoring client as the provided config was not a K8sConntrackConfig")
+		if err := checkUnknownFields(value, fieldType); err != nil {
+			return fmt.Errorf("%s: %s", key, err)
 		}
-		return k8sconntrack.NewK8sConntrackMonitor(k8sconntrackMonitoring)
+	}
+	return nil
+}
 
-	//case "Prometheus":
-	default:
-		return nil, fmt.Errorf("Unsupported monitoring source %s", source)
+// Get the types of the exported fields of a struct type, including those of its embedded structs, by the lower case
+// of their JSON names.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			for embeddedName, embeddedType := range jsonFields(fieldType) {
+				fields[embeddedName] = embeddedType
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[strings.ToLower(name)] = field.Type
 	}
+	return fields
+}
 
+// Config block of Kubelet and K8sConntrack.
+type agentConfig struct {
+	Port  *int64 `json:"port,omitempty"`
+	Https bool   `json:"https,omitempty"`
+}
+
+// Config block of Kubelet.
+type kubeletConfig struct {
+	agentConfig
+
+	// network throughput capacity of a node in Mbit/s if the node is not annotated.
+	NetThroughputCapacity *float64 `json:"netThroughputCapacity,omitempty"`
+}
+
+func buildKubeletMonitorConfig(kubeConfig *restclient.Config, config json.RawMessage) (MonitorWorkerConfig, error) {
+	var c kubeletConfig
+	if err := decodeConfig(config, &c); err != nil {
+		return nil, err
+	}
+	kubeletConfig := kubelet.NewKubeletMonitorConfig(kubeConfig).EnableHttps(c.Https)
+	if c.Port != nil {
+		if *c.Port <= 0 {
+			return nil, fmt.Errorf("invalid port %d", *c.Port)
+		}
+		kubeletConfig.WithPort(uint(*c.Port))
+	}
+	if c.NetThroughputCapacity != nil {
+		if *c.NetThroughputCapacity <= 0 {
+			return nil, fmt.Errorf("invalid network throughput capacity %f", *c.NetThroughputCapacity)
+		}
+		kubeletConfig.WithNetThroughputCapacity(*c.NetThroughputCapacity)
+	}
+	return kubeletConfig, nil
+}
+
+func buildKubeletMonitor(config MonitorWorkerConfig) (MonitoringWorker, error) {
+	kubeletConfig, ok := config.(*kubelet.KubeletMonitorConfig)
+	if !ok {
+		return nil, errors.New("failed to build a Kubelet monitoring client as the provided config was not a KubeletMonitorConfig")
+	}
+	return kubelet.NewKubeletMonitor(kubeletConfig)
+}
+
+func buildClusterMonitorConfig(kubeConfig *restclient.Config, config json.RawMessage) (MonitorWorkerConfig, error) {
+	if err := decodeConfig(config, &struct{}{}); err != nil {
+		return nil, err
+	}
+	return master.NewClusterMonitorConfig(kubeConfig)
+}
+
+func buildClusterMonitor(config MonitorWorkerConfig) (MonitoringWorker, error) {
+	clusterMonitorConfig, ok := config.(*master.ClusterMonitorConfig)
+	if !ok {
+		return nil, errors.New("Failed to build a cluster monitoring client as the provided config was not a ClusterMonitorConfig")
+	}
+	return master.NewClusterMonitor(clusterMonitorConfig)
+}
+
+func buildK8sConntrackMonitorConfig(_ *restclient.Config, config json.RawMessage) (MonitorWorkerConfig, error) {
+	var c agentConfig
+	if err := decodeConfig(config, &c); err != nil {
+		return nil, err
+	}
+	// The source is listed in the monitoring sources of turboconfig, rather than added by default.
+	k8sConntrackConfig := k8sconntrack.NewK8sConntrackMonitorConfig().EnableExplicitly()
+	if c.Port != nil {
+		if *c.Port <= 0 {
+			return nil, fmt.Errorf("invalid port %d", *c.Port)
+		}
+		k8sConntrackConfig.WithPort(*c.Port)
+	}
+	if c.Https {
+		k8sConntrackConfig.EnableHttps()
+	}
+	return k8sConntrackConfig, nil
+}
+
+func buildK8sConntrackMonitor(config MonitorWorkerConfig) (MonitoringWorker, error) {
+	k8sconntrackMonitoring, ok := config.(*k8sconntrack.K8sConntrackMonitorConfig)
+	if !ok {
+		return nil, errors.New("Failed to build a k8sconntrack monitoring client as the provided config was not a K8sConntrackConfig")
+	}
+	return k8sconntrack.NewK8sConntrackMonitor(k8sconntrackMonitoring)
+}
+
+// Config block of Prometheus.
+type prometheusConfig struct {
+	Address string `json:"address"`
+	Window  string `json:"window,omitempty"`
+
+	// the kubelets to get the cpu frequency of the nodes whose node-exporter does not expose it from.
+	Kubelet agentConfig `json:"kubelet,omitempty"`
+}
+
+func buildPrometheusMonitorConfig(kubeConfig *restclient.Config, config json.RawMessage) (MonitorWorkerConfig, error) {
+	var c prometheusConfig
+	if err := decodeConfig(config, &c); err != nil {
+		return nil, err
+	}
+	if c.Address == "" {
+		return nil, errors.New("address is required")
+	}
+	prometheusMonitorConfig := prometheus.NewPrometheusMonitorConfig(c.Address)
+	if c.Window != "" {
+		window, err := time.ParseDuration(c.Window)
+		if err != nil || window <= 0 {
+			return nil, fmt.Errorf("invalid window %q", c.Window)
+		}
+		prometheusMonitorConfig.WithWindow(window)
+	}
+	kubeletConfig := kubelet.NewKubeletMonitorConfig(kubeConfig).EnableHttps(c.Kubelet.Https)
+	if c.Kubelet.Port != nil {
+		if *c.Kubelet.Port <= 0 {
+			return nil, fmt.Errorf("invalid kubelet port %d", *c.Kubelet.Port)
+		}
+		kubeletConfig.WithPort(uint(*c.Kubelet.Port))
+	}
+	cpuFrequencyGetter, err := kubelet.NewCpuFrequencyGetter(kubeletConfig)
+	if err != nil {
+		return nil, err
+	}
+	return prometheusMonitorConfig.WithCpuFrequencyGetter(cpuFrequencyGetter), nil
+}
+
+func buildPrometheusMonitor(config MonitorWorkerConfig) (MonitoringWorker, error) {
+	promConfig, ok := config.(*prometheus.PrometheusMonitorConfig)
+	if !ok {
+		return nil, errors.New("Failed to build a Prometheus monitoring client as the provided config was not a PrometheusMonitorConfig")
+	}
+	return prometheus.NewPrometheusMonitor(promConfig)
 }
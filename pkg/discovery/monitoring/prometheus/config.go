@@ -0,0 +1,78 @@
+package prometheus
+
+import (
+	"context"
+	"time"
+
+	api "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/types"
+)
+
+const (
+	// The time window over which the usage is averaged.
+	DefaultPrometheusQueryWindow = time.Minute * 5
+
+	defaultPrometheusQueryTimeout = time.Second * 30
+)
+
+// Config for building a Prometheus monitor worker.
+type PrometheusMonitorConfig struct {
+	// the address of the Prometheus server, e.g. http://prometheus.monitoring:9090
+	address string
+
+	// the time window of range vector selectors in the queries.
+	window time.Duration
+
+	// the timeout of a single query.
+	timeout time.Duration
+
+	// gets the cpu frequency of the nodes whose node-exporter does not expose it, if it is set.
+	cpuFrequencyGetter CpuFrequencyGetter
+}
+
+// CpuFrequencyGetter gets the cpu frequency of a node in MHz from another source, e.g. the kubelet.
+type CpuFrequencyGetter interface {
+	GetCpuFrequency(ctx context.Context, node *api.Node) (float64, error)
+}
+
+func NewPrometheusMonitorConfig(address string) *PrometheusMonitorConfig {
+	return &PrometheusMonitorConfig{
+		address: address,
+		window:  DefaultPrometheusQueryWindow,
+		timeout: defaultPrometheusQueryTimeout,
+	}
+}
+
+// Assign a different query window if it is not the default window.
+func (pmc *PrometheusMonitorConfig) WithWindow(window time.Duration) *PrometheusMonitorConfig {
+	if window > 0 {
+		pmc.window = window
+	}
+	return pmc
+}
+
+// Assign a different query timeout if it is not the default timeout.
+func (pmc *PrometheusMonitorConfig) WithTimeout(timeout time.Duration) *PrometheusMonitorConfig {
+	if timeout > 0 {
+		pmc.timeout = timeout
+	}
+	return pmc
+}
+
+// Get the cpu frequency of the nodes from the given getter if Prometheus does not have it. Without the cpu frequency,
+// the cpu capacity of a node and the cpu of its pods and containers cannot be converted to MHz.
+func (pmc *PrometheusMonitorConfig) WithCpuFrequencyGetter(getter CpuFrequencyGetter) *PrometheusMonitorConfig {
+	pmc.cpuFrequencyGetter = getter
+	return pmc
+}
+
+// Implement MonitoringWorkerConfig interface.
+func (pmc *PrometheusMonitorConfig) GetMonitorType() types.MonitorType {
+	return types.ResourceMonitor
+}
+
+// Implement MonitoringWorkerConfig interface.
+func (pmc *PrometheusMonitorConfig) GetMonitoringSource() types.MonitoringSource {
+	return types.PrometheusSource
+}
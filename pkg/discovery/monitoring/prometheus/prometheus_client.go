@@ -0,0 +1,110 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/turbonomic/kubeturbo/pkg/discovery/util/httputil"
+)
+
+const (
+	queryPath string = "/api/v1/query"
+
+	statusSuccess    string = "success"
+	resultTypeVector string = "vector"
+)
+
+// A single sample of an instant vector returned by Prometheus.
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// The response of the Prometheus HTTP API.
+type queryResponse struct {
+	Status    string    `json:"status"`
+	Data      queryData `json:"data"`
+	ErrorType string    `json:"errorType"`
+	Error     string    `json:"error"`
+}
+
+type queryData struct {
+	ResultType string        `json:"resultType"`
+	Result     []queryResult `json:"result"`
+}
+
+type queryResult struct {
+	Metric map[string]string `json:"metric"`
+	// [ <unix_time>, "<sample_value>" ]
+	Value []interface{} `json:"value"`
+}
+
+// PrometheusClient is used to send instant queries to a Prometheus server and parse the response.
+type PrometheusClient struct {
+	address *url.URL
+	client  *http.Client
+}
+
+func NewPrometheusClient(address string, timeout time.Duration) (*PrometheusClient, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Prometheus address %s: %s", address, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid Prometheus address %s: scheme and host are required", address)
+	}
+	return &PrometheusClient{
+		address: u,
+		client:  &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Execute an instant query and return the samples of the resulting vector.
+func (c *PrometheusClient) Query(ctx context.Context, query string) ([]Sample, error) {
+	requestURL := *c.address
+	requestURL.Path = strings.TrimSuffix(requestURL.Path, "/") + queryPath
+	requestURL.RawQuery = url.Values{"query": []string{query}}.Encode()
+
+	req, err := http.NewRequest("GET", requestURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	var resp queryResponse
+	if err := httputil.PostRequestAndGetValue(c.client, req, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Status != statusSuccess {
+		return nil, fmt.Errorf("query %s failed: %s %s", query, resp.ErrorType, resp.Error)
+	}
+	if resp.Data.ResultType != resultTypeVector {
+		return nil, fmt.Errorf("query %s returned %s, expected %s", query, resp.Data.ResultType, resultTypeVector)
+	}
+
+	samples := make([]Sample, 0, len(resp.Data.Result))
+	for _, r := range resp.Data.Result {
+		value, err := parseSampleValue(r.Value)
+		if err != nil {
+			return nil, fmt.Errorf("query %s returned an invalid sample %v: %s", query, r.Value, err)
+		}
+		samples = append(samples, Sample{Labels: r.Metric, Value: value})
+	}
+	return samples, nil
+}
+
+// The sample value is encoded as a string to keep NaN and Inf.
+func parseSampleValue(value []interface{}) (float64, error) {
+	if len(value) != 2 {
+		return 0, fmt.Errorf("expected a pair of timestamp and value")
+	}
+	s, ok := value[1].(string)
+	if !ok {
+		return 0, fmt.Errorf("sample value is not a string")
+	}
+	return strconv.ParseFloat(s, 64)
+}
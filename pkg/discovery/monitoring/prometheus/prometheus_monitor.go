@@ -0,0 +1,318 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	api "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/turbonomic/kubeturbo/pkg/discovery/metrics"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/types"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/task"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/util"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// Node usage comes from node-exporter. Samples are grouped by the instance label, which is the address of the node.
+	nodeCpuUsageQuery     = `sum by (instance) (rate(node_cpu_seconds_total{mode!="idle"}[%s]))`
+	nodeMemoryUsageQuery  = `avg_over_time(node_memory_MemTotal_bytes[%s]) - avg_over_time(node_memory_MemAvailable_bytes[%s])`
+	nodeCpuFrequencyQuery = `max by (instance) (node_cpu_scaling_frequency_max_hertz) or ` +
+		`max by (instance) (node_cpu_frequency_max_hertz)`
+
+	// Container usage comes from cAdvisor. Older versions of cAdvisor use pod_name and container_name labels.
+	// The image label is empty for the cgroups of pods and of the node, which are excluded.
+	containerCpuUsageQuery = `sum by (namespace, pod, pod_name, container, container_name) ` +
+		`(rate(container_cpu_usage_seconds_total{image!=""}[%s]))`
+	containerMemoryUsageQuery = `sum by (namespace, pod, pod_name, container, container_name) ` +
+		`(avg_over_time(container_memory_usage_bytes{image!=""}[%s]))`
+
+	instanceLabel     = "instance"
+	namespaceLabel    = "namespace"
+	podLabel          = "pod"
+	podLabelOld       = "pod_name"
+	containerLabel    = "container"
+	containerLabelOld = "container_name"
+	podInfraContainer = "POD"
+	hertzToMegaHertz  = 1e6
+)
+
+// PrometheusMonitor is a resource monitoring worker, which gets the resource usage from a Prometheus server,
+// which scrapes cAdvisor and node-exporter.
+type PrometheusMonitor struct {
+	client *PrometheusClient
+
+	window time.Duration
+
+	cpuFrequencyGetter CpuFrequencyGetter
+
+	nodeList []*api.Node
+
+	podList []*api.Pod
+
+	metricSink *metrics.EntityMetricSink
+}
+
+func NewPrometheusMonitor(config *PrometheusMonitorConfig) (*PrometheusMonitor, error) {
+	client, err := NewPrometheusClient(config.address, config.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create Prometheus client based on given config: %s", err)
+	}
+
+	return &PrometheusMonitor{
+		client:             client,
+		window:             config.window,
+		cpuFrequencyGetter: config.cpuFrequencyGetter,
+		metricSink:         metrics.NewEntityMetricSink(),
+	}, nil
+}
+
+func (m *PrometheusMonitor) reset() {
+	m.metricSink = metrics.NewEntityMetricSink()
+}
+
+func (m *PrometheusMonitor) GetMonitoringSource() types.MonitoringSource {
+	return types.PrometheusSource
+}
+
+func (m *PrometheusMonitor) ReceiveTask(task *task.Task) {
+	m.reset()
+
+	m.nodeList = task.NodeList()
+	m.podList = task.PodList()
+}
+
+func (m *PrometheusMonitor) Do(ctx context.Context) *metrics.EntityMetricSink {
+	glog.V(4).Infof("%s has started task.", m.GetMonitoringSource())
+	err := m.RetrieveResourceStat(ctx)
+	if err != nil {
+		glog.Errorf("Failed to execute task: %s", err)
+	}
+	glog.V(4).Infof("%s monitor has finished task.", m.GetMonitoringSource())
+	return m.metricSink
+}
+
+// Start to retrieve resource stats for the received list of nodes and pods. Stop early with what has been retrieved
+// if ctx is done.
+func (m *PrometheusMonitor) RetrieveResourceStat(ctx context.Context) error {
+	if m.nodeList == nil || len(m.nodeList) == 0 {
+		return errors.New("Invalid nodeList or empty nodeList. Finish Immediately...")
+	}
+
+	steps := []func(context.Context) error{
+		m.scrapeNodes,
+		m.scrapeContainers,
+	}
+	for _, step := range steps {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := step(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Format the window as a Prometheus duration, in seconds.
+func (m *PrometheusMonitor) rangeWindow() string {
+	return fmt.Sprintf("%ds", int64(m.window/time.Second))
+}
+
+// Retrieve cpu frequency, cpu and memory usage of the nodes.
+func (m *PrometheusMonitor) scrapeNodes(ctx context.Context) error {
+	nodesByAddress := make(map[string]*api.Node)
+	for _, node := range m.nodeList {
+		nodesByAddress[node.Name] = node
+		for _, addr := range node.Status.Addresses {
+			if addr.Address != "" {
+				nodesByAddress[addr.Address] = node
+			}
+		}
+	}
+	findNode := func(sample Sample) (*api.Node, bool) {
+		instance := sample.Labels[instanceLabel]
+		if host, _, err := net.SplitHostPort(instance); err == nil {
+			instance = host
+		}
+		node, exist := nodesByAddress[instance]
+		return node, exist
+	}
+
+	window := m.rangeWindow()
+
+	// cpu frequency. Not every node-exporter exposes it, so it is got from the fallback source for the others.
+	frequencies, err := m.client.Query(ctx, nodeCpuFrequencyQuery)
+	if err != nil {
+		glog.Warningf("Failed to get cpu frequency from Prometheus: %s", err)
+	}
+	nodesWithFrequency := make(map[string]bool)
+	for _, sample := range frequencies {
+		if node, exist := findNode(sample); exist {
+			cpuFrequencyMHz := sample.Value / hertzToMegaHertz
+			m.metricSink.AddNewMetricEntries(metrics.NewEntityStateMetric(task.NodeType, util.NodeKeyFunc(node),
+				metrics.CpuFrequency, cpuFrequencyMHz))
+			nodesWithFrequency[node.Name] = true
+		}
+	}
+	m.getMissingCpuFrequencies(ctx, nodesWithFrequency)
+
+	// cpu
+	cpuUsages, err := m.client.Query(ctx, fmt.Sprintf(nodeCpuUsageQuery, window))
+	if err != nil {
+		return fmt.Errorf("failed to get node cpu usage from Prometheus: %s", err)
+	}
+	for _, sample := range cpuUsages {
+		node, exist := findNode(sample)
+		if !exist {
+			continue
+		}
+		glog.V(4).Infof("Cpu usage of node %s is %f core", node.Name, sample.Value)
+		m.metricSink.AddNewMetricEntries(metrics.NewEntityResourceMetric(task.NodeType, util.NodeKeyFunc(node),
+			metrics.CPU, metrics.Used, sample.Value))
+	}
+
+	// memory
+	memoryUsages, err := m.client.Query(ctx, fmt.Sprintf(nodeMemoryUsageQuery, window, window))
+	if err != nil {
+		return fmt.Errorf("failed to get node memory usage from Prometheus: %s", err)
+	}
+	for _, sample := range memoryUsages {
+		node, exist := findNode(sample)
+		if !exist {
+			continue
+		}
+		memoryUsageKiloBytes := sample.Value / util.KilobytesToBytes
+		glog.V(4).Infof("Memory usage of node %s is %f Kb", node.Name, memoryUsageKiloBytes)
+		m.metricSink.AddNewMetricEntries(metrics.NewEntityResourceMetric(task.NodeType, util.NodeKeyFunc(node),
+			metrics.Memory, metrics.Used, memoryUsageKiloBytes))
+	}
+
+	return nil
+}
+
+// Get the cpu frequency of the nodes not in the given set from the fallback source, if any.
+func (m *PrometheusMonitor) getMissingCpuFrequencies(ctx context.Context, nodesWithFrequency map[string]bool) {
+	if m.cpuFrequencyGetter == nil {
+		return
+	}
+	var wg sync.WaitGroup
+	for _, node := range m.nodeList {
+		if nodesWithFrequency[node.Name] {
+			continue
+		}
+		wg.Add(1)
+		go func(node *api.Node) {
+			defer wg.Done()
+			cpuFrequencyMHz, err := m.cpuFrequencyGetter.GetCpuFrequency(ctx, node)
+			if err != nil {
+				glog.Warningf("Failed to get cpu frequency of node %s, which is not in Prometheus: %s", node.Name,
+					err)
+				return
+			}
+			glog.V(4).Infof("Cpu frequency of node %s is %f MHz from the fallback source", node.Name, cpuFrequencyMHz)
+			m.metricSink.AddNewMetricEntries(metrics.NewEntityStateMetric(task.NodeType, util.NodeKeyFunc(node),
+				metrics.CpuFrequency, cpuFrequencyMHz))
+		}(node)
+	}
+	wg.Wait()
+}
+
+type containerUsage struct {
+	podKey       string
+	containerKey string
+	cpuCore      float64
+	memoryKB     float64
+}
+
+// Retrieve cpu and memory usage of the containers, and sum them up into the usage of the pods.
+func (m *PrometheusMonitor) scrapeContainers(ctx context.Context) error {
+	pods := make(map[string]*api.Pod)
+	for _, pod := range m.podList {
+		pods[util.PodKeyFunc(pod)] = pod
+	}
+
+	usages := make(map[string]*containerUsage)
+	getUsage := func(sample Sample) (*containerUsage, bool) {
+		podName := labelValue(sample.Labels, podLabel, podLabelOld)
+		containerName := labelValue(sample.Labels, containerLabel, containerLabelOld)
+		if podName == "" || containerName == "" || containerName == podInfraContainer {
+			return nil, false
+		}
+		pod, exist := pods[sample.Labels[namespaceLabel]+"/"+podName]
+		if !exist {
+			return nil, false
+		}
+		key := util.ContainerKeyFunc(pod, containerName)
+		usage, exist := usages[key]
+		if !exist {
+			usage = &containerUsage{podKey: util.PodKeyFunc(pod), containerKey: key}
+			usages[key] = usage
+		}
+		return usage, true
+	}
+
+	window := m.rangeWindow()
+
+	cpuUsages, err := m.client.Query(ctx, fmt.Sprintf(containerCpuUsageQuery, window))
+	if err != nil {
+		return fmt.Errorf("failed to get container cpu usage from Prometheus: %s", err)
+	}
+	for _, sample := range cpuUsages {
+		if usage, ok := getUsage(sample); ok {
+			usage.cpuCore = sample.Value
+		}
+	}
+
+	memoryUsages, err := m.client.Query(ctx, fmt.Sprintf(containerMemoryUsageQuery, window))
+	if err != nil {
+		return fmt.Errorf("failed to get container memory usage from Prometheus: %s", err)
+	}
+	for _, sample := range memoryUsages {
+		if usage, ok := getUsage(sample); ok {
+			usage.memoryKB = sample.Value / util.KilobytesToBytes
+		}
+	}
+
+	podCpuUsageCore := make(map[string]float64)
+	podMemoryUsageKiloBytes := make(map[string]float64)
+	for _, usage := range usages {
+		podCpuUsageCore[usage.podKey] += usage.cpuCore
+		podMemoryUsageKiloBytes[usage.podKey] += usage.memoryKB
+
+		glog.V(4).Infof("Cpu usage of container %s is %f core", usage.containerKey, usage.cpuCore)
+		glog.V(4).Infof("Memory usage of container %s is %f Kb", usage.containerKey, usage.memoryKB)
+		// The application consumes the resources of the container it runs in.
+		m.metricSink.AddNewMetricEntries(
+			metrics.NewEntityResourceMetric(task.ContainerType, usage.containerKey, metrics.CPU, metrics.Used, usage.cpuCore),
+			metrics.NewEntityResourceMetric(task.ContainerType, usage.containerKey, metrics.Memory, metrics.Used, usage.memoryKB),
+			metrics.NewEntityResourceMetric(task.ApplicationType, usage.containerKey, metrics.CPU, metrics.Used, usage.cpuCore),
+			metrics.NewEntityResourceMetric(task.ApplicationType, usage.containerKey, metrics.Memory, metrics.Used, usage.memoryKB))
+	}
+
+	for podKey, cpuUsageCore := range podCpuUsageCore {
+		memoryUsageKiloBytes := podMemoryUsageKiloBytes[podKey]
+		glog.V(4).Infof("Cpu usage of pod %s is %f core", podKey, cpuUsageCore)
+		glog.V(4).Infof("Memory usage of pod %s is %f Kb", podKey, memoryUsageKiloBytes)
+		m.metricSink.AddNewMetricEntries(
+			metrics.NewEntityResourceMetric(task.PodType, podKey, metrics.CPU, metrics.Used, cpuUsageCore),
+			metrics.NewEntityResourceMetric(task.PodType, podKey, metrics.Memory, metrics.Used, memoryUsageKiloBytes))
+	}
+
+	return nil
+}
+
+// Return the value of the first label that is not empty.
+func labelValue(labels map[string]string, names ...string) string {
+	for _, name := range names {
+		if v := labels[name]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,226 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	api "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/turbonomic/kubeturbo/pkg/discovery/metrics"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/task"
+)
+
+// A stub of the Prometheus HTTP API. The response is chosen by the metric name in the query.
+func newStubPrometheusServer(t *testing.T, results map[string][]queryResult) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != queryPath {
+			http.NotFound(w, r)
+			return
+		}
+		query := r.URL.Query().Get("query")
+		resp := queryResponse{Status: statusSuccess, Data: queryData{ResultType: resultTypeVector, Result: []queryResult{}}}
+		for metricName, result := range results {
+			if strings.Contains(query, metricName) {
+				resp.Data.Result = result
+			}
+		}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			t.Errorf("Failed to encode response: %s", err)
+		}
+	}))
+}
+
+func sample(value string, labels ...string) queryResult {
+	metric := make(map[string]string)
+	for i := 0; i+1 < len(labels); i += 2 {
+		metric[labels[i]] = labels[i+1]
+	}
+	return queryResult{Metric: metric, Value: []interface{}{1500000000.0, value}}
+}
+
+func newNode(name, ip string) *api.Node {
+	return &api.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: api.NodeStatus{
+			Addresses: []api.NodeAddress{{Type: api.NodeInternalIP, Address: ip}},
+		},
+	}
+}
+
+func newPod(namespace, name, nodeName string) *api.Pod {
+	return &api.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       api.PodSpec{NodeName: nodeName},
+	}
+}
+
+func TestPrometheusMonitor(t *testing.T) {
+	server := newStubPrometheusServer(t, map[string][]queryResult{
+		"node_cpu_scaling_frequency_max_hertz": {sample("2400000000", "instance", "10.0.0.1:9100")},
+		"node_cpu_seconds_total":               {sample("1.5", "instance", "10.0.0.1:9100"), sample("3", "instance", "10.0.0.9:9100")},
+		"node_memory_MemTotal_bytes":           {sample("2048000", "instance", "10.0.0.1:9100")},
+		"container_cpu_usage_seconds_total": {
+			sample("0.25", "namespace", "default", "pod", "web", "container", "nginx"),
+			sample("0.5", "namespace", "default", "pod_name", "web", "container_name", "sidecar"),
+			sample("0.1", "namespace", "default", "pod", "web", "container", "POD"),
+			sample("1", "namespace", "default", "pod", "other", "container", "app"),
+		},
+		"container_memory_usage_bytes": {
+			sample("1024", "namespace", "default", "pod", "web", "container", "nginx"),
+			sample("3072", "namespace", "default", "pod", "web", "container", "sidecar"),
+		},
+	})
+	defer server.Close()
+
+	monitor, err := NewPrometheusMonitor(NewPrometheusMonitorConfig(server.URL))
+	if err != nil {
+		t.Fatalf("Failed to create Prometheus monitor: %s", err)
+	}
+	monitor.ReceiveTask(task.NewTask().
+		WithNodes([]*api.Node{newNode("node-1", "10.0.0.1")}).
+		WithPods([]*api.Pod{newPod("default", "web", "node-1")}))
+	sink := monitor.Do(context.Background())
+
+	table := []struct {
+		uid      string
+		expected float64
+	}{
+		{metrics.GenerateEntityStateMetricUID(task.NodeType, "node-1", metrics.CpuFrequency), 2400},
+		{metrics.GenerateEntityResourceMetricUID(task.NodeType, "node-1", metrics.CPU, metrics.Used), 1.5},
+		{metrics.GenerateEntityResourceMetricUID(task.NodeType, "node-1", metrics.Memory, metrics.Used), 2000},
+		{metrics.GenerateEntityResourceMetricUID(task.PodType, "default/web", metrics.CPU, metrics.Used), 0.75},
+		{metrics.GenerateEntityResourceMetricUID(task.PodType, "default/web", metrics.Memory, metrics.Used), 4},
+		{metrics.GenerateEntityResourceMetricUID(task.ContainerType, "default/web/nginx", metrics.CPU, metrics.Used), 0.25},
+		{metrics.GenerateEntityResourceMetricUID(task.ContainerType, "default/web/sidecar", metrics.Memory, metrics.Used), 3},
+		{metrics.GenerateEntityResourceMetricUID(task.ApplicationType, "default/web/sidecar", metrics.CPU, metrics.Used), 0.5},
+	}
+	for _, item := range table {
+		m, err := sink.GetMetric(item.uid)
+		if err != nil {
+			t.Errorf("Expected metric %s, got error: %s", item.uid, err)
+			continue
+		}
+		if value := m.GetValue().(float64); math.Abs(value-item.expected) > 1e-9 {
+			t.Errorf("Expected %f for %s, got %f", item.expected, item.uid, value)
+		}
+	}
+
+	// Pods and nodes not in the task, and the infra container are ignored.
+	notExpected := []string{
+		metrics.GenerateEntityResourceMetricUID(task.PodType, "default/other", metrics.CPU, metrics.Used),
+		metrics.GenerateEntityResourceMetricUID(task.ContainerType, "default/web/POD", metrics.CPU, metrics.Used),
+	}
+	for _, uid := range notExpected {
+		if _, err := sink.GetMetric(uid); err == nil {
+			t.Errorf("Unexpected metric %s", uid)
+		}
+	}
+}
+
+// A CpuFrequencyGetter with the cpu frequency of some nodes.
+type fakeCpuFrequencyGetter map[string]float64
+
+func (g fakeCpuFrequencyGetter) GetCpuFrequency(_ context.Context, node *api.Node) (float64, error) {
+	if frequency, exist := g[node.Name]; exist {
+		return frequency, nil
+	}
+	return 0, fmt.Errorf("no cpu frequency of node %s", node.Name)
+}
+
+func TestPrometheusMonitorCpuFrequencyFallback(t *testing.T) {
+	server := newStubPrometheusServer(t, map[string][]queryResult{
+		"node_cpu_scaling_frequency_max_hertz": {sample("2400000000", "instance", "10.0.0.1:9100")},
+	})
+	defer server.Close()
+
+	getter := fakeCpuFrequencyGetter{"node-1": 1000, "node-2": 3000}
+	monitor, err := NewPrometheusMonitor(NewPrometheusMonitorConfig(server.URL).WithCpuFrequencyGetter(getter))
+	if err != nil {
+		t.Fatalf("Failed to create Prometheus monitor: %s", err)
+	}
+	monitor.ReceiveTask(task.NewTask().WithNodes([]*api.Node{
+		newNode("node-1", "10.0.0.1"), newNode("node-2", "10.0.0.2"), newNode("node-3", "10.0.0.3"),
+	}))
+	sink := monitor.Do(context.Background())
+
+	table := []struct {
+		node     string
+		expected float64
+		exist    bool
+	}{
+		// Prometheus takes precedence over the fallback.
+		{"node-1", 2400, true},
+		{"node-2", 3000, true},
+		{"node-3", 0, false},
+	}
+	for _, item := range table {
+		uid := metrics.GenerateEntityStateMetricUID(task.NodeType, item.node, metrics.CpuFrequency)
+		m, err := sink.GetMetric(uid)
+		if !item.exist {
+			if err == nil {
+				t.Errorf("Unexpected cpu frequency of %s", item.node)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Expected cpu frequency of %s, got error: %s", item.node, err)
+			continue
+		}
+		if value := m.GetValue().(float64); value != item.expected {
+			t.Errorf("Expected cpu frequency %f of %s, got %f", item.expected, item.node, value)
+		}
+	}
+}
+
+func TestPrometheusMonitorCancelled(t *testing.T) {
+	queried := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queried = true
+	}))
+	defer server.Close()
+
+	monitor, err := NewPrometheusMonitor(NewPrometheusMonitorConfig(server.URL))
+	if err != nil {
+		t.Fatalf("Failed to create Prometheus monitor: %s", err)
+	}
+	monitor.ReceiveTask(task.NewTask().WithNodes([]*api.Node{newNode("node-1", "10.0.0.1")}))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if sink := monitor.Do(ctx); sink == nil {
+		t.Error("Expected an empty sink from a cancelled task, got nil")
+	}
+	if queried {
+		t.Error("Expected no query after the task is cancelled")
+	}
+}
+
+func TestPrometheusClientQueryError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"parse error"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewPrometheusClient(server.URL, defaultPrometheusQueryTimeout)
+	if err != nil {
+		t.Fatalf("Failed to create Prometheus client: %s", err)
+	}
+	if _, err := client.Query(context.Background(), "up"); err == nil {
+		t.Error("Expected an error for a failed query")
+	}
+}
+
+func TestNewPrometheusClientInvalidAddress(t *testing.T) {
+	table := []string{"", "prometheus:9090", "://"}
+	for _, address := range table {
+		if _, err := NewPrometheusClient(address, defaultPrometheusQueryTimeout); err == nil {
+			t.Errorf("Expected an error for address %q", address)
+		}
+	}
+}
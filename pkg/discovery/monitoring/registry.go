@@ -0,0 +1,201 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	restclient "k8s.io/client-go/rest"
+
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/types"
+)
+
+// Build the config of a monitoring worker from the config block of the monitoring source in turboconfig.
+// The config block is nil if it is not specified.
+type MonitorWorkerConfigFactory func(kubeConfig *restclient.Config, config json.RawMessage) (MonitorWorkerConfig, error)
+
+// Build a monitoring worker from its config.
+type MonitorWorkerFactory func(config MonitorWorkerConfig) (MonitoringWorker, error)
+
+type monitoringSourceFactory struct {
+	configFactory MonitorWorkerConfigFactory
+	workerFactory MonitorWorkerFactory
+}
+
+var (
+	registryLock sync.RWMutex
+	registry     = make(map[types.MonitoringSource]*monitoringSourceFactory)
+)
+
+// Register a monitoring source. A monitoring source maintained outside of kubeturbo can register itself in the init()
+// of its package, and be enabled in turboconfig once the package is imported.
+func RegisterMonitoringSource(source types.MonitoringSource, configFactory MonitorWorkerConfigFactory,
+	workerFactory MonitorWorkerFactory) error {
+	if source == "" {
+		return errors.New("monitoring source name is empty")
+	}
+	if configFactory == nil || workerFactory == nil {
+		return fmt.Errorf("factories of monitoring source %s must not be nil", source)
+	}
+
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if _, exist := registry[source]; exist {
+		return fmt.Errorf("monitoring source %s has already been registered", source)
+	}
+	registry[source] = &monitoringSourceFactory{
+		configFactory: configFactory,
+		workerFactory: workerFactory,
+	}
+	return nil
+}
+
+// Return the names of all the registered monitoring sources in alphabetical order.
+func RegisteredMonitoringSources() []types.MonitoringSource {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	return registeredSourcesLocked()
+}
+
+func getMonitoringSourceFactory(source types.MonitoringSource) (*monitoringSourceFactory, error) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	factory, exist := registry[source]
+	if !exist {
+		return nil, fmt.Errorf("unknown monitoring source %q, registered sources are %v", source,
+			registeredSourcesLocked())
+	}
+	return factory, nil
+}
+
+func registeredSourcesLocked() []types.MonitoringSource {
+	var sources []types.MonitoringSource
+	for source := range registry {
+		sources = append(sources, source)
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i] < sources[j] })
+	return sources
+}
+
+// Build a monitoring worker of the given source.
+func BuildMonitorWorker(source types.MonitoringSource, config MonitorWorkerConfig) (MonitoringWorker, error) {
+	factory, err := getMonitoringSourceFactory(source)
+	if err != nil {
+		return nil, err
+	}
+	return factory.workerFactory(config)
+}
+
+// Build the config of a monitoring worker of the given source from its config block.
+func BuildMonitorWorkerConfig(source types.MonitoringSource, kubeConfig *restclient.Config,
+	config json.RawMessage) (MonitorWorkerConfig, error) {
+	factory, err := getMonitoringSourceFactory(source)
+	if err != nil {
+		return nil, err
+	}
+	workerConfig, err := factory.configFactory(kubeConfig, config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config of monitoring source %s: %s", source, err)
+	}
+	return workerConfig, nil
+}
+
+// MonitoringSourceSpec is the spec of one monitoring source in turboconfig, e.g.
+//
+//	{"source": "Prometheus", "timeout": "2m", "config": {"address": "http://prometheus:9090"}}
+type MonitoringSourceSpec struct {
+	Source types.MonitoringSource `json:"source"`
+
+	// A source is enabled unless it is explicitly disabled.
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// The max time a monitoring worker of the source may take to finish one task, e.g. "30s".
+	Timeout string `json:"timeout,omitempty"`
+
+	// The config block of the source. Its format is defined by the source.
+	Config json.RawMessage `json:"config,omitempty"`
+}
+
+func (s *MonitoringSourceSpec) IsEnabled() bool {
+	return s.Enabled == nil || *s.Enabled
+}
+
+// Get the timeout of the source. Zero means the timeout is not specified.
+func (s *MonitoringSourceSpec) GetTimeout() (time.Duration, error) {
+	if s.Timeout == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(s.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q of monitoring source %s: %s", s.Timeout, s.Source, err)
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q of monitoring source %s: must be positive", s.Timeout, s.Source)
+	}
+	return timeout, nil
+}
+
+// Validate the monitoring source specs without building any monitoring worker config.
+// Every source must be registered and specified at most once. The cluster source cannot be disabled,
+// as the topology of the cluster comes from it.
+func ValidateMonitoringSourceSpecs(specs []MonitoringSourceSpec) error {
+	seen := make(map[types.MonitoringSource]bool)
+	for i := range specs {
+		spec := &specs[i]
+		if _, err := getMonitoringSourceFactory(spec.Source); err != nil {
+			return err
+		}
+		if seen[spec.Source] {
+			return fmt.Errorf("monitoring source %s is specified more than once", spec.Source)
+		}
+		seen[spec.Source] = true
+		if spec.Source == types.ClusterSource && !spec.IsEnabled() {
+			return fmt.Errorf("monitoring source %s cannot be disabled", types.ClusterSource)
+		}
+		if _, err := spec.GetTimeout(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Build the monitoring worker configs of all the enabled sources in the specs, together with the timeouts that are
+// specified. The cluster source is always enabled, even if it is not in the specs.
+func BuildMonitorWorkerConfigs(specs []MonitoringSourceSpec, kubeConfig *restclient.Config) (
+	[]MonitorWorkerConfig, map[types.MonitoringSource]time.Duration, error) {
+	if err := ValidateMonitoringSourceSpecs(specs); err != nil {
+		return nil, nil, err
+	}
+
+	var configs []MonitorWorkerConfig
+	timeouts := make(map[types.MonitoringSource]time.Duration)
+	hasClusterSource := false
+	for i := range specs {
+		spec := &specs[i]
+		if !spec.IsEnabled() {
+			continue
+		}
+		if spec.Source == types.ClusterSource {
+			hasClusterSource = true
+		}
+		config, err := BuildMonitorWorkerConfig(spec.Source, kubeConfig, spec.Config)
+		if err != nil {
+			return nil, nil, err
+		}
+		configs = append(configs, config)
+		if timeout, _ := spec.GetTimeout(); timeout > 0 {
+			timeouts[spec.Source] = timeout
+		}
+	}
+	if !hasClusterSource {
+		config, err := BuildMonitorWorkerConfig(types.ClusterSource, kubeConfig, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		configs = append(configs, config)
+	}
+	return configs, timeouts, nil
+}
@@ -0,0 +1,119 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"testing"
+
+	restclient "k8s.io/client-go/rest"
+
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/types"
+)
+
+func TestValidateMonitoringSourceSpecs(t *testing.T) {
+	disabled := false
+	table := []struct {
+		specs     []MonitoringSourceSpec
+		expectErr bool
+	}{
+		{
+			specs:     nil,
+			expectErr: false,
+		},
+		{
+			specs: []MonitoringSourceSpec{
+				{Source: types.KubeletSource, Timeout: "30s"},
+				{Source: types.K8sConntrackSource, Enabled: &disabled},
+			},
+			expectErr: false,
+		},
+		{
+			specs:     []MonitoringSourceSpec{{Source: "Unknown"}},
+			expectErr: true,
+		},
+		{
+			specs:     []MonitoringSourceSpec{{Source: types.KubeletSource}, {Source: types.KubeletSource}},
+			expectErr: true,
+		},
+		{
+			specs:     []MonitoringSourceSpec{{Source: types.ClusterSource, Enabled: &disabled}},
+			expectErr: true,
+		},
+		{
+			specs:     []MonitoringSourceSpec{{Source: types.KubeletSource, Timeout: "soon"}},
+			expectErr: true,
+		},
+		{
+			specs:     []MonitoringSourceSpec{{Source: types.KubeletSource, Timeout: "-1s"}},
+			expectErr: true,
+		},
+	}
+	for i, item := range table {
+		err := ValidateMonitoringSourceSpecs(item.specs)
+		if item.expectErr != (err != nil) {
+			t.Errorf("Test case %d failed: expect error %t, got %v", i, item.expectErr, err)
+		}
+	}
+}
+
+func TestBuildMonitorWorkerConfig(t *testing.T) {
+	table := []struct {
+		source    types.MonitoringSource
+		config    string
+		expectErr bool
+	}{
+		{types.KubeletSource, `{"port": 10250, "https": true, "netThroughputCapacity": 10000}`, false},
+		{types.KubeletSource, `{"netThroughputCapacity": 0}`, true},
+		{types.KubeletSource, `{"port": 10250, "netThroughputCapcity": 10000}`, true},
+		{types.K8sConntrackSource, ``, false},
+		{types.K8sConntrackSource, `{"port": 3333, "https": true}`, false},
+		{types.K8sConntrackSource, `{"port": 0}`, true},
+		{types.K8sConntrackSource, `{"prot": 3333}`, true},
+		{types.PrometheusSource, `{"address": "http://prometheus:9090", "window": "10m"}`, false},
+		{types.PrometheusSource, `{}`, true},
+		{types.PrometheusSource, `{"address": "http://prometheus:9090", "window": "long"}`, true},
+		{types.PrometheusSource, `{"address": "http://prometheus:9090", "kubelet": {"port": 10250, "https": true}}`, false},
+		{types.PrometheusSource, `{"address": "http://prometheus:9090", "kubelet": {"port": -1}}`, true},
+		{types.PrometheusSource, `{"address": "http://prometheus:9090", "kubelet": {"prot": 10250}}`, true},
+		{types.PrometheusSource, `{"Address": "http://prometheus:9090", "Window": "10m"}`, false},
+		{types.PrometheusSource, `{"address": "http://prometheus:9090", "window": 10}`, true},
+		{"Unknown", ``, true},
+	}
+	for i, item := range table {
+		config, err := BuildMonitorWorkerConfig(item.source, &restclient.Config{}, json.RawMessage(item.config))
+		if item.expectErr != (err != nil) {
+			t.Errorf("Test case %d failed: expect error %t, got %v", i, item.expectErr, err)
+			continue
+		}
+		if err == nil && config.GetMonitoringSource() != item.source {
+			t.Errorf("Test case %d failed: expect source %s, got %s", i, item.source, config.GetMonitoringSource())
+		}
+	}
+}
+
+func TestRegisterMonitoringSource(t *testing.T) {
+	if err := RegisterMonitoringSource(types.KubeletSource, buildKubeletMonitorConfig, buildKubeletMonitor); err == nil {
+		t.Error("Expected an error when registering a source twice")
+	}
+	if err := RegisterMonitoringSource("", buildKubeletMonitorConfig, buildKubeletMonitor); err == nil {
+		t.Error("Expected an error when registering a source without name")
+	}
+	if err := RegisterMonitoringSource("InHouse", nil, buildKubeletMonitor); err == nil {
+		t.Error("Expected an error when registering a source without config factory")
+	}
+
+	if err := RegisterMonitoringSource("InHouse", buildPrometheusMonitorConfig, buildPrometheusMonitor); err != nil {
+		t.Fatalf("Failed to register a new source: %s", err)
+	}
+	found := false
+	for _, source := range RegisteredMonitoringSources() {
+		if source == "InHouse" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Registered source is not listed")
+	}
+	if _, err := BuildMonitorWorkerConfig("InHouse", &restclient.Config{}, json.RawMessage(`{"address": "http://localhost:9090"}`)); err != nil {
+		t.Errorf("Failed to build config of the registered source: %s", err)
+	}
+}
@@ -0,0 +1,147 @@
+package sampling
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/turbonomic/kubeturbo/pkg/cluster"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/metrics"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/types"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/task"
+
+	"github.com/golang/glog"
+)
+
+const (
+	DefaultSamplingWindow  = time.Minute * 10
+	DefaultUsagePercentile = 95.0
+)
+
+type MetricSamplerConfig struct {
+	clusterScraper *cluster.ClusterScraper
+
+	// configs of the resource monitoring sources to sample.
+	monitoringConfigs []monitoring.MonitorWorkerConfig
+
+	// the interval between two samples.
+	interval time.Duration
+
+	// samples older than the window are dropped.
+	window time.Duration
+}
+
+func NewMetricSamplerConfig(clusterScraper *cluster.ClusterScraper, monitoringConfigs []monitoring.MonitorWorkerConfig,
+	interval time.Duration) *MetricSamplerConfig {
+	return &MetricSamplerConfig{
+		clusterScraper:    clusterScraper,
+		monitoringConfigs: monitoringConfigs,
+		interval:          interval,
+		window:            DefaultSamplingWindow,
+	}
+}
+
+// Assign a different window if it is not the default window.
+func (c *MetricSamplerConfig) WithWindow(window time.Duration) *MetricSamplerConfig {
+	if window > 0 {
+		c.window = window
+	}
+	return c
+}
+
+// MetricSampler scrapes the resource monitoring sources in the background between discoveries, and keeps the used
+// values in a rolling window. Discovery uses the history to report the peak and percentile of the usage.
+type MetricSampler struct {
+	config *MetricSamplerConfig
+
+	monitoringWorkers []monitoring.MonitoringWorker
+
+	history *metrics.MetricHistory
+}
+
+func NewMetricSampler(config *MetricSamplerConfig) (*MetricSampler, error) {
+	if config.interval <= 0 {
+		return nil, fmt.Errorf("invalid sampling interval %s", config.interval)
+	}
+
+	// Only resource monitoring sources provide used values.
+	var monitoringWorkers []monitoring.MonitoringWorker
+	for _, mc := range config.monitoringConfigs {
+		if mc.GetMonitorType() != types.ResourceMonitor {
+			continue
+		}
+		w, err := monitoring.BuildMonitorWorker(mc.GetMonitoringSource(), mc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build %s monitoring worker for sampling: %s", mc.GetMonitoringSource(), err)
+		}
+		monitoringWorkers = append(monitoringWorkers, w)
+	}
+	if len(monitoringWorkers) == 0 {
+		return nil, fmt.Errorf("no resource monitoring source to sample")
+	}
+
+	return &MetricSampler{
+		config:            config,
+		monitoringWorkers: monitoringWorkers,
+		history:           metrics.NewMetricHistory(config.window),
+	}, nil
+}
+
+// The history of the sampled used values.
+func (s *MetricSampler) GetHistory() *metrics.MetricHistory {
+	return s.history
+}
+
+// Sample periodically until stopCh is closed. An ongoing sample is cancelled once stopCh is closed.
+func (s *MetricSampler) Run(stopCh <-chan struct{}) {
+	glog.V(2).Infof("Start sampling every %s with a window of %s.", s.config.interval, s.config.window)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(s.config.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			glog.V(2).Infof("Stop sampling.")
+			return
+		case <-ticker.C:
+			if err := s.sample(ctx); err != nil {
+				glog.Errorf("Failed to sample metrics: %s", err)
+			}
+		}
+	}
+}
+
+// Take one sample of all the running pods in the cluster. A sample must finish within the sampling interval, otherwise
+// the metrics scraped so far are kept as a partial sample.
+func (s *MetricSampler) sample(ctx context.Context) error {
+	snapshot, err := s.config.clusterScraper.TakeSnapshot()
+	if err != nil {
+		return err
+	}
+	currTask := task.NewTask().WithNodes(snapshot.Nodes).WithPods(snapshot.GetRunningPodsOnNodes(snapshot.Nodes))
+
+	timestamp := time.Now()
+	sampleCtx, cancel := context.WithTimeout(ctx, s.config.interval)
+	defer cancel()
+	for _, w := range s.monitoringWorkers {
+		w.ReceiveTask(currTask)
+		sink := w.Do(sampleCtx)
+		s.history.AddSamples(sink, timestamp)
+	}
+	if sampleCtx.Err() == context.DeadlineExceeded {
+		glog.Warningf("Sampling did not finish within the sampling interval %s. The sample is partial.",
+			s.config.interval)
+	}
+	glog.V(3).Infof("Finished sampling at %s.", timestamp)
+	return nil
+}
@@ -0,0 +1,65 @@
+package sampling
+
+import (
+	"testing"
+	"time"
+
+	restclient "k8s.io/client-go/rest"
+
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/master"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/prometheus"
+)
+
+func TestMetricSamplerConfigWithWindow(t *testing.T) {
+	table := []struct {
+		window   time.Duration
+		expected time.Duration
+	}{
+		{0, DefaultSamplingWindow},
+		{-time.Minute, DefaultSamplingWindow},
+		{time.Minute * 30, time.Minute * 30},
+	}
+	for i, item := range table {
+		config := NewMetricSamplerConfig(nil, nil, time.Minute).WithWindow(item.window)
+		if config.window != item.expected {
+			t.Errorf("Test case %d failed: expected window %s, got %s", i, item.expected, config.window)
+		}
+	}
+}
+
+func TestNewMetricSampler(t *testing.T) {
+	clusterMonitorConfig, err := master.NewClusterMonitorConfig(&restclient.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create cluster monitor config: %s", err)
+	}
+	prometheusMonitorConfig := prometheus.NewPrometheusMonitorConfig("http://prometheus:9090")
+
+	table := []struct {
+		monitoringConfigs []monitoring.MonitorWorkerConfig
+		interval          time.Duration
+		expectErr         bool
+	}{
+		{[]monitoring.MonitorWorkerConfig{prometheusMonitorConfig, clusterMonitorConfig}, time.Minute, false},
+		{[]monitoring.MonitorWorkerConfig{prometheusMonitorConfig}, 0, true},
+		// Only the resource monitoring sources are sampled.
+		{[]monitoring.MonitorWorkerConfig{clusterMonitorConfig}, time.Minute, true},
+		{nil, time.Minute, true},
+	}
+	for i, item := range table {
+		sampler, err := NewMetricSampler(NewMetricSamplerConfig(nil, item.monitoringConfigs, item.interval))
+		if item.expectErr != (err != nil) {
+			t.Errorf("Test case %d failed: expect error %t, got %v", i, item.expectErr, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if len(sampler.monitoringWorkers) != 1 {
+			t.Errorf("Test case %d failed: expected 1 monitoring worker, got %d", i, len(sampler.monitoringWorkers))
+		}
+		if sampler.GetHistory() == nil {
+			t.Errorf("Test case %d failed: no history", i)
+		}
+	}
+}
@@ -223,8 +223,11 @@ func (s *StitchingManager) GenerateReconciliationMetaData() (*proto.EntityDTO_Re
 	default:
 		return nil, fmt.Errorf("Stitching property type %s is not supported.", s.stitchingPropertyType)
 	}
+	// The vStorage commodities of the node are keyed by the filesystems seen by the pods, which the VM discovered
+	// by the hypervisor does not sell.
 	replacementEntityMetaDataBuilder.PatchSelling(proto.CommodityDTO_CLUSTER).
-		PatchSelling(proto.CommodityDTO_VMPM_ACCESS)
+		PatchSelling(proto.CommodityDTO_VMPM_ACCESS).
+		PatchSelling(proto.CommodityDTO_VSTORAGE)
 	meta := replacementEntityMetaDataBuilder.Build()
 	return meta, nil
 }
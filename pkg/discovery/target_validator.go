@@ -0,0 +1,160 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	api "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/turbonomic/kubeturbo/pkg/cluster"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/k8sconntrack"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/types"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/util"
+
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// The max number of nodes on which the monitoring agents are checked during validation.
+	validationNodeSampleSize = 3
+
+	// The max time to wait for the monitoring agent on a node to respond during validation.
+	nodeAgentCheckTimeout = time.Second * 30
+)
+
+// targetValidator checks that the Kubernetes cluster can be discovered: the API server is reachable and accepts the
+// credentials of kubeturbo, and the monitoring agents running on the nodes can be scraped.
+type targetValidator struct {
+	clusterScraper *cluster.ClusterScraper
+
+	monitoringConfigs []monitoring.MonitorWorkerConfig
+}
+
+func newTargetValidator(clusterScraper *cluster.ClusterScraper, monitoringConfigs []monitoring.MonitorWorkerConfig) *targetValidator {
+	return &targetValidator{
+		clusterScraper:    clusterScraper,
+		monitoringConfigs: monitoringConfigs,
+	}
+}
+
+// Validate the target and return an ErrorDTO for every problem found.
+func (v *targetValidator) Validate() []*proto.ErrorDTO {
+	if _, err := v.clusterScraper.Discovery().ServerVersion(); err != nil {
+		return []*proto.ErrorDTO{newErrorDTO(proto.ErrorDTO_CRITICAL, fmt.Sprintf("Cannot reach the Kubernetes API "+
+			"server: %s. Check the master address in the kubeconfig of kubeturbo, and that the API server can be "+
+			"reached from the kubeturbo pod.", err))}
+	}
+
+	// Nodes are listed from the API server rather than the local cache, so that the credentials are verified.
+	nodeList, err := v.clusterScraper.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		var description string
+		switch {
+		case apierrors.IsUnauthorized(err):
+			description = fmt.Sprintf("The Kubernetes API server rejected the credentials of kubeturbo: %s. Check the "+
+				"token or certificates in the kubeconfig, or the service account of kubeturbo.", err)
+		case apierrors.IsForbidden(err):
+			description = fmt.Sprintf("Kubeturbo is not allowed to list nodes: %s. Grant the service account of "+
+				"kubeturbo access to the cluster, e.g. bind it to the cluster-admin role.", err)
+		default:
+			description = fmt.Sprintf("Failed to list nodes from the Kubernetes API server: %s.", err)
+		}
+		return []*proto.ErrorDTO{newErrorDTO(proto.ErrorDTO_CRITICAL, description)}
+	}
+
+	nodes := sampleReadyNodes(nodeList.Items, validationNodeSampleSize)
+	if len(nodes) == 0 {
+		return []*proto.ErrorDTO{newErrorDTO(proto.ErrorDTO_WARNING, "No ready node is found in the cluster. "+
+			"The monitoring agents on the nodes cannot be checked.")}
+	}
+
+	var errorDTOs []*proto.ErrorDTO
+	for _, config := range v.monitoringConfigs {
+		if !shouldCheckNodeAgents(config) {
+			glog.V(3).Infof("Skip checking the agents of %s, which is not enabled explicitly.",
+				config.GetMonitoringSource())
+			continue
+		}
+		w, err := monitoring.BuildMonitorWorker(config.GetMonitoringSource(), config)
+		if err != nil {
+			errorDTOs = append(errorDTOs, newErrorDTO(proto.ErrorDTO_CRITICAL, fmt.Sprintf("Failed to build the %s "+
+				"monitoring worker: %s. Check the config of the monitoring source.", config.GetMonitoringSource(), err)))
+			continue
+		}
+		agentWorker, ok := w.(monitoring.NodeAgentMonitoringWorker)
+		if !ok {
+			continue
+		}
+		errorDTOs = append(errorDTOs, checkNodeAgents(agentWorker, config.GetMonitorType(), nodes)...)
+	}
+	return errorDTOs
+}
+
+// K8sConntrack is added by default, but few clusters run it, so its agents are only checked if it is enabled
+// explicitly in the monitoring sources of turboconfig.
+func shouldCheckNodeAgents(config monitoring.MonitorWorkerConfig) bool {
+	if k8sConntrackConfig, ok := config.(*k8sconntrack.K8sConntrackMonitorConfig); ok {
+		return k8sConntrackConfig.IsExplicitlyEnabled()
+	}
+	return true
+}
+
+// Check the agent of the monitoring worker on each of the nodes. The failures are warnings, unless the agent of a
+// resource monitoring source cannot be reached on any node, in which case there will be no resource usage at all.
+// K8sConntrack only provides the optional network metrics, so its failures are always warnings.
+func checkNodeAgents(w monitoring.NodeAgentMonitoringWorker, monitorType types.MonitorType,
+	nodes []*api.Node) []*proto.ErrorDTO {
+	source := w.GetMonitoringSource()
+	var errorDTOs []*proto.ErrorDTO
+	for _, node := range nodes {
+		ctx, cancel := context.WithTimeout(context.Background(), nodeAgentCheckTimeout)
+		err := w.CheckNodeAgent(ctx, node)
+		cancel()
+		if err != nil {
+			glog.V(2).Infof("Failed to check %s on node %s: %s", source, node.Name, err)
+			errorDTO := newErrorDTO(proto.ErrorDTO_WARNING, fmt.Sprintf("Cannot reach %s on node %s: %s. Check that "+
+				"%s is running on the node, and the port and https settings of the %s monitoring source.",
+				source, node.Name, err, source, source))
+			uuid := string(node.UID)
+			entityType := proto.EntityDTO_VIRTUAL_MACHINE.String()
+			errorDTO.EntityUuid = &uuid
+			errorDTO.EntityType = &entityType
+			errorDTOs = append(errorDTOs, errorDTO)
+		}
+	}
+	if monitorType == types.ResourceMonitor && source != types.K8sConntrackSource && len(errorDTOs) == len(nodes) {
+		for _, errorDTO := range errorDTOs {
+			errorDTO.Severity = proto.ErrorDTO_CRITICAL.Enum()
+		}
+	}
+	return errorDTOs
+}
+
+// Return up to the given number of ready nodes, in the order of their names.
+func sampleReadyNodes(nodes []api.Node, size int) []*api.Node {
+	var readyNodes []*api.Node
+	for i := range nodes {
+		if util.NodeIsReady(&nodes[i]) {
+			readyNodes = append(readyNodes, &nodes[i])
+		}
+	}
+	sort.Slice(readyNodes, func(i, j int) bool { return readyNodes[i].Name < readyNodes[j].Name })
+	if len(readyNodes) > size {
+		readyNodes = readyNodes[:size]
+	}
+	return readyNodes
+}
+
+func newErrorDTO(severity proto.ErrorDTO_ErrorSeverity, description string) *proto.ErrorDTO {
+	return &proto.ErrorDTO{
+		Severity:    severity.Enum(),
+		Description: &description,
+	}
+}
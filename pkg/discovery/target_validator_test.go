@@ -0,0 +1,123 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	api "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/turbonomic/kubeturbo/pkg/discovery/metrics"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/k8sconntrack"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/prometheus"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/types"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/task"
+
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+)
+
+type fakeAgentWorker struct {
+	source      types.MonitoringSource
+	unreachable map[string]bool
+}
+
+func (w *fakeAgentWorker) Do(ctx context.Context) *metrics.EntityMetricSink {
+	return metrics.NewEntityMetricSink()
+}
+func (w *fakeAgentWorker) ReceiveTask(task *task.Task) {}
+func (w *fakeAgentWorker) GetMonitoringSource() types.MonitoringSource {
+	return w.source
+}
+func (w *fakeAgentWorker) CheckNodeAgent(ctx context.Context, node *api.Node) error {
+	if w.unreachable[node.Name] {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func newValidationNode(name string, ready bool) api.Node {
+	status := api.ConditionFalse
+	if ready {
+		status = api.ConditionTrue
+	}
+	return api.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, UID: k8stypes.UID("uid-" + name)},
+		Status: api.NodeStatus{
+			Conditions: []api.NodeCondition{{Type: api.NodeReady, Status: status}},
+		},
+	}
+}
+
+func TestSampleReadyNodes(t *testing.T) {
+	nodes := []api.Node{
+		newValidationNode("d", true),
+		newValidationNode("a", false),
+		newValidationNode("c", true),
+		newValidationNode("b", true),
+		newValidationNode("e", true),
+	}
+	sampled := sampleReadyNodes(nodes, 3)
+	expected := []string{"b", "c", "d"}
+	if len(sampled) != len(expected) {
+		t.Fatalf("Expected %d nodes, got %d", len(expected), len(sampled))
+	}
+	for i, node := range sampled {
+		if node.Name != expected[i] {
+			t.Errorf("Expected node %s at %d, got %s", expected[i], i, node.Name)
+		}
+	}
+}
+
+func TestCheckNodeAgents(t *testing.T) {
+	a, b := newValidationNode("a", true), newValidationNode("b", true)
+	nodes := []*api.Node{&a, &b}
+
+	table := []struct {
+		source           types.MonitoringSource
+		unreachable      map[string]bool
+		monitorType      types.MonitorType
+		expectedErrors   int
+		expectedSeverity proto.ErrorDTO_ErrorSeverity
+	}{
+		{types.KubeletSource, map[string]bool{}, types.ResourceMonitor, 0, proto.ErrorDTO_WARNING},
+		{types.KubeletSource, map[string]bool{"a": true}, types.ResourceMonitor, 1, proto.ErrorDTO_WARNING},
+		{types.KubeletSource, map[string]bool{"a": true, "b": true}, types.ResourceMonitor, 2, proto.ErrorDTO_CRITICAL},
+		{types.KubeletSource, map[string]bool{"a": true, "b": true}, types.StateMonitor, 2, proto.ErrorDTO_WARNING},
+		{types.K8sConntrackSource, map[string]bool{"a": true, "b": true}, types.ResourceMonitor, 2, proto.ErrorDTO_WARNING},
+	}
+	for i, item := range table {
+		worker := &fakeAgentWorker{source: item.source, unreachable: item.unreachable}
+		errorDTOs := checkNodeAgents(worker, item.monitorType, nodes)
+		if len(errorDTOs) != item.expectedErrors {
+			t.Errorf("Test case %d failed: expected %d errors, got %d", i, item.expectedErrors, len(errorDTOs))
+			continue
+		}
+		for _, errorDTO := range errorDTOs {
+			if errorDTO.GetSeverity() != item.expectedSeverity {
+				t.Errorf("Test case %d failed: expected severity %s, got %s", i, item.expectedSeverity, errorDTO.GetSeverity())
+			}
+			if errorDTO.GetEntityType() != proto.EntityDTO_VIRTUAL_MACHINE.String() {
+				t.Errorf("Test case %d failed: unexpected entity type %s", i, errorDTO.GetEntityType())
+			}
+		}
+	}
+}
+
+func TestShouldCheckNodeAgents(t *testing.T) {
+	table := []struct {
+		config   monitoring.MonitorWorkerConfig
+		expected bool
+	}{
+		{k8sconntrack.NewK8sConntrackMonitorConfig(), false},
+		{k8sconntrack.NewK8sConntrackMonitorConfig().EnableExplicitly(), true},
+		{prometheus.NewPrometheusMonitorConfig("http://prometheus:9090"), true},
+	}
+	for i, item := range table {
+		if got := shouldCheckNodeAgents(item.config); got != item.expected {
+			t.Errorf("Test case %d failed: expected %v, got %v", i, item.expected, got)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package task
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DiscoveryError is a failure to discover part of the cluster, e.g. a node whose kubelet cannot be scraped, or an
+// entity whose entityDTO cannot be built. It is reported to the server instead of failing the whole discovery.
+type DiscoveryError struct {
+	// Where the failure happens, e.g. a monitoring source or an entityDTO builder.
+	Source string
+
+	// The entity the failure is related to, if any.
+	EntityType DiscoveredEntityType
+	EntityName string
+	EntityUID  string
+
+	Err error
+}
+
+func NewDiscoveryError(source string, err error) *DiscoveryError {
+	return &DiscoveryError{
+		Source: source,
+		Err:    err,
+	}
+}
+
+// Assign the entity the failure is related to.
+func (e *DiscoveryError) WithEntity(eType DiscoveredEntityType, name, uid string) *DiscoveryError {
+	e.EntityType = eType
+	e.EntityName = name
+	e.EntityUID = uid
+	return e
+}
+
+func (e *DiscoveryError) Error() string {
+	if e.EntityType == "" {
+		return fmt.Sprintf("%s: %s", e.Source, e.Err)
+	}
+	return fmt.Sprintf("%s: %s %s: %s", e.Source, e.EntityType, e.EntityName, e.Err)
+}
+
+// DiscoveryErrors collects the discovery errors. It is safe for concurrent use.
+type DiscoveryErrors struct {
+	lock   sync.Mutex
+	errors []*DiscoveryError
+}
+
+func NewDiscoveryErrors() *DiscoveryErrors {
+	return &DiscoveryErrors{}
+}
+
+func (e *DiscoveryErrors) Add(errs ...*DiscoveryError) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.errors = append(e.errors, errs...)
+}
+
+// Get a copy of all the errors collected.
+func (e *DiscoveryErrors) List() []*DiscoveryError {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return append([]*DiscoveryError(nil), e.errors...)
+}
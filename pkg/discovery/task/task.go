@@ -1,6 +1,8 @@
 package task
 
 import (
+	"context"
+
 	api "k8s.io/client-go/pkg/api/v1"
 
 	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
@@ -12,6 +14,7 @@ const (
 	ClusterType     DiscoveredEntityType = "Cluster"
 	NodeType        DiscoveredEntityType = "Node"
 	PodType         DiscoveredEntityType = "Pod"
+	ContainerType   DiscoveredEntityType = "Container"
 	ApplicationType DiscoveredEntityType = "Application"
 	ServiceType     DiscoveredEntityType = "Service"
 
@@ -24,8 +27,15 @@ type DiscoveredEntityType string
 type Task struct {
 	uid string
 
+	// the task is abandoned once ctx is done.
+	ctx context.Context
+
 	nodeList []*api.Node
-	podList  []*api.Pod
+	// all the pods running on the nodes, which count toward the usage of the nodes.
+	podList []*api.Pod
+	// the pods to build entities for. It is podList unless the discovery has a scope.
+	podsInScope []*api.Pod
+	scoped      bool
 }
 
 // Worker task is consisted of a list of nodes the worker must discover.
@@ -47,6 +57,27 @@ func (t *Task) WithPods(podList []*api.Pod) *Task {
 	return t
 }
 
+// Assign the context of the discovery the task belongs to. The workers abandon the task once it is done.
+func (t *Task) WithContext(ctx context.Context) *Task {
+	t.ctx = ctx
+	return t
+}
+
+// Get the context of the task. A task without a context is never cancelled.
+func (t *Task) Context() context.Context {
+	if t.ctx == nil {
+		return context.Background()
+	}
+	return t.ctx
+}
+
+// Assign the pods in the discovery scope to the task. They must be a subset of the pods of the task.
+func (t *Task) WithPodsInScope(podList []*api.Pod) *Task {
+	t.podsInScope = podList
+	t.scoped = true
+	return t
+}
+
 // Get node list from the task.
 func (t *Task) NodeList() []*api.Node {
 	return t.nodeList
@@ -57,16 +88,26 @@ func (t *Task) PodList() []*api.Pod {
 	return t.podList
 }
 
+// Get the pods in the discovery scope from the task.
+func (t *Task) PodsInScope() []*api.Pod {
+	if !t.scoped {
+		return t.podList
+	}
+	return t.podsInScope
+}
+
 type TaskResultState string
 
 // A TaskResult contains a state, indicate whether the task is finished successfully; a err if there is any; a list of
-// EntityDTO.
+// EntityDTO; and the errors of the parts of the task which failed.
 type TaskResult struct {
 	workerID string
 	state    TaskResultState
 	err      error
 
 	content []*proto.EntityDTO
+
+	discoveryErrors []*DiscoveryError
 }
 
 func NewTaskResult(workerID string, state TaskResultState) *TaskResult {
@@ -97,3 +138,12 @@ func (r *TaskResult) WithContent(entityDTOs []*proto.EntityDTO) *TaskResult {
 	r.content = entityDTOs
 	return r
 }
+
+func (r *TaskResult) DiscoveryErrors() []*DiscoveryError {
+	return r.discoveryErrors
+}
+
+func (r *TaskResult) WithDiscoveryErrors(errs []*DiscoveryError) *TaskResult {
+	r.discoveryErrors = errs
+	return r
+}
@@ -15,6 +15,24 @@ func PodKeyFunc(pod *api.Pod) string {
 	return pod.Namespace + "/" + pod.Name
 }
 
+// ContainerStatsKeyFunc and ContainerKeyFunc should return the same value.
+func ContainerStatsKeyFunc(podStat stats.PodStats, containerStat stats.ContainerStats) string {
+	return PodStatsKeyFunc(podStat) + "/" + containerStat.Name
+}
+
+func ContainerKeyFunc(pod *api.Pod, containerName string) string {
+	return PodKeyFunc(pod) + "/" + containerName
+}
+
+// PodVolumeStatsKeyFunc and PodVolumeKeyFunc should return the same value.
+func PodVolumeStatsKeyFunc(podStat stats.PodStats, volumeStat stats.VolumeStats) string {
+	return PodStatsKeyFunc(podStat) + "/" + volumeStat.Name
+}
+
+func PodVolumeKeyFunc(pod *api.Pod, volumeName string) string {
+	return PodKeyFunc(pod) + "/" + volumeName
+}
+
 // NodeStatsKeyFunc and NodeKeyFunc should return the same value.
 func NodeStatsKeyFunc(nodeStat stats.NodeStats) string {
 	return nodeStat.NodeName
@@ -3,6 +3,8 @@ package util
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 
 	api "k8s.io/client-go/pkg/api/v1"
 	kubelettypes "k8s.io/kubernetes/pkg/kubelet/types"
@@ -114,3 +116,21 @@ func GroupPodsByNode(pods []*api.Pod) map[string][]*api.Pod {
 	}
 	return podsNodeMap
 }
+
+// The ID of a container is consisted of the UID of the hosting pod and the index of the container in the pod spec.
+// Container names are not unique across pods, and Kubernetes does not assign UIDs to containers.
+func ContainerIDFunc(pod *api.Pod, index int) string {
+	return fmt.Sprintf("%s-%d", pod.UID, index)
+}
+
+// Get the UID of the hosting pod from the given container ID.
+func PodIDFromContainerID(containerID string) (string, error) {
+	i := strings.LastIndex(containerID, "-")
+	if i <= 0 {
+		return "", fmt.Errorf("%s is not a valid container ID", containerID)
+	}
+	if _, err := strconv.Atoi(containerID[i+1:]); err != nil {
+		return "", fmt.Errorf("%s is not a valid container ID", containerID)
+	}
+	return containerID[:i], nil
+}
@@ -0,0 +1,49 @@
+package util
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	api "k8s.io/client-go/pkg/api/v1"
+	stats "k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
+)
+
+func TestContainerIDFunc(t *testing.T) {
+	table := []struct {
+		podUID string
+		index  int
+	}{
+		{"5f2c7a3e-9b1d-11e7-8f1a-0800271d8a2b", 0},
+		{"5f2c7a3e-9b1d-11e7-8f1a-0800271d8a2b", 12},
+		{"uid", 1},
+	}
+	for i, item := range table {
+		pod := &api.Pod{ObjectMeta: metav1.ObjectMeta{UID: types.UID(item.podUID)}}
+		containerID := ContainerIDFunc(pod, item.index)
+		podID, err := PodIDFromContainerID(containerID)
+		if err != nil {
+			t.Errorf("Test case %d failed: %s", i, err)
+		} else if podID != item.podUID {
+			t.Errorf("Test case %d failed: expected pod ID %s from container ID %s, got %s", i, item.podUID,
+				containerID, podID)
+		}
+	}
+}
+
+func TestPodIDFromInvalidContainerID(t *testing.T) {
+	for _, containerID := range []string{"", "uid", "-0", "5f2c7a3e-9b1d-11e7-8f1a-0800271d8a2b"} {
+		if podID, err := PodIDFromContainerID(containerID); err == nil {
+			t.Errorf("Expected an error for invalid container ID %q, got pod ID %s", containerID, podID)
+		}
+	}
+}
+
+func TestContainerKeyFunc(t *testing.T) {
+	pod := &api.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"}}
+	podStat := stats.PodStats{PodRef: stats.PodReference{Namespace: "default", Name: "web"}}
+	containerStat := stats.ContainerStats{Name: "app"}
+	if key, statsKey := ContainerKeyFunc(pod, "app"), ContainerStatsKeyFunc(podStat, containerStat); key != statsKey {
+		t.Errorf("Container key %s differs from the key %s of its stats", key, statsKey)
+	}
+}
@@ -1,6 +1,8 @@
 package compliance
 
 import (
+	"errors"
+
 	api "k8s.io/client-go/pkg/api/v1"
 
 	"github.com/turbonomic/kubeturbo/pkg/cluster"
@@ -14,13 +16,13 @@ import (
 
 // affinityProcessorConfig defines necessary configuration for build an affinity processor.
 type affinityProcessorConfig struct {
-	// define how affinityProcessor accesses Kubernetes cluster.
-	k8sClusterScraper *cluster.ClusterScraper
+	// the snapshot of Kubernetes cluster that affinityProcessor works on.
+	clusterSnapshot *cluster.ClusterSnapshot
 }
 
-func NewAffinityProcessorConfig(k8sClusterScraper *cluster.ClusterScraper) *affinityProcessorConfig {
+func NewAffinityProcessorConfig(clusterSnapshot *cluster.ClusterSnapshot) *affinityProcessorConfig {
 	return &affinityProcessorConfig{
-		k8sClusterScraper: k8sClusterScraper,
+		clusterSnapshot: clusterSnapshot,
 	}
 }
 
@@ -35,20 +37,15 @@ type AffinityProcessor struct {
 }
 
 func NewAffinityProcessor(config *affinityProcessorConfig) (*AffinityProcessor, error) {
-	allNodes, err := config.k8sClusterScraper.GetAllNodes()
-	if err != nil {
-		return nil, err
-	}
-	allPods, err := config.k8sClusterScraper.GetAllPods()
-	if err != nil {
-		return nil, err
+	if config.clusterSnapshot == nil {
+		return nil, errors.New("cluster snapshot is not provided")
 	}
 	return &AffinityProcessor{
 		ComplianceProcessor: NewComplianceProcessor(),
 		commManager:         NewAffinityCommodityManager(),
 
-		nodes: allNodes,
-		pods:  allPods,
+		nodes: config.clusterSnapshot.Nodes,
+		pods:  config.clusterSnapshot.Pods,
 	}, nil
 }
 
@@ -1,36 +1,45 @@
 package worker
 
 import (
-	"fmt"
-	"math"
-
-	api "k8s.io/client-go/pkg/api/v1"
+	"context"
+	"errors"
 
+	"github.com/turbonomic/kubeturbo/pkg/cluster"
 	"github.com/turbonomic/kubeturbo/pkg/discovery/configs"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/metrics"
 	"github.com/turbonomic/kubeturbo/pkg/discovery/task"
 
 	"github.com/golang/glog"
-	"github.com/turbonomic/kubeturbo/pkg/cluster"
 )
 
 type DispatcherConfig struct {
-	clusterInfoScraper *cluster.ClusterScraper
-	probeConfig        *configs.ProbeConfig
+	probeConfig *configs.ProbeConfig
 
 	workerCount int
+
+	// the history of sampled usage. It is nil if sampling is disabled.
+	metricHistory *metrics.MetricHistory
 }
 
-func NewDispatcherConfig(clusterInfoScraper *cluster.ClusterScraper, probeConfig *configs.ProbeConfig, workerCount int) *DispatcherConfig {
+func NewDispatcherConfig(probeConfig *configs.ProbeConfig, workerCount int) *DispatcherConfig {
 	return &DispatcherConfig{
-		clusterInfoScraper: clusterInfoScraper,
-		probeConfig:        probeConfig,
-		workerCount:        workerCount,
+		probeConfig: probeConfig,
+		workerCount: workerCount,
 	}
 }
 
+// Let the workers report the peak and percentile of the sampled usage.
+func (dc *DispatcherConfig) WithMetricHistory(history *metrics.MetricHistory) *DispatcherConfig {
+	dc.metricHistory = history
+	return dc
+}
+
 type Dispatcher struct {
 	config     *DispatcherConfig
 	workerPool chan chan *task.Task
+
+	// the number of workers built by Init, which may be less than the configured worker count.
+	workerCount int
 }
 
 func NewDispatcher(config *DispatcherConfig) *Dispatcher {
@@ -42,56 +51,83 @@ func NewDispatcher(config *DispatcherConfig) *Dispatcher {
 	}
 }
 
-func (d *Dispatcher) Init(c *ResultCollector) {
+// Build the workers and let them wait for tasks. The workers failed to be built are skipped, and an error is returned
+// if none is built, as no task could be dispatched.
+func (d *Dispatcher) Init(c *ResultCollector) error {
 	for i := 0; i < d.config.workerCount; i++ {
 		workerConfig := NewK8sDiscoveryWorkerConfig(d.config.probeConfig.StitchingPropertyType)
 		for _, mc := range d.config.probeConfig.MonitoringConfigs {
 			workerConfig.WithMonitoringWorkerConfig(mc)
 		}
+		for source, timeout := range d.config.probeConfig.MonitoringTimeouts {
+			workerConfig.WithMonitoringWorkerTimeout(source, timeout)
+		}
+		workerConfig.WithDefaultMonitoringWorkerTimeout(d.config.probeConfig.DefaultMonitoringTimeout)
+		if d.config.metricHistory != nil {
+			workerConfig.WithMetricHistory(d.config.metricHistory, d.config.probeConfig.UsagePercentile)
+		}
 		// create workers
 		discoveryWorker, err := NewK8sDiscoveryWorker(workerConfig)
 		if err != nil {
-			fmt.Errorf("failed to build discovery worker %s", err)
+			glog.Errorf("Failed to build discovery worker: %s", err)
+			continue
 		}
+		d.workerCount++
 
 		go discoveryWorker.RegisterAndRun(d, c)
 	}
+	if d.workerCount == 0 {
+		return errors.New("failed to build any discovery worker")
+	}
+	if d.workerCount < d.config.workerCount {
+		glog.Warningf("Only %d of %d discovery workers are built.", d.workerCount, d.config.workerCount)
+	}
+	return nil
 }
 
 func (d *Dispatcher) RegisterWorker(worker *k8sDiscoveryWorker) {
 	d.workerPool <- worker.taskChan
 }
 
-func (d *Dispatcher) Dispatch(nodes []*api.Node) int {
-	// make sure when len(node) < workerCount, worker will receive at most 1 node to discover
-	perTaskNodeLength := int(math.Ceil(float64(len(nodes)) / float64(d.config.workerCount)))
-	glog.V(3).Infof("The number of nodes per task is: %d", perTaskNodeLength)
-	assignedNodesCount := 0
-	assignedWorkerCount := 0
-	for assignedNodesCount+perTaskNodeLength <= len(nodes) {
-		currNodes := nodes[assignedNodesCount : assignedNodesCount+perTaskNodeLength]
-		currPods := d.config.clusterInfoScraper.GetRunningPodsOnNodes(currNodes)
-		currTask := task.NewTask().WithNodes(currNodes).WithPods(currPods)
-		d.assignTask(currTask)
-
-		assignedNodesCount += perTaskNodeLength
-
-		assignedWorkerCount++
+// Split the nodes in the given cluster snapshot into tasks and dispatch them to workers. There is at most one task
+// per worker, and the tasks are balanced by the number of pods running on the nodes. The workers abandon the tasks
+// once ctx is done, and no more task is dispatched.
+// Return the number of tasks dispatched.
+func (d *Dispatcher) Dispatch(ctx context.Context, snapshot *cluster.ClusterSnapshot) int {
+	podCounts := make(map[string]int)
+	for _, pod := range snapshot.GetRunningPodsOnNodes(snapshot.Nodes) {
+		podCounts[pod.Spec.NodeName]++
 	}
-	if assignedNodesCount < len(nodes)-1 {
-		currNodes := nodes[assignedNodesCount:]
-		currTask := task.NewTask().WithNodes(currNodes)
-		d.assignTask(currTask)
 
-		assignedWorkerCount++
+	// There is a task per worker built, otherwise the tasks left would wait for a worker forever.
+	partitions := partitionNodes(snapshot.Nodes, podCounts, d.workerCount)
+	dispatched := 0
+	for _, currNodes := range partitions {
+		currPods := snapshot.GetRunningPodsOnNodes(currNodes)
+		currPodsInScope := snapshot.GetRunningPodsInScope(currNodes)
+		glog.V(3).Infof("Dispatch a task of %d nodes and %d pods, %d of which are in scope", len(currNodes),
+			len(currPods), len(currPodsInScope))
+		currTask := task.NewTask().WithNodes(currNodes).WithPods(currPods).WithPodsInScope(currPodsInScope).
+			WithContext(ctx)
+		if !d.assignTask(ctx, currTask) {
+			glog.Warningf("Stop dispatching discovery tasks: %s", ctx.Err())
+			break
+		}
+		dispatched++
 	}
-	glog.V(3).Infof("Dispatched discovery task to %d workers", assignedWorkerCount)
+	glog.V(3).Infof("Dispatched discovery task to %d workers", dispatched)
 
-	return assignedWorkerCount
+	return dispatched
 }
 
-func (d *Dispatcher) assignTask(t *task.Task) {
-	// assignTask to a task channel of a worker.
-	taskChannel := <-d.workerPool
-	taskChannel <- t
+// Assign the task to the task channel of an available worker. Return false if ctx is done before any worker is
+// available.
+func (d *Dispatcher) assignTask(ctx context.Context, t *task.Task) bool {
+	select {
+	case taskChannel := <-d.workerPool:
+		taskChannel <- t
+		return true
+	case <-ctx.Done():
+		return false
+	}
 }
@@ -0,0 +1,34 @@
+package worker
+
+import (
+	"testing"
+
+	"github.com/turbonomic/kubeturbo/pkg/discovery/configs"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/prometheus"
+)
+
+func TestDispatcherInit(t *testing.T) {
+	table := []struct {
+		monitoringConfigs   []monitoring.MonitorWorkerConfig
+		workerCount         int
+		expectErr           bool
+		expectedWorkerCount int
+	}{
+		{[]monitoring.MonitorWorkerConfig{prometheus.NewPrometheusMonitorConfig("http://prometheus:9090")}, 3, false, 3},
+		// No worker can be built without a monitoring source.
+		{nil, 3, true, 0},
+	}
+	for i, item := range table {
+		probeConfig := &configs.ProbeConfig{MonitoringConfigs: item.monitoringConfigs}
+		dispatcher := NewDispatcher(NewDispatcherConfig(probeConfig, item.workerCount))
+		err := dispatcher.Init(NewResultCollector(item.workerCount * 2))
+		if item.expectErr != (err != nil) {
+			t.Errorf("Test case %d failed: expect error %t, got %v", i, item.expectErr, err)
+		}
+		if dispatcher.workerCount != item.expectedWorkerCount {
+			t.Errorf("Test case %d failed: expected %d workers, got %d", i, item.expectedWorkerCount,
+				dispatcher.workerCount)
+		}
+	}
+}
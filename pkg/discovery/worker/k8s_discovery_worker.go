@@ -1,16 +1,20 @@
 package worker
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
+	"github.com/turbonomic/kubeturbo/pkg/discovery/configs"
 	"github.com/turbonomic/kubeturbo/pkg/discovery/dtofactory"
 	"github.com/turbonomic/kubeturbo/pkg/discovery/metrics"
 	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring"
 	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/types"
 	"github.com/turbonomic/kubeturbo/pkg/discovery/stitching"
 	"github.com/turbonomic/kubeturbo/pkg/discovery/task"
+	kubeturbometrics "github.com/turbonomic/kubeturbo/pkg/metrics"
 
 	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
 
@@ -19,7 +23,8 @@ import (
 )
 
 const (
-	defaultMonitoringWorkerTimeout time.Duration = time.Minute * 5
+	// Source of the discovery errors of failed discovery tasks.
+	discoveryWorkerSource = "DiscoveryWorker"
 )
 
 type k8sDiscoveryWorkerConfig struct {
@@ -29,13 +34,25 @@ type k8sDiscoveryWorkerConfig struct {
 	// key: monitor type; value: monitor worker config.
 	monitoringSourceConfigs map[types.MonitorType][]monitoring.MonitorWorkerConfig
 
+	// the max time a monitoring worker of a source may take to finish one task.
+	// key: monitoring source; value: timeout.
+	monitoringWorkerTimeouts map[types.MonitoringSource]time.Duration
+	// the timeout of the sources not in monitoringWorkerTimeouts.
+	defaultMonitoringWorkerTimeout time.Duration
+
+	// the history of sampled usage, and the percentile to report. The history is nil if sampling is disabled.
+	metricHistory   *metrics.MetricHistory
+	usagePercentile float64
+
 	stitchingPropertyType stitching.StitchingPropertyType
 }
 
 func NewK8sDiscoveryWorkerConfig(sType stitching.StitchingPropertyType) *k8sDiscoveryWorkerConfig {
 	return &k8sDiscoveryWorkerConfig{
-		stitchingPropertyType:   sType,
-		monitoringSourceConfigs: make(map[types.MonitorType][]monitoring.MonitorWorkerConfig),
+		stitchingPropertyType:          sType,
+		monitoringSourceConfigs:        make(map[types.MonitorType][]monitoring.MonitorWorkerConfig),
+		monitoringWorkerTimeouts:       make(map[types.MonitoringSource]time.Duration),
+		defaultMonitoringWorkerTimeout: configs.DefaultMonitoringTimeout,
 	}
 }
 
@@ -53,6 +70,35 @@ func (c *k8sDiscoveryWorkerConfig) WithMonitoringWorkerConfig(config monitoring.
 	return c
 }
 
+// Set the timeout of the monitoring workers of the given source.
+func (c *k8sDiscoveryWorkerConfig) WithMonitoringWorkerTimeout(source types.MonitoringSource, timeout time.Duration) *k8sDiscoveryWorkerConfig {
+	c.monitoringWorkerTimeouts[source] = timeout
+	return c
+}
+
+// Set the timeout of the monitoring workers of the sources without a timeout, if it is positive.
+func (c *k8sDiscoveryWorkerConfig) WithDefaultMonitoringWorkerTimeout(timeout time.Duration) *k8sDiscoveryWorkerConfig {
+	if timeout > 0 {
+		c.defaultMonitoringWorkerTimeout = timeout
+	}
+	return c
+}
+
+// Report the peak and the given percentile of the usage in the history.
+func (c *k8sDiscoveryWorkerConfig) WithMetricHistory(history *metrics.MetricHistory, percentile float64) *k8sDiscoveryWorkerConfig {
+	c.metricHistory = history
+	c.usagePercentile = percentile
+	return c
+}
+
+// Get the timeout of the monitoring workers of the given source.
+func (c *k8sDiscoveryWorkerConfig) getMonitoringWorkerTimeout(source types.MonitoringSource) time.Duration {
+	if timeout, exist := c.monitoringWorkerTimeouts[source]; exist && timeout > 0 {
+		return timeout
+	}
+	return c.defaultMonitoringWorkerTimeout
+}
+
 // k8sDiscoveryWorker receives a discovery task from dispatcher(DiscoveryClient). Then ask available monitoring workers
 // to scrape metrics source and get topology information. Finally it builds entityDTOs and send back to DiscoveryClient.
 type k8sDiscoveryWorker struct {
@@ -134,58 +180,61 @@ func (worker *k8sDiscoveryWorker) executeTask(currTask *task.Task) *task.TaskRes
 	// wait group to make sure metrics scraping finishes.
 	var wg sync.WaitGroup
 
-	// Resource monitoring
-	resourceMonitorTask := currTask
-	//if resourceMonitoringWorkers, exist := worker.monitoringWorker[types.ResourceMonitor]; exist {
-	for _, resourceMonitoringWorkers := range worker.monitoringWorker {
-		for _, rmWorker := range resourceMonitoringWorkers {
+	// failures of the monitoring workers and the entityDTO builders.
+	discoveryErrors := task.NewDiscoveryErrors()
+
+	// The task is abandoned if the discovery is cancelled.
+	ctx := currTask.Context()
+
+	for _, monitoringWorkers := range worker.monitoringWorker {
+		for _, mWorker := range monitoringWorkers {
 			wg.Add(1)
 			go func(w monitoring.MonitoringWorker) {
-				finishCh := make(chan struct{})
-				stopCh := make(chan struct{}, 1)
-				defer close(finishCh)
-				defer close(stopCh)
 				defer wg.Done()
 
-				w.ReceiveTask(resourceMonitorTask)
-				t := time.NewTimer(defaultMonitoringWorkerTimeout)
-				go func() {
-					glog.V(2).Infof("A %s monitoring worker is invoked.", w.GetMonitoringSource())
-					// Assign task to monitoring worker.
-					monitoringSink := w.Do()
-					select {
-					case <-stopCh:
-						//glog.Infof("Calling thread: %s monitoring worker timeout!", w.GetMonitoringSource())
-						return
-					default:
-					}
-					//glog.Infof("%s has finished", w.GetMonitoringSource())
-					t.Stop()
-					// Don't do any filtering
-					worker.sink.MergeSink(monitoringSink, nil)
-					//glog.Infof("send to finish channel %p", finishCh)
-					finishCh <- struct{}{}
-				}()
-
-				// either finish as expected or timeout.
-				select {
-				case <-finishCh:
-					//glog.Infof("Worker %s finished as expected.", w.GetMonitoringSource())
-					return
-				case <-t.C:
-					glog.Errorf("%s monitoring worker exceeds the max time limit for "+
-						"completing the task.", w.GetMonitoringSource())
-					stopCh <- struct{}{}
-					//glog.Infof("%s stop", w.GetMonitoringSource())
-					w.Stop()
-					return
+				source := w.GetMonitoringSource()
+				timeout := worker.config.getMonitoringWorkerTimeout(source)
+				monitoringCtx, cancel := context.WithTimeout(ctx, timeout)
+				defer cancel()
+
+				glog.V(2).Infof("A %s monitoring worker is invoked.", source)
+				w.ReceiveTask(currTask)
+				start := time.Now()
+				// A worker which times out still returns the metrics collected so far.
+				monitoringSink := w.Do(monitoringCtx)
+				kubeturbometrics.MonitoringScrapeLatency.WithLabelValues(string(source)).
+					Observe(kubeturbometrics.SinceInSeconds(start))
+				if monitoringCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+					glog.Errorf("%s monitoring worker exceeds the max time limit %s for completing the task.",
+						source, timeout)
+					discoveryErrors.Add(task.NewDiscoveryError(string(source),
+						fmt.Errorf("exceeded the max time limit %s for completing the task", timeout)))
+					kubeturbometrics.MonitoringWorkerTimeouts.WithLabelValues(string(source)).Inc()
 				}
-			}(rmWorker)
+				if reporter, ok := w.(monitoring.ErrorReportingMonitoringWorker); ok {
+					workerErrors := reporter.GetDiscoveryErrors()
+					discoveryErrors.Add(workerErrors...)
+					kubeturbometrics.MonitoringScrapeFailures.WithLabelValues(string(source)).
+						Add(float64(len(workerErrors)))
+				}
+				// Don't do any filtering
+				worker.sink.MergeSink(monitoringSink, nil)
+			}(mWorker)
 		}
 	}
 
 	wg.Wait()
 
+	if err := ctx.Err(); err != nil {
+		glog.Warningf("Worker %s abandons the discovery task: %s", worker.id, err)
+		return task.NewTaskResult(worker.id, task.TaskFailed).WithErr(err)
+	}
+
+	// Peak and percentile of the usage
+	if worker.config.metricHistory != nil {
+		worker.config.metricHistory.AddAggregatedMetrics(worker.sink, worker.config.usagePercentile)
+	}
+
 	var discoveryResult []*proto.EntityDTO
 	// Build EntityDTO
 	// node
@@ -204,20 +253,32 @@ func (worker *k8sDiscoveryWorker) executeTask(currTask *task.Task) *task.TaskRes
 		glog.Errorf("Error while creating node entityDTOs: %v", err)
 		// TODO Node discovery fails, directly return?
 	}
+	discoveryErrors.Add(nodeEntityDTOBuilder.GetDiscoveryErrors()...)
 	glog.V(2).Infof("Worker %s builds %d node entityDTOs.", worker.id, len(nodeEntityDTOs))
 	discoveryResult = append(discoveryResult, nodeEntityDTOs...)
 
-	// pod
-	pods := currTask.PodList()
+	// pod. The pods out of the discovery scope only count toward the usage of the nodes.
+	pods := currTask.PodsInScope()
 	podEntityDTOBuilder := dtofactory.NewPodEntityDTOBuilder(worker.sink, stitchingManager, nodeNameUIDMap)
 	podEntityDTOs, err := podEntityDTOBuilder.BuildEntityDTOs(pods)
 	if err != nil {
 		glog.Errorf("Error while creating pod entityDTOs: %v", err)
 		// TODO Pod discovery fails, directly return?
 	}
+	discoveryErrors.Add(podEntityDTOBuilder.GetDiscoveryErrors()...)
 	discoveryResult = append(discoveryResult, podEntityDTOs...)
 	glog.V(2).Infof("Worker %s builds %d pod entityDTOs.", worker.id, len(podEntityDTOs))
 
+	// container
+	containerEntityDTOBuilder := dtofactory.NewContainerEntityDTOBuilder(worker.sink)
+	containerEntityDTOs, err := containerEntityDTOBuilder.BuildEntityDTOs(pods)
+	if err != nil {
+		glog.Errorf("Error while creating container entityDTOs: %v", err)
+	}
+	discoveryErrors.Add(containerEntityDTOBuilder.GetDiscoveryErrors()...)
+	discoveryResult = append(discoveryResult, containerEntityDTOs...)
+	glog.V(2).Infof("Worker %s builds %d container entityDTOs.", worker.id, len(containerEntityDTOs))
+
 	// application
 	applicationEntityDTOBuilder := dtofactory.NewApplicationEntityDTOBuilder(worker.sink)
 	appEntityDTOs, err := applicationEntityDTOBuilder.BuildEntityDTOs(pods)
@@ -225,12 +286,14 @@ func (worker *k8sDiscoveryWorker) executeTask(currTask *task.Task) *task.TaskRes
 		glog.Errorf("Error while creating application entityDTOs: %v", err)
 		// TODO Application discovery fails, return?
 	}
+	discoveryErrors.Add(applicationEntityDTOBuilder.GetDiscoveryErrors()...)
 	discoveryResult = append(discoveryResult, appEntityDTOs...)
 	glog.V(2).Infof("Worker %s builds %d application entityDTOs.", worker.id, len(appEntityDTOs))
 
 	// Send result
 	glog.V(3).Infof("Discovery result of worker %s is: %++v", worker.id, discoveryResult)
 
-	result := task.NewTaskResult(worker.id, task.TaskSucceeded).WithContent(discoveryResult)
+	result := task.NewTaskResult(worker.id, task.TaskSucceeded).WithContent(discoveryResult).
+		WithDiscoveryErrors(discoveryErrors.List())
 	return result
 }
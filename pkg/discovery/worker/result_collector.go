@@ -1,7 +1,6 @@
 package worker
 
 import (
-	"strings"
 	"sync"
 
 	"github.com/turbonomic/kubeturbo/pkg/discovery/task"
@@ -26,9 +25,11 @@ func (rc *ResultCollector) ResultPool() chan *task.TaskResult {
 	return rc.resultPool
 }
 
-func (rc *ResultCollector) Collect(count int) []*proto.EntityDTO {
+// Collect the results of the given number of tasks. The errors of the parts of the tasks which failed are returned
+// along with the entityDTOs.
+func (rc *ResultCollector) Collect(count int) ([]*proto.EntityDTO, []*task.DiscoveryError) {
 	discoveryResult := []*proto.EntityDTO{}
-	discoveryErrorString := []string{}
+	discoveryErrors := []*task.DiscoveryError{}
 
 	stopChan := make(chan struct{})
 	var wg sync.WaitGroup
@@ -40,10 +41,11 @@ func (rc *ResultCollector) Collect(count int) []*proto.EntityDTO {
 				return
 			case result := <-rc.resultPool:
 				if err := result.Err(); err != nil {
-					discoveryErrorString = append(discoveryErrorString, err.Error())
+					discoveryErrors = append(discoveryErrors, task.NewDiscoveryError(discoveryWorkerSource, err))
 				} else {
 					discoveryResult = append(discoveryResult, result.Content()...)
 				}
+				discoveryErrors = append(discoveryErrors, result.DiscoveryErrors()...)
 				wg.Done()
 			}
 		}
@@ -52,9 +54,9 @@ func (rc *ResultCollector) Collect(count int) []*proto.EntityDTO {
 	// stop the result waiting goroutine.
 	stopChan <- struct{}{}
 
-	if len(discoveryErrorString) > 0 {
-		glog.Errorf("One or more discovery worker failed: %s", strings.Join(discoveryErrorString, "\t\t"))
+	if len(discoveryErrors) > 0 {
+		glog.Errorf("Discovery workers failed to discover %d parts of the cluster.", len(discoveryErrors))
 	}
 
-	return discoveryResult
+	return discoveryResult, discoveryErrors
 }
@@ -1,8 +1,8 @@
 package worker
 
 import (
-	"fmt"
 	"errors"
+	"fmt"
 
 	api "k8s.io/client-go/pkg/api/v1"
 
@@ -21,19 +21,18 @@ const (
 )
 
 type k8sServiceDiscoveryWorkerConfig struct {
-	k8sClusterScraper *cluster.ClusterScraper
+	clusterSnapshot *cluster.ClusterSnapshot
 }
 
-func NewK8sServiceDiscoveryWorkerConfig(k8sClusterScraper *cluster.ClusterScraper) *k8sServiceDiscoveryWorkerConfig {
+func NewK8sServiceDiscoveryWorkerConfig(clusterSnapshot *cluster.ClusterSnapshot) *k8sServiceDiscoveryWorkerConfig {
 	return &k8sServiceDiscoveryWorkerConfig{
-		k8sClusterScraper: k8sClusterScraper,
+		clusterSnapshot: clusterSnapshot,
 	}
 }
 
 type k8sServiceDiscoveryWorker struct {
 	id string
 
-	// TODO, once we know what are the required method, we can use a clusterAccessor.
 	config *k8sServiceDiscoveryWorkerConfig
 
 	// Cluster ID for the current Kubernetes cluster.
@@ -43,7 +42,7 @@ type k8sServiceDiscoveryWorker struct {
 }
 
 func NewK8sServiceDiscoveryWorker(config *k8sServiceDiscoveryWorkerConfig) (*k8sServiceDiscoveryWorker, error) {
-	clusterID, err := config.k8sClusterScraper.GetKubernetesServiceID()
+	clusterID, err := config.clusterSnapshot.GetKubernetesServiceID()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get current Kubernetes cluster ID: %s", err)
 	}
@@ -59,7 +58,7 @@ func NewK8sServiceDiscoveryWorker(config *k8sServiceDiscoveryWorkerConfig) (*k8s
 func (svcDiscWorker *k8sServiceDiscoveryWorker) Do(entityDTOs []*proto.EntityDTO) *task.TaskResult {
 
 	applicationDTOs := getAllApplicationEntityDTOs(entityDTOs)
-	if len(applicationDTOs) < 1{
+	if len(applicationDTOs) < 1 {
 		return task.NewTaskResult(svcDiscWorker.id, task.TaskFailed).WithErr(errors.New("No applicatoin found"))
 	}
 
@@ -84,20 +83,9 @@ func getAllApplicationEntityDTOs(entityDTOs []*proto.EntityDTO) map[string]*prot
 
 // Parse Services inside Kubernetes and build entityDTO as VApp.
 func (svcDiscWorker *k8sServiceDiscoveryWorker) parseService(appDTOs map[string]*proto.EntityDTO) ([]*proto.EntityDTO, error) {
-
-	serviceList, err := svcDiscWorker.config.k8sClusterScraper.GetAllServices()
-	if err != nil {
-		return nil, err
-	}
-	endpointList, err := svcDiscWorker.config.k8sClusterScraper.GetAllEndpoints()
-	if err != nil {
-		return nil, err
-	}
-
-	podClusterIDToPodMap, err := svcDiscWorker.buildPodClusterIDToPod()
-	if err != nil {
-		return nil, fmt.Errorf("failed to index pods in current cluster: %s", err)
-	}
+	serviceList := svcDiscWorker.config.clusterSnapshot.Services
+	endpointList := svcDiscWorker.config.clusterSnapshot.Endpoints
+	podClusterIDToPodMap := svcDiscWorker.buildPodClusterIDToPod()
 
 	svcPodMap := groupPodsAndServices(serviceList, endpointList, podClusterIDToPodMap)
 
@@ -171,14 +159,10 @@ func findPodEndpoints(service *api.Service, endpointMap map[string]*api.Endpoint
 }
 
 // Index pod based on pod's clusterID.
-func (svcDiscWorker *k8sServiceDiscoveryWorker) buildPodClusterIDToPod() (map[string]*api.Pod, error) {
-	podList, err := svcDiscWorker.config.k8sClusterScraper.GetAllPods()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get all running pods in Kubernetes cluster: %s", err)
-	}
+func (svcDiscWorker *k8sServiceDiscoveryWorker) buildPodClusterIDToPod() map[string]*api.Pod {
 	podMap := make(map[string]*api.Pod)
-	for _, pod := range podList {
+	for _, pod := range svcDiscWorker.config.clusterSnapshot.Pods {
 		podMap[util.BuildK8sEntityClusterID(pod.Namespace, pod.Name)] = pod
 	}
-	return podMap, nil
+	return podMap
 }
@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"sort"
+
+	api "k8s.io/client-go/pkg/api/v1"
+)
+
+// Split the nodes into at most the given number of partitions, so that the partitions have about the same workload.
+// The workload of a node is the number of pods running on it plus one for the node itself. Every node is in exactly
+// one partition, and no partition is empty.
+func partitionNodes(nodes []*api.Node, podCounts map[string]int, partitionCount int) [][]*api.Node {
+	if len(nodes) == 0 || partitionCount <= 0 {
+		return nil
+	}
+	if partitionCount > len(nodes) {
+		partitionCount = len(nodes)
+	}
+
+	workload := func(node *api.Node) int {
+		return podCounts[node.Name] + 1
+	}
+	// Assign the heaviest node first to the lightest partition. Ties are broken by node name, so that the partitions
+	// are deterministic.
+	sorted := make([]*api.Node, len(nodes))
+	copy(sorted, nodes)
+	sort.Slice(sorted, func(i, j int) bool {
+		wi, wj := workload(sorted[i]), workload(sorted[j])
+		if wi != wj {
+			return wi > wj
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	partitions := make([][]*api.Node, partitionCount)
+	workloads := make([]int, partitionCount)
+	for _, node := range sorted {
+		lightest := 0
+		for i := 1; i < partitionCount; i++ {
+			if workloads[i] < workloads[lightest] {
+				lightest = i
+			}
+		}
+		partitions[lightest] = append(partitions[lightest], node)
+		workloads[lightest] += workload(node)
+	}
+	return partitions
+}
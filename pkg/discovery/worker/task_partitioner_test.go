@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"fmt"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	api "k8s.io/client-go/pkg/api/v1"
+)
+
+func TestPartitionNodes(t *testing.T) {
+	table := []struct {
+		podCounts      []int
+		partitionCount int
+		// the expected number of partitions and the max workload of a partition.
+		expectedPartitions int
+		expectedMaxLoad    int
+	}{
+		{[]int{}, 4, 0, 0},
+		{[]int{10}, 4, 1, 11},
+		{[]int{1, 2, 3, 4, 5}, 4, 4, 6},
+		// One node left over after splitting by count is kept.
+		{[]int{0, 0, 0, 0, 0}, 4, 4, 2},
+		{[]int{300, 10, 10, 10, 10, 10, 10, 10, 10}, 2, 2, 301},
+		{[]int{100, 100, 50, 50, 10, 10, 10, 10}, 4, 4, 101},
+		{[]int{5, 5, 5}, 0, 0, 0},
+	}
+	for i, item := range table {
+		var nodes []*api.Node
+		podCounts := make(map[string]int)
+		for j, count := range item.podCounts {
+			name := fmt.Sprintf("node-%d", j)
+			nodes = append(nodes, &api.Node{ObjectMeta: metav1.ObjectMeta{Name: name}})
+			podCounts[name] = count
+		}
+
+		partitions := partitionNodes(nodes, podCounts, item.partitionCount)
+		if len(partitions) != item.expectedPartitions {
+			t.Errorf("Test case %d failed: expected %d partitions, got %d", i, item.expectedPartitions, len(partitions))
+			continue
+		}
+		seen := make(map[string]bool)
+		maxLoad := 0
+		for _, partition := range partitions {
+			if len(partition) == 0 {
+				t.Errorf("Test case %d failed: empty partition", i)
+			}
+			load := 0
+			for _, node := range partition {
+				if seen[node.Name] {
+					t.Errorf("Test case %d failed: node %s is in more than one partition", i, node.Name)
+				}
+				seen[node.Name] = true
+				load += podCounts[node.Name] + 1
+			}
+			if load > maxLoad {
+				maxLoad = load
+			}
+		}
+		if len(partitions) > 0 && len(seen) != len(nodes) {
+			t.Errorf("Test case %d failed: expected %d nodes in partitions, got %d", i, len(nodes), len(seen))
+		}
+		if maxLoad != item.expectedMaxLoad {
+			t.Errorf("Test case %d failed: expected max workload %d, got %d", i, item.expectedMaxLoad, maxLoad)
+		}
+	}
+}
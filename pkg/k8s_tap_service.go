@@ -5,23 +5,58 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"sync"
+	"time"
 
 	client "k8s.io/client-go/kubernetes"
 
 	"github.com/turbonomic/kubeturbo/pkg/action"
 	"github.com/turbonomic/kubeturbo/pkg/discovery"
 	"github.com/turbonomic/kubeturbo/pkg/discovery/configs"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/stitching"
+	"github.com/turbonomic/kubeturbo/pkg/metrics"
 	"github.com/turbonomic/kubeturbo/pkg/registration"
 
+	"github.com/turbonomic/turbo-go-sdk/pkg/mediationcontainer"
 	"github.com/turbonomic/turbo-go-sdk/pkg/probe"
 	"github.com/turbonomic/turbo-go-sdk/pkg/service"
 
 	"github.com/golang/glog"
 )
 
+const (
+	// The max time to wait for the discovery clients of the targets to be created before registering the targets.
+	discoveryClientCreationTimeout = time.Minute
+
+	// The time to let the SDK send the results of the actions finished while draining before closing the connection
+	// to Turbo.
+	actionResultFlushDelay = time.Second * 2
+)
+
 type K8sTAPServiceSpec struct {
 	*service.TurboCommunicationConfig `json:"communicationConfig,omitempty"`
 	*configs.K8sTargetConfig          `json:"targetConfig,omitempty"`
+
+	// The monitoring sources to enable. If it is not specified, the sources are chosen based on command line flags.
+	MonitoringSources []monitoring.MonitoringSourceSpec `json:"monitoringSources,omitempty"`
+
+	// The clusters to discover, each as a separate target. If it is not specified, the cluster connected to by
+	// command line flags is discovered as the target in targetConfig.
+	Clusters []*K8sClusterSpec `json:"clusters,omitempty"`
+}
+
+// The spec of one of the clusters discovered by a kubeturbo instance.
+type K8sClusterSpec struct {
+	// The context in the kubeconfig file to connect to the cluster. The current context is used if it is empty.
+	KubeContext string `json:"kubeContext,omitempty"`
+
+	// The target of the cluster. The probe category and target type default to those in the top level targetConfig.
+	*configs.K8sTargetConfig `json:"targetConfig,omitempty"`
+
+	// The property to stitch the nodes to the VMs, "IP" or "UUID". It is chosen based on --usevmware if it is empty.
+	// The clusters of the same target type must use the same property.
+	StitchingPropertyType stitching.StitchingPropertyType `json:"stitchingPropertyType,omitempty"`
 }
 
 func ParseK8sTAPServiceSpec(configFile string) (*K8sTAPServiceSpec, error) {
@@ -39,15 +74,58 @@ func ParseK8sTAPServiceSpec(configFile string) (*K8sTAPServiceSpec, error) {
 		return nil, err
 	}
 
-	if tapSpec.K8sTargetConfig == nil {
-		return nil, errors.New("Target config is missing")
+	if len(tapSpec.Clusters) > 0 {
+		if err := tapSpec.validateClusterSpecs(); err != nil {
+			return nil, fmt.Errorf("Invalid clusters: %s", err)
+		}
+	} else {
+		if tapSpec.K8sTargetConfig == nil {
+			return nil, errors.New("Target config is missing")
+		}
+		if err := tapSpec.ValidateK8sTargetConfig(); err != nil {
+			return nil, err
+		}
 	}
-	if err := tapSpec.ValidateK8sTargetConfig(); err != nil {
-		return nil, err
+
+	if err := monitoring.ValidateMonitoringSourceSpecs(tapSpec.MonitoringSources); err != nil {
+		return nil, fmt.Errorf("Invalid monitoring sources: %s", err)
 	}
 	return tapSpec, nil
 }
 
+// Validate the cluster specs, and default their probe categories and target types to the top level ones.
+func (spec *K8sTAPServiceSpec) validateClusterSpecs() error {
+	targetIdentifiers := make(map[string]bool)
+	for i, cluster := range spec.Clusters {
+		if cluster == nil || cluster.K8sTargetConfig == nil {
+			return fmt.Errorf("target config of cluster %d is missing", i)
+		}
+		if spec.K8sTargetConfig != nil {
+			if cluster.ProbeCategory == "" {
+				cluster.ProbeCategory = spec.ProbeCategory
+			}
+			if cluster.TargetType == "" {
+				cluster.TargetType = spec.TargetType
+			}
+		}
+		if err := cluster.ValidateK8sTargetConfig(); err != nil {
+			return fmt.Errorf("invalid target config of cluster %d: %s", i, err)
+		}
+		if targetIdentifiers[cluster.TargetIdentifier] {
+			return fmt.Errorf("target identifier %s is used by more than one cluster", cluster.TargetIdentifier)
+		}
+		targetIdentifiers[cluster.TargetIdentifier] = true
+
+		switch cluster.StitchingPropertyType {
+		case "", stitching.IP, stitching.UUID:
+		default:
+			return fmt.Errorf("stitching property type %s of cluster %s is not supported",
+				cluster.StitchingPropertyType, cluster.TargetIdentifier)
+		}
+	}
+	return nil
+}
+
 func readK8sTAPServiceSpec(path string) (*K8sTAPServiceSpec, error) {
 	file, e := ioutil.ReadFile(path)
 	if e != nil {
@@ -62,51 +140,271 @@ func readK8sTAPServiceSpec(path string) (*K8sTAPServiceSpec, error) {
 }
 
 type K8sTAPServiceConfig struct {
-	spec                     *K8sTAPServiceSpec
-	probeConfig              *configs.ProbeConfig
-	registrationClientConfig *registration.RegistrationConfig
-	discoveryClientConfig    *discovery.DiscoveryClientConfig
+	spec *K8sTAPServiceSpec
+
+	// the clusters discovered as the targets, in the order they are added.
+	targets []*k8sTargetServiceConfig
+}
+
+// The config of a cluster discovered as a target.
+type k8sTargetServiceConfig struct {
+	targetConfig          *configs.K8sTargetConfig
+	stitchingPropertyType stitching.StitchingPropertyType
+	discoveryClientConfig *discovery.DiscoveryClientConfig
+	// creates the action handler of the target, only once the target is to be registered.
+	newActionHandler func() *action.ActionHandler
+	actionHandler    *action.ActionHandler
 }
 
-func NewK8sTAPServiceConfig(kubeClient *client.Clientset, probeConfig *configs.ProbeConfig,
-	spec *K8sTAPServiceSpec) *K8sTAPServiceConfig {
-	registrationClientConfig := registration.NewRegistrationClientConfig(probeConfig.StitchingPropertyType)
-	discoveryClientConfig := discovery.NewDiscoveryConfig(kubeClient, probeConfig, spec.K8sTargetConfig)
+func NewK8sTAPServiceConfig(spec *K8sTAPServiceSpec) *K8sTAPServiceConfig {
 	return &K8sTAPServiceConfig{
 		spec: spec,
-		registrationClientConfig: registrationClientConfig,
-		discoveryClientConfig:    discoveryClientConfig,
 	}
 }
 
+// Discover the cluster of the given client as the given target, and execute its actions by the action handler
+// created by newActionHandler. The action handler is not created if the target is not registered.
+func (c *K8sTAPServiceConfig) WithTarget(kubeClient *client.Clientset, probeConfig *configs.ProbeConfig,
+	targetConfig *configs.K8sTargetConfig, newActionHandler func() *action.ActionHandler) *K8sTAPServiceConfig {
+	c.targets = append(c.targets, &k8sTargetServiceConfig{
+		targetConfig:          targetConfig,
+		stitchingPropertyType: probeConfig.StitchingPropertyType,
+		discoveryClientConfig: discovery.NewDiscoveryConfig(kubeClient, probeConfig, targetConfig),
+		newActionHandler:      newActionHandler,
+	})
+	return c
+}
+
+// Serves the summary of the last discovery of a target, as its discovery client does.
+type lastDiscoveryGetter interface {
+	LastDiscovery() *discovery.DiscoveryStatus
+}
+
 type K8sTAPService struct {
 	*service.TAPService
+
+	// the types of the probes registered, one per target type of the clusters.
+	probeTypes []string
+
+	// <target_identifier : discovery client of the target>
+	discoveryClients map[string]lastDiscoveryGetter
+
+	// Guards connecting, flushActionResults and the closing of disconnectFromTurbo.
+	connectionLock sync.Mutex
+	connecting     bool
+	// Whether to wait for the SDK to send the results of the actions before closing the connection.
+	flushActionResults bool
+	// Closed to disconnect from Turbo.
+	disconnectFromTurbo chan struct{}
+	// Closed once ConnectToTurbo returns, after closing the connection if it was registered.
+	disconnected chan struct{}
 }
 
-func NewKubernetesTAPService(config *K8sTAPServiceConfig, actionHandler *action.ActionHandler) (*K8sTAPService, error) {
-	if config == nil || config.spec == nil {
+func NewKubernetesTAPService(config *K8sTAPServiceConfig) (*K8sTAPService, error) {
+	if config == nil || config.spec == nil || len(config.targets) == 0 {
 		return nil, errors.New("Invalid K8sTAPServiceConfig")
 	}
-	// Kubernetes Probe Registration Client
-	registrationClient := registration.NewK8sRegistrationClient(config.registrationClientConfig)
-	// Kubernetes Probe Discovery Client
-	discoveryClient := discovery.NewK8sDiscoveryClient(config.discoveryClientConfig)
-
-	tapService, err :=
-		service.NewTAPServiceBuilder().
-			WithTurboCommunicator(config.spec.TurboCommunicationConfig).
-			WithTurboProbe(probe.NewProbeBuilder(config.spec.TargetType, config.spec.ProbeCategory).
-				RegisteredBy(registrationClient).
-				DiscoversTarget(config.spec.TargetIdentifier, discoveryClient).
-				ExecutesActionsBy(actionHandler)).
-			Create()
+
+	// Kubernetes Probe Discovery Clients. Only the targets with a discovery client are registered.
+	targets, discoveryClients := createDiscoveryClients(config.targets, discoveryClientCreationTimeout,
+		discovery.NewK8sDiscoveryClient)
+	if len(targets) == 0 {
+		return nil, errors.New("failed to create the discovery client of any target")
+	}
+
+	// The clusters of the same target type are discovered by the same probe.
+	var probeTypes []string
+	probeBuilders := make(map[string]*probe.ProbeBuilder)
+	probeTargets := make(map[string][]*k8sTargetServiceConfig)
+	actionRouters := make(map[string]*action.ActionRouter)
+	for i, target := range targets {
+		targetType := target.targetConfig.TargetType
+		if _, exist := probeBuilders[targetType]; !exist {
+			// Kubernetes Probe Registration Client
+			registrationClient := registration.NewK8sRegistrationClient(
+				registration.NewRegistrationClientConfig(target.stitchingPropertyType))
+			probeTypes = append(probeTypes, targetType)
+			probeBuilders[targetType] = probe.NewProbeBuilder(targetType, target.targetConfig.ProbeCategory).
+				RegisteredBy(registrationClient)
+			actionRouters[targetType] = action.NewActionRouter(registration.TargetIdentifierField)
+		} else {
+			// The supply chain registered for a probe depends on the stitching property type.
+			first := probeTargets[targetType][0]
+			if target.targetConfig.ProbeCategory != first.targetConfig.ProbeCategory ||
+				target.stitchingPropertyType != first.stitchingPropertyType {
+				return nil, fmt.Errorf("targets %s and %s of type %s have different probe categories or "+
+					"stitching property types", first.targetConfig.TargetIdentifier,
+					target.targetConfig.TargetIdentifier, targetType)
+			}
+		}
+		probeTargets[targetType] = append(probeTargets[targetType], target)
+		target.actionHandler = target.newActionHandler()
+		probeBuilders[targetType].DiscoversTarget(target.targetConfig.TargetIdentifier, discoveryClients[i])
+		actionRouters[targetType].WithHandler(target.targetConfig.TargetIdentifier, target.actionHandler)
+	}
+
+	serviceBuilder := service.NewTAPServiceBuilder().WithTurboCommunicator(config.spec.TurboCommunicationConfig)
+	for _, probeType := range probeTypes {
+		if targets := probeTargets[probeType]; len(targets) == 1 {
+			probeBuilders[probeType].ExecutesActionsBy(targets[0].actionHandler)
+		} else {
+			probeBuilders[probeType].ExecutesActionsBy(actionRouters[probeType])
+		}
+		serviceBuilder.WithTurboProbe(probeBuilders[probeType])
+	}
+	tapService, err := serviceBuilder.Create()
 	if err != nil {
 		return nil, fmt.Errorf("Error when creating KubernetesTAPService: %s", err)
 	}
 
-	return &K8sTAPService{tapService}, nil
+	clientsByTarget := make(map[string]lastDiscoveryGetter)
+	for i, target := range targets {
+		clientsByTarget[target.targetConfig.TargetIdentifier] = discoveryClients[i]
+	}
+	return &K8sTAPService{
+		TAPService:       tapService,
+		probeTypes:       probeTypes,
+		discoveryClients: clientsByTarget,
+
+		disconnectFromTurbo: make(chan struct{}),
+		disconnected:        make(chan struct{}),
+	}, nil
+}
+
+// Create the discovery clients of the targets in parallel, so that a cluster slow to respond does not delay the
+// others. The targets whose discovery client fails to be created, or is not created within the timeout, are left out,
+// and their discovery client configs are stopped, so that a client created late stops watching and sampling its
+// cluster. Return the targets left and their discovery clients.
+func createDiscoveryClients(targets []*k8sTargetServiceConfig, timeout time.Duration,
+	newClient func(*discovery.DiscoveryClientConfig) (*discovery.K8sDiscoveryClient, error)) (
+	[]*k8sTargetServiceConfig, []*discovery.K8sDiscoveryClient) {
+	type result struct {
+		index  int
+		client *discovery.K8sDiscoveryClient
+		err    error
+	}
+	// Buffered, so that the clients created after the timeout do not block.
+	results := make(chan result, len(targets))
+	for i, target := range targets {
+		go func(i int, target *k8sTargetServiceConfig) {
+			client, err := newClient(target.discoveryClientConfig)
+			results <- result{index: i, client: client, err: err}
+		}(i, target)
+	}
+
+	clients := make([]*discovery.K8sDiscoveryClient, len(targets))
+	done := make([]bool, len(targets))
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+wait:
+	for pending := len(targets); pending > 0; pending-- {
+		select {
+		case r := <-results:
+			done[r.index] = true
+			if r.err != nil {
+				glog.Errorf("Target %s is not registered, as its discovery client cannot be created: %s",
+					targets[r.index].targetConfig.TargetIdentifier, r.err)
+				close(targets[r.index].discoveryClientConfig.StopEverything)
+				continue
+			}
+			clients[r.index] = r.client
+		case <-timer.C:
+			break wait
+		}
+	}
+
+	var createdTargets []*k8sTargetServiceConfig
+	var createdClients []*discovery.K8sDiscoveryClient
+	for i, target := range targets {
+		if !done[i] {
+			glog.Errorf("Target %s is not registered, as its discovery client is not created within %s.",
+				target.targetConfig.TargetIdentifier, timeout)
+			close(target.discoveryClientConfig.StopEverything)
+		}
+		if clients[i] == nil {
+			continue
+		}
+		createdTargets = append(createdTargets, target)
+		createdClients = append(createdClients, clients[i])
+	}
+	return createdTargets, createdClients
 }
 
 func (s *K8sTAPService) Run() {
 	s.ConnectToTurbo()
 }
+
+// Connect to the Turbo server, register the probes and add the targets of all the probes. The TAPService of the SDK
+// only adds the targets of the last probe it is built with. It blocks until DisconnectFromTurbo is called.
+func (s *K8sTAPService) ConnectToTurbo() {
+	s.connectionLock.Lock()
+	select {
+	case <-s.disconnectFromTurbo:
+		s.connectionLock.Unlock()
+		return
+	default:
+	}
+	s.connecting = true
+	s.connectionLock.Unlock()
+	defer close(s.disconnected)
+
+	metrics.TurboConnectionState.Set(metrics.TurboConnecting)
+	isRegistered := make(chan bool, 1)
+	go mediationcontainer.InitMediationContainer(isRegistered)
+	select {
+	case registered := <-isRegistered:
+		if !registered {
+			glog.Errorf("Probes %v are not registered", s.probeTypes)
+			metrics.TurboConnectionState.Set(metrics.TurboDisconnected)
+			return
+		}
+	case <-s.disconnectFromTurbo:
+		// The connection is not closed as the SDK cannot close a connection not registered yet.
+		glog.Warningf("Stop connecting to Turbo before the probes %v are registered", s.probeTypes)
+		metrics.TurboConnectionState.Set(metrics.TurboDisconnected)
+		return
+	}
+	metrics.TurboConnectionState.Set(metrics.TurboRegistered)
+
+	for _, probeType := range s.probeTypes {
+		turboProbe, err := mediationcontainer.GetProbe(probeType)
+		if err != nil {
+			glog.Errorf("Failed to add the targets of probe %s: %s", probeType, err)
+			continue
+		}
+		for _, targetInfo := range turboProbe.GetProbeTargets() {
+			resp, err := s.AddTarget(targetInfo.GetTargetInstance())
+			if err != nil {
+				glog.Errorf("Error while adding %s %s target: %s", targetInfo.TargetCategory(),
+					targetInfo.TargetType(), err)
+				continue
+			}
+			glog.V(3).Infof("Successfully add target: %v", resp)
+		}
+	}
+
+	<-s.disconnectFromTurbo
+	// The SDK sends the result of an action asynchronously after the action handler returns it, and closing the
+	// mediation container neither waits for nor flushes the results not sent yet. So it only waits a while if an
+	// action has been reported just before disconnecting.
+	if s.flushActionResults {
+		time.Sleep(actionResultFlushDelay)
+	}
+	mediationcontainer.CloseMediationContainer()
+	metrics.TurboConnectionState.Set(metrics.TurboDisconnected)
+}
+
+// DisconnectFromTurbo closes the connection to Turbo, and waits until it is closed if ConnectToTurbo is running. It
+// must be called only once. If flushActionResults is true, the results of the actions just reported are given time to
+// be sent before closing the connection.
+func (s *K8sTAPService) DisconnectFromTurbo(flushActionResults bool) {
+	s.connectionLock.Lock()
+	s.flushActionResults = flushActionResults
+	close(s.disconnectFromTurbo)
+	connecting := s.connecting
+	s.connectionLock.Unlock()
+	if connecting {
+		<-s.disconnected
+		glog.V(2).Infof("Disconnected from Turbo.")
+	}
+}
@@ -0,0 +1,125 @@
+package kubeturbo
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/turbonomic/kubeturbo/pkg/discovery"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/configs"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/stitching"
+)
+
+func TestValidateClusterSpecs(t *testing.T) {
+	cluster := func(targetType, id string, pType stitching.StitchingPropertyType) *K8sClusterSpec {
+		return &K8sClusterSpec{
+			K8sTargetConfig:       &configs.K8sTargetConfig{TargetType: targetType, TargetIdentifier: id},
+			StitchingPropertyType: pType,
+		}
+	}
+	table := []struct {
+		targetConfig *configs.K8sTargetConfig
+		clusters     []*K8sClusterSpec
+		expectedErr  bool
+	}{
+		{
+			&configs.K8sTargetConfig{ProbeCategory: "Cloud Native", TargetType: "Kubernetes"},
+			[]*K8sClusterSpec{cluster("", "cluster-a", ""), cluster("Kubernetes-vmware", "cluster-b", stitching.UUID)},
+			false,
+		},
+		// The probe category cannot be defaulted.
+		{nil, []*K8sClusterSpec{cluster("Kubernetes", "cluster-a", "")}, true},
+		{
+			&configs.K8sTargetConfig{ProbeCategory: "Cloud Native", TargetType: "Kubernetes"},
+			[]*K8sClusterSpec{cluster("", "cluster-a", ""), cluster("", "cluster-a", "")},
+			true,
+		},
+		{
+			&configs.K8sTargetConfig{ProbeCategory: "Cloud Native", TargetType: "Kubernetes"},
+			[]*K8sClusterSpec{cluster("", "cluster-a", "MAC")},
+			true,
+		},
+		{
+			&configs.K8sTargetConfig{ProbeCategory: "Cloud Native", TargetType: "Kubernetes"},
+			[]*K8sClusterSpec{{KubeContext: "cluster-a"}},
+			true,
+		},
+	}
+	for i, item := range table {
+		spec := &K8sTAPServiceSpec{K8sTargetConfig: item.targetConfig, Clusters: item.clusters}
+		err := spec.validateClusterSpecs()
+		if (err != nil) != item.expectedErr {
+			t.Errorf("Test case %d failed: expected error %t, got %v", i, item.expectedErr, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		for _, cluster := range spec.Clusters {
+			if cluster.ProbeCategory == "" || cluster.TargetType == "" {
+				t.Errorf("Test case %d failed: probe category or target type of %s is not defaulted", i,
+					cluster.TargetIdentifier)
+			}
+		}
+	}
+}
+
+func TestCreateDiscoveryClients(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	// The discovery client of each target is created by the behavior of the same name.
+	healthy := func() (*discovery.K8sDiscoveryClient, error) { return &discovery.K8sDiscoveryClient{}, nil }
+	behaviors := map[string]func() (*discovery.K8sDiscoveryClient, error){
+		"healthy":   healthy,
+		"healthy-2": healthy,
+		"failed":    func() (*discovery.K8sDiscoveryClient, error) { return nil, errors.New("no worker") },
+		"hanging":   func() (*discovery.K8sDiscoveryClient, error) { <-block; return healthy() },
+	}
+	var targets []*k8sTargetServiceConfig
+	targetsByConfig := make(map[*discovery.DiscoveryClientConfig]string)
+	for _, id := range []string{"hanging", "healthy", "failed", "healthy-2"} {
+		discoveryClientConfig := &discovery.DiscoveryClientConfig{StopEverything: make(chan struct{})}
+		targetsByConfig[discoveryClientConfig] = id
+		targets = append(targets, &k8sTargetServiceConfig{
+			targetConfig:          &configs.K8sTargetConfig{TargetIdentifier: id},
+			discoveryClientConfig: discoveryClientConfig,
+		})
+	}
+	newClient := func(config *discovery.DiscoveryClientConfig) (*discovery.K8sDiscoveryClient, error) {
+		return behaviors[targetsByConfig[config]]()
+	}
+
+	start := time.Now()
+	createdTargets, clients := createDiscoveryClients(targets, time.Millisecond*200, newClient)
+	if elapsed := time.Since(start); elapsed > time.Second*5 {
+		t.Errorf("Expected to stop waiting after the timeout, waited %s", elapsed)
+	}
+	expected := []string{"healthy", "healthy-2"}
+	if len(createdTargets) != len(expected) || len(clients) != len(expected) {
+		t.Fatalf("Expected targets %v, got %d targets and %d clients", expected, len(createdTargets), len(clients))
+	}
+	for i, target := range createdTargets {
+		if target.targetConfig.TargetIdentifier != expected[i] {
+			t.Errorf("Expected target %s at %d, got %s", expected[i], i, target.targetConfig.TargetIdentifier)
+		}
+		if clients[i] == nil {
+			t.Errorf("Expected a discovery client of target %s", expected[i])
+		}
+	}
+
+	// The discovery client configs of the targets left out are stopped.
+	for _, target := range targets {
+		id := target.targetConfig.TargetIdentifier
+		stopped := false
+		select {
+		case <-target.discoveryClientConfig.StopEverything:
+			stopped = true
+		default:
+		}
+		if expectedStopped := id == "failed" || id == "hanging"; stopped != expectedStopped {
+			t.Errorf("Expected discovery client config of target %s stopped %t, got %t", id, expectedStopped,
+				stopped)
+		}
+	}
+}
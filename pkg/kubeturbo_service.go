@@ -2,6 +2,8 @@ package kubeturbo
 
 import (
 	"fmt"
+	"sync"
+	"time"
 
 	"k8s.io/apimachinery/pkg/util/wait"
 	api "k8s.io/client-go/pkg/api/v1"
@@ -14,17 +16,57 @@ import (
 	"github.com/golang/glog"
 )
 
+// The interval of restoring the controllers left on the placeholder scheduler by interrupted moves.
+const schedulerNameReconcileInterval = 5 * time.Minute
+
 type KubeturboService struct {
+	// The clusters discovered and managed, one per target.
+	clusters []*clusterService
+
+	k8sTAPService *K8sTAPService
+}
+
+// The scheduler and action handler of one cluster.
+type clusterService struct {
 	config *Config
 
 	// Turbonomic scheduler
 	TurboScheduler *turboscheduler.TurboScheduler
 	actionHandler  *action.ActionHandler
 
-	k8sTAPService *K8sTAPService
+	// Drop the pending pods no action waits for, instead of scheduling them with Turbo.
+	dropUnclaimedPods bool
+}
+
+// Create the service discovering and managing the clusters of the given configs, each as a separate target.
+func NewKubeturboService(configs ...*Config) *KubeturboService {
+	if len(configs) == 0 {
+		glog.Fatalf("No cluster is configured for Kubeturbo service")
+	}
+	k8sTAPServiceConfig := NewK8sTAPServiceConfig(configs[0].tapSpec)
+	// Only the clusters registered as targets are managed.
+	var clusters []*clusterService
+	for _, c := range configs {
+		c := c
+		k8sTAPServiceConfig.WithTarget(c.Client, c.ProbeConfig, c.targetConfig, func() *action.ActionHandler {
+			cluster := newClusterService(c)
+			clusters = append(clusters, cluster)
+			return cluster.actionHandler
+		})
+	}
+
+	k8sTAPService, err := NewKubernetesTAPService(k8sTAPServiceConfig)
+	if err != nil {
+		glog.Fatalf("Unexpected error while creating Kuberntes TAP service: %s", err)
+	}
+	return &KubeturboService{
+		clusters: clusters,
+
+		k8sTAPService: k8sTAPService,
+	}
 }
 
-func NewKubeturboService(c *Config) *KubeturboService {
+func newClusterService(c *Config) *clusterService {
 	turboScheduler := turboscheduler.NewTurboScheduler(c.Client, c.tapSpec.TurboServer,
 		c.tapSpec.OpsManagerUsername, c.tapSpec.OpsManagerPassword)
 
@@ -32,33 +74,73 @@ func NewKubeturboService(c *Config) *KubeturboService {
 	actionHandlerConfig := action.NewActionHandlerConfig(c.Client, c.broker)
 	actionHandler := action.NewActionHandler(actionHandlerConfig, turboScheduler)
 
-	k8sTAPServiceConfig := NewK8sTAPServiceConfig(c.Client, c.ProbeConfig, c.tapSpec)
-
-	k8sTAPService, err := NewKubernetesTAPService(k8sTAPServiceConfig, actionHandler)
-	if err != nil {
-		glog.Fatalf("Unexpected error while creating Kuberntes TAP service: %s", err)
-	}
-	return &KubeturboService{
+	return &clusterService{
 		config:         c,
 		TurboScheduler: turboScheduler,
 		actionHandler:  actionHandler,
-
-		k8sTAPService: k8sTAPService,
 	}
 }
 
+// NewClusterActionHandler creates the action handler of the cluster of the given config, to execute actions without
+// connecting to Turbo. Like the service, it passes the pending pods of the cluster to the actions waiting for them,
+// until the config is stopped. Unlike the service, it leaves the other pending pods alone, as they are scheduled by
+// the running kubeturbo.
+func NewClusterActionHandler(c *Config) *action.ActionHandler {
+	cluster := newClusterService(c)
+	cluster.dropUnclaimedPods = true
+	c.runUnassignedPodReflector()
+	go wait.Until(cluster.getNextPod, 0, c.StopEverything)
+	return cluster.actionHandler
+}
+
 // Run begins watching and scheduling. It starts a goroutine and returns immediately.
 func (v *KubeturboService) Run() {
 	glog.V(2).Infof("********** Start runnning Kubeturbo Service **********")
 
 	// These three go routine is responsible for watching corresponding watchable resource.
 	//go wait.Until(v.getNextNode, 0, v.config.StopEverything)
-	go wait.Until(v.getNextPod, 0, v.config.StopEverything)
+	for _, cluster := range v.clusters {
+		cluster.config.runUnassignedPodReflector()
+		go wait.Until(cluster.getNextPod, 0, cluster.config.StopEverything)
+		cluster.actionHandler.StartSchedulerNameReconciler(schedulerNameReconcileInterval,
+			cluster.config.StopEverything)
+	}
 	go v.k8sTAPService.ConnectToTurbo()
 
 }
 
-func (v *KubeturboService) getNextPod() {
+// Stop stops accepting new actions, waits up to the timeout for the in-flight actions of all the clusters to be
+// executed, checked and reported, then disconnects from Turbo and stops watching the clusters. An action not finished
+// in time is abandoned.
+func (v *KubeturboService) Stop(timeout time.Duration) {
+	glog.V(2).Infof("********** Stop Kubeturbo Service, waiting up to %s for in-flight actions **********", timeout)
+	var wg sync.WaitGroup
+	for _, cluster := range v.clusters {
+		wg.Add(1)
+		go func(cluster *clusterService) {
+			defer wg.Done()
+			if !cluster.actionHandler.Drain(timeout) {
+				glog.Warningf("In-flight actions of target %s are not finished in %s.",
+					cluster.config.targetConfig.TargetIdentifier, timeout)
+			}
+		}(cluster)
+	}
+	wg.Wait()
+
+	flushActionResults := false
+	for _, cluster := range v.clusters {
+		if cluster.actionHandler.ReportedWhileDraining() {
+			flushActionResults = true
+		}
+	}
+	v.k8sTAPService.DisconnectFromTurbo(flushActionResults)
+	for _, cluster := range v.clusters {
+		cluster.actionHandler.Stop()
+		close(cluster.config.StopEverything)
+	}
+}
+
+func (v *clusterService) getNextPod() {
 	p, err := v.config.PodQueue.Pop(nil)
 	if err != nil {
 		glog.Errorf("Failed to get the pending pod: %s", err)
@@ -74,15 +156,24 @@ func (v *KubeturboService) getNextPod() {
 	} else {
 		key = fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)
 	}
+	if v.dropUnclaimedPods && !v.config.broker.HasKey(key) {
+		glog.V(3).Infof("No action waits for pending pod %s/%s, skip it.", pod.Namespace, pod.Name)
+		return
+	}
 	producer := &turbostore.PodProducer{}
 	err = producer.Produce(v.config.broker, key, pod)
 	if err != nil {
+		if v.dropUnclaimedPods {
+			glog.Errorf("Failed to pass pending pod %s/%s to the action waiting for it: %s", pod.Namespace,
+				pod.Name, err)
+			return
+		}
 		glog.Errorf("Got error when producing pod: %s", err)
 		v.regularSchedulePod(pod)
 	}
 }
 
-func (v *KubeturboService) regularSchedulePod(pod *api.Pod) {
+func (v *clusterService) regularSchedulePod(pod *api.Pod) {
 	if err := v.TurboScheduler.Schedule(pod); err != nil {
 		glog.Errorf("Scheduling failed: %s", err)
 	}
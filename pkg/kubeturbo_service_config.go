@@ -16,6 +16,9 @@ import (
 type Config struct {
 	tapSpec *K8sTAPServiceSpec
 
+	// The target the cluster is discovered as.
+	targetConfig *configs.K8sTargetConfig
+
 	//turboStore *turbostore.TurboStore
 	broker turbostore.Broker
 
@@ -37,6 +40,7 @@ func NewVMTConfig(client *client.Clientset, probeConfig *configs.ProbeConfig, br
 	spec *K8sTAPServiceSpec) *Config {
 	config := &Config{
 		tapSpec:        spec,
+		targetConfig:   spec.K8sTargetConfig,
 		broker:         broker,
 		ProbeConfig:    probeConfig,
 		Client:         client,
@@ -49,12 +53,21 @@ func NewVMTConfig(client *client.Clientset, probeConfig *configs.ProbeConfig, br
 	// Minions may be listed frequently, so provide a local up-to-date cache.
 	// cache.NewReflector(config.createMinionLW(), &api.Node{}, config.NodeQueue, 0).RunUntil(config.StopEverything)
 
-	// monitor unassigned pod
-	cache.NewReflector(config.createUnassignedPodLW(), &api.Pod{}, config.PodQueue, 0).RunUntil(config.StopEverything)
-
 	return config
 }
 
+// Monitor the unassigned pods, which are put in PodQueue, until the config is stopped. Only the kubeturbo scheduling
+// the pending pods watches them.
+func (c *Config) runUnassignedPodReflector() {
+	cache.NewReflector(c.createUnassignedPodLW(), &api.Pod{}, c.PodQueue, 0).RunUntil(c.StopEverything)
+}
+
+// Discover the cluster as the given target instead of the one in the spec.
+func (c *Config) WithTargetConfig(targetConfig *configs.K8sTargetConfig) *Config {
+	c.targetConfig = targetConfig
+	return c
+}
+
 // Create a list and watch for node to filter out nodes those cannot be scheduled.
 func (c *Config) createMinionLW() *cache.ListWatch {
 	//fields := fields.Set{api.NodeUnschedulableField: "false"}.AsSelector()
@@ -0,0 +1,71 @@
+package kubeturbo
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	api "k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/tools/cache"
+
+	vmtcache "github.com/turbonomic/kubeturbo/pkg/cache"
+	"github.com/turbonomic/kubeturbo/pkg/turbostore"
+)
+
+// A consumer recording the names of the pods it consumes.
+type fakePodConsumer struct {
+	pods []string
+}
+
+func (c *fakePodConsumer) GetUID() (string, error) {
+	return "fake", nil
+}
+
+func (c *fakePodConsumer) Consume(obj interface{}) error {
+	c.pods = append(c.pods, obj.(*api.Pod).Name)
+	return nil
+}
+
+func (c *fakePodConsumer) Leave(key string, broker turbostore.Broker) error {
+	return broker.UnSubscribe(key, c)
+}
+
+func TestGetNextPodDropUnclaimedPods(t *testing.T) {
+	newPendingPod := func(name string, parentUID types.UID) *api.Pod {
+		pod := &api.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name}}
+		if parentUID != "" {
+			createdBy := fmt.Sprintf(`{"kind":"SerializedReference","reference":{"kind":"ReplicaSet",`+
+				`"namespace":"default","uid":"%s"}}`, parentUID)
+			pod.Annotations = map[string]string{"kubernetes.io/created-by": createdBy}
+		}
+		return pod
+	}
+	pods := []*api.Pod{
+		newPendingPod("web-1", "rs-uid"),
+		newPendingPod("standalone", ""),
+		newPendingPod("other-1", "other-uid"),
+		newPendingPod("web-2", "rs-uid"),
+	}
+
+	broker := turbostore.NewPodBroker()
+	consumer := &fakePodConsumer{}
+	broker.Subscribe("rs-uid", consumer)
+	config := &Config{
+		broker:   broker,
+		PodQueue: vmtcache.NewHashedFIFO(cache.MetaNamespaceKeyFunc),
+	}
+	for _, pod := range pods {
+		config.PodQueue.Add(pod)
+	}
+
+	// Without a scheduler, scheduling an unclaimed pod would panic.
+	cluster := &clusterService{config: config, dropUnclaimedPods: true}
+	for range pods {
+		cluster.getNextPod()
+	}
+	if expected := []string{"web-1", "web-2"}; !reflect.DeepEqual(consumer.pods, expected) {
+		t.Errorf("Expected pods %v passed to the waiting action, got %v", expected, consumer.pods)
+	}
+}
@@ -0,0 +1,243 @@
+package leaderelection
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/golang/glog"
+	"github.com/spf13/pflag"
+)
+
+const (
+	DefaultLeaseDuration = 15 * time.Second
+	DefaultRenewDeadline = 10 * time.Second
+	DefaultRetryPeriod   = 2 * time.Second
+
+	DefaultLockNamespace = "default"
+	DefaultLockName      = "kubeturbo"
+)
+
+// LeaderElectionConfiguration is the command line configuration of leader election.
+type LeaderElectionConfiguration struct {
+	// Elect a leader among the replicas before connecting to Turbo. Without leader election, every replica
+	// connects to Turbo.
+	LeaderElect bool
+	// The time a follower waits since the last renewal of the leader before taking over.
+	LeaseDuration time.Duration
+	// The time the leader retries renewing the lease before giving up the leadership.
+	RenewDeadline time.Duration
+	// The interval of trying to acquire or renew the lease.
+	RetryPeriod time.Duration
+	// The type of the lock object, configmaps or endpoints.
+	ResourceLock string
+	// The namespace and name of the lock object.
+	LockNamespace string
+	LockName      string
+}
+
+func NewLeaderElectionConfiguration() LeaderElectionConfiguration {
+	return LeaderElectionConfiguration{
+		LeaseDuration: DefaultLeaseDuration,
+		RenewDeadline: DefaultRenewDeadline,
+		RetryPeriod:   DefaultRetryPeriod,
+		ResourceLock:  ConfigMapsResourceLock,
+		LockNamespace: DefaultLockNamespace,
+		LockName:      DefaultLockName,
+	}
+}
+
+// BindFlags binds the leader election flags to the given configuration.
+func BindFlags(l *LeaderElectionConfiguration, fs *pflag.FlagSet) {
+	fs.BoolVar(&l.LeaderElect, "leader-elect", l.LeaderElect, "Elect a leader among the kubeturbo replicas. Only the leader connects to Turbo, schedules the pending pods and executes actions; the others take over once it stops renewing its lease")
+	fs.DurationVar(&l.LeaseDuration, "leader-elect-lease-duration", l.LeaseDuration, "The time a follower waits since the last renewal of the leader before taking over. It bounds the time without a leader if the leader dies")
+	fs.DurationVar(&l.RenewDeadline, "leader-elect-renew-deadline", l.RenewDeadline, "The time the leader retries renewing its lease before giving up the leadership and exiting. It must be less than the lease duration")
+	fs.DurationVar(&l.RetryPeriod, "leader-elect-retry-period", l.RetryPeriod, "The interval of trying to acquire or renew the lease")
+	fs.StringVar(&l.ResourceLock, "leader-elect-resource-lock", l.ResourceLock, "The type of the object holding the lease, configmaps or endpoints")
+	fs.StringVar(&l.LockNamespace, "leader-elect-namespace", l.LockNamespace, "The namespace of the object holding the lease")
+	fs.StringVar(&l.LockName, "leader-elect-name", l.LockName, "The name of the object holding the lease. The replicas of the same kubeturbo must use the same one")
+}
+
+// Check the durations of the configuration.
+func (l *LeaderElectionConfiguration) Validate() error {
+	if l.RetryPeriod <= 0 || l.RenewDeadline <= l.RetryPeriod || l.LeaseDuration <= l.RenewDeadline {
+		return fmt.Errorf("leader election retry period %s, renew deadline %s and lease duration %s must be "+
+			"positive and increasing", l.RetryPeriod, l.RenewDeadline, l.LeaseDuration)
+	}
+	return nil
+}
+
+// LeaderCallbacks are called when the leadership changes.
+type LeaderCallbacks struct {
+	// Called in a new goroutine once this candidate becomes the leader. The given channel is closed once it stops
+	// being the leader.
+	OnStartedLeading func(stop <-chan struct{})
+	// Called once this candidate stops being the leader, or stops trying to be.
+	OnStoppedLeading func()
+}
+
+type LeaderElector struct {
+	lock          ResourceLock
+	leaseDuration time.Duration
+	renewDeadline time.Duration
+	retryPeriod   time.Duration
+	callbacks     LeaderCallbacks
+
+	// The record last observed, and the local time it was observed at. A follower only takes over once the record
+	// has not changed for a lease duration, so the clocks of the candidates need not be in sync.
+	observedLock   sync.Mutex
+	observedRecord LeaderElectionRecord
+	observedTime   time.Time
+
+	now func() time.Time
+}
+
+func NewLeaderElector(config LeaderElectionConfiguration, lock ResourceLock,
+	callbacks LeaderCallbacks) (*LeaderElector, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if callbacks.OnStartedLeading == nil || callbacks.OnStoppedLeading == nil {
+		return nil, fmt.Errorf("both OnStartedLeading and OnStoppedLeading callbacks must be given")
+	}
+	return &LeaderElector{
+		lock:          lock,
+		leaseDuration: config.LeaseDuration,
+		renewDeadline: config.RenewDeadline,
+		retryPeriod:   config.RetryPeriod,
+		callbacks:     callbacks,
+		now:           time.Now,
+	}, nil
+}
+
+// Run waits to acquire the lease, calls OnStartedLeading, and keeps renewing the lease until it fails for the renew
+// deadline or stop is closed. Then it calls OnStoppedLeading and returns.
+func (le *LeaderElector) Run(stop <-chan struct{}) {
+	defer le.callbacks.OnStoppedLeading()
+	if !le.acquire(stop) {
+		return
+	}
+	leading := make(chan struct{})
+	defer close(leading)
+	go le.callbacks.OnStartedLeading(leading)
+	le.renew(stop)
+}
+
+// Check whether this candidate is the leader as last observed.
+func (le *LeaderElector) IsLeader() bool {
+	return le.GetLeader() == le.lock.Identity()
+}
+
+// Get the identity of the leader as last observed.
+func (le *LeaderElector) GetLeader() string {
+	le.observedLock.Lock()
+	defer le.observedLock.Unlock()
+	return le.observedRecord.HolderIdentity
+}
+
+// Try to acquire the lease every retry period until it is acquired or stop is closed.
+func (le *LeaderElector) acquire(stop <-chan struct{}) bool {
+	glog.V(2).Infof("Trying to acquire the lease of %s as %s.", le.lock.Describe(), le.lock.Identity())
+	for {
+		if le.tryAcquireOrRenew() {
+			glog.V(2).Infof("Acquired the lease of %s as %s.", le.lock.Describe(), le.lock.Identity())
+			return true
+		}
+		if !waitOrStop(le.retryPeriod, stop) {
+			return false
+		}
+	}
+}
+
+// Renew the lease every retry period until it fails to be renewed for the renew deadline or stop is closed.
+func (le *LeaderElector) renew(stop <-chan struct{}) {
+	for {
+		deadline := le.now().Add(le.renewDeadline)
+		for !le.tryAcquireOrRenew() {
+			if le.now().After(deadline) {
+				glog.Errorf("Failed to renew the lease of %s within %s.", le.lock.Describe(), le.renewDeadline)
+				return
+			}
+			if !waitOrStop(le.retryPeriod, stop) {
+				return
+			}
+		}
+		if !waitOrStop(le.retryPeriod, stop) {
+			return
+		}
+	}
+}
+
+// Acquire the lease if it is free or expired, or renew it if this candidate holds it. It returns whether this
+// candidate holds the lease now.
+func (le *LeaderElector) tryAcquireOrRenew() bool {
+	now := metav1.NewTime(le.now())
+	record := LeaderElectionRecord{
+		HolderIdentity:       le.lock.Identity(),
+		LeaseDurationSeconds: int(le.leaseDuration / time.Second),
+		AcquireTime:          now,
+		RenewTime:            now,
+	}
+
+	oldRecord, err := le.lock.Get()
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			glog.Errorf("Failed to get %s: %s", le.lock.Describe(), err)
+			return false
+		}
+		if err := le.lock.Create(record); err != nil {
+			glog.Errorf("Failed to create %s: %s", le.lock.Describe(), err)
+			return false
+		}
+		le.observe(record, now.Time)
+		return true
+	}
+
+	le.observedLock.Lock()
+	if !reflect.DeepEqual(le.observedRecord, *oldRecord) {
+		le.observedRecord = *oldRecord
+		le.observedTime = now.Time
+	}
+	observedTime := le.observedTime
+	le.observedLock.Unlock()
+
+	if oldRecord.HolderIdentity != "" && oldRecord.HolderIdentity != record.HolderIdentity &&
+		observedTime.Add(le.leaseDuration).After(now.Time) {
+		glog.V(4).Infof("The lease of %s is held by %s.", le.lock.Describe(), oldRecord.HolderIdentity)
+		return false
+	}
+
+	if oldRecord.HolderIdentity == record.HolderIdentity {
+		record.AcquireTime = oldRecord.AcquireTime
+		record.LeaderTransitions = oldRecord.LeaderTransitions
+	} else {
+		record.LeaderTransitions = oldRecord.LeaderTransitions + 1
+	}
+	if err := le.lock.Update(record); err != nil {
+		glog.Errorf("Failed to update %s: %s", le.lock.Describe(), err)
+		return false
+	}
+	le.observe(record, now.Time)
+	return true
+}
+
+func (le *LeaderElector) observe(record LeaderElectionRecord, observedTime time.Time) {
+	le.observedLock.Lock()
+	defer le.observedLock.Unlock()
+	le.observedRecord = record
+	le.observedTime = observedTime
+}
+
+// Wait for the given duration. It returns false if stop is closed before that.
+func waitOrStop(d time.Duration, stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
@@ -0,0 +1,109 @@
+package leaderelection
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// The lock object shared by the fake locks of the candidates. The record is stored as JSON like in the annotation.
+type fakeLockObject struct {
+	record []byte
+}
+
+type fakeLock struct {
+	object   *fakeLockObject
+	identity string
+}
+
+func (l *fakeLock) Get() (*LeaderElectionRecord, error) {
+	if l.object.record == nil {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: ConfigMapsResourceLock}, DefaultLockName)
+	}
+	record := &LeaderElectionRecord{}
+	err := json.Unmarshal(l.object.record, record)
+	return record, err
+}
+
+func (l *fakeLock) Create(record LeaderElectionRecord) error {
+	return l.Update(record)
+}
+
+func (l *fakeLock) Update(record LeaderElectionRecord) error {
+	value, err := json.Marshal(record)
+	l.object.record = value
+	return err
+}
+
+func (l *fakeLock) Identity() string {
+	return l.identity
+}
+
+func (l *fakeLock) Describe() string {
+	return "fake lock"
+}
+
+func newFakeLeaderElector(t *testing.T, object *fakeLockObject, identity string, now *time.Time) *LeaderElector {
+	le, err := NewLeaderElector(NewLeaderElectionConfiguration(), &fakeLock{object, identity}, LeaderCallbacks{
+		OnStartedLeading: func(stop <-chan struct{}) {},
+		OnStoppedLeading: func() {},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	le.now = func() time.Time {
+		return *now
+	}
+	return le
+}
+
+func TestTryAcquireOrRenew(t *testing.T) {
+	object := &fakeLockObject{}
+	start := time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC)
+	nowA, nowB := start, start
+	a := newFakeLeaderElector(t, object, "a", &nowA)
+	b := newFakeLeaderElector(t, object, "b", &nowB)
+	getRecord := func() *LeaderElectionRecord {
+		record, _ := a.lock.Get()
+		return record
+	}
+
+	table := []struct {
+		description         string
+		candidate           *LeaderElector
+		now                 *time.Time
+		after               time.Duration
+		expectedAcquired    bool
+		expectedHolder      string
+		expectedAcquire     time.Duration
+		expectedTransitions int
+	}{
+		{"a creates the lock", a, &nowA, 0, true, "a", 0, 0},
+		{"b waits for the lease of a", b, &nowB, 0, false, "a", 0, 0},
+		{"a renews its lease", a, &nowA, 5 * time.Second, true, "a", 0, 0},
+		// b observed the renewal 10s after its own observation started, so the lease is valid for 15s from then.
+		{"b sees the renewal", b, &nowB, 10 * time.Second, false, "a", 0, 0},
+		{"b still waits before the lease expires", b, &nowB, 24 * time.Second, false, "a", 0, 0},
+		{"b takes over the expired lease", b, &nowB, 26 * time.Second, true, "b", 26 * time.Second, 1},
+		{"a cannot renew the lease of b", a, &nowA, 27 * time.Second, false, "b", 26 * time.Second, 1},
+	}
+	for _, item := range table {
+		*item.now = start.Add(item.after)
+		if acquired := item.candidate.tryAcquireOrRenew(); acquired != item.expectedAcquired {
+			t.Errorf("%s: expected acquired %v, got %v", item.description, item.expectedAcquired, acquired)
+		}
+		record := getRecord()
+		if record.HolderIdentity != item.expectedHolder ||
+			!record.AcquireTime.Time.Equal(start.Add(item.expectedAcquire)) ||
+			record.LeaderTransitions != item.expectedTransitions {
+			t.Errorf("%s: unexpected record %+v", item.description, record)
+		}
+		if item.candidate.IsLeader() != (item.expectedHolder == item.candidate.lock.Identity()) {
+			t.Errorf("%s: expected leader %s, got %s", item.description, item.expectedHolder,
+				item.candidate.GetLeader())
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package leaderelection
+
+import (
+	"encoding/json"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1core "k8s.io/client-go/kubernetes/typed/core/v1"
+	api "k8s.io/client-go/pkg/api/v1"
+)
+
+const (
+	// The annotation holding the leader election record on the lock object. It is the same as the one of the
+	// Kubernetes components, so that the record can be read with the same tools.
+	LeaderElectionRecordAnnotationKey = "control-plane.alpha.kubernetes.io/leader"
+
+	ConfigMapsResourceLock = "configmaps"
+	EndpointsResourceLock  = "endpoints"
+)
+
+// LeaderElectionRecord is the record of the leader stored in the lock object.
+type LeaderElectionRecord struct {
+	HolderIdentity       string      `json:"holderIdentity"`
+	LeaseDurationSeconds int         `json:"leaseDurationSeconds"`
+	AcquireTime          metav1.Time `json:"acquireTime"`
+	RenewTime            metav1.Time `json:"renewTime"`
+	LeaderTransitions    int         `json:"leaderTransitions"`
+}
+
+// ResourceLock stores the leader election record in a Kubernetes object.
+type ResourceLock interface {
+	// Get the current record. The error satisfies apierrors.IsNotFound if the lock object does not exist.
+	Get() (*LeaderElectionRecord, error)
+	// Create the lock object with the given record.
+	Create(record LeaderElectionRecord) error
+	// Update the record in the lock object got last time. It fails if the object is changed since then.
+	Update(record LeaderElectionRecord) error
+	// The identity of this candidate.
+	Identity() string
+	// Describe the lock object, e.g. configmaps default/kubeturbo.
+	Describe() string
+}
+
+// Create a lock of the given type, configmaps or endpoints, on the object of the given namespace and name.
+func NewResourceLock(lockType, namespace, name string, client v1core.CoreV1Interface,
+	identity string) (ResourceLock, error) {
+	meta := metav1.ObjectMeta{Namespace: namespace, Name: name}
+	switch lockType {
+	case ConfigMapsResourceLock:
+		return &configMapLock{meta: meta, client: client, identity: identity}, nil
+	case EndpointsResourceLock:
+		return &endpointsLock{meta: meta, client: client, identity: identity}, nil
+	default:
+		return nil, fmt.Errorf("invalid resource lock %q, it must be %s or %s", lockType, ConfigMapsResourceLock,
+			EndpointsResourceLock)
+	}
+}
+
+type configMapLock struct {
+	meta     metav1.ObjectMeta
+	client   v1core.ConfigMapsGetter
+	identity string
+	// The config map got or written last time.
+	configMap *api.ConfigMap
+}
+
+func (l *configMapLock) Get() (*LeaderElectionRecord, error) {
+	configMap, err := l.client.ConfigMaps(l.meta.Namespace).Get(l.meta.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	l.configMap = configMap
+	return getRecord(&configMap.ObjectMeta)
+}
+
+func (l *configMapLock) Create(record LeaderElectionRecord) error {
+	meta := l.meta
+	if err := setRecord(&meta, record); err != nil {
+		return err
+	}
+	configMap, err := l.client.ConfigMaps(meta.Namespace).Create(&api.ConfigMap{ObjectMeta: meta})
+	if err != nil {
+		return err
+	}
+	l.configMap = configMap
+	return nil
+}
+
+func (l *configMapLock) Update(record LeaderElectionRecord) error {
+	if l.configMap == nil {
+		return fmt.Errorf("%s is not got before updating", l.Describe())
+	}
+	if err := setRecord(&l.configMap.ObjectMeta, record); err != nil {
+		return err
+	}
+	configMap, err := l.client.ConfigMaps(l.meta.Namespace).Update(l.configMap)
+	if err != nil {
+		return err
+	}
+	l.configMap = configMap
+	return nil
+}
+
+func (l *configMapLock) Identity() string {
+	return l.identity
+}
+
+func (l *configMapLock) Describe() string {
+	return fmt.Sprintf("%s %s/%s", ConfigMapsResourceLock, l.meta.Namespace, l.meta.Name)
+}
+
+type endpointsLock struct {
+	meta     metav1.ObjectMeta
+	client   v1core.EndpointsGetter
+	identity string
+	// The endpoints got or written last time.
+	endpoints *api.Endpoints
+}
+
+func (l *endpointsLock) Get() (*LeaderElectionRecord, error) {
+	endpoints, err := l.client.Endpoints(l.meta.Namespace).Get(l.meta.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	l.endpoints = endpoints
+	return getRecord(&endpoints.ObjectMeta)
+}
+
+func (l *endpointsLock) Create(record LeaderElectionRecord) error {
+	meta := l.meta
+	if err := setRecord(&meta, record); err != nil {
+		return err
+	}
+	endpoints, err := l.client.Endpoints(meta.Namespace).Create(&api.Endpoints{ObjectMeta: meta})
+	if err != nil {
+		return err
+	}
+	l.endpoints = endpoints
+	return nil
+}
+
+func (l *endpointsLock) Update(record LeaderElectionRecord) error {
+	if l.endpoints == nil {
+		return fmt.Errorf("%s is not got before updating", l.Describe())
+	}
+	if err := setRecord(&l.endpoints.ObjectMeta, record); err != nil {
+		return err
+	}
+	endpoints, err := l.client.Endpoints(l.meta.Namespace).Update(l.endpoints)
+	if err != nil {
+		return err
+	}
+	l.endpoints = endpoints
+	return nil
+}
+
+func (l *endpointsLock) Identity() string {
+	return l.identity
+}
+
+func (l *endpointsLock) Describe() string {
+	return fmt.Sprintf("%s %s/%s", EndpointsResourceLock, l.meta.Namespace, l.meta.Name)
+}
+
+// Get the record in the annotation of the lock object. An object without the annotation has an empty record.
+func getRecord(meta *metav1.ObjectMeta) (*LeaderElectionRecord, error) {
+	record := &LeaderElectionRecord{}
+	if value, exist := meta.Annotations[LeaderElectionRecordAnnotationKey]; exist {
+		if err := json.Unmarshal([]byte(value), record); err != nil {
+			return nil, fmt.Errorf("invalid leader election record of %s/%s: %s", meta.Namespace, meta.Name, err)
+		}
+	}
+	return record, nil
+}
+
+func setRecord(meta *metav1.ObjectMeta, record LeaderElectionRecord) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if meta.Annotations == nil {
+		meta.Annotations = make(map[string]string)
+	}
+	meta.Annotations[LeaderElectionRecordAnnotationKey] = string(value)
+	return nil
+}
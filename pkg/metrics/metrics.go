@@ -0,0 +1,211 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	schedulermetrics "k8s.io/kubernetes/plugin/pkg/scheduler/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	kubeturboNamespace = "kubeturbo"
+
+	discoverySubsystem  = "discovery"
+	monitoringSubsystem = "monitoring"
+	actionSubsystem     = "action"
+	turboSubsystem      = "turbo"
+	leaderSubsystem     = "leader_election"
+)
+
+// The phases of a discovery.
+const (
+	DiscoveryPhaseSnapshot = "snapshot"
+	DiscoveryPhaseWorkers  = "workers"
+	DiscoveryPhaseAffinity = "affinity"
+	DiscoveryPhaseServices = "services"
+	DiscoveryPhaseTotal    = "total"
+)
+
+// The states of the connection to the Turbo server.
+const (
+	TurboDisconnected = 0
+	TurboConnecting   = 1
+	TurboRegistered   = 2
+)
+
+var (
+	DiscoveryPhaseLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: kubeturboNamespace,
+			Subsystem: discoverySubsystem,
+			Name:      "phase_duration_seconds",
+			Help:      "Duration of each phase of the discoveries, and of the whole discoveries as phase total",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 15),
+		},
+		[]string{"target", "phase"},
+	)
+	DiscoveredEntities = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: kubeturboNamespace,
+			Subsystem: discoverySubsystem,
+			Name:      "entities",
+			Help:      "Number of entities built by the last successful discovery, by entity type",
+		},
+		[]string{"target", "entity_type"},
+	)
+	MonitoringScrapeLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: kubeturboNamespace,
+			Subsystem: monitoringSubsystem,
+			Name:      "scrape_duration_seconds",
+			Help:      "Duration of the monitoring workers scraping the nodes of a discovery task, by monitoring source",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 15),
+		},
+		[]string{"source"},
+	)
+	MonitoringScrapeFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: kubeturboNamespace,
+			Subsystem: monitoringSubsystem,
+			Name:      "scrape_failures_total",
+			Help:      "Number of failures reported by the monitoring workers, e.g. a kubelet failed to be scraped, by monitoring source",
+		},
+		[]string{"source"},
+	)
+	MonitoringWorkerTimeouts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: kubeturboNamespace,
+			Subsystem: monitoringSubsystem,
+			Name:      "worker_timeouts_total",
+			Help:      "Number of monitoring workers exceeding the time limit of their source, by monitoring source",
+		},
+		[]string{"source"},
+	)
+	ActionsReceived = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: kubeturboNamespace,
+			Subsystem: actionSubsystem,
+			Name:      "received_total",
+			Help:      "Number of actions received from Turbo, by turbo action type",
+		},
+		[]string{"action_type"},
+	)
+	ActionsSucceeded = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: kubeturboNamespace,
+			Subsystem: actionSubsystem,
+			Name:      "succeeded_total",
+			Help:      "Number of actions reported to Turbo as succeeded, by turbo action type",
+		},
+		[]string{"action_type"},
+	)
+	ActionsFailed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: kubeturboNamespace,
+			Subsystem: actionSubsystem,
+			Name:      "failed_total",
+			Help:      "Number of actions reported to Turbo as failed, by turbo action type",
+		},
+		[]string{"action_type"},
+	)
+	ActionExecutionLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: kubeturboNamespace,
+			Subsystem: actionSubsystem,
+			Name:      "execution_duration_seconds",
+			Help:      "Duration from receiving an action to reporting its result to Turbo, by turbo action type",
+			Buckets:   prometheus.ExponentialBuckets(0.1, 2, 15),
+		},
+		[]string{"action_type"},
+	)
+	TurboConnectionState = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: kubeturboNamespace,
+			Subsystem: turboSubsystem,
+			Name:      "connection_state",
+			Help:      "State of the websocket connection to the Turbo server: 0 disconnected, 1 connecting, 2 registered",
+		},
+	)
+	TurboLastRequestTime = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: kubeturboNamespace,
+			Subsystem: turboSubsystem,
+			Name:      "last_request_timestamp_seconds",
+			Help:      "Unix time of the last validation, discovery or action request received from the Turbo server",
+		},
+	)
+	LeaderElectionIsLeader = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: kubeturboNamespace,
+			Subsystem: leaderSubsystem,
+			Name:      "is_leader",
+			Help:      "Whether this kubeturbo is the leader connected to the Turbo server, 1 if it is, otherwise 0",
+		},
+	)
+)
+
+var registerMetrics sync.Once
+
+// Register all metrics, including the ones of the scheduler.
+func Register() {
+	registerMetrics.Do(func() {
+		prometheus.MustRegister(DiscoveryPhaseLatency)
+		prometheus.MustRegister(DiscoveredEntities)
+		prometheus.MustRegister(MonitoringScrapeLatency)
+		prometheus.MustRegister(MonitoringScrapeFailures)
+		prometheus.MustRegister(MonitoringWorkerTimeouts)
+		prometheus.MustRegister(ActionsReceived)
+		prometheus.MustRegister(ActionsSucceeded)
+		prometheus.MustRegister(ActionsFailed)
+		prometheus.MustRegister(ActionExecutionLatency)
+		prometheus.MustRegister(TurboConnectionState)
+		prometheus.MustRegister(TurboLastRequestTime)
+		prometheus.MustRegister(LeaderElectionIsLeader)
+		schedulermetrics.Register()
+	})
+}
+
+var (
+	// <target : entity types reported by the last successful discovery>
+	reportedEntityTypes     = make(map[string]map[string]bool)
+	reportedEntityTypesLock sync.Mutex
+)
+
+// Set the number of entities of each type discovered in the target. The types not discovered any more are removed.
+func SetDiscoveredEntities(target string, entityCounts map[string]int) {
+	reportedEntityTypesLock.Lock()
+	defer reportedEntityTypesLock.Unlock()
+	for entityType := range reportedEntityTypes[target] {
+		if _, exist := entityCounts[entityType]; !exist {
+			DiscoveredEntities.DeleteLabelValues(target, entityType)
+		}
+	}
+	entityTypes := make(map[string]bool)
+	for entityType, count := range entityCounts {
+		DiscoveredEntities.WithLabelValues(target, entityType).Set(float64(count))
+		entityTypes[entityType] = true
+	}
+	reportedEntityTypes[target] = entityTypes
+}
+
+// Record the end of an action, which is succeeded or failed.
+func ObserveActionResult(actionType string, succeeded bool, start time.Time) {
+	if succeeded {
+		ActionsSucceeded.WithLabelValues(actionType).Inc()
+	} else {
+		ActionsFailed.WithLabelValues(actionType).Inc()
+	}
+	ActionExecutionLatency.WithLabelValues(actionType).Observe(SinceInSeconds(start))
+}
+
+// Record a request received from the Turbo server.
+func TurboRequestReceived() {
+	TurboLastRequestTime.Set(float64(time.Now().Unix()))
+}
+
+// Gets the time since the specified start in seconds.
+func SinceInSeconds(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
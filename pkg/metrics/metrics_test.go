@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func getDiscoveredEntities(t *testing.T, target, entityType string) float64 {
+	metric := &dto.Metric{}
+	if err := DiscoveredEntities.WithLabelValues(target, entityType).Write(metric); err != nil {
+		t.Fatal(err)
+	}
+	return metric.GetGauge().GetValue()
+}
+
+func TestSetDiscoveredEntities(t *testing.T) {
+	SetDiscoveredEntities("cluster-a", map[string]int{"VIRTUAL_MACHINE": 3, "CONTAINER_POD": 10})
+	SetDiscoveredEntities("cluster-b", map[string]int{"VIRTUAL_MACHINE": 2})
+	SetDiscoveredEntities("cluster-a", map[string]int{"CONTAINER_POD": 12})
+
+	table := []struct {
+		target        string
+		entityType    string
+		expectedCount float64
+	}{
+		{"cluster-a", "CONTAINER_POD", 12},
+		// The types not discovered any more are removed, so they read as a new gauge.
+		{"cluster-a", "VIRTUAL_MACHINE", 0},
+		{"cluster-b", "VIRTUAL_MACHINE", 2},
+	}
+	for _, item := range table {
+		if count := getDiscoveredEntities(t, item.target, item.entityType); count != item.expectedCount {
+			t.Errorf("Expected %v %s entities in %s, got %v", item.expectedCount, item.entityType, item.target, count)
+		}
+	}
+}
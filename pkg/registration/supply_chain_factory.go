@@ -15,6 +15,9 @@ var (
 	cpuProvisionedType proto.CommodityDTO_CommodityType = proto.CommodityDTO_CPU_PROVISIONED
 	memProvisionedType proto.CommodityDTO_CommodityType = proto.CommodityDTO_MEM_PROVISIONED
 	transactionType    proto.CommodityDTO_CommodityType = proto.CommodityDTO_TRANSACTION
+	vStorageType       proto.CommodityDTO_CommodityType = proto.CommodityDTO_VSTORAGE
+	storageAmountType  proto.CommodityDTO_CommodityType = proto.CommodityDTO_STORAGE_AMOUNT
+	netThroughputType  proto.CommodityDTO_CommodityType = proto.CommodityDTO_NET_THROUGHPUT
 
 	clusterType    proto.CommodityDTO_CommodityType = proto.CommodityDTO_CLUSTER
 	appCommType    proto.CommodityDTO_CommodityType = proto.CommodityDTO_APPLICATION
@@ -29,6 +32,9 @@ var (
 	applicationTemplateComm    *proto.TemplateCommodity = &proto.TemplateCommodity{Key: &fakeKey, CommodityType: &appCommType}
 	clusterTemplateComm        *proto.TemplateCommodity = &proto.TemplateCommodity{Key: &fakeKey, CommodityType: &clusterType}
 	transactionTemplateComm    *proto.TemplateCommodity = &proto.TemplateCommodity{Key: &fakeKey, CommodityType: &transactionType}
+	vStorageTemplateComm       *proto.TemplateCommodity = &proto.TemplateCommodity{Key: &fakeKey, CommodityType: &vStorageType}
+	storageAmountTemplateComm  *proto.TemplateCommodity = &proto.TemplateCommodity{Key: &fakeKey, CommodityType: &storageAmountType}
+	netThroughputTemplateComm  *proto.TemplateCommodity = &proto.TemplateCommodity{CommodityType: &netThroughputType}
 )
 
 type SupplyChainFactory struct {
@@ -55,6 +61,12 @@ func (f *SupplyChainFactory) createSupplyChain() ([]*proto.TemplateDTO, error) {
 		return nil, err
 	}
 
+	// Container supply chain builder
+	containerSupplyChainNodeBuilder, err := f.buildContainerSupplyBuilder()
+	if err != nil {
+		return nil, err
+	}
+
 	// Application supply chain builder
 	appSupplyChainNodeBuilder, err := f.buildApplicationSupplyBuilder()
 	if err != nil {
@@ -70,6 +82,7 @@ func (f *SupplyChainFactory) createSupplyChain() ([]*proto.TemplateDTO, error) {
 	supplyChainBuilder := supplychain.NewSupplyChainBuilder()
 	supplyChainBuilder.Top(vAppSupplyChainNodeBuilder)
 	supplyChainBuilder.Entity(appSupplyChainNodeBuilder)
+	supplyChainBuilder.Entity(containerSupplyChainNodeBuilder)
 	supplyChainBuilder.Entity(podSupplyChainNodeBuilder)
 	supplyChainBuilder.Entity(nodeSupplyChainNodeBuilder)
 
@@ -81,6 +94,8 @@ func (f *SupplyChainFactory) buildNodeSupplyBuilder() (*proto.TemplateDTO, error
 	nodeSupplyChainNodeBuilder = nodeSupplyChainNodeBuilder.
 		Sells(vCpuTemplateComm).
 		Sells(vMemTemplateComm).
+		Sells(vStorageTemplateComm).
+		Sells(netThroughputTemplateComm).
 		// TODO we will re-include provisioned commodities sold by node later.
 		//Sells(cpuProvisionedTemplateComm).
 		//Sells(memProvisionedTemplateComm)
@@ -95,8 +110,12 @@ func (f *SupplyChainFactory) buildPodSupplyBuilder() (*proto.TemplateDTO, error)
 	podSupplyChainNodeBuilder = podSupplyChainNodeBuilder.
 		Sells(vCpuTemplateComm).
 		Sells(vMemTemplateComm).
+		Sells(vStorageTemplateComm).
+		Sells(storageAmountTemplateComm).
 		Sells(applicationTemplateComm).
 		Provider(proto.EntityDTO_VIRTUAL_MACHINE, proto.Provider_HOSTING).
+		Buys(vStorageTemplateComm).
+		Buys(netThroughputTemplateComm).
 		// TODO we will re-include provisioned commodities bought by pod later.
 		//Buys(cpuProvisionedTemplateComm).
 		//Buys(memProvisionedTemplateComm).
@@ -107,6 +126,8 @@ func (f *SupplyChainFactory) buildPodSupplyBuilder() (*proto.TemplateDTO, error)
 	vmPodExtLinkBuilder.Link(proto.EntityDTO_CONTAINER_POD, proto.EntityDTO_VIRTUAL_MACHINE, proto.Provider_HOSTING).
 		Commodity(vCpuType, false).
 		Commodity(vMemType, false).
+		Commodity(vStorageType, true).
+		Commodity(netThroughputType, false).
 		//Commodity(cpuProvisionedType, false).
 		//Commodity(memProvisionedType, false).
 		Commodity(vmPMAccessType, true).
@@ -133,12 +154,27 @@ func (f *SupplyChainFactory) buildPodSupplyBuilder() (*proto.TemplateDTO, error)
 	return podSupplyChainNodeBuilder.ConnectsTo(vmPodExternalLink).Create()
 }
 
+func (f *SupplyChainFactory) buildContainerSupplyBuilder() (*proto.TemplateDTO, error) {
+	// Container supply chain node builder
+	containerSupplyChainNodeBuilder := supplychain.NewSupplyChainNodeBuilder(proto.EntityDTO_CONTAINER)
+	containerSupplyChainNodeBuilder = containerSupplyChainNodeBuilder.
+		Sells(vCpuTemplateComm).
+		Sells(vMemTemplateComm).
+		Sells(applicationTemplateComm).
+		Provider(proto.EntityDTO_CONTAINER_POD, proto.Provider_HOSTING).
+		Buys(vCpuTemplateComm).
+		Buys(vMemTemplateComm).
+		Buys(applicationTemplateComm)
+
+	return containerSupplyChainNodeBuilder.Create()
+}
+
 func (f *SupplyChainFactory) buildApplicationSupplyBuilder() (*proto.TemplateDTO, error) {
 	// Application supply chain builder
 	appSupplyChainNodeBuilder := supplychain.NewSupplyChainNodeBuilder(proto.EntityDTO_APPLICATION)
 	appSupplyChainNodeBuilder = appSupplyChainNodeBuilder.
 		Sells(transactionTemplateComm).
-		Provider(proto.EntityDTO_CONTAINER_POD, proto.Provider_HOSTING).
+		Provider(proto.EntityDTO_CONTAINER, proto.Provider_HOSTING).
 		Buys(vCpuTemplateComm).
 		Buys(vMemTemplateComm).
 		Buys(applicationTemplateComm)
@@ -0,0 +1,281 @@
+package mockturbo
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/turbonomic/turbo-api/pkg/api"
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+
+	goproto "github.com/golang/protobuf/proto"
+)
+
+const (
+	DefaultRequestTimeout = 10 * time.Minute
+
+	consoleHelp = `Commands:
+  probes                            list the registered probes
+  targets                           list the added targets, numbered from 1
+  validate [target]                 validate a target, the first one by default
+  discover [target] [file]          discover a target, and write the full response as JSON to file if it is given
+  action <file> [target]            execute the action request in the protobuf text file for a target. The probe
+                                    type and the account values of the target are used unless they are in the file
+  help                              show this help
+  quit                              exit
+`
+)
+
+// Console drives a mediation server with commands read line by line, and writes the responses it gets.
+type Console struct {
+	server  *MediationServer
+	out     io.Writer
+	timeout time.Duration
+}
+
+func NewConsole(server *MediationServer, out io.Writer) *Console {
+	return &Console{
+		server:  server,
+		out:     out,
+		timeout: DefaultRequestTimeout,
+	}
+}
+
+// The max time to wait for the response of a request.
+func (c *Console) WithTimeout(timeout time.Duration) *Console {
+	c.timeout = timeout
+	return c
+}
+
+// Run the commands read from in until it ends or a quit command is read. A failed command does not stop the console.
+func (c *Console) Run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	fmt.Fprint(c.out, "> ")
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "quit" || line == "exit" {
+			return nil
+		}
+		if line != "" {
+			if err := c.Execute(line); err != nil {
+				fmt.Fprintf(c.out, "Error: %s\n", err)
+			}
+		}
+		fmt.Fprint(c.out, "> ")
+	}
+	return scanner.Err()
+}
+
+// Execute a single command.
+func (c *Console) Execute(line string) error {
+	args := strings.Fields(line)
+	switch args[0] {
+	case "help":
+		fmt.Fprint(c.out, consoleHelp)
+		return nil
+	case "probes":
+		return c.listProbes()
+	case "targets":
+		return c.listTargets()
+	case "validate":
+		return c.validate(args[1:])
+	case "discover":
+		return c.discover(args[1:])
+	case "action":
+		return c.executeAction(args[1:])
+	}
+	return fmt.Errorf("unknown command %q, try help", args[0])
+}
+
+func (c *Console) listProbes() error {
+	probes := c.server.Probes()
+	if len(probes) == 0 {
+		fmt.Fprintln(c.out, "No probe is registered.")
+	}
+	for _, probeInfo := range probes {
+		fmt.Fprintf(c.out, "%s (%s): %d supply chain templates, %d action policies\n", probeInfo.GetProbeType(),
+			probeInfo.GetProbeCategory(), len(probeInfo.GetSupplyChainDefinitionSet()), len(probeInfo.GetActionPolicy()))
+	}
+	return nil
+}
+
+func (c *Console) listTargets() error {
+	targets := c.server.Targets()
+	if len(targets) == 0 {
+		fmt.Fprintln(c.out, "No target is added.")
+	}
+	for i, target := range targets {
+		var fields []string
+		for _, field := range target.InputFields {
+			fields = append(fields, field.Name+"="+field.Value)
+		}
+		fmt.Fprintf(c.out, "%d. %s %s [%s]\n", i+1, target.Type, target.UUID, strings.Join(fields, ", "))
+	}
+	return nil
+}
+
+// Get the target of the given 1-based number, or the first target if arg is empty.
+func (c *Console) getTarget(arg string) (*api.Target, error) {
+	targets := c.server.Targets()
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no target is added")
+	}
+	if arg == "" {
+		return targets[0], nil
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 || n > len(targets) {
+		return nil, fmt.Errorf("invalid target %q, it should be between 1 and %d", arg, len(targets))
+	}
+	return targets[n-1], nil
+}
+
+func (c *Console) validate(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: validate [target]")
+	}
+	target, err := c.getTarget(optionalArg(args, 0))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	response, err := c.server.Validate(ctx, target.Type, AccountValues(target))
+	if err != nil {
+		return fmt.Errorf("validation failed: %s", err)
+	}
+	return writeJSON(c.out, response)
+}
+
+func (c *Console) discover(args []string) error {
+	if len(args) > 2 {
+		return fmt.Errorf("usage: discover [target] [file]")
+	}
+	target, err := c.getTarget(optionalArg(args, 0))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	start := time.Now()
+	response, err := c.server.Discover(ctx, target.Type, AccountValues(target))
+	if err != nil {
+		return fmt.Errorf("discovery failed: %s", err)
+	}
+
+	if file := optionalArg(args, 1); file != "" {
+		data, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal the discovery response: %s", err)
+		}
+		if err := ioutil.WriteFile(file, data, 0644); err != nil {
+			return fmt.Errorf("failed to write the discovery response: %s", err)
+		}
+		fmt.Fprintf(c.out, "Discovery response is written to %s.\n", file)
+	} else if err := writeJSON(c.out, response); err != nil {
+		return err
+	}
+	c.writeDiscoverySummary(response, time.Since(start))
+	return nil
+}
+
+func (c *Console) writeDiscoverySummary(response *proto.DiscoveryResponse, elapsed time.Duration) {
+	count := make(map[string]int)
+	for _, entityDTO := range response.GetEntityDTO() {
+		count[entityDTO.GetEntityType().String()]++
+	}
+	var entityTypes []string
+	for entityType := range count {
+		entityTypes = append(entityTypes, entityType)
+	}
+	sort.Strings(entityTypes)
+
+	fmt.Fprintf(c.out, "Discovered %d entities in %s:\n", len(response.GetEntityDTO()), elapsed)
+	for _, entityType := range entityTypes {
+		fmt.Fprintf(c.out, "  %s: %d\n", entityType, count[entityType])
+	}
+	for _, errorDTO := range response.GetErrorDTO() {
+		fmt.Fprintf(c.out, "  %s: %s\n", errorDTO.GetSeverity(), errorDTO.GetDescription())
+	}
+}
+
+func (c *Console) executeAction(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("usage: action <file> [target]")
+	}
+	actionRequest, err := LoadActionRequest(args[0])
+	if err != nil {
+		return err
+	}
+	target, err := c.getTarget(optionalArg(args, 1))
+	if err != nil {
+		return err
+	}
+	if actionRequest.ProbeType == nil {
+		actionRequest.ProbeType = &target.Type
+	}
+	if len(actionRequest.AccountValue) == 0 {
+		actionRequest.AccountValue = AccountValues(target)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	result, err := c.server.ExecuteAction(ctx, actionRequest, func(progress *proto.ActionProgress) {
+		response := progress.GetResponse()
+		fmt.Fprintf(c.out, "Progress: %s %d%% %s\n", response.GetActionResponseState(), response.GetProgress(),
+			response.GetResponseDescription())
+	})
+	if err != nil {
+		return fmt.Errorf("action failed: %s", err)
+	}
+	response := result.GetResponse()
+	fmt.Fprintf(c.out, "Result: %s %d%% %s\n", response.GetActionResponseState(), response.GetProgress(),
+		response.GetResponseDescription())
+	return nil
+}
+
+// Load a hand-written action request from a file in protobuf text format. JSON is not supported, as it cannot set
+// the oneof entity data, e.g. the IP addresses of the VM to move a pod to.
+func LoadActionRequest(path string) (*proto.ActionRequest, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read action request %s: %s", path, err)
+	}
+	actionRequest := &proto.ActionRequest{}
+	// The probe type and the account values may be left to the target, and other missing required fields fail the
+	// request when it is sent.
+	if err := goproto.UnmarshalText(string(content), actionRequest); err != nil {
+		if _, isRequiredNotSet := err.(*goproto.RequiredNotSetError); !isRequiredNotSet {
+			return nil, fmt.Errorf("failed to parse action request %s: %s", path, err)
+		}
+	}
+	if actionRequest.ActionExecutionDTO == nil {
+		return nil, fmt.Errorf("action request %s has no actionExecutionDTO", path)
+	}
+	return actionRequest, nil
+}
+
+func writeJSON(w io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		return fmt.Errorf("failed to write %T: %s", value, err)
+	}
+	return nil
+}
+
+func optionalArg(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+	return ""
+}
@@ -0,0 +1,47 @@
+package mockturbo
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+)
+
+func TestLoadActionRequest(t *testing.T) {
+	actionRequest, err := LoadActionRequest("testdata/move-pod.txt")
+	if err != nil {
+		t.Fatalf("Failed to load action request: %s", err)
+	}
+	actionItems := actionRequest.GetActionExecutionDTO().GetActionItem()
+	if len(actionItems) != 1 {
+		t.Fatalf("Expected 1 action item, got %d", len(actionItems))
+	}
+	actionItem := actionItems[0]
+	if actionItem.GetActionType() != proto.ActionItemDTO_MOVE ||
+		actionItem.GetTargetSE().GetEntityType() != proto.EntityDTO_CONTAINER_POD ||
+		len(actionItem.GetNewSE().GetVirtualMachineData().GetIpAddress()) != 1 {
+		t.Errorf("Unexpected action item %v", actionItem)
+	}
+}
+
+func TestConsoleExecute(t *testing.T) {
+	table := []struct {
+		line      string
+		expectErr bool
+	}{
+		{line: "help"},
+		{line: "probes"},
+		{line: "targets"},
+		{line: "validate", expectErr: true},
+		{line: "discover 1 file extra", expectErr: true},
+		{line: "action", expectErr: true},
+		{line: "action testdata/move-pod.txt", expectErr: true},
+		{line: "unknown", expectErr: true},
+	}
+	console := NewConsole(NewMediationServer(), &bytes.Buffer{})
+	for _, item := range table {
+		if err := console.Execute(item.line); (err != nil) != item.expectErr {
+			t.Errorf("%q: expected error %t, got %v", item.line, item.expectErr, err)
+		}
+	}
+}
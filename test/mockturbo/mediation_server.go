@@ -0,0 +1,385 @@
+package mockturbo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/turbonomic/turbo-api/pkg/api"
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+	"github.com/turbonomic/turbo-go-sdk/pkg/version"
+
+	"github.com/golang/glog"
+	goproto "github.com/golang/protobuf/proto"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// The paths the probes connect to, the same as those of a Turbonomic server.
+	MediationPath = "/vmturbo/remoteMediation"
+	TargetsPath   = "/vmturbo/rest/targets"
+)
+
+var errNotConnected = errors.New("no probe container is connected")
+
+// MediationServer implements the server side of the Turbonomic mediation protocol for a single probe container.
+// It accepts the probe registration and the targets added through the REST API, and sends validation, discovery and
+// action requests on demand. A new connection of the probe container replaces the previous one.
+type MediationServer struct {
+	lock sync.Mutex
+	// The connection of the registered probe container, nil until the probes are registered.
+	conn *websocket.Conn
+	// Closed once the probes are registered over the current connection.
+	registered chan struct{}
+	probes     []*proto.ProbeInfo
+	targets    []*api.Target
+
+	nextMessageID int32
+	// <message_id : request waiting for client messages>
+	pending map[int32]*pendingRequest
+
+	// Only one message is written to the connection at a time.
+	sendLock sync.Mutex
+}
+
+func NewMediationServer() *MediationServer {
+	return &MediationServer{
+		registered:    make(chan struct{}),
+		nextMessageID: 1,
+		pending:       make(map[int32]*pendingRequest),
+	}
+}
+
+// A request sent over a connection, waiting for the client messages responding to it.
+type pendingRequest struct {
+	conn *websocket.Conn
+	// Closed if the connection is closed before the request is answered.
+	messages chan *proto.MediationClientMessage
+}
+
+// The handler serving the mediation websocket and the target REST API.
+func (s *MediationServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(MediationPath, websocket.Server{Handler: s.serveConn})
+	mux.HandleFunc(TargetsPath, s.serveTargets)
+	return mux
+}
+
+// Get the probes registered by the probe container.
+func (s *MediationServer) Probes() []*proto.ProbeInfo {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.probes
+}
+
+// Get the targets added through the REST API, in the order they are added.
+func (s *MediationServer) Targets() []*api.Target {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.targets
+}
+
+// Wait until the probe container has registered its probes, or ctx is done.
+func (s *MediationServer) WaitForRegistration(ctx context.Context) error {
+	s.lock.Lock()
+	registered := s.registered
+	s.lock.Unlock()
+	select {
+	case <-registered:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Serve the connection of a probe container: negotiate the protocol version, accept the probe registration, then
+// dispatch the client messages to the requests they respond to.
+func (s *MediationServer) serveConn(ws *websocket.Conn) {
+	glog.V(2).Infof("Probe container connected from %s", ws.Request().RemoteAddr)
+	defer ws.Close()
+
+	negotiationRequest := &version.NegotiationRequest{}
+	if err := receive(ws, negotiationRequest); err != nil {
+		glog.Errorf("Failed to receive the negotiation request: %s", err)
+		return
+	}
+	glog.V(2).Infof("Received negotiation request for protocol version %s", negotiationRequest.GetProtocolVersion())
+	result := version.NegotiationAnswer_ACCEPTED
+	description := "Version " + negotiationRequest.GetProtocolVersion() + " is accepted by the mock server"
+	if err := send(ws, &version.NegotiationAnswer{NegotiationResult: &result, Description: &description}); err != nil {
+		glog.Errorf("Failed to send the negotiation answer: %s", err)
+		return
+	}
+
+	containerInfo := &proto.ContainerInfo{}
+	if err := receive(ws, containerInfo); err != nil {
+		glog.Errorf("Failed to receive the probe registration: %s", err)
+		return
+	}
+	if err := send(ws, &proto.Ack{}); err != nil {
+		glog.Errorf("Failed to acknowledge the probe registration: %s", err)
+		return
+	}
+	for _, probeInfo := range containerInfo.GetProbes() {
+		glog.V(2).Infof("Registered probe %s of category %s", probeInfo.GetProbeType(), probeInfo.GetProbeCategory())
+	}
+
+	s.lock.Lock()
+	if s.conn != nil {
+		// The requests sent over the previous connection fail once it is closed.
+		glog.Warningf("The previous probe container connection is replaced")
+		s.conn.Close()
+	} else {
+		close(s.registered)
+	}
+	s.conn = ws
+	s.probes = containerInfo.GetProbes()
+	s.lock.Unlock()
+
+	for {
+		clientMsg := &proto.MediationClientMessage{}
+		if err := receive(ws, clientMsg); err != nil {
+			glog.Errorf("Probe container disconnected: %s", err)
+			break
+		}
+		s.dispatch(ws, clientMsg)
+	}
+	s.disconnect(ws)
+}
+
+// Deliver a client message received over ws to the request with the same message ID. It is only called by the
+// goroutine serving ws, which is the only one closing the channels of the requests sent over ws.
+func (s *MediationServer) dispatch(ws *websocket.Conn, clientMsg *proto.MediationClientMessage) {
+	s.lock.Lock()
+	request, exist := s.pending[clientMsg.GetMessageID()]
+	s.lock.Unlock()
+	if !exist || request.conn != ws {
+		glog.Warningf("Dropped client message for unknown message ID %d: %s", clientMsg.GetMessageID(), clientMsg)
+		return
+	}
+	select {
+	case request.messages <- clientMsg:
+	default:
+		glog.Warningf("Dropped client message for message ID %d as the request is not reading", clientMsg.GetMessageID())
+	}
+}
+
+// Fail the requests sent over the closed connection ws, and wait for a new registration if ws is the current one.
+func (s *MediationServer) disconnect(ws *websocket.Conn) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for id, request := range s.pending {
+		if request.conn == ws {
+			close(request.messages)
+			delete(s.pending, id)
+		}
+	}
+	if s.conn == ws {
+		s.conn = nil
+		s.registered = make(chan struct{})
+	}
+}
+
+// Send a server message with a new message ID, and pass the client messages responding to it to handle until handle
+// returns true, the connection is closed or ctx is done.
+func (s *MediationServer) request(ctx context.Context, serverMsg *proto.MediationServerMessage,
+	handle func(*proto.MediationClientMessage) bool) error {
+	s.lock.Lock()
+	ws := s.conn
+	if ws == nil {
+		s.lock.Unlock()
+		return errNotConnected
+	}
+	id := s.nextMessageID
+	s.nextMessageID++
+	// Buffered, so that the connection is not blocked by a slow request.
+	request := &pendingRequest{
+		conn:     ws,
+		messages: make(chan *proto.MediationClientMessage, 64),
+	}
+	s.pending[id] = request
+	s.lock.Unlock()
+
+	defer func() {
+		s.lock.Lock()
+		delete(s.pending, id)
+		s.lock.Unlock()
+	}()
+
+	serverMsg.MessageID = &id
+	s.sendLock.Lock()
+	err := send(ws, serverMsg)
+	s.sendLock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case clientMsg, ok := <-request.messages:
+			if !ok {
+				return fmt.Errorf("probe container disconnected before message %d is answered", id)
+			}
+			if handle(clientMsg) {
+				return nil
+			}
+		}
+	}
+}
+
+// Validate a target of the probe of the given type.
+func (s *MediationServer) Validate(ctx context.Context, probeType string,
+	accountValues []*proto.AccountValue) (*proto.ValidationResponse, error) {
+	serverMsg := &proto.MediationServerMessage{
+		MediationServerMessage: &proto.MediationServerMessage_ValidationRequest{
+			ValidationRequest: &proto.ValidationRequest{
+				ProbeType:    &probeType,
+				AccountValue: accountValues,
+			},
+		},
+	}
+	var response *proto.ValidationResponse
+	err := s.request(ctx, serverMsg, func(clientMsg *proto.MediationClientMessage) bool {
+		response = clientMsg.GetValidationResponse()
+		return response != nil
+	})
+	return response, err
+}
+
+// Discover a target of the probe of the given type. The probe sends keep alive messages while discovering, then the
+// discovery response, followed by an empty response to mark the end of the discovery.
+func (s *MediationServer) Discover(ctx context.Context, probeType string,
+	accountValues []*proto.AccountValue) (*proto.DiscoveryResponse, error) {
+	serverMsg := &proto.MediationServerMessage{
+		MediationServerMessage: &proto.MediationServerMessage_DiscoveryRequest{
+			DiscoveryRequest: &proto.DiscoveryRequest{
+				ProbeType:    &probeType,
+				AccountValue: accountValues,
+			},
+		},
+	}
+	var response *proto.DiscoveryResponse
+	err := s.request(ctx, serverMsg, func(clientMsg *proto.MediationClientMessage) bool {
+		if clientMsg.GetKeepAlive() != nil {
+			glog.V(3).Infof("Discovery %d is still ongoing", clientMsg.GetMessageID())
+			return false
+		}
+		discoveryResponse := clientMsg.GetDiscoveryResponse()
+		if discoveryResponse == nil {
+			glog.Warningf("Unexpected message for discovery %d: %s", clientMsg.GetMessageID(), clientMsg)
+			return false
+		}
+		if response == nil {
+			response = discoveryResponse
+			return false
+		}
+		return true
+	})
+	return response, err
+}
+
+// Execute an action. Each action progress reported by the probe is passed to onProgress before the result is returned.
+func (s *MediationServer) ExecuteAction(ctx context.Context, actionRequest *proto.ActionRequest,
+	onProgress func(*proto.ActionProgress)) (*proto.ActionResult, error) {
+	serverMsg := &proto.MediationServerMessage{
+		MediationServerMessage: &proto.MediationServerMessage_ActionRequest{
+			ActionRequest: actionRequest,
+		},
+	}
+	var result *proto.ActionResult
+	err := s.request(ctx, serverMsg, func(clientMsg *proto.MediationClientMessage) bool {
+		if progress := clientMsg.GetActionProgress(); progress != nil {
+			if onProgress != nil {
+				onProgress(progress)
+			}
+			return false
+		}
+		result = clientMsg.GetActionResponse()
+		return result != nil
+	})
+	return result, err
+}
+
+// Accept the targets added by the probe container. A target replaces the one of the same type with the same
+// input field values.
+func (s *MediationServer) serveTargets(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, req.Method+" is not supported", http.StatusMethodNotAllowed)
+		return
+	}
+	target := &api.Target{}
+	if err := json.NewDecoder(req.Body).Decode(target); err != nil {
+		http.Error(w, fmt.Sprintf("invalid target: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	s.lock.Lock()
+	replaced := false
+	for i, existing := range s.targets {
+		if sameTarget(existing, target) {
+			target.UUID = existing.UUID
+			s.targets[i] = target
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		target.UUID = fmt.Sprintf("target-%d", len(s.targets)+1)
+		s.targets = append(s.targets, target)
+	}
+	s.lock.Unlock()
+	glog.V(2).Infof("Added %s target %s", target.Type, target.UUID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(target)
+}
+
+func sameTarget(t1, t2 *api.Target) bool {
+	if t1.Type != t2.Type || len(t1.InputFields) != len(t2.InputFields) {
+		return false
+	}
+	for i := range t1.InputFields {
+		if t1.InputFields[i].Name != t2.InputFields[i].Name || t1.InputFields[i].Value != t2.InputFields[i].Value {
+			return false
+		}
+	}
+	return true
+}
+
+// The account values of a target, as sent in the requests for the target.
+func AccountValues(target *api.Target) []*proto.AccountValue {
+	var accountValues []*proto.AccountValue
+	for _, field := range target.InputFields {
+		key, value := field.Name, field.Value
+		accountValues = append(accountValues, &proto.AccountValue{
+			Key:         &key,
+			StringValue: &value,
+		})
+	}
+	return accountValues
+}
+
+// Every message is sent as a binary websocket frame holding a single protobuf message.
+func send(ws *websocket.Conn, msg goproto.Message) error {
+	data, err := goproto.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %T: %s", msg, err)
+	}
+	return websocket.Message.Send(ws, data)
+}
+
+func receive(ws *websocket.Conn, msg goproto.Message) error {
+	var data []byte
+	if err := websocket.Message.Receive(ws, &data); err != nil {
+		return err
+	}
+	if err := goproto.Unmarshal(data, msg); err != nil {
+		return fmt.Errorf("failed to unmarshal %T: %s", msg, err)
+	}
+	return nil
+}
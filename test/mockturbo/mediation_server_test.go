@@ -0,0 +1,188 @@
+package mockturbo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/turbonomic/turbo-api/pkg/api"
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+	"github.com/turbonomic/turbo-go-sdk/pkg/version"
+
+	"golang.org/x/net/websocket"
+)
+
+const testProbeType = "Kubernetes"
+
+// Connect to the server as a probe container, and register a probe.
+func connectProbe(t *testing.T, serverURL string) *websocket.Conn {
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(serverURL, "http")+MediationPath, "", "http://127.0.0.1")
+	if err != nil {
+		t.Fatalf("Failed to connect: %s", err)
+	}
+	protocolVersion := string(version.PROTOBUF_VERSION)
+	if err := send(ws, &version.NegotiationRequest{ProtocolVersion: &protocolVersion}); err != nil {
+		t.Fatalf("Failed to send negotiation request: %s", err)
+	}
+	answer := &version.NegotiationAnswer{}
+	if err := receive(ws, answer); err != nil || answer.GetNegotiationResult() != version.NegotiationAnswer_ACCEPTED {
+		t.Fatalf("Expected the protocol version accepted, got %v: %v", answer, err)
+	}
+
+	probeType, probeCategory, targetIdentifierField := testProbeType, "Cloud Native", "targetIdentifier"
+	containerInfo := &proto.ContainerInfo{
+		Probes: []*proto.ProbeInfo{{
+			ProbeType:             &probeType,
+			ProbeCategory:         &probeCategory,
+			TargetIdentifierField: &targetIdentifierField,
+		}},
+	}
+	if err := send(ws, containerInfo); err != nil {
+		t.Fatalf("Failed to send registration: %s", err)
+	}
+	if err := receive(ws, &proto.Ack{}); err != nil {
+		t.Fatalf("Failed to receive registration ack: %s", err)
+	}
+	return ws
+}
+
+func sendClientMessage(t *testing.T, ws *websocket.Conn, id int32, clientMsg *proto.MediationClientMessage) {
+	clientMsg.MessageID = &id
+	if err := send(ws, clientMsg); err != nil {
+		t.Errorf("Failed to send client message: %s", err)
+	}
+}
+
+// Answer the requests from the server like the SDK does.
+func serveProbe(t *testing.T, ws *websocket.Conn) {
+	for {
+		serverMsg := &proto.MediationServerMessage{}
+		if err := receive(ws, serverMsg); err != nil {
+			return
+		}
+		id := serverMsg.GetMessageID()
+		switch {
+		case serverMsg.GetValidationRequest() != nil:
+			sendClientMessage(t, ws, id, &proto.MediationClientMessage{
+				MediationClientMessage: &proto.MediationClientMessage_ValidationResponse{
+					ValidationResponse: &proto.ValidationResponse{},
+				},
+			})
+		case serverMsg.GetDiscoveryRequest() != nil:
+			entityType, entityId := proto.EntityDTO_VIRTUAL_MACHINE, "node-1"
+			for _, response := range []*proto.MediationClientMessage{
+				{MediationClientMessage: &proto.MediationClientMessage_KeepAlive{KeepAlive: &proto.KeepAlive{}}},
+				{MediationClientMessage: &proto.MediationClientMessage_DiscoveryResponse{
+					DiscoveryResponse: &proto.DiscoveryResponse{
+						EntityDTO: []*proto.EntityDTO{{EntityType: &entityType, Id: &entityId}},
+					},
+				}},
+				{MediationClientMessage: &proto.MediationClientMessage_DiscoveryResponse{
+					DiscoveryResponse: &proto.DiscoveryResponse{},
+				}},
+			} {
+				sendClientMessage(t, ws, id, response)
+			}
+		case serverMsg.GetActionRequest() != nil:
+			for _, progress := range []int32{0, 50} {
+				sendClientMessage(t, ws, id, &proto.MediationClientMessage{
+					MediationClientMessage: &proto.MediationClientMessage_ActionProgress{
+						ActionProgress: &proto.ActionProgress{Response: newActionResponse(
+							proto.ActionResponseState_IN_PROGRESS, progress)},
+					},
+				})
+			}
+			sendClientMessage(t, ws, id, &proto.MediationClientMessage{
+				MediationClientMessage: &proto.MediationClientMessage_ActionResponse{
+					ActionResponse: &proto.ActionResult{Response: newActionResponse(
+						proto.ActionResponseState_SUCCEEDED, 100)},
+				},
+			})
+		}
+	}
+}
+
+func newActionResponse(state proto.ActionResponseState, progress int32) *proto.ActionResponse {
+	description := state.String()
+	return &proto.ActionResponse{
+		ActionResponseState: &state,
+		Progress:            &progress,
+		ResponseDescription: &description,
+	}
+}
+
+func TestMediationServer(t *testing.T) {
+	server := NewMediationServer()
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := server.Discover(ctx, testProbeType, nil); err != errNotConnected {
+		t.Errorf("Expected discovery to fail before the probe is registered, got %v", err)
+	}
+
+	ws := connectProbe(t, httpServer.URL)
+	go serveProbe(t, ws)
+	if err := server.WaitForRegistration(ctx); err != nil {
+		t.Fatalf("Probe is not registered: %s", err)
+	}
+	if probes := server.Probes(); len(probes) != 1 || probes[0].GetProbeType() != testProbeType {
+		t.Errorf("Expected probe %s registered, got %v", testProbeType, probes)
+	}
+
+	target := &api.Target{
+		Type:        testProbeType,
+		InputFields: []*api.InputField{{Name: "targetIdentifier", Value: "cluster-1"}},
+	}
+	for i := 0; i < 2; i++ {
+		data, _ := json.Marshal(target)
+		resp, err := http.Post(httpServer.URL+TargetsPath, "application/json", bytes.NewReader(data))
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("Failed to add target: %v %v", resp, err)
+		}
+		resp.Body.Close()
+	}
+	targets := server.Targets()
+	if len(targets) != 1 {
+		t.Fatalf("Expected the target added once, got %d targets", len(targets))
+	}
+	accountValues := AccountValues(targets[0])
+
+	if _, err := server.Validate(ctx, testProbeType, accountValues); err != nil {
+		t.Errorf("Validation failed: %s", err)
+	}
+
+	discoveryResponse, err := server.Discover(ctx, testProbeType, accountValues)
+	if err != nil {
+		t.Errorf("Discovery failed: %s", err)
+	} else if len(discoveryResponse.GetEntityDTO()) != 1 {
+		t.Errorf("Expected the discovery response before the final empty one, got %v", discoveryResponse)
+	}
+
+	probeType, actionType := testProbeType, proto.ActionItemDTO_MOVE
+	actionRequest := &proto.ActionRequest{
+		ProbeType:          &probeType,
+		ActionExecutionDTO: &proto.ActionExecutionDTO{ActionType: &actionType},
+	}
+	var progresses []int32
+	result, err := server.ExecuteAction(ctx, actionRequest,
+		func(progress *proto.ActionProgress) {
+			progresses = append(progresses, progress.GetResponse().GetProgress())
+		})
+	if err != nil {
+		t.Errorf("Action failed: %s", err)
+	} else if len(progresses) != 2 || result.GetResponse().GetActionResponseState() != proto.ActionResponseState_SUCCEEDED {
+		t.Errorf("Expected 2 progresses and a succeeded result, got %v and %v", progresses, result)
+	}
+
+	ws.Close()
+	if _, err := server.Discover(ctx, testProbeType, accountValues); err == nil {
+		t.Errorf("Expected discovery to fail after the probe container disconnects")
+	}
+}
@@ -0,0 +1,260 @@
+package simulation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/golang/glog"
+)
+
+const (
+	coreGroupVersion       = "v1"
+	extensionsGroupVersion = "extensions/v1beta1"
+
+	// All the objects are served with the same resource version, as they never change.
+	fixtureResourceVersion = "1"
+)
+
+// An object served by the simulated API server.
+type storedObject struct {
+	name      string
+	namespace string
+	labels    labels.Set
+	fields    fields.Set
+	// The JSON representation of the object, with its kind and API version.
+	content map[string]interface{}
+}
+
+// The objects of a resource type, e.g. pods.
+type storedResource struct {
+	kind         string
+	groupVersion string
+	namespaced   bool
+	objects      []*storedObject
+}
+
+// apiServer is an http.RoundTripper serving the objects of a fixture as a read-only Kubernetes API server.
+// It supports get and list with label and field selectors. A watch never receives any event.
+type apiServer struct {
+	// <group_version/resource : objects>
+	resources map[string]*storedResource
+}
+
+func newAPIServer(fixture *Fixture) (*apiServer, error) {
+	s := &apiServer{resources: make(map[string]*storedResource)}
+
+	nodes := s.addResource(coreGroupVersion, "nodes", "Node", false)
+	for i := range fixture.Nodes {
+		if err := nodes.add(&fixture.Nodes[i].ObjectMeta, &fixture.Nodes[i], nil); err != nil {
+			return nil, err
+		}
+	}
+	pods := s.addResource(coreGroupVersion, "pods", "Pod", true)
+	for i := range fixture.Pods {
+		pod := &fixture.Pods[i]
+		podFields := fields.Set{
+			"spec.nodeName": pod.Spec.NodeName,
+			"status.phase":  string(pod.Status.Phase),
+		}
+		if err := pods.add(&pod.ObjectMeta, pod, podFields); err != nil {
+			return nil, err
+		}
+	}
+	services := s.addResource(coreGroupVersion, "services", "Service", true)
+	for i := range fixture.Services {
+		if err := services.add(&fixture.Services[i].ObjectMeta, &fixture.Services[i], nil); err != nil {
+			return nil, err
+		}
+	}
+	endpoints := s.addResource(coreGroupVersion, "endpoints", "Endpoints", true)
+	for i := range fixture.Endpoints {
+		if err := endpoints.add(&fixture.Endpoints[i].ObjectMeta, &fixture.Endpoints[i], nil); err != nil {
+			return nil, err
+		}
+	}
+	rcs := s.addResource(coreGroupVersion, "replicationcontrollers", "ReplicationController", true)
+	for i := range fixture.ReplicationControllers {
+		rc := &fixture.ReplicationControllers[i]
+		if err := rcs.add(&rc.ObjectMeta, rc, nil); err != nil {
+			return nil, err
+		}
+	}
+	rss := s.addResource(extensionsGroupVersion, "replicasets", "ReplicaSet", true)
+	for i := range fixture.ReplicaSets {
+		if err := rss.add(&fixture.ReplicaSets[i].ObjectMeta, &fixture.ReplicaSets[i], nil); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *apiServer) addResource(groupVersion, resource, kind string, namespaced bool) *storedResource {
+	r := &storedResource{
+		kind:         kind,
+		groupVersion: groupVersion,
+		namespaced:   namespaced,
+	}
+	s.resources[groupVersion+"/"+resource] = r
+	return r
+}
+
+func (r *storedResource) add(meta *metav1.ObjectMeta, obj interface{}, objFields fields.Set) error {
+	if r.namespaced && meta.Namespace == "" {
+		meta.Namespace = metav1.NamespaceDefault
+	}
+	if meta.UID == "" {
+		// Entities are identified by the UIDs of the objects, so make them unique and stable.
+		meta.UID = types.UID(fmt.Sprintf("%s-%s-%s", strings.ToLower(r.kind), meta.Namespace, meta.Name))
+	}
+	meta.ResourceVersion = fixtureResourceVersion
+
+	content, err := toContent(obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert %s %s/%s: %s", r.kind, meta.Namespace, meta.Name, err)
+	}
+	content["kind"] = r.kind
+	content["apiVersion"] = r.groupVersion
+
+	if objFields == nil {
+		objFields = fields.Set{}
+	}
+	objFields["metadata.name"] = meta.Name
+	if r.namespaced {
+		objFields["metadata.namespace"] = meta.Namespace
+	}
+	r.objects = append(r.objects, &storedObject{
+		name:      meta.Name,
+		namespace: meta.Namespace,
+		labels:    labels.Set(meta.Labels),
+		fields:    objFields,
+		content:   content,
+	})
+	return nil
+}
+
+func toContent(obj interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	content := make(map[string]interface{})
+	if err := json.Unmarshal(raw, &content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// Implement http.RoundTripper interface.
+func (s *apiServer) RoundTrip(req *http.Request) (*http.Response, error) {
+	glog.V(4).Infof("Simulated API server received %s %s", req.Method, req.URL)
+	if req.Method != http.MethodGet {
+		return newStatusResponse(req, http.StatusMethodNotAllowed, metav1.StatusReasonMethodNotAllowed,
+			fmt.Sprintf("%s is not supported by the simulated cluster", req.Method)), nil
+	}
+
+	groupVersion, segments, ok := splitAPIPath(req.URL.Path)
+	if !ok {
+		return newNotFoundResponse(req), nil
+	}
+	namespace := ""
+	if len(segments) >= 3 && segments[0] == "namespaces" {
+		namespace = segments[1]
+		segments = segments[2:]
+	}
+	if len(segments) == 0 || len(segments) > 2 {
+		return newNotFoundResponse(req), nil
+	}
+	resource, exist := s.resources[groupVersion+"/"+segments[0]]
+	if !exist {
+		return newNotFoundResponse(req), nil
+	}
+
+	if len(segments) == 2 {
+		for _, obj := range resource.objects {
+			if obj.namespace == namespace && obj.name == segments[1] {
+				return newJSONResponse(req, http.StatusOK, obj.content)
+			}
+		}
+		return newNotFoundResponse(req), nil
+	}
+
+	query := req.URL.Query()
+	labelSelector, err := labels.Parse(query.Get("labelSelector"))
+	if err != nil {
+		return newStatusResponse(req, http.StatusBadRequest, metav1.StatusReasonBadRequest, err.Error()), nil
+	}
+	fieldSelector, err := fields.ParseSelector(query.Get("fieldSelector"))
+	if err != nil {
+		return newStatusResponse(req, http.StatusBadRequest, metav1.StatusReasonBadRequest, err.Error()), nil
+	}
+	if watch := query.Get("watch"); watch == "true" || watch == "1" {
+		return newWatchResponse(req), nil
+	}
+
+	items := []interface{}{}
+	for _, obj := range resource.objects {
+		if namespace != "" && obj.namespace != namespace {
+			continue
+		}
+		if labelSelector.Matches(obj.labels) && fieldSelector.Matches(obj.fields) {
+			items = append(items, obj.content)
+		}
+	}
+	return newJSONResponse(req, http.StatusOK, map[string]interface{}{
+		"kind":       resource.kind + "List",
+		"apiVersion": resource.groupVersion,
+		"metadata":   map[string]interface{}{"resourceVersion": fixtureResourceVersion},
+		"items":      items,
+	})
+}
+
+// Split the path of a request into the group version and the remaining segments,
+// e.g. /api/v1/namespaces/default/pods into v1 and [namespaces default pods].
+func splitAPIPath(path string) (string, []string, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case len(segments) >= 2 && segments[0] == "api":
+		return segments[1], segments[2:], true
+	case len(segments) >= 3 && segments[0] == "apis":
+		return segments[1] + "/" + segments[2], segments[3:], true
+	}
+	return "", nil, false
+}
+
+func newNotFoundResponse(req *http.Request) *http.Response {
+	return newStatusResponse(req, http.StatusNotFound, metav1.StatusReasonNotFound,
+		fmt.Sprintf("%s is not found in the simulated cluster", req.URL.Path))
+}
+
+func newStatusResponse(req *http.Request, code int, reason metav1.StatusReason, message string) *http.Response {
+	status := &metav1.Status{
+		TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: coreGroupVersion},
+		Status:   metav1.StatusFailure,
+		Message:  message,
+		Reason:   reason,
+		Code:     int32(code),
+	}
+	// A status can always be marshalled.
+	resp, _ := newJSONResponse(req, code, status)
+	return resp
+}
+
+// The watch stream stays open without any event, until the watcher is stopped or the request is cancelled.
+func newWatchResponse(req *http.Request) *http.Response {
+	reader, writer := io.Pipe()
+	if done := req.Context().Done(); done != nil {
+		go func() {
+			<-done
+			writer.Close()
+		}()
+	}
+	return newResponse(req, http.StatusOK, reader)
+}
@@ -0,0 +1,114 @@
+package simulation
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	api "k8s.io/client-go/pkg/api/v1"
+	extensions "k8s.io/client-go/pkg/apis/extensions/v1beta1"
+
+	"github.com/ghodss/yaml"
+)
+
+const (
+	// The UID of the default/kubernetes service added if the fixture has none. It identifies the simulated cluster.
+	defaultClusterUID = "simulated-cluster"
+)
+
+// Fixture is a simulated cluster: the objects served by the API server and the metrics served by the kubelets.
+type Fixture struct {
+	Nodes                  []api.Node                  `json:"nodes"`
+	Pods                   []api.Pod                   `json:"pods"`
+	Services               []api.Service               `json:"services,omitempty"`
+	Endpoints              []api.Endpoints             `json:"endpoints,omitempty"`
+	ReplicationControllers []api.ReplicationController `json:"replicationControllers,omitempty"`
+	ReplicaSets            []extensions.ReplicaSet     `json:"replicaSets,omitempty"`
+
+	Metrics Metrics `json:"metrics,omitempty"`
+}
+
+// The resource usage reported by the kubelets.
+type Metrics struct {
+	// <node_name : metrics>
+	Nodes map[string]NodeMetrics `json:"nodes,omitempty"`
+	// <namespace/pod_name/container_name : metrics>
+	Containers map[string]ContainerMetrics `json:"containers,omitempty"`
+}
+
+type NodeMetrics struct {
+	CPUFrequencyMHz  float64 `json:"cpuFrequencyMHz"`
+	CPUUsageCores    float64 `json:"cpuUsageCores"`
+	MemoryUsageBytes uint64  `json:"memoryUsageBytes"`
+	// The capacity and the usage of the root filesystem.
+	FsCapacityBytes uint64 `json:"fsCapacityBytes,omitempty"`
+	FsUsedBytes     uint64 `json:"fsUsedBytes,omitempty"`
+}
+
+type ContainerMetrics struct {
+	CPUUsageCores    float64 `json:"cpuUsageCores"`
+	MemoryUsageBytes uint64  `json:"memoryUsageBytes"`
+}
+
+// Load the fixture from a YAML or JSON file.
+func LoadFixture(path string) (*Fixture, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture %s: %s", path, err)
+	}
+	fixture := &Fixture{}
+	if err := yaml.Unmarshal(content, fixture); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture %s: %s", path, err)
+	}
+	if err := fixture.validate(); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %s", path, err)
+	}
+	fixture.addKubernetesService()
+	return fixture, nil
+}
+
+func (f *Fixture) validate() error {
+	if len(f.Nodes) == 0 {
+		return fmt.Errorf("no node is defined")
+	}
+	nodes := make(map[string]bool)
+	for _, node := range f.Nodes {
+		if node.Name == "" {
+			return fmt.Errorf("a node has no name")
+		}
+		if nodes[node.Name] {
+			return fmt.Errorf("node %s is defined more than once", node.Name)
+		}
+		nodes[node.Name] = true
+	}
+	for name := range f.Metrics.Nodes {
+		if !nodes[name] {
+			return fmt.Errorf("metrics are defined for unknown node %s", name)
+		}
+	}
+	for _, pod := range f.Pods {
+		if pod.Name == "" || pod.Namespace == "" {
+			return fmt.Errorf("pod %s/%s has no name or namespace", pod.Namespace, pod.Name)
+		}
+		if pod.Spec.NodeName != "" && !nodes[pod.Spec.NodeName] {
+			return fmt.Errorf("pod %s/%s is on unknown node %s", pod.Namespace, pod.Name, pod.Spec.NodeName)
+		}
+	}
+	return nil
+}
+
+// Discovery identifies the cluster by the UID of the default/kubernetes service, so add one if it is missing.
+func (f *Fixture) addKubernetesService() {
+	for _, svc := range f.Services {
+		if svc.Namespace == metav1.NamespaceDefault && svc.Name == "kubernetes" {
+			return
+		}
+	}
+	f.Services = append(f.Services, api.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kubernetes",
+			Namespace: metav1.NamespaceDefault,
+			UID:       defaultClusterUID,
+		},
+	})
+}
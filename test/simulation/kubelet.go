@@ -0,0 +1,161 @@
+package simulation
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	api "k8s.io/client-go/pkg/api/v1"
+	stats "k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
+
+	"github.com/golang/glog"
+	cadvisorapi "github.com/google/cadvisor/info/v1"
+)
+
+const (
+	// The CPU frequency of a node whose frequency is not in the fixture metrics.
+	defaultCPUFrequencyMHz float64 = 2000
+
+	specPath    = "/spec"
+	summaryPath = "/stats/summary"
+)
+
+// kubelets is an http.RoundTripper serving the /spec and /stats/summary responses of the kubelets of the simulated
+// nodes. A request is routed to a node by the InternalIP address of the node.
+type kubelets struct {
+	fixture *Fixture
+	// <internal_ip : node>
+	nodes map[string]*api.Node
+	// <node_name : pods on the node>
+	pods map[string][]*api.Pod
+}
+
+func newKubelets(fixture *Fixture) *kubelets {
+	k := &kubelets{
+		fixture: fixture,
+		nodes:   make(map[string]*api.Node),
+		pods:    make(map[string][]*api.Pod),
+	}
+	for i := range fixture.Nodes {
+		node := &fixture.Nodes[i]
+		for _, addr := range node.Status.Addresses {
+			if addr.Type == api.NodeInternalIP && addr.Address != "" {
+				k.nodes[addr.Address] = node
+			}
+		}
+	}
+	for i := range fixture.Pods {
+		pod := &fixture.Pods[i]
+		if pod.Spec.NodeName != "" {
+			k.pods[pod.Spec.NodeName] = append(k.pods[pod.Spec.NodeName], pod)
+		}
+	}
+	return k
+}
+
+// Implement http.RoundTripper interface.
+func (k *kubelets) RoundTrip(req *http.Request) (*http.Response, error) {
+	glog.V(4).Infof("Simulated kubelet received %s %s", req.Method, req.URL)
+	host := req.URL.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	node, exist := k.nodes[host]
+	if !exist {
+		// Like an unreachable kubelet.
+		return nil, fmt.Errorf("no simulated node has the address %s", host)
+	}
+
+	switch strings.TrimSuffix(req.URL.Path, "/") {
+	case specPath:
+		return newJSONResponse(req, http.StatusOK, k.machineInfo(node))
+	case summaryPath:
+		return newJSONResponse(req, http.StatusOK, k.summary(node))
+	}
+	return newResponse(req, http.StatusNotFound, http.NoBody), nil
+}
+
+func (k *kubelets) machineInfo(node *api.Node) *cadvisorapi.MachineInfo {
+	frequencyMHz := k.fixture.Metrics.Nodes[node.Name].CPUFrequencyMHz
+	if frequencyMHz <= 0 {
+		frequencyMHz = defaultCPUFrequencyMHz
+	}
+	machineInfo := &cadvisorapi.MachineInfo{
+		CpuFrequency: uint64(frequencyMHz * 1000),
+		MachineID:    node.Status.NodeInfo.MachineID,
+		SystemUUID:   node.Status.NodeInfo.SystemUUID,
+	}
+	if cpu, exist := node.Status.Capacity[api.ResourceCPU]; exist {
+		machineInfo.NumCores = int(cpu.Value())
+	}
+	if memory, exist := node.Status.Capacity[api.ResourceMemory]; exist {
+		machineInfo.MemoryCapacity = uint64(memory.Value())
+	}
+	return machineInfo
+}
+
+func (k *kubelets) summary(node *api.Node) *stats.Summary {
+	now := metav1.Now()
+	nodeMetrics := k.fixture.Metrics.Nodes[node.Name]
+	summary := &stats.Summary{
+		Node: stats.NodeStats{
+			NodeName:  node.Name,
+			StartTime: node.CreationTimestamp,
+			CPU:       newCPUStats(now, nodeMetrics.CPUUsageCores),
+			Memory:    newMemoryStats(now, nodeMetrics.MemoryUsageBytes),
+		},
+	}
+	if nodeMetrics.FsCapacityBytes > 0 {
+		capacity, used := nodeMetrics.FsCapacityBytes, nodeMetrics.FsUsedBytes
+		available := capacity - used
+		if used > capacity {
+			available = 0
+		}
+		summary.Node.Fs = &stats.FsStats{
+			Time:           now,
+			CapacityBytes:  &capacity,
+			UsedBytes:      &used,
+			AvailableBytes: &available,
+		}
+	}
+
+	for _, pod := range k.pods[node.Name] {
+		podStats := stats.PodStats{
+			PodRef: stats.PodReference{
+				Name:      pod.Name,
+				Namespace: pod.Namespace,
+				UID:       string(pod.UID),
+			},
+			StartTime: pod.CreationTimestamp,
+		}
+		for _, container := range pod.Spec.Containers {
+			containerMetrics := k.fixture.Metrics.Containers[pod.Namespace+"/"+pod.Name+"/"+container.Name]
+			podStats.Containers = append(podStats.Containers, stats.ContainerStats{
+				Name:      container.Name,
+				StartTime: pod.CreationTimestamp,
+				CPU:       newCPUStats(now, containerMetrics.CPUUsageCores),
+				Memory:    newMemoryStats(now, containerMetrics.MemoryUsageBytes),
+			})
+		}
+		summary.Pods = append(summary.Pods, podStats)
+	}
+	return summary
+}
+
+func newCPUStats(now metav1.Time, usageCores float64) *stats.CPUStats {
+	usageNanoCores := uint64(usageCores * 1e9)
+	return &stats.CPUStats{
+		Time:           now,
+		UsageNanoCores: &usageNanoCores,
+	}
+}
+
+func newMemoryStats(now metav1.Time, usageBytes uint64) *stats.MemoryStats {
+	return &stats.MemoryStats{
+		Time:            now,
+		UsageBytes:      &usageBytes,
+		WorkingSetBytes: &usageBytes,
+	}
+}
@@ -0,0 +1,34 @@
+package simulation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+func newJSONResponse(req *http.Request, code int, value interface{}) (*http.Response, error) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the response to %s: %s", req.URL, err)
+	}
+	resp := newResponse(req, code, ioutil.NopCloser(bytes.NewReader(body)))
+	resp.ContentLength = int64(len(body))
+	return resp, nil
+}
+
+func newResponse(req *http.Request, code int, body io.ReadCloser) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		StatusCode:    code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          body,
+		ContentLength: -1,
+		Request:       req,
+	}
+}
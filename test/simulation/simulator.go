@@ -0,0 +1,43 @@
+package simulation
+
+import (
+	"net/http"
+
+	restclient "k8s.io/client-go/rest"
+)
+
+const (
+	// The address of the simulated API server. Requests never leave the process.
+	simulatedAPIServerHost = "http://simulated-apiserver"
+)
+
+// Simulator serves a cluster defined by a fixture, through an in-process API server and kubelets.
+type Simulator struct {
+	apiServer *apiServer
+	kubelets  *kubelets
+}
+
+func NewSimulator(fixture *Fixture) (*Simulator, error) {
+	// The API server completes the fixture objects, e.g. their UIDs, which are then reported by the kubelets.
+	apiServer, err := newAPIServer(fixture)
+	if err != nil {
+		return nil, err
+	}
+	return &Simulator{
+		apiServer: apiServer,
+		kubelets:  newKubelets(fixture),
+	}, nil
+}
+
+// The config of the clients to access the simulated API server.
+func (s *Simulator) KubeConfig() *restclient.Config {
+	return &restclient.Config{
+		Host:      simulatedAPIServerHost,
+		Transport: s.apiServer,
+	}
+}
+
+// The transport to access the simulated kubelets.
+func (s *Simulator) KubeletTransport() http.RoundTripper {
+	return s.kubelets
+}
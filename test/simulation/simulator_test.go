@@ -0,0 +1,125 @@
+package simulation
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	client "k8s.io/client-go/kubernetes"
+	api "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/turbonomic/kubeturbo/pkg/discovery"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/configs"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/kubelet"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/monitoring/master"
+	"github.com/turbonomic/kubeturbo/pkg/discovery/stitching"
+
+	"github.com/turbonomic/turbo-go-sdk/pkg/proto"
+)
+
+const testFixture = "testdata/cluster.yaml"
+
+func newTestSimulator(t *testing.T) (*Simulator, *client.Clientset) {
+	fixture, err := LoadFixture(testFixture)
+	if err != nil {
+		t.Fatalf("Failed to load fixture: %s", err)
+	}
+	simulator, err := NewSimulator(fixture)
+	if err != nil {
+		t.Fatalf("Failed to create simulator: %s", err)
+	}
+	kubeClient, err := client.NewForConfig(simulator.KubeConfig())
+	if err != nil {
+		t.Fatalf("Failed to create kubeClient: %s", err)
+	}
+	return simulator, kubeClient
+}
+
+func TestAPIServer(t *testing.T) {
+	_, kubeClient := newTestSimulator(t)
+
+	table := []struct {
+		namespace string
+		opts      metav1.ListOptions
+		expected  int
+	}{
+		{api.NamespaceAll, metav1.ListOptions{}, 2},
+		{"shop", metav1.ListOptions{LabelSelector: "app=web"}, 2},
+		{api.NamespaceAll, metav1.ListOptions{FieldSelector: "spec.nodeName=node-2"}, 1},
+		{api.NamespaceAll, metav1.ListOptions{LabelSelector: "app=db"}, 0},
+		{metav1.NamespaceDefault, metav1.ListOptions{}, 0},
+	}
+	for i, item := range table {
+		podList, err := kubeClient.CoreV1().Pods(item.namespace).List(item.opts)
+		if err != nil {
+			t.Errorf("Test case %d failed: %s", i, err)
+			continue
+		}
+		if len(podList.Items) != item.expected {
+			t.Errorf("Test case %d failed: expected %d pods, got %d", i, item.expected, len(podList.Items))
+		}
+	}
+
+	svc, err := kubeClient.CoreV1().Services(metav1.NamespaceDefault).Get("kubernetes", metav1.GetOptions{})
+	if err != nil || svc.UID != defaultClusterUID {
+		t.Errorf("Expected the default kubernetes service, got %+v: %v", svc, err)
+	}
+	if _, err := kubeClient.CoreV1().Nodes().Get("node-3", metav1.GetOptions{}); err == nil {
+		t.Errorf("Expected an error getting an unknown node")
+	}
+}
+
+func TestDiscoverSimulatedCluster(t *testing.T) {
+	simulator, kubeClient := newTestSimulator(t)
+	kubeConfig := simulator.KubeConfig()
+
+	clusterMonitoringConfig, err := master.NewClusterMonitorConfig(kubeConfig)
+	if err != nil {
+		t.Fatalf("Failed to create cluster monitoring config: %s", err)
+	}
+	probeConfig := &configs.ProbeConfig{
+		StitchingPropertyType: stitching.IP,
+		MonitoringConfigs: []monitoring.MonitorWorkerConfig{
+			kubelet.NewKubeletMonitorConfig(kubeConfig).WithTransport(simulator.KubeletTransport()),
+			clusterMonitoringConfig,
+		},
+		UsagePercentile:          100,
+		MaxFailedNodeFraction:    0,
+		DefaultMonitoringTimeout: configs.DefaultMonitoringTimeout,
+	}
+	targetConfig := configs.NewK8sTargetConfig("Cloud Native", "Kubernetes", "simulation", "", "")
+	if err := targetConfig.ValidateK8sTargetConfig(); err != nil {
+		t.Fatalf("Invalid target config: %s", err)
+	}
+	discoveryConfig := discovery.NewDiscoveryConfig(kubeClient, probeConfig, targetConfig)
+	defer close(discoveryConfig.StopEverything)
+
+	discoveryClient, err := discovery.NewK8sDiscoveryClient(discoveryConfig)
+	if err != nil {
+		t.Fatalf("Failed to create discovery client: %s", err)
+	}
+	response, err := discoveryClient.Discover(nil)
+	if err != nil {
+		t.Fatalf("Failed to discover the simulated cluster: %s", err)
+	}
+	if len(response.GetErrorDTO()) > 0 {
+		t.Errorf("Unexpected discovery errors: %+v", response.GetErrorDTO())
+	}
+
+	count := make(map[proto.EntityDTO_EntityType]int)
+	for _, entityDTO := range response.GetEntityDTO() {
+		count[entityDTO.GetEntityType()]++
+	}
+	expected := map[proto.EntityDTO_EntityType]int{
+		proto.EntityDTO_VIRTUAL_MACHINE:     2,
+		proto.EntityDTO_CONTAINER_POD:       2,
+		proto.EntityDTO_CONTAINER:           2,
+		proto.EntityDTO_APPLICATION:         2,
+		proto.EntityDTO_VIRTUAL_APPLICATION: 1,
+	}
+	for entityType, n := range expected {
+		if count[entityType] != n {
+			t.Errorf("Expected %d %s entities, got %d", n, entityType, count[entityType])
+		}
+	}
+}